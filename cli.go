@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/core"
+	"github.com/vrsandeep/mango-go/internal/store"
+)
+
+const cliUsage = `Usage:
+  mango-go                                   Start the server
+  mango-go user reset-password [flags] USER  Set a new password for USER
+
+Flags for reset-password:
+`
+
+// runCommand runs a command-line subcommand and returns the process exit code.
+func runCommand(args []string) int {
+	fs := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	password := fs.String("password", "", "the new password (default: generate one)")
+	disable2FA := fs.Bool("disable-2fa", false, "also turn off two-factor authentication")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), cliUsage)
+		fs.PrintDefaults()
+	}
+
+	if len(args) < 2 || args[0] != "user" || args[1] != "reset-password" {
+		fs.Usage()
+		return 2
+	}
+	if err := fs.Parse(args[2:]); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	username := fs.Arg(0)
+
+	app, err := core.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer app.Close()
+
+	newPassword, err := resetUserPassword(store.New(app.DB()), username, *password, *disable2FA)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Password for %q has been reset and all of its sessions logged out.\n", username)
+	if *password == "" {
+		fmt.Printf("New password: %s\n", newPassword)
+	}
+	return 0
+}
+
+// resetUserPassword gives a user a new password (generating one if password is empty), lifts
+// any login lockout and logs out all of the user's sessions, so an admin who is locked out can
+// get back in from the server's console.
+func resetUserPassword(st *store.Store, username, password string, disable2FA bool) (string, error) {
+	user, err := st.GetUserByUsername(username)
+	if err != nil {
+		return "", fmt.Errorf("user %q not found", username)
+	}
+	if password == "" {
+		password = generateRandomPassword(16)
+	}
+
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return "", err
+	}
+	if err := st.UpdateUserPassword(user.ID, passwordHash); err != nil {
+		return "", err
+	}
+	// The KOReader sync key is derived from the password, so the old one must stop working too.
+	keyHash, err := auth.HashKOSyncKey(password)
+	if err != nil {
+		return "", err
+	}
+	if err := st.UpdateUserKOSyncKey(user.ID, keyHash); err != nil {
+		return "", err
+	}
+	if err := st.ResetLoginFailures(user.ID); err != nil {
+		return "", err
+	}
+	if _, err := st.DeleteUserSessions(user.ID); err != nil {
+		return "", err
+	}
+	if disable2FA {
+		if err := st.DisableUserTOTP(user.ID); err != nil {
+			return "", err
+		}
+	}
+	return password, nil
+}
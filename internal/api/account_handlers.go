@@ -0,0 +1,168 @@
+package api
+
+// This file lets users manage their own account, and lets admins hand out one-time password
+// reset links.
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/store"
+)
+
+// passwordResetTokenLifetime is how long an admin-issued reset link stays valid.
+const passwordResetTokenLifetime = 24 * time.Hour
+
+// setPassword stores a new password for a user, along with the KOReader sync key derived from it.
+func (s *Server) setPassword(userID int64, password string) error {
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.store.UpdateUserPassword(userID, passwordHash); err != nil {
+		return err
+	}
+	s.setKOSyncKey(userID, password)
+	return nil
+}
+
+// handleUpdateMe changes the current user's username and/or password. Both need the current
+// password. Changing the password logs out the user's other sessions.
+func (s *Server) handleUpdateMe(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	var payload struct {
+		Username        string `json:"username"`
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	payload.Username = strings.TrimSpace(payload.Username)
+	if payload.Username == "" && payload.NewPassword == "" {
+		RespondWithError(w, http.StatusBadRequest, "Nothing to update")
+		return
+	}
+	if payload.Username != "" {
+		if problem := usernameProblem(payload.Username); problem != "" {
+			RespondWithError(w, http.StatusBadRequest, problem)
+			return
+		}
+	}
+
+	// The context user does not carry the password hash when authenticated by some methods.
+	current, err := s.store.GetUserByID(user.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+	if s.accountLoginBlocked(w, current.ID) {
+		return
+	}
+	if !auth.CheckPasswordHash(payload.CurrentPassword, current.PasswordHash) {
+		s.recordFailedLogin(r, current.ID, current.Username)
+		RespondWithError(w, http.StatusForbidden, "Current password is incorrect")
+		return
+	}
+
+	if payload.Username != "" && payload.Username != current.Username {
+		if _, err := s.store.GetUserByUsername(payload.Username); err == nil {
+			RespondWithError(w, http.StatusConflict, "Username already exists")
+			return
+		}
+		if err := s.store.UpdateUser(current.ID, payload.Username, current.Role); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Failed to update user")
+			return
+		}
+	}
+
+	if payload.NewPassword != "" {
+		if err := s.setPassword(current.ID, payload.NewPassword); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Failed to update password")
+			return
+		}
+		if _, err := s.store.DeleteOtherUserSessions(current.ID, s.currentSessionID(r)); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Failed to log out other sessions")
+			return
+		}
+	}
+
+	updated, err := s.store.GetUserByID(current.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to load user")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, updated)
+}
+
+// handleAdminCreatePasswordReset issues a one-time link the user can open to choose a new
+// password. Any earlier link for the user stops working.
+func (s *Server) handleAdminCreatePasswordReset(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	target, err := s.store.GetUserByID(userID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if !canManageUser(w, r, target) {
+		return
+	}
+	token, expiresAt, err := s.store.CreatePasswordResetToken(userID, passwordResetTokenLifetime)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to create reset token")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"token":      token,
+		"reset_url":  "/reset-password?token=" + token,
+		"expires_at": expiresAt,
+	})
+}
+
+// handleResetPassword sets a new password with a reset token. It logs the user out everywhere
+// and lifts any lockout.
+func (s *Server) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if payload.NewPassword == "" {
+		RespondWithError(w, http.StatusBadRequest, "A new password is required")
+		return
+	}
+	userID, err := s.store.ConsumePasswordResetToken(payload.Token)
+	if err != nil {
+		if errors.Is(err, store.ErrPasswordResetTokenInvalid) {
+			RespondWithError(w, http.StatusBadRequest, "This reset link is invalid or has expired")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Failed to check reset token")
+		return
+	}
+
+	if err := s.setPassword(userID, payload.NewPassword); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to update password")
+		return
+	}
+	if _, err := s.store.DeleteUserSessions(userID); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to log out old sessions")
+		return
+	}
+	s.resetLoginFailures(userID)
+	w.WriteHeader(http.StatusNoContent)
+}
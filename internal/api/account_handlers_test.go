@@ -0,0 +1,109 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestAccountHandlers(t *testing.T) {
+	server, _, _ := testutil.SetupTestServer(t)
+	router := server.Router()
+	adminCookie := testutil.CookieForUser(t, server, "boss", "password", "admin")
+	cookie := testutil.CookieForUser(t, server, "reader", "password", "user")
+
+	do := func(method, path, body string, c *http.Cookie) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		if c != nil {
+			req.AddCookie(c)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	login := func(username, password string) int {
+		body := fmt.Sprintf(`{"username":%q,"password":%q}`, username, password)
+		return do("POST", "/api/users/login", body, nil).Code
+	}
+	rr := do("POST", "/api/users/login", `{"username":"reader","password":"password"}`, nil)
+	otherSession := rr.Result().Cookies()[0]
+
+	t.Run("Change Password", func(t *testing.T) {
+		if rr := do("PUT", "/api/users/me", `{"current_password":"wrong","new_password":"secret"}`, cookie); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 with a wrong current password, got %d", rr.Code)
+		}
+		if rr := do("PUT", "/api/users/me", `{"current_password":"password"}`, cookie); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 with nothing to change, got %d", rr.Code)
+		}
+
+		rr := do("PUT", "/api/users/me", `{"current_password":"password","new_password":"secret"}`, cookie)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+		}
+		if login("reader", "password") != http.StatusUnauthorized || login("reader", "secret") != http.StatusOK {
+			t.Error("Expected only the new password to work")
+		}
+		if rr := do("GET", "/api/users/me", "", cookie); rr.Code != http.StatusOK {
+			t.Errorf("Expected the current session to stay logged in, got %d", rr.Code)
+		}
+		if rr := do("GET", "/api/users/me", "", otherSession); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected other sessions to be logged out, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Change Username", func(t *testing.T) {
+		long := `{"current_password":"secret","username":"` + strings.Repeat("a", 65) + `"}`
+		if rr := do("PUT", "/api/users/me", long, cookie); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a too long username, got %d", rr.Code)
+		}
+		if rr := do("PUT", "/api/users/me", `{"current_password":"secret","username":"boss"}`, cookie); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for a taken username, got %d", rr.Code)
+		}
+		rr := do("PUT", "/api/users/me", `{"current_password":"secret","username":"reader2"}`, cookie)
+		var user map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &user)
+		if rr.Code != http.StatusOK || user["username"] != "reader2" {
+			t.Fatalf("Expected the username to change, got %d %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Admin Reset Link", func(t *testing.T) {
+		user, _ := server.Store().GetUserByUsername("reader2")
+		path := fmt.Sprintf("/api/admin/users/%d/password-reset", user.ID)
+		if rr := do("POST", path, "", cookie); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected non-admins to be forbidden, got %d", rr.Code)
+		}
+		rr := do("POST", path, "", adminCookie)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d", rr.Code)
+		}
+		var reset struct {
+			Token    string `json:"token"`
+			ResetURL string `json:"reset_url"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &reset)
+		if reset.Token == "" || reset.ResetURL != "/reset-password?token="+reset.Token {
+			t.Fatalf("Unexpected reset response %s", rr.Body.String())
+		}
+
+		body := fmt.Sprintf(`{"token":%q,"new_password":"fresh"}`, reset.Token)
+		if rr := do("POST", "/api/users/reset-password", body, nil); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected 204, got %d %s", rr.Code, rr.Body.String())
+		}
+		if login("reader2", "fresh") != http.StatusOK {
+			t.Error("Expected the new password to work")
+		}
+		if rr := do("GET", "/api/users/me", "", cookie); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected existing sessions to be logged out, got %d", rr.Code)
+		}
+		if rr := do("POST", "/api/users/reset-password", body, nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected a used token to be rejected, got %d", rr.Code)
+		}
+	})
+}
@@ -18,7 +18,9 @@ func (s *Server) handleGetVersion(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 	config := s.app.Config()
 	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"library_path": config.Library.Path,
+		"library_path":      config.Library.Path,
+		"oidc_enabled":      config.OIDC.Enabled(),
+		"registration_open": config.Registration.Open,
 	})
 }
 
@@ -47,6 +49,47 @@ func (s *Server) handleGetAdminJobsStatus(w http.ResponseWriter, r *http.Request
 	RespondWithJSON(w, http.StatusOK, statuses)
 }
 
+// handleWebSocket upgrades an authenticated request to a progress websocket. Job events are
+// only delivered to users who can run jobs, and downloader events to users who manage downloads.
+// The user's role is re-read periodically, so changed permissions reach the open socket, and it
+// is closed once the session or API token it was opened with, or the user, is gone.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	userID := user.ID
+	credentialValid := s.credentialCheck(r)
+	s.app.WsHub().ServeWs(w, r, user.Permissions, func() ([]string, bool) {
+		if !credentialValid() {
+			return nil, false
+		}
+		current, err := s.store.GetUserByID(userID)
+		if err != nil {
+			return nil, false
+		}
+		return current.Permissions, true
+	})
+}
+
+// credentialCheck returns a function reporting whether the API token or session a request was
+// authenticated with still exists. Requests authenticated by a trusted proxy have neither, so
+// only their user is checked.
+func (s *Server) credentialCheck(r *http.Request) func() bool {
+	if token, ok := bearerToken(r); ok {
+		return func() bool {
+			_, _, err := s.store.GetUserFromAPIToken(token)
+			return err == nil
+		}
+	}
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if _, err := s.store.GetSession(cookie.Value); err == nil {
+			return func() bool {
+				_, err := s.store.GetSession(cookie.Value)
+				return err == nil
+			}
+		}
+	}
+	return func() bool { return true }
+}
+
 // handleGetBadFiles retrieves all bad chapter-file records from the database.
 func (s *Server) handleGetBadFiles(w http.ResponseWriter, r *http.Request) {
 	badFileStore := store.NewBadFileStore(s.app.DB())
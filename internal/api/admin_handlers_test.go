@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/vrsandeep/mango-go/internal/jobs"
 	"github.com/vrsandeep/mango-go/internal/testutil"
 )
@@ -108,3 +110,34 @@ func TestAdminHandlers(t *testing.T) {
 		}
 	})
 }
+
+func TestProgressWebSocketAuth(t *testing.T) {
+	server, _, _ := testutil.SetupTestServer(t)
+	ts := httptest.NewServer(server.Router())
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws/admin/progress"
+	cookie := testutil.CookieForUser(t, server, "reader", "password", "user")
+
+	dial := func(header http.Header) (*http.Response, error) {
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+		if conn != nil {
+			conn.Close()
+		}
+		return resp, err
+	}
+
+	resp, err := dial(nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a session, got %v (%v)", resp, err)
+	}
+
+	if _, err := dial(http.Header{"Cookie": {cookie.String()}}); err != nil {
+		t.Errorf("Expected an authenticated user to connect, got %v", err)
+	}
+
+	// Other sites cannot open the socket with the user's cookie
+	resp, err = dial(http.Header{"Cookie": {cookie.String()}, "Origin": {"https://evil.example"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected a cross-origin upgrade to be rejected, got %v (%v)", resp, err)
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+)
+
+// roleNamePattern limits role names to short lowercase identifiers.
+var roleNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+type rolePayload struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// decodeRolePayload reads a role from the request body and checks its permissions.
+func decodeRolePayload(w http.ResponseWriter, r *http.Request) (*rolePayload, bool) {
+	var payload rolePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return nil, false
+	}
+	for _, p := range payload.Permissions {
+		if !models.IsValidPermission(p) {
+			RespondWithError(w, http.StatusBadRequest, "Unknown permission: "+p)
+			return nil, false
+		}
+	}
+	return &payload, true
+}
+
+// canGrantPermissions checks that the user making the request holds every one of perms, so
+// managing roles cannot be used to gain permissions. It answers the request if not.
+func canGrantPermissions(w http.ResponseWriter, r *http.Request, perms []string) bool {
+	if !getUserFromContext(r).HasAllPermissions(perms...) {
+		RespondWithError(w, http.StatusForbidden, "You cannot grant permissions you do not have")
+		return false
+	}
+	return true
+}
+
+// canGrantRole checks that the user making the request may give someone the named role: the
+// admin role only if they are an admin themselves, and other roles only if they hold all of the
+// role's permissions. It answers the request if not.
+func (s *Server) canGrantRole(w http.ResponseWriter, r *http.Request, name string) bool {
+	if name == models.RoleAdmin && getUserFromContext(r).Role != models.RoleAdmin {
+		RespondWithError(w, http.StatusForbidden, "Only admins can grant the admin role")
+		return false
+	}
+	role, err := s.store.GetRole(name)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "A valid role is required")
+		return false
+	}
+	return canGrantPermissions(w, r, role.Permissions)
+}
+
+// removesLastAdmin reports whether taking the admin role from user, by changing their role or
+// deleting them, would leave no admins, answering the request if so.
+func (s *Server) removesLastAdmin(w http.ResponseWriter, user *models.User) bool {
+	if user.Role != models.RoleAdmin {
+		return false
+	}
+	admins, err := s.store.GetRole(models.RoleAdmin)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to count admins")
+		return true
+	}
+	if admins.UserCount <= 1 {
+		RespondWithError(w, http.StatusConflict, "The last admin cannot be removed")
+		return true
+	}
+	return false
+}
+
+// handleAdminListPermissions lists the permissions that can be granted to roles.
+func (s *Server) handleAdminListPermissions(w http.ResponseWriter, r *http.Request) {
+	RespondWithJSON(w, http.StatusOK, models.AllPermissions)
+}
+
+func (s *Server) handleAdminListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := s.store.ListRoles()
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve roles")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, roles)
+}
+
+func (s *Server) handleAdminCreateRole(w http.ResponseWriter, r *http.Request) {
+	payload, ok := decodeRolePayload(w, r)
+	if !ok {
+		return
+	}
+	if !roleNamePattern.MatchString(payload.Name) {
+		RespondWithError(w, http.StatusBadRequest, "Role names must be 1-32 lowercase letters, digits, '-' or '_'")
+		return
+	}
+	if !canGrantPermissions(w, r, payload.Permissions) {
+		return
+	}
+
+	role, err := s.store.CreateRole(payload.Name, payload.Description, payload.Permissions)
+	if err != nil {
+		if errors.Is(err, store.ErrRoleExists) {
+			RespondWithError(w, http.StatusConflict, "A role with this name already exists")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Failed to create role")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, role)
+}
+
+func (s *Server) handleAdminUpdateRole(w http.ResponseWriter, r *http.Request) {
+	payload, ok := decodeRolePayload(w, r)
+	if !ok {
+		return
+	}
+
+	name := chi.URLParam(r, "roleName")
+	// The role's current permissions count too, or they could be taken from its users.
+	if current, err := s.store.GetRole(name); err == nil && !canGrantPermissions(w, r, append(current.Permissions, payload.Permissions...)) {
+		return
+	}
+	if err := s.store.UpdateRole(name, payload.Description, payload.Permissions); err != nil {
+		switch {
+		case errors.Is(err, store.ErrRoleNotFound):
+			RespondWithError(w, http.StatusNotFound, "Role not found")
+		case errors.Is(err, store.ErrBuiltInRole):
+			RespondWithError(w, http.StatusBadRequest, "The admin role cannot be changed")
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "Failed to update role")
+		}
+		return
+	}
+
+	role, err := s.store.GetRole(name)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve role")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, role)
+}
+
+func (s *Server) handleAdminDeleteRole(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "roleName")
+	// Deleting a role also deletes the invites to it, so it counts as managing its permissions.
+	if current, err := s.store.GetRole(name); err == nil && !canGrantPermissions(w, r, current.Permissions) {
+		return
+	}
+	if err := s.store.DeleteRole(name); err != nil {
+		switch {
+		case errors.Is(err, store.ErrRoleNotFound):
+			RespondWithError(w, http.StatusNotFound, "Role not found")
+		case errors.Is(err, store.ErrBuiltInRole):
+			RespondWithError(w, http.StatusBadRequest, "Built-in roles cannot be deleted")
+		case errors.Is(err, store.ErrRoleInUse):
+			RespondWithError(w, http.StatusConflict, "The role is still assigned to users")
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "Failed to delete role")
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,229 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestAdminRoleHandlers(t *testing.T) {
+	server, _, _ := testutil.SetupTestServer(t)
+	router := server.Router()
+	adminCookie := testutil.CookieForUser(t, server, "roleadmin", "password", "admin")
+	userCookie := testutil.CookieForUser(t, server, "roleuser", "password", "user")
+
+	do := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(cookie)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Users without manage_users cannot list roles", func(t *testing.T) {
+		if rr := do("GET", "/api/admin/roles", "", userCookie); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Create, update and delete a custom role", func(t *testing.T) {
+		rr := do("POST", "/api/admin/roles", `{"name":"Bad Name","permissions":[]}`, adminCookie)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected an invalid name to be rejected, got %d", rr.Code)
+		}
+		rr = do("POST", "/api/admin/roles", `{"name":"reader","permissions":["fly"]}`, adminCookie)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected an unknown permission to be rejected, got %d", rr.Code)
+		}
+
+		rr = do("POST", "/api/admin/roles", `{"name":"reader","description":"Read only","permissions":["read_library"]}`, adminCookie)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if rr := do("POST", "/api/admin/roles", `{"name":"reader"}`, adminCookie); rr.Code != http.StatusConflict {
+			t.Errorf("Expected a duplicate role to conflict, got %d", rr.Code)
+		}
+
+		rr = do("PUT", "/api/admin/roles/reader", `{"description":"Readers","permissions":["read_library","manage_tags"]}`, adminCookie)
+		var role models.Role
+		json.Unmarshal(rr.Body.Bytes(), &role)
+		if rr.Code != http.StatusOK || len(role.Permissions) != 2 {
+			t.Errorf("Expected the role to be updated, got %d %+v", rr.Code, role)
+		}
+		if rr := do("PUT", "/api/admin/roles/admin", `{"permissions":[]}`, adminCookie); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected the admin role to be immutable, got %d", rr.Code)
+		}
+
+		if rr := do("DELETE", "/api/admin/roles/user", "", adminCookie); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected built-in roles to be kept, got %d", rr.Code)
+		}
+		if rr := do("DELETE", "/api/admin/roles/reader", "", adminCookie); rr.Code != http.StatusNoContent {
+			t.Errorf("Expected status 204, got %d", rr.Code)
+		}
+		if rr := do("DELETE", "/api/admin/roles/reader", "", adminCookie); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Users can only be given existing roles", func(t *testing.T) {
+		rr := do("POST", "/api/admin/users", `{"username":"x","password":"pw","role":"nope"}`, adminCookie)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected an unknown role to be rejected, got %d", rr.Code)
+		}
+	})
+
+	t.Run("User managers cannot gain permissions", func(t *testing.T) {
+		st := server.Store()
+		if _, err := st.CreateRole("usermanager", "", []string{models.PermReadLibrary, models.PermManageUsers}); err != nil {
+			t.Fatalf("CreateRole failed: %v", err)
+		}
+		managerCookie := testutil.CookieForUser(t, server, "manager", "password", "usermanager")
+		roleUser, _ := st.GetUserByUsername("roleuser")
+
+		if rr := do("POST", "/api/admin/roles", `{"name":"jobs","permissions":["run_jobs"]}`, managerCookie); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected a role with permissions the manager lacks to be refused, got %d", rr.Code)
+		}
+		if rr := do("POST", "/api/admin/roles", `{"name":"readonly","permissions":["read_library"]}`, managerCookie); rr.Code != http.StatusCreated {
+			t.Errorf("Expected a role within the manager's permissions, got %d", rr.Code)
+		}
+		if rr := do("PUT", "/api/admin/roles/user", `{"permissions":["read_library"]}`, managerCookie); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected editing a role with more permissions to be refused, got %d", rr.Code)
+		}
+		if _, err := st.CreateRole("jobrunner", "", []string{models.PermRunJobs}); err != nil {
+			t.Fatalf("CreateRole failed: %v", err)
+		}
+		if rr := do("DELETE", "/api/admin/roles/jobrunner", "", managerCookie); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected deleting a role with more permissions to be refused, got %d", rr.Code)
+		}
+
+		for _, role := range []string{"admin", "user"} {
+			body := `{"username":"escalated-` + role + `","password":"pw","role":"` + role + `"}`
+			if rr := do("POST", "/api/admin/users", body, managerCookie); rr.Code != http.StatusForbidden {
+				t.Errorf("Expected creating a %s to be refused, got %d", role, rr.Code)
+			}
+		}
+		if rr := do("POST", "/api/admin/users", `{"username":"helper","password":"pw","role":"readonly"}`, managerCookie); rr.Code != http.StatusCreated {
+			t.Errorf("Expected creating a user with a lesser role, got %d", rr.Code)
+		}
+		rr := do("PUT", fmt.Sprintf("/api/admin/users/%d", roleUser.ID), `{"username":"roleuser","role":"admin"}`, managerCookie)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("Expected promoting to admin to be refused, got %d", rr.Code)
+		}
+	})
+
+	t.Run("The last admin is kept", func(t *testing.T) {
+		admin, _ := server.Store().GetUserByUsername("roleadmin")
+		rr := do("PUT", fmt.Sprintf("/api/admin/users/%d", admin.ID), `{"username":"roleadmin","role":"user"}`, adminCookie)
+		if rr.Code != http.StatusConflict {
+			t.Errorf("Expected demoting the last admin to be refused, got %d", rr.Code)
+		}
+	})
+
+	t.Run("User managers cannot act on users with more permissions", func(t *testing.T) {
+		managerCookie := testutil.CookieForUser(t, server, "manager", "password", "usermanager")
+		admin, _ := server.Store().GetUserByUsername("roleadmin")
+		testutil.CookieForUser(t, server, "lesser", "password", "readonly")
+		lesser, _ := server.Store().GetUserByUsername("lesser")
+
+		actions := []struct{ method, path, body string }{
+			{"PUT", "/api/admin/users/%d", `{"username":"renamed","role":"%s","password":"taken-over"}`},
+			{"POST", "/api/admin/users/%d/password-reset", ""},
+			{"DELETE", "/api/admin/users/%d/2fa", ""},
+			{"DELETE", "/api/admin/users/%d/sessions", ""},
+			{"POST", "/api/admin/users/%d/unlock", ""},
+			{"DELETE", "/api/admin/users/%d", ""},
+		}
+		for _, a := range actions {
+			body := a.body
+			if body != "" {
+				body = fmt.Sprintf(body, "admin")
+			}
+			if rr := do(a.method, fmt.Sprintf(a.path, admin.ID), body, managerCookie); rr.Code != http.StatusForbidden {
+				t.Errorf("Expected %s %s on an admin to be refused, got %d", a.method, a.path, rr.Code)
+			}
+		}
+		for _, a := range actions {
+			body := a.body
+			if body != "" {
+				body = fmt.Sprintf(body, "readonly")
+			}
+			if rr := do(a.method, fmt.Sprintf(a.path, lesser.ID), body, managerCookie); rr.Code >= 300 {
+				t.Errorf("Expected %s %s on a lesser user to succeed, got %d %s", a.method, a.path, rr.Code, rr.Body.String())
+			}
+		}
+	})
+}
+
+func TestPermissionEnforcement(t *testing.T) {
+	server, _, _ := testutil.SetupTestServer(t)
+	router := server.Router()
+
+	st := server.Store()
+	if _, err := st.CreateRole("reader", "", []string{models.PermReadLibrary}); err != nil {
+		t.Fatalf("CreateRole failed: %v", err)
+	}
+	if _, err := st.CreateRole("operator", "", []string{models.PermRunJobs}); err != nil {
+		t.Fatalf("CreateRole failed: %v", err)
+	}
+	readerCookie := testutil.CookieForUser(t, server, "reader", "password", "reader")
+	operatorCookie := testutil.CookieForUser(t, server, "operator", "password", "operator")
+
+	cases := []struct {
+		name   string
+		cookie *http.Cookie
+		method string
+		path   string
+		want   int
+	}{
+		{"reader browses", readerCookie, "GET", "/api/home", http.StatusOK},
+		{"reader cannot tag", readerCookie, "POST", "/api/folders/1/tags", http.StatusForbidden},
+		{"reader cannot upload covers", readerCookie, "POST", "/api/folders/1/cover", http.StatusForbidden},
+		{"reader cannot download", readerCookie, "GET", "/api/downloads/queue", http.StatusForbidden},
+		{"reader cannot list plugins", readerCookie, "GET", "/api/plugins", http.StatusForbidden},
+		{"reader cannot run jobs", readerCookie, "GET", "/api/admin/jobs/status", http.StatusForbidden},
+		{"operator runs jobs", operatorCookie, "GET", "/api/admin/jobs/status", http.StatusOK},
+		{"operator cannot browse", operatorCookie, "GET", "/api/home", http.StatusForbidden},
+		{"operator cannot manage users", operatorCookie, "GET", "/api/admin/users", http.StatusForbidden},
+		{"operator cannot manage plugins", operatorCookie, "POST", "/api/admin/plugins/reload", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+			req.AddCookie(tc.cookie)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != tc.want {
+				t.Errorf("Expected status %d, got %d", tc.want, rr.Code)
+			}
+		})
+	}
+
+	t.Run("OPDS requires read_library", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/opds/", nil)
+		req.SetBasicAuth("operator", "password")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Permissions are reported by /api/users/me", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/api/users/me", nil)
+		req.AddCookie(readerCookie)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var me models.User
+		json.Unmarshal(rr.Body.Bytes(), &me)
+		if len(me.Permissions) != 1 || me.Permissions[0] != models.PermReadLibrary {
+			t.Errorf("Expected the reader's permissions, got %v", me.Permissions)
+		}
+	})
+}
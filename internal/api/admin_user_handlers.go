@@ -1,12 +1,16 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
 )
 
 func (s *Server) handleAdminListUsers(w http.ResponseWriter, r *http.Request) {
@@ -28,10 +32,13 @@ func (s *Server) handleAdminCreateUser(w http.ResponseWriter, r *http.Request) {
 		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
-	if payload.Username == "" || payload.Password == "" || (payload.Role != "admin" && payload.Role != "user") {
+	if payload.Username == "" || payload.Password == "" || !s.roleExists(payload.Role) {
 		RespondWithError(w, http.StatusBadRequest, "Username, password, and a valid role are required")
 		return
 	}
+	if !s.canGrantRole(w, r, payload.Role) {
+		return
+	}
 
 	passwordHash, err := auth.HashPassword(payload.Password)
 	if err != nil {
@@ -45,6 +52,7 @@ func (s *Server) handleAdminCreateUser(w http.ResponseWriter, r *http.Request) {
 		RespondWithError(w, http.StatusConflict, "Username already exists")
 		return
 	}
+	s.setKOSyncKey(user.ID, payload.Password)
 	RespondWithJSON(w, http.StatusCreated, user)
 }
 
@@ -59,6 +67,23 @@ func (s *Server) handleAdminUpdateUser(w http.ResponseWriter, r *http.Request) {
 		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
+	if payload.Username == "" || !s.roleExists(payload.Role) {
+		RespondWithError(w, http.StatusBadRequest, "Username and a valid role are required")
+		return
+	}
+	target, err := s.store.GetUserByID(userID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if !canManageUser(w, r, target) {
+		return
+	}
+	if payload.Role != target.Role {
+		if !s.canGrantRole(w, r, payload.Role) || (payload.Role != models.RoleAdmin && s.removesLastAdmin(w, target)) {
+			return
+		}
+	}
 
 	// Update basic info
 	if err := s.store.UpdateUser(userID, payload.Username, payload.Role); err != nil {
@@ -77,6 +102,7 @@ func (s *Server) handleAdminUpdateUser(w http.ResponseWriter, r *http.Request) {
 			RespondWithError(w, http.StatusInternalServerError, "Failed to update password")
 			return
 		}
+		s.setKOSyncKey(userID, payload.Password)
 	}
 	w.WriteHeader(http.StatusOK)
 }
@@ -90,6 +116,17 @@ func (s *Server) handleAdminDeleteUser(w http.ResponseWriter, r *http.Request) {
 		RespondWithError(w, http.StatusBadRequest, "Cannot delete your own account")
 		return
 	}
+	target, err := s.store.GetUserByID(userID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if !canManageUser(w, r, target) {
+		return
+	}
+	if s.removesLastAdmin(w, target) {
+		return
+	}
 
 	if err := s.store.DeleteUser(userID); err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Failed to delete user")
@@ -97,3 +134,76 @@ func (s *Server) handleAdminDeleteUser(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// canManageUser checks that the user making the request holds every permission target does, so
+// managing users cannot be used to take over the account of someone with more permissions, e.g.
+// by resetting their password or 2FA. It answers the request if not.
+func canManageUser(w http.ResponseWriter, r *http.Request, target *models.User) bool {
+	if !getUserFromContext(r).HasAllPermissions(target.Permissions...) {
+		RespondWithError(w, http.StatusForbidden, "You cannot manage users with permissions you do not have")
+		return false
+	}
+	return true
+}
+
+// roleExists reports whether role names an existing role.
+func (s *Server) roleExists(role string) bool {
+	if role == "" {
+		return false
+	}
+	exists, err := s.store.RoleExists(role)
+	return err == nil && exists
+}
+
+// handleAdminGetUserRestrictions returns the folders and tags a user is limited to or hidden from.
+func (s *Server) handleAdminGetUserRestrictions(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	restrictions, err := s.store.GetContentRestrictions(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			RespondWithError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve restrictions")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, restrictions)
+}
+
+// handleAdminUpdateUserRestrictions replaces a user's content restrictions.
+func (s *Server) handleAdminUpdateUserRestrictions(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	target, err := s.store.GetUserByID(userID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if !canManageUser(w, r, target) {
+		return
+	}
+
+	var payload models.ContentRestrictions
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	if err := s.store.SetContentRestrictions(userID, &payload); err != nil {
+		switch {
+		case errors.Is(err, store.ErrFolderNotFound):
+			RespondWithError(w, http.StatusBadRequest, "Folder not found")
+		case errors.Is(err, store.ErrNotTopLevelFolder):
+			RespondWithError(w, http.StatusBadRequest, "Only top-level folders can be granted")
+		default:
+			RespondWithError(w, http.StatusInternalServerError, "Failed to update restrictions")
+		}
+		return
+	}
+
+	restrictions, err := s.store.GetContentRestrictions(target.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve restrictions")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, restrictions)
+}
@@ -94,3 +94,113 @@ func TestAdminUserHandlers(t *testing.T) {
 		}
 	})
 }
+
+func TestContentRestrictionHandlers(t *testing.T) {
+	server, db, _ := testutil.SetupTestServer(t)
+	router := server.Router()
+	testutil.PersistOneFolderAndChapter(t, db)
+	adminCookie := testutil.CookieForUser(t, server, "restrictadmin", "password", "admin")
+	userCookie := testutil.CookieForUser(t, server, "restricted", "password", "user")
+	user, _ := server.Store().GetUserByUsername("restricted")
+	other, _ := server.Store().CreateFolder("/Other", "Other", nil)
+
+	do := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	restrictionsPath := fmt.Sprintf("/api/admin/users/%d/restrictions", user.ID)
+
+	t.Run("Only user managers can change restrictions", func(t *testing.T) {
+		if rr := do("PUT", restrictionsPath, `{"restrict_folders":true}`, userCookie); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", rr.Code)
+		}
+		if rr := do("GET", "/api/admin/users/9999/restrictions", "", adminCookie); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for an unknown user, got %d", rr.Code)
+		}
+	})
+
+	t.Run("User managers cannot restrict users with more permissions", func(t *testing.T) {
+		if _, err := server.Store().CreateRole("usermanager", "", []string{models.PermReadLibrary, models.PermManageUsers}); err != nil {
+			t.Fatalf("CreateRole failed: %v", err)
+		}
+		managerCookie := testutil.CookieForUser(t, server, "manager", "password", "usermanager")
+		admin, _ := server.Store().GetUserByUsername("restrictadmin")
+
+		adminPath := fmt.Sprintf("/api/admin/users/%d/restrictions", admin.ID)
+		if rr := do("PUT", adminPath, `{"restrict_folders":true}`, managerCookie); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 restricting an admin, got %d", rr.Code)
+		}
+		if restrictions, _ := server.Store().GetContentRestrictions(admin.ID); restrictions.RestrictFolders {
+			t.Error("Expected the admin's restrictions to be unchanged")
+		}
+		server.Store().CreateRole("reader", "", []string{models.PermReadLibrary})
+		testutil.CookieForUser(t, server, "reader", "password", "reader")
+		reader, _ := server.Store().GetUserByUsername("reader")
+		readerPath := fmt.Sprintf("/api/admin/users/%d/restrictions", reader.ID)
+		if rr := do("PUT", readerPath, `{"restrict_folders":true}`, managerCookie); rr.Code != http.StatusOK {
+			t.Errorf("Expected status 200 restricting a lesser user, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Hidden chapter cannot be fetched by ID", func(t *testing.T) {
+		body := fmt.Sprintf(`{"restrict_folders":true,"folder_ids":[%d],"hidden_tags":["Mature"]}`, other.ID)
+		rr := do("PUT", restrictionsPath, body, adminCookie)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var restrictions models.ContentRestrictions
+		json.Unmarshal(rr.Body.Bytes(), &restrictions)
+		if !restrictions.RestrictFolders || len(restrictions.FolderIDs) != 1 || restrictions.HiddenTags[0] != "mature" {
+			t.Errorf("Unexpected restrictions: %+v", restrictions)
+		}
+
+		for _, path := range []string{"/api/chapters/1/pages/1", "/api/chapters/1", "/api/folders/1/settings", "/api/browse?folderId=1"} {
+			if rr := do("GET", path, "", userCookie); rr.Code != http.StatusNotFound {
+				t.Errorf("GET %s: expected status 404, got %d", path, rr.Code)
+			}
+			if rr := do("GET", path, "", adminCookie); rr.Code != http.StatusOK {
+				t.Errorf("GET %s: expected admin to get status 200, got %d", path, rr.Code)
+			}
+		}
+
+		rr = do("GET", "/api/browse", "", userCookie)
+		var listing struct {
+			Subfolders []*models.Folder `json:"subfolders"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &listing)
+		if len(listing.Subfolders) != 1 || listing.Subfolders[0].ID != other.ID {
+			t.Errorf("Expected only the granted folder at the root, got %d folders", len(listing.Subfolders))
+		}
+
+		req, _ := http.NewRequest("GET", "/opds/chapters/1/pages/1", nil)
+		req.SetBasicAuth("restricted", "password")
+		opds := httptest.NewRecorder()
+		router.ServeHTTP(opds, req)
+		if opds.Code != http.StatusNotFound {
+			t.Errorf("Expected OPDS page stream to return 404, got %d", opds.Code)
+		}
+	})
+
+	t.Run("Only top-level folders can be granted", func(t *testing.T) {
+		child, _ := server.Store().CreateFolder("/Other/Child", "Child", &other.ID)
+		body := fmt.Sprintf(`{"restrict_folders":true,"folder_ids":[%d]}`, child.ID)
+		if rr := do("PUT", restrictionsPath, body, adminCookie); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Clearing restrictions restores access", func(t *testing.T) {
+		if rr := do("PUT", restrictionsPath, `{}`, adminCookie); rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rr.Code)
+		}
+		if rr := do("GET", "/api/chapters/1/pages/1", "", userCookie); rr.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", rr.Code)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+)
+
+func (s *Server) handleListAPITokens(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	tokens, err := s.store.ListAPITokens(user.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve API tokens")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, tokens)
+}
+
+// handleCreateAPIToken creates a personal API token. The response is the only time the
+// token itself is returned.
+func (s *Server) handleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	var payload struct {
+		Name      string     `json:"name"`
+		ReadOnly  bool       `json:"read_only"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"` // Optional, RFC 3339
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" {
+		RespondWithError(w, http.StatusBadRequest, "Token name is required")
+		return
+	}
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		RespondWithError(w, http.StatusBadRequest, "Expiry must be in the future")
+		return
+	}
+
+	apiToken, token, err := s.store.CreateAPIToken(user.ID, payload.Name, payload.ReadOnly, payload.ExpiresAt)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to create API token")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, struct {
+		*models.APIToken
+		Token string `json:"token"`
+	}{apiToken, token})
+}
+
+func (s *Server) handleDeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	tokenID, err := strconv.ParseInt(chi.URLParam(r, "tokenID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid token ID")
+		return
+	}
+	if err := s.store.DeleteAPIToken(user.ID, tokenID); err != nil {
+		if errors.Is(err, store.ErrAPITokenNotFound) {
+			RespondWithError(w, http.StatusNotFound, "API token not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Failed to revoke API token")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
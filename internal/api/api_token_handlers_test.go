@@ -0,0 +1,120 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestAPITokenHandlers(t *testing.T) {
+	server, db, _ := testutil.SetupTestServer(t)
+	router := server.Router()
+	testutil.PersistOneFolderAndChapter(t, db)
+	cookie := testutil.CookieForUser(t, server, "scripter", "password", "user")
+
+	do := func(method, path, body string, auth func(*http.Request)) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		auth(req)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	withCookie := func(req *http.Request) { req.AddCookie(cookie) }
+	withToken := func(token string) func(*http.Request) {
+		return func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }
+	}
+	create := func(body string) (int64, string) {
+		t.Helper()
+		rr := do("POST", "/api/users/me/tokens", body, withCookie)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d %s", rr.Code, rr.Body.String())
+		}
+		var created struct {
+			ID    int64  `json:"id"`
+			Token string `json:"token"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &created)
+		return created.ID, created.Token
+	}
+
+	t.Run("Full Access Token", func(t *testing.T) {
+		_, token := create(`{"name":"script"}`)
+		rr := do("GET", "/api/users/me", "", withToken(token))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected the token to authenticate, got %d", rr.Code)
+		}
+		if rr := do("POST", "/api/chapters/999/progress", `{"progress_percent":10}`, withToken(token)); rr.Code == http.StatusForbidden || rr.Code == http.StatusUnauthorized {
+			t.Errorf("Expected writes to be allowed, got %d", rr.Code)
+		}
+		if rr := do("GET", "/opds", "", withToken(token)); rr.Code != http.StatusOK {
+			t.Errorf("Expected the token to work for OPDS, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Read Only Token", func(t *testing.T) {
+		_, token := create(`{"name":"reader app","read_only":true}`)
+		if rr := do("GET", "/api/tags", "", withToken(token)); rr.Code != http.StatusOK {
+			t.Errorf("Expected reads to be allowed, got %d", rr.Code)
+		}
+		if rr := do("POST", "/api/users/me/tokens", `{"name":"escalate"}`, withToken(token)); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected writes to be forbidden, got %d", rr.Code)
+		}
+
+		// Streaming a page over OPDS must not record progress, whichever way the token is sent
+		withBasicToken := func(req *http.Request) { req.SetBasicAuth("scripter", token) }
+		for _, auth := range []func(*http.Request){withToken(token), withBasicToken} {
+			if rr := do("GET", "/opds/chapters/1/pages/1", "", auth); rr.Code != http.StatusOK {
+				t.Fatalf("Expected the page to be served, got %d", rr.Code)
+			}
+		}
+		user, _ := server.Store().GetUserByUsername("scripter")
+		if chapter, _ := server.Store().GetChapterByID(1, user.ID); chapter.ProgressPercent != 0 || chapter.Read {
+			t.Errorf("Expected no progress from a read-only token, got %d (read=%v)", chapter.ProgressPercent, chapter.Read)
+		}
+		if rr := do("POST", "/opds", "", withBasicToken); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected writes with a read-only token as the Basic password to be forbidden, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		if rr := do("POST", "/api/users/me/tokens", `{"name":" "}`, withCookie); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 without a name, got %d", rr.Code)
+		}
+		if rr := do("POST", "/api/users/me/tokens", `{"name":"old","expires_at":"2000-01-01T00:00:00Z"}`, withCookie); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a past expiry, got %d", rr.Code)
+		}
+		if rr := do("GET", "/api/users/me", "", withToken("mgo_unknown")); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for an unknown token, got %d", rr.Code)
+		}
+	})
+
+	t.Run("List And Revoke", func(t *testing.T) {
+		id, token := create(`{"name":"temporary","expires_at":"2999-01-01T00:00:00Z"}`)
+
+		rr := do("GET", "/api/users/me/tokens", "", withCookie)
+		var tokens []map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &tokens)
+		if len(tokens) != 3 || tokens[0]["name"] != "temporary" || tokens[0]["expires_at"] == nil {
+			t.Fatalf("Expected three tokens, newest first, got %s", rr.Body.String())
+		}
+		if _, leaked := tokens[0]["token"]; leaked {
+			t.Error("Listing must not return the token")
+		}
+
+		path := fmt.Sprintf("/api/users/me/tokens/%d", id)
+		if rr := do("DELETE", path, "", withCookie); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected 204, got %d", rr.Code)
+		}
+		if rr := do("GET", "/api/users/me", "", withToken(token)); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected a revoked token to be rejected, got %d", rr.Code)
+		}
+		if rr := do("DELETE", path, "", withCookie); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for a revoked token, got %d", rr.Code)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package api
+
+// This file records administrative and destructive actions in the audit log.
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+)
+
+// maxAuditPayload is how much of a request body is kept in the audit log.
+const maxAuditPayload = 4096
+
+// auditRedactedFields are payload fields whose values are never written to the audit log.
+var auditRedactedFields = []string{"password", "secret", "token", "code"}
+
+// Audit returns a middleware that records the request in the audit log under the given action,
+// once the handler has answered it. The entry holds the user, their IP address, the URL and
+// query parameters naming the target, the JSON request body with secrets removed, and the
+// response status. It must be chained *after* the authentication middleware.
+func (s *Server) Audit(action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			payload := auditPayload(r)
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			entry := &models.AuditEntry{
+				IPAddress: clientIP(r),
+				Action:    action,
+				Target:    auditTarget(r),
+				Payload:   payload,
+				Status:    ww.Status(),
+			}
+			if entry.Status == 0 {
+				entry.Status = http.StatusOK
+			}
+			if user := getUserFromContext(r); user != nil {
+				entry.ActorID = &user.ID
+				entry.ActorUsername = user.Username
+			}
+			if err := s.store.RecordAudit(entry); err != nil {
+				log.Printf("Failed to record audit entry %s: %v", action, err)
+			}
+		})
+	}
+}
+
+// auditPayload returns the request's JSON body with secrets redacted, leaving the body intact for
+// the handler. File uploads and bodies that are not JSON are not recorded.
+func auditPayload(r *http.Request) string {
+	if r.Body == nil || strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAuditPayload+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil || len(body) == 0 {
+		return ""
+	}
+
+	var value interface{}
+	if len(body) > maxAuditPayload || json.Unmarshal(body, &value) != nil {
+		return "(not recorded)"
+	}
+	redacted, err := json.Marshal(redactAuditValue(value))
+	if err != nil {
+		return ""
+	}
+	return string(redacted)
+}
+
+// redactAuditValue replaces the values of secret fields anywhere in a decoded JSON value.
+func redactAuditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if isRedactedAuditField(key) {
+				v[key] = "[redacted]"
+			} else {
+				v[key] = redactAuditValue(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactAuditValue(item)
+		}
+	}
+	return value
+}
+
+func isRedactedAuditField(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range auditRedactedFields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditTarget describes what a request acted on from its URL and query parameters, e.g.
+// "userID=3" or "id=12".
+func auditTarget(r *http.Request) string {
+	var parts []string
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		for i, key := range rctx.URLParams.Keys {
+			if key != "*" && rctx.URLParams.Values[i] != "" {
+				parts = append(parts, key+"="+rctx.URLParams.Values[i])
+			}
+		}
+	}
+	query := r.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts = append(parts, key+"="+query.Get(key))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// handleAdminListAudit lists audit log entries, newest first. It can be filtered by actor,
+// action (an exact action, or a prefix such as "user."), target, and a since/until time range
+// in RFC 3339, and is paginated with page and per_page.
+func (s *Server) handleAdminListAudit(w http.ResponseWriter, r *http.Request) {
+	page, perPage, _, _, _ := getListParams(r)
+	query := r.URL.Query()
+	filter := store.AuditFilter{
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+		Target: query.Get("target"),
+		Limit:  perPage,
+		Offset: (page - 1) * perPage,
+	}
+	for param, dest := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				RespondWithError(w, http.StatusBadRequest, "Invalid "+param+" time, expected RFC 3339")
+				return
+			}
+			*dest = t
+		}
+	}
+
+	entries, total, err := s.store.ListAuditEntries(filter)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to list audit log")
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	RespondWithJSON(w, http.StatusOK, entries)
+}
@@ -0,0 +1,95 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestAuditLog(t *testing.T) {
+	server, _, _ := testutil.SetupTestServer(t)
+	router := server.Router()
+	adminCookie := testutil.CookieForUser(t, server, "auditadmin", "password", "admin")
+	userCookie := testutil.CookieForUser(t, server, "audituser", "password", "user")
+
+	do := func(method, path, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "192.0.2.10:40000"
+		req.AddCookie(cookie)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	list := func(query string) ([]models.AuditEntry, *httptest.ResponseRecorder) {
+		t.Helper()
+		rr := do("GET", "/api/admin/audit"+query, "", adminCookie)
+		var entries []models.AuditEntry
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &entries); err != nil {
+				t.Fatalf("Invalid response: %v", err)
+			}
+		}
+		return entries, rr
+	}
+
+	t.Run("Admin actions are recorded without secrets", func(t *testing.T) {
+		rr := do("POST", "/api/admin/users", `{"username":"audited","password":"hunter22","role":"user"}`, adminCookie)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d", rr.Code)
+		}
+		created, _ := server.Store().GetUserByUsername("audited")
+		if rr := do("DELETE", fmt.Sprintf("/api/admin/users/%d", created.ID), "", adminCookie); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", rr.Code)
+		}
+
+		entries, _ := list("?action=user.")
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 user entries, got %d", len(entries))
+		}
+		deleted, createdEntry := entries[0], entries[1]
+		if deleted.Action != "user.delete" || deleted.Target != fmt.Sprintf("userID=%d", created.ID) || deleted.Status != http.StatusNoContent {
+			t.Errorf("Unexpected delete entry: %+v", deleted)
+		}
+		if createdEntry.ActorUsername != "auditadmin" || createdEntry.IPAddress != "192.0.2.10" {
+			t.Errorf("Expected the actor and IP to be recorded, got %+v", createdEntry)
+		}
+		if strings.Contains(createdEntry.Payload, "hunter22") || !strings.Contains(createdEntry.Payload, `"username":"audited"`) {
+			t.Errorf("Expected the payload with the password redacted, got %s", createdEntry.Payload)
+		}
+	})
+
+	t.Run("Failed actions are recorded with their status", func(t *testing.T) {
+		do("DELETE", "/api/admin/roles/admin", "", adminCookie)
+		entries, _ := list("?action=role.delete")
+		if len(entries) != 1 || entries[0].Status != http.StatusBadRequest {
+			t.Errorf("Expected a role.delete entry with status 400, got %+v", entries)
+		}
+	})
+
+	t.Run("Filtering and pagination", func(t *testing.T) {
+		entries, rr := list("?actor=auditadmin&per_page=1&page=2")
+		if rr.Header().Get("X-Total-Count") != "3" || len(entries) != 1 {
+			t.Errorf("Expected page 2 of 3 entries, got %d (total %s)", len(entries), rr.Header().Get("X-Total-Count"))
+		}
+		if entries, _ := list("?actor=nobody"); len(entries) != 0 {
+			t.Errorf("Expected no entries for an unknown actor, got %d", len(entries))
+		}
+		if _, rr := list("?since=yesterday"); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for an invalid time, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Only user managers can read the audit log", func(t *testing.T) {
+		if rr := do("GET", "/api/admin/audit", "", userCookie); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", rr.Code)
+		}
+	})
+}
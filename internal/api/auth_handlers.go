@@ -2,10 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/models"
 )
 
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
@@ -18,45 +20,74 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Throttling is checked before the password so blocked attempts cost no bcrypt work.
+	if s.ipLoginBlocked(w, r) {
+		return
+	}
 	user, err := s.store.GetUserByUsername(payload.Username)
 	if err != nil {
+		s.recordFailedLogin(r, 0, payload.Username)
 		RespondWithError(w, http.StatusUnauthorized, "Invalid username or password")
 		return
 	}
+	if s.accountLoginBlocked(w, user.ID) {
+		return
+	}
 
 	if !auth.CheckPasswordHash(payload.Password, user.PasswordHash) {
+		s.recordFailedLogin(r, user.ID, user.Username)
 		RespondWithError(w, http.StatusUnauthorized, "Invalid username or password")
 		return
 	}
+	if user.Pending {
+		RespondWithError(w, http.StatusForbidden, "Your account is awaiting approval by an admin")
+		return
+	}
 
-	token, err := s.store.CreateSession(user.ID)
-	if err != nil {
+	s.refreshKOSyncKey(user, payload.Password)
+	// Failures are only cleared once the second factor is checked too.
+	if s.loginRequiresTwoFactor(w, user) {
+		return
+	}
+	s.resetLoginFailures(user.ID)
+
+	if err := s.startSession(w, r, user.ID); err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Failed to create session")
 		return
 	}
+	w.WriteHeader(http.StatusOK)
+}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    token,
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   r.TLS != nil, // Set secure flag if using HTTPS
-		SameSite: http.SameSiteLaxMode,
-	})
+// refreshKOSyncKey records the KOReader sync key for a password that was just verified, so
+// users created before kosync support (or by the CLI) can sync after their next login.
+func (s *Server) refreshKOSyncKey(user *models.User, password string) {
+	if user.KOSyncKeyHash != "" && auth.CheckPasswordHash(auth.KOSyncKey(password), user.KOSyncKeyHash) {
+		return
+	}
+	s.setKOSyncKey(user.ID, password)
+}
 
-	w.WriteHeader(http.StatusOK)
+// setKOSyncKey stores the KOReader sync key derived from a user's new password. Failures only
+// affect KOReader sync, so they are logged rather than failing the request.
+func (s *Server) setKOSyncKey(userID int64, password string) {
+	keyHash, err := auth.HashKOSyncKey(password)
+	if err == nil {
+		err = s.store.UpdateUserKOSyncKey(userID, keyHash)
+	}
+	if err != nil {
+		log.Printf("Failed to store KOReader sync key for user %d: %v", userID, err)
+	}
 }
 
 func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session_token")
+	cookie, err := r.Cookie(sessionCookieName)
 	if err == nil {
 		s.store.DeleteSession(cookie.Value)
 	}
 
 	// Expire the cookie on the client side
 	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
+		Name:     sessionCookieName,
 		Value:    "",
 		Expires:  time.Unix(0, 0),
 		MaxAge:   -1,
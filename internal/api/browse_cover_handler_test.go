@@ -73,8 +73,39 @@ func TestHandleUploadFolderCover(t *testing.T) {
 		if updatedFolder.Thumbnail == "" {
 			t.Error("Expected folder thumbnail to be updated, but it was empty.")
 		}
-		if !strings.HasPrefix(updatedFolder.Thumbnail, "data:image/jpeg;base64,") {
-			t.Errorf("Expected thumbnail to be a JPEG data URI, but it was: %s", updatedFolder.Thumbnail)
+		if !strings.HasPrefix(updatedFolder.Thumbnail, "/api/thumbnails/") {
+			t.Fatalf("Expected thumbnail to be a thumbnail URL, but it was: %s", updatedFolder.Thumbnail)
+		}
+
+		// 6. The thumbnail is served, and can be revalidated with its ETag.
+		req, _ = http.NewRequest("GET", updatedFolder.Thumbnail+"?size=small", nil)
+		req.AddCookie(cookie)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "image/jpeg" || rr.Body.Len() == 0 {
+			t.Fatalf("Expected the thumbnail image, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+		}
+		etag := rr.Header().Get("ETag")
+		if etag == "" || !strings.Contains(rr.Header().Get("Cache-Control"), "immutable") {
+			t.Errorf("Expected caching headers, got ETag %q Cache-Control %q", etag, rr.Header().Get("Cache-Control"))
+		}
+		req, _ = http.NewRequest("GET", updatedFolder.Thumbnail+"?size=small", nil)
+		req.AddCookie(cookie)
+		req.Header.Set("If-None-Match", etag)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotModified {
+			t.Errorf("Expected 304 for a matching ETag, got %d", rr.Code)
+		}
+
+		for _, path := range []string{updatedFolder.Thumbnail + "?size=huge", "/api/thumbnails/" + strings.Repeat("0", 64)} {
+			req, _ = http.NewRequest("GET", path, nil)
+			req.AddCookie(cookie)
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != http.StatusNotFound {
+				t.Errorf("Expected 404 for %s, got %d", path, rr.Code)
+			}
 		}
 	})
 
@@ -2,9 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 
@@ -33,6 +35,13 @@ func (s *Server) handleBrowseFolder(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		tagID = &id
+		if ok, err := s.store.CanAccessTag(user.ID, id); err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve library contents")
+			return
+		} else if !ok {
+			RespondWithError(w, http.StatusNotFound, "Tag not found")
+			return
+		}
 	}
 
 	opts := store.ListItemsOptions{
@@ -46,6 +55,10 @@ func (s *Server) handleBrowseFolder(w http.ResponseWriter, r *http.Request) {
 		SortDir:  sortDir,
 	}
 	folder, subfolders, chapters, total, err := s.store.ListItems(opts)
+	if errors.Is(err, store.ErrFolderNotFound) {
+		RespondWithError(w, http.StatusNotFound, "Folder not found")
+		return
+	}
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve library contents")
 		return
@@ -71,6 +84,13 @@ func (s *Server) handleGetBreadcrumb(w http.ResponseWriter, r *http.Request) {
 	}
 
 	folderID, _ := strconv.ParseInt(folderIDStr, 10, 64)
+	if ok, err := s.store.CanAccessFolder(getUserFromContext(r).ID, folderID); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve breadcrumb path")
+		return
+	} else if !ok {
+		RespondWithError(w, http.StatusNotFound, "Folder not found")
+		return
+	}
 	breadcrumb, err := s.store.GetFolderPath(folderID)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve breadcrumb path")
@@ -207,13 +227,13 @@ func (s *Server) handleUploadFolderCover(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	thumbnailDataURI, err := library.GenerateThumbnail(fileBytes)
+	thumbnail, err := library.GenerateThumbnail(s.app.Config().ThumbnailDir(), fileBytes)
 	if err != nil {
 		RespondWithError(w, http.StatusBadRequest, "Unsupported image format or corrupt file")
 		return
 	}
 
-	if err := s.store.UpdateFolderThumbnail(folderID, thumbnailDataURI); err != nil {
+	if err := s.store.UpdateFolderThumbnail(folderID, thumbnail); err != nil {
 		if err == store.ErrFolderNotFound {
 			RespondWithError(w, http.StatusNotFound, "Folder not found")
 			return
@@ -225,6 +245,52 @@ func (s *Server) handleUploadFolderCover(w http.ResponseWriter, r *http.Request)
 	RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Cover updated successfully."})
 }
 
+// handleGetThumbnail serves a thumbnail file in the size asked for with ?size=small, medium
+// or large (medium by default).
+func (s *Server) handleGetThumbnail(w http.ResponseWriter, r *http.Request) {
+	hash := chi.URLParam(r, "hash")
+	// Covers of folders hidden from the user are not served, even to someone who knows the hash.
+	ok, err := s.store.CanAccessThumbnail(getUserFromContext(r).ID, library.ThumbnailURLPrefix+hash)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to check access")
+		return
+	}
+	if !ok {
+		RespondWithError(w, http.StatusNotFound, "Thumbnail not found")
+		return
+	}
+	s.serveThumbnailFile(w, r, hash)
+}
+
+func (s *Server) serveThumbnailFile(w http.ResponseWriter, r *http.Request, hash string) {
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		size = library.DefaultThumbnailSize
+	}
+	path, ok := library.ThumbnailPath(s.app.Config().ThumbnailDir(), hash, size)
+	if !ok {
+		RespondWithError(w, http.StatusNotFound, "Thumbnail not found")
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "Thumbnail not found")
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to read thumbnail")
+		return
+	}
+
+	// A changed cover gets a new hash, so a thumbnail file never changes.
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	w.Header().Set("ETag", `"`+hash+"-"+size+`"`)
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
 // handleListAllFolders returns a simple list of all folders for subscription folder selection
 func (s *Server) handleListAllFolders(w http.ResponseWriter, r *http.Request) {
 	folders, err := s.store.GetAllFoldersByPath()
@@ -232,11 +298,19 @@ func (s *Server) handleListAllFolders(w http.ResponseWriter, r *http.Request) {
 		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve folders")
 		return
 	}
+	visible, err := s.store.VisibleFolderIDs(getUserFromContext(r).ID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve folders")
+		return
+	}
 
 	// Convert to a simple list format with relative paths
 	libraryPath := s.app.Config().Library.Path
 	var folderList []map[string]interface{}
 	for _, folder := range folders {
+		if visible != nil && !visible[folder.ID] {
+			continue
+		}
 		// Convert full path to relative path
 		relativePath := folder.Path
 		if strings.HasPrefix(folder.Path, libraryPath) {
@@ -262,7 +336,8 @@ func (s *Server) handleSearchFolders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	folders, err := s.store.SearchFoldersByName(query, 20)
+	user := getUserFromContext(r)
+	folders, err := s.store.SearchFoldersByName(user.ID, query, 20)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve folders")
 		return
@@ -0,0 +1,124 @@
+package api
+
+// This file works out which address a request came from. Behind a reverse proxy every request
+// arrives from the proxy, which names the client in X-Forwarded-For or X-Real-IP; those headers
+// are only believed from the proxies listed in trusted_proxies, because any client can send them.
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const peerAddrContextKey = contextKey("peer_addr")
+
+// trustedNetworks is a list of addresses and networks requests are trusted from.
+type trustedNetworks []*net.IPNet
+
+// parseTrustedNetworks parses CIDRs and single IPs. Invalid entries are logged and skipped.
+func parseTrustedNetworks(entries []string) trustedNetworks {
+	var networks trustedNetworks
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("Ignoring invalid trusted proxy %q: %v", entry, err)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// contains reports whether ip is one of the trusted addresses.
+func (t trustedNetworks) contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range t {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RememberPeerAddr records the request's TCP peer address. It must run before RealIP, which
+// replaces RemoteAddr with the client address a trusted proxy reports.
+func RememberPeerAddr(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), peerAddrContextKey, r.RemoteAddr)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// peerIP returns the IP address of the request's TCP peer, or nil if it is unknown.
+func peerIP(r *http.Request) net.IP {
+	addr, _ := r.Context().Value(peerAddrContextKey).(string)
+	if addr == "" {
+		addr = r.RemoteAddr
+	}
+	return net.ParseIP(hostOf(addr))
+}
+
+// RealIP sets RemoteAddr to the client address named by X-Forwarded-For or X-Real-IP, but only
+// for requests whose TCP peer is a trusted proxy. X-Forwarded-For is read from the right,
+// skipping further trusted proxies, since clients can put anything at its left end.
+func (s *Server) RealIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.trustedProxies.contains(peerIP(r)) {
+			if ip := s.forwardedClientIP(r); ip != "" {
+				r.RemoteAddr = ip
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedClientIP returns the client address reported by a trusted proxy, or "".
+func (s *Server) forwardedClientIP(r *http.Request) string {
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		client := ""
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			client = ip.String()
+			if !s.trustedProxies.contains(ip) {
+				break
+			}
+		}
+		return client
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// clientIP returns the client address of a request: the TCP peer, or the client a trusted proxy
+// named, as RealIP has already applied it to RemoteAddr.
+func clientIP(r *http.Request) string {
+	return hostOf(r.RemoteAddr)
+}
+
+// hostOf strips the port from an address, if it has one.
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"path/filepath"
@@ -9,7 +10,9 @@ import (
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/vrsandeep/mango-go/internal/library"
 	"github.com/vrsandeep/mango-go/internal/library/chapterfiles"
+	"github.com/vrsandeep/mango-go/internal/models"
 )
 
 // getListParams extracts all query params for list endpoints.
@@ -28,7 +31,12 @@ func getListParams(r *http.Request) (page, perPage int, search, sortBy, sortDir
 	return
 }
 
-// handleGetPage finds a specific page within a chapter file and serves it as an image.
+// maxPageDimension is the largest width or height a page can be resized to.
+const maxPageDimension = 8192
+
+// handleGetPage finds a specific page within a chapter file and serves it as an image. The
+// optional width, height, quality and format query parameters scale the page down and
+// re-encode it; see parsePageTransform. Pages carry caching headers (see http_cache.go).
 func (s *Server) handleGetPage(w http.ResponseWriter, r *http.Request) {
 	chapterIDStr := chi.URLParam(r, "chapterID")
 	chapterID, err := strconv.ParseInt(chapterIDStr, 10, 64)
@@ -45,6 +53,11 @@ func (s *Server) handleGetPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	pageIndex := pageNumber - 1
+	transform, err := parsePageTransform(r)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Get chapter details (we need its path) from the database
 	user := getUserFromContext(r)
@@ -59,12 +72,34 @@ func (s *Server) handleGetPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !chapterfiles.IsSupportedChapterFile(filepath.Base(chapter.Path)) {
+	if !chapterfiles.IsSupportedChapterPath(chapter.Path) {
 		RespondWithError(w, http.StatusUnsupportedMediaType, "Unsupported chapter file type")
 		return
 	}
 
-	pageData, fileName, err := chapterfiles.GetChapterPage(r.Context(), chapter.Path, pageIndex)
+	s.servePage(w, r, user.ID, chapter, pageIndex, transform)
+}
+
+// servePage writes one page of a chapter, resized as transform asks, for the web reader and
+// OPDS page streaming alike. Resized pages are kept in the page cache. It reports whether the
+// page was sent, rather than a 304 Not Modified or an error.
+func (s *Server) servePage(w http.ResponseWriter, r *http.Request, userID int64, chapter *models.Chapter, pageIndex int, transform library.PageTransform) bool {
+	etag := pageETag(chapter, pageIndex, pageVariant(transform))
+	if pageNotModified(w, r, chapter, etag) {
+		return false
+	}
+
+	cacheKey := ""
+	if !transform.IsZero() && s.pageCache != nil {
+		cacheKey = pageCacheKey(chapter, pageIndex, transform)
+		if data, ok := s.pageCache.Get(cacheKey); ok {
+			s.prefetch.readAhead(userID, chapter, pageIndex)
+			writePage(w, r, chapter, etag, http.DetectContentType(data), data)
+			return true
+		}
+	}
+
+	pageData, fileName, err := s.prefetch.page(r.Context(), chapter, pageIndex)
 	if err != nil {
 		log.Printf("Error extracting page %d from chapter file %s: %v", pageIndex, chapter.Path, err)
 		if strings.Contains(err.Error(), "out of bounds") {
@@ -72,26 +107,105 @@ func (s *Server) handleGetPage(w http.ResponseWriter, r *http.Request) {
 		} else {
 			RespondWithError(w, http.StatusInternalServerError, "Could not read page")
 		}
-		return
+		return false
 	}
+	s.prefetch.readAhead(userID, chapter, pageIndex)
 
-	// Set the correct Content-Type header based on image extension
-	ext := filepath.Ext(fileName)
-	contentType := "application/octet-stream" // fallback
-	switch ext {
+	// The Content-Type comes from the image extension
+	contentType := pageContentType(fileName)
+
+	if !transform.IsZero() {
+		// Resizing is CPU heavy, so only a few pages are resized at once.
+		select {
+		case s.transcodes <- struct{}{}:
+		case <-r.Context().Done():
+			return false
+		}
+		data, format, changed, err := library.TransformPage(pageData, transform)
+		<-s.transcodes
+		if err != nil {
+			// The original is sent instead, so it must not be tagged as the resized variant.
+			log.Printf("Error resizing page %d of %s: %v", pageIndex, chapter.Path, err)
+			etag = pageETag(chapter, pageIndex, "")
+		} else if changed {
+			pageData, contentType = data, "image/"+format
+			if cacheKey != "" {
+				if err := s.pageCache.Put(cacheKey, data); err != nil {
+					log.Printf("Failed to cache resized page %d of %s: %v", pageIndex, chapter.Path, err)
+				}
+			}
+		}
+	}
+
+	writePage(w, r, chapter, etag, contentType, pageData)
+	return true
+}
+
+// parsePageTransform reads how a page should be resized from its request: width and height
+// (pixels, the page is scaled down to fit both), quality (JPEG quality, 1-100) and format
+// ("jpeg" or "png"). Pages in other formats, or without parameters, are served as they are.
+func parsePageTransform(r *http.Request) (library.PageTransform, error) {
+	var t library.PageTransform
+	query := r.URL.Query()
+	params := []struct {
+		name string
+		dest *int
+		max  int
+	}{
+		{"width", &t.Width, maxPageDimension},
+		{"height", &t.Height, maxPageDimension},
+		{"quality", &t.Quality, 100},
+	}
+	for _, param := range params {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > param.max {
+			return t, fmt.Errorf("%s must be between 1 and %d", param.name, param.max)
+		}
+		*param.dest = n
+	}
+	switch format := strings.ToLower(query.Get("format")); format {
+	case "":
+	case "jpeg", "jpg":
+		t.Format = "jpeg"
+	case "png":
+		t.Format = "png"
+	default:
+		return t, fmt.Errorf("format must be jpeg or png")
+	}
+	return t, nil
+}
+
+// pageCacheKey identifies a resized page. It includes the chapter file's hash, modification time
+// and size, so pages of a changed file are not served from the cache.
+func pageCacheKey(chapter *models.Chapter, pageIndex int, t library.PageTransform) string {
+	var mtime, size int64
+	if chapter.FileMtime != nil {
+		mtime = chapter.FileMtime.UnixNano()
+	}
+	if chapter.FileSize != nil {
+		size = *chapter.FileSize
+	}
+	return fmt.Sprintf("page/%d/%s/%d/%d/%d/%dx%d/q%d/%s",
+		chapter.ID, chapter.ContentHash, mtime, size, pageIndex, t.Width, t.Height, t.Quality, t.Format)
+}
+
+// pageContentType returns the Content-Type for a page from its file name's extension.
+func pageContentType(fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
 	case ".jpg", ".jpeg":
-		contentType = "image/jpeg"
+		return "image/jpeg"
 	case ".png":
-		contentType = "image/png"
+		return "image/png"
 	case ".gif":
-		contentType = "image/gif"
+		return "image/gif"
 	case ".webp":
-		contentType = "image/webp"
+		return "image/webp"
 	}
-	w.Header().Set("Content-Type", contentType)
-
-	// Write the page data to the response
-	w.Write(pageData)
+	return "application/octet-stream" // fallback
 }
 
 // handleGetChapterDetails retrieves and returns details for a single chapter.
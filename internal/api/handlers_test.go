@@ -2,7 +2,12 @@ package api_test
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -10,8 +15,10 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/vrsandeep/mango-go/internal/assets"
+	"github.com/vrsandeep/mango-go/internal/config"
 	"github.com/vrsandeep/mango-go/internal/models"
 	"github.com/vrsandeep/mango-go/internal/store"
 	"github.com/vrsandeep/mango-go/internal/testutil"
@@ -57,6 +64,84 @@ func TestHandleGetPage(t *testing.T) {
 	})
 }
 
+func TestHandleGetPageCaching(t *testing.T) {
+	server, db, _ := testutil.SetupTestServer(t)
+	router := server.Router()
+	dir := t.TempDir()
+	chapterPath := testutil.CreateTestCBZ(t, dir, "ch1.cbz", []string{"page1.png", "page2.png"})
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	size := int64(1234)
+	st := store.New(db)
+	folder, _ := st.CreateFolder(dir, "Folder", nil)
+	if _, err := st.CreateChapterWithMetadata(folder.ID, chapterPath, "hash1", 2, "", &mtime, &size); err != nil {
+		t.Fatalf("Failed to create chapter: %v", err)
+	}
+	cookie := testutil.CookieForUser(t, server, "testuser", "password", "user")
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.AddCookie(cookie)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/api/chapters/1/pages/1", nil)
+	etag := rr.Header().Get("ETag")
+	if rr.Code != http.StatusOK || etag == "" || rr.Header().Get("Cache-Control") == "" {
+		t.Fatalf("Expected caching headers, got %d ETag %q Cache-Control %q", rr.Code, etag, rr.Header().Get("Cache-Control"))
+	}
+	if lastModified := rr.Header().Get("Last-Modified"); lastModified != mtime.Format(http.TimeFormat) {
+		t.Errorf("Expected Last-Modified from the file's mtime, got %q", lastModified)
+	}
+	full := rr.Body.Bytes()
+
+	t.Run("ETags differ per page and size", func(t *testing.T) {
+		for _, path := range []string{"/api/chapters/1/pages/2", "/api/chapters/1/pages/1?width=1"} {
+			if other := get(path, nil).Header().Get("ETag"); other == "" || other == etag {
+				t.Errorf("Expected a distinct ETag for %s, got %q", path, other)
+			}
+		}
+	})
+
+	t.Run("Conditional requests", func(t *testing.T) {
+		for _, headers := range []map[string]string{
+			{"If-None-Match": etag},
+			{"If-None-Match": `"other", W/` + etag},
+			{"If-Modified-Since": mtime.Add(time.Minute).Format(http.TimeFormat)},
+		} {
+			rr := get("/api/chapters/1/pages/1", headers)
+			if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 || rr.Header().Get("ETag") != etag {
+				t.Errorf("Expected 304 for %v, got %d", headers, rr.Code)
+			}
+		}
+		for _, headers := range []map[string]string{
+			{"If-None-Match": `"other"`},
+			{"If-Modified-Since": mtime.Add(-time.Minute).Format(http.TimeFormat)},
+		} {
+			if rr := get("/api/chapters/1/pages/1", headers); rr.Code != http.StatusOK {
+				t.Errorf("Expected 200 for %v, got %d", headers, rr.Code)
+			}
+		}
+	})
+
+	t.Run("Range requests", func(t *testing.T) {
+		rr := get("/api/chapters/1/pages/1", map[string]string{"Range": "bytes=0-9"})
+		if rr.Code != http.StatusPartialContent || !bytes.Equal(rr.Body.Bytes(), full[:10]) {
+			t.Errorf("Expected the first 10 bytes, got %d (%d bytes)", rr.Code, rr.Body.Len())
+		}
+	})
+
+	t.Run("Errors are not cached", func(t *testing.T) {
+		rr := get("/api/chapters/1/pages/99", nil)
+		if rr.Code != http.StatusNotFound || rr.Header().Get("Cache-Control") != "" {
+			t.Errorf("Expected an uncached 404, got %d Cache-Control %q", rr.Code, rr.Header().Get("Cache-Control"))
+		}
+	})
+}
+
 func TestHandleGetChapterDetails(t *testing.T) {
 	server, db, _ := testutil.SetupTestServer(t)
 	router := server.Router()
@@ -85,6 +170,28 @@ func TestHandleGetChapterDetails(t *testing.T) {
 		}
 	})
 
+	t.Run("With ComicInfo", func(t *testing.T) {
+		volume := 4
+		if err := store.New(db).UpdateChapterComicInfo(1, &models.ComicInfo{Series: "Test Series", Number: "12", Volume: &volume, LanguageISO: "en"}); err != nil {
+			t.Fatalf("Failed to store ComicInfo: %v", err)
+		}
+		req, _ := http.NewRequest("GET", "/api/chapters/1", nil)
+		rr := httptest.NewRecorder()
+		req.AddCookie(testutil.CookieForUser(t, server, "testuser", "password", "user"))
+		router.ServeHTTP(rr, req)
+
+		var chapter models.Chapter
+		if err := json.Unmarshal(rr.Body.Bytes(), &chapter); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if chapter.ComicInfo == nil {
+			t.Fatalf("Expected comic_info in response, got %s", rr.Body.String())
+		}
+		if chapter.ComicInfo.Number != "12" || chapter.ComicInfo.Volume == nil || *chapter.ComicInfo.Volume != 4 || chapter.ComicInfo.LanguageISO != "en" {
+			t.Errorf("Unexpected comic_info: %+v", chapter.ComicInfo)
+		}
+	})
+
 	t.Run("Not Found", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/api/chapters/999", nil)
 		rr := httptest.NewRecorder()
@@ -274,3 +381,92 @@ func TestServeLibraryHTML(t *testing.T) {
 		t.Error("handler returned body that does not match cmd/mango-server/web/reader.html content, got: " + rr.Body.String())
 	}
 }
+
+func TestHandleGetPageTransform(t *testing.T) {
+	cacheDir := t.TempDir()
+	server, db, _ := testutil.SetupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.PageCache = config.PageCacheConfig{Path: cacheDir, MaxSizeMB: 10}
+	})
+	router := server.Router()
+
+	var page bytes.Buffer
+	png.Encode(&page, image.NewRGBA(image.Rect(0, 0, 400, 200)))
+	dir := t.TempDir()
+	chapterPath := testutil.CreateTestCBZWithThumbnail(t, dir, "ch1.cbz", []string{"page1.png"}, base64.StdEncoding.EncodeToString(page.Bytes()))
+	st := store.New(db)
+	folder, _ := st.CreateFolder(dir, "Folder", nil)
+	if _, err := st.CreateChapter(folder.ID, chapterPath, "hash1", 1, ""); err != nil {
+		t.Fatalf("Failed to create chapter: %v", err)
+	}
+	cookie := testutil.CookieForUser(t, server, "testuser", "password", "user")
+	get := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/chapters/1/pages/1"+query, nil)
+		req.AddCookie(cookie)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Original without parameters", func(t *testing.T) {
+		rr := get("")
+		if rr.Code != http.StatusOK || !bytes.Equal(rr.Body.Bytes(), page.Bytes()) {
+			t.Errorf("Expected the original page, got %d (%d bytes)", rr.Code, rr.Body.Len())
+		}
+	})
+
+	t.Run("Resizes and converts", func(t *testing.T) {
+		for i := 0; i < 2; i++ { // the second request is served from the cache
+			rr := get("?width=100&format=jpg&quality=70")
+			if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "image/jpeg" {
+				t.Fatalf("Expected a JPEG page, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+			}
+			cfg, err := jpeg.DecodeConfig(rr.Body)
+			if err != nil || cfg.Width != 100 || cfg.Height != 50 {
+				t.Errorf("Expected a 100x50 page, got %dx%d (%v)", cfg.Width, cfg.Height, err)
+			}
+		}
+		if entries, _ := os.ReadDir(cacheDir); len(entries) == 0 {
+			t.Error("Expected the resized page to be cached")
+		}
+	})
+
+	t.Run("Does not enlarge", func(t *testing.T) {
+		rr := get("?width=1000&height=1000")
+		if rr.Code != http.StatusOK || !bytes.Equal(rr.Body.Bytes(), page.Bytes()) {
+			t.Errorf("Expected the original page, got %d (%d bytes)", rr.Code, rr.Body.Len())
+		}
+	})
+
+	t.Run("Sends the original when resizing fails", func(t *testing.T) {
+		// A truncated PNG: its size can be read, but decoding it fails
+		truncated := page.Bytes()[:40]
+		broken := testutil.CreateTestCBZWithThumbnail(t, dir, "ch2.cbz", []string{"page1.png"}, base64.StdEncoding.EncodeToString(truncated))
+		chapter, err := st.CreateChapter(folder.ID, broken, "hash2", 1, "")
+		if err != nil {
+			t.Fatalf("Failed to create chapter: %v", err)
+		}
+		fetch := func(query string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/chapters/%d/pages/1%s", chapter.ID, query), nil)
+			req.AddCookie(cookie)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			return rr
+		}
+		original := fetch("")
+		rr := fetch("?width=100")
+		if rr.Code != http.StatusOK || !bytes.Equal(rr.Body.Bytes(), truncated) {
+			t.Fatalf("Expected the original page, got %d (%d bytes)", rr.Code, rr.Body.Len())
+		}
+		if etag := rr.Header().Get("ETag"); etag == "" || etag != original.Header().Get("ETag") {
+			t.Errorf("Expected the original page's ETag %s, got %s", original.Header().Get("ETag"), etag)
+		}
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"?width=0", "?height=abc", "?width=9000", "?quality=101", "?format=webp", "?format=gif"} {
+			if rr := get(query); rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400 for %s, got %d", query, rr.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package api
+
+// This file sets the HTTP caching headers of chapter pages, so browsers and reader apps can
+// keep pages and revalidate them with conditional requests instead of downloading them again.
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/library"
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+// pageCacheControl lets clients reuse a page for an hour before revalidating it. Pages live at
+// the same URL when their chapter file is replaced, so they cannot be cached forever.
+const pageCacheControl = "private, max-age=3600"
+
+// pageETag returns the strong ETag of a page, and variant is how it was resized ("" for the
+// original). The content hash only covers the chapter file's first page, so the file's
+// modification time is part of the tag as well. Chapters without a hash have no ETag.
+func pageETag(chapter *models.Chapter, pageIndex int, variant string) string {
+	if chapter.ContentHash == "" {
+		return ""
+	}
+	tag := chapter.ContentHash
+	if chapter.FileMtime != nil {
+		tag += "-" + strconv.FormatInt(chapter.FileMtime.Unix(), 36)
+	}
+	tag += "-" + strconv.Itoa(pageIndex)
+	if variant != "" {
+		tag += "-" + variant
+	}
+	return `"` + tag + `"`
+}
+
+// pageVariant names a page transform for use in ETags.
+func pageVariant(t library.PageTransform) string {
+	if t.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%dx%dq%d%s", t.Width, t.Height, t.Quality, t.Format)
+}
+
+// pageModTime is the Last-Modified time of a chapter's pages, or zero when it is unknown.
+func pageModTime(chapter *models.Chapter) time.Time {
+	if chapter.FileMtime == nil {
+		return time.Time{}
+	}
+	return *chapter.FileMtime
+}
+
+// pageNotModified answers a conditional request for a page the client already has with 304 Not
+// Modified, before the page is read from its chapter file. It reports whether it did.
+func pageNotModified(w http.ResponseWriter, r *http.Request, chapter *models.Chapter, etag string) bool {
+	modTime := pageModTime(chapter)
+	if !requestNotModified(r, etag, modTime) {
+		return false
+	}
+	setPageCacheHeaders(w, etag)
+	if !modTime.IsZero() {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// writePage writes a page with its caching headers. http.ServeContent answers conditional and
+// Range requests.
+func writePage(w http.ResponseWriter, r *http.Request, chapter *models.Chapter, etag, contentType string, data []byte) {
+	setPageCacheHeaders(w, etag)
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, "", pageModTime(chapter), bytes.NewReader(data))
+}
+
+func setPageCacheHeaders(w http.ResponseWriter, etag string) {
+	w.Header().Set("Cache-Control", pageCacheControl)
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+}
+
+// requestNotModified reports whether a GET or HEAD request's If-None-Match, or failing that its
+// If-Modified-Since, header shows the client's copy is current, following RFC 9110.
+func requestNotModified(r *http.Request, etag string, modTime time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etag == "" {
+			return false
+		}
+		// If-None-Match uses the weak comparison, so W/ prefixes are ignored.
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if modTime.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modTime.Truncate(time.Second).After(since)
+}
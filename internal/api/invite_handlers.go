@@ -0,0 +1,213 @@
+package api
+
+// This file lets admins hand out invite codes and lets people register their own accounts,
+// either with an invite or, when registration is open, as pending accounts for an admin to
+// approve.
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+)
+
+const (
+	// defaultInviteLifetime is how long an invite stays valid when the admin does not say.
+	defaultInviteLifetime = 7 * 24 * time.Hour
+	maxUsernameLength     = 64
+)
+
+// usernameProblem returns why a trimmed username cannot be chosen, or "" if it can. It is shared by
+// registration and self-service renames, so both accept the same names.
+func usernameProblem(username string) string {
+	switch {
+	case username == "":
+		return "Username is required"
+	case len(username) > maxUsernameLength:
+		return "Username is too long"
+	}
+	return ""
+}
+
+// handleRegister creates an account from the login page. With an invite code the new user is
+// logged in straight away; without one the account waits for an admin's approval, and is only
+// created when open registration is enabled.
+func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Username   string `json:"username"`
+		Password   string `json:"password"`
+		InviteCode string `json:"invite_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	payload.Username = strings.TrimSpace(payload.Username)
+	payload.InviteCode = strings.TrimSpace(payload.InviteCode)
+	if payload.Username == "" || payload.Password == "" {
+		RespondWithError(w, http.StatusBadRequest, "Username and password are required")
+		return
+	}
+	if problem := usernameProblem(payload.Username); problem != "" {
+		RespondWithError(w, http.StatusBadRequest, problem)
+		return
+	}
+	open := s.app.Config().Registration.Open
+	if payload.InviteCode == "" && !open {
+		RespondWithError(w, http.StatusForbidden, "An invite code is required to register")
+		return
+	}
+
+	// Guessing invite codes and probing for usernames count as failed logins, so they are
+	// throttled the same way. Both are checked before the costly password hash.
+	if s.ipLoginBlocked(w, r) {
+		return
+	}
+	if s.registrationRefused(w, r, payload.Username, s.store.CheckRegistration(payload.Username, payload.InviteCode, open)) {
+		return
+	}
+	passwordHash, err := auth.HashPassword(payload.Password)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to hash password")
+		return
+	}
+	user, err := s.store.RegisterUser(payload.Username, passwordHash, payload.InviteCode, open)
+	if s.registrationRefused(w, r, payload.Username, err) {
+		return
+	}
+	s.setKOSyncKey(user.ID, payload.Password)
+
+	if user.Pending {
+		RespondWithJSON(w, http.StatusCreated, map[string]string{"status": "pending"})
+		return
+	}
+	if err := s.startSession(w, r, user.ID); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+	RespondWithJSON(w, http.StatusCreated, map[string]string{"status": "active"})
+}
+
+// registrationRefused answers a registration that failed with err, recording invalid invites
+// and taken usernames as failed logins. It reports whether it answered.
+func (s *Server) registrationRefused(w http.ResponseWriter, r *http.Request, username string, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, store.ErrInviteInvalid):
+		s.recordFailedLogin(r, 0, username)
+		RespondWithError(w, http.StatusBadRequest, "This invite code is invalid, used up or expired")
+	case errors.Is(err, store.ErrUsernameTaken):
+		s.recordFailedLogin(r, 0, username)
+		RespondWithError(w, http.StatusConflict, "Username already exists")
+	case errors.Is(err, store.ErrRegistrationClosed):
+		RespondWithError(w, http.StatusForbidden, "An invite code is required to register")
+	default:
+		RespondWithError(w, http.StatusInternalServerError, "Failed to register")
+	}
+	return true
+}
+
+// handleAdminListInvites lists every invite, including used up and expired ones.
+func (s *Server) handleAdminListInvites(w http.ResponseWriter, r *http.Request) {
+	invites, err := s.store.ListInvites()
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to list invites")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, invites)
+}
+
+// handleAdminCreateInvite issues an invite code. The code is only shown in this response.
+func (s *Server) handleAdminCreateInvite(w http.ResponseWriter, r *http.Request) {
+	payload := struct {
+		Role           string `json:"role"`
+		MaxUses        int    `json:"max_uses"`
+		ExpiresInHours int    `json:"expires_in_hours"`
+	}{Role: models.RoleUser, MaxUses: 1}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if !s.roleExists(payload.Role) {
+		RespondWithError(w, http.StatusBadRequest, "A valid role is required")
+		return
+	}
+	// An invite grants its role, so it is held to the same rules as assigning it.
+	if !s.canGrantRole(w, r, payload.Role) {
+		return
+	}
+	if payload.MaxUses < 1 || payload.ExpiresInHours < 0 {
+		RespondWithError(w, http.StatusBadRequest, "max_uses must be at least 1 and expires_in_hours cannot be negative")
+		return
+	}
+	lifetime := defaultInviteLifetime
+	if payload.ExpiresInHours > 0 {
+		lifetime = time.Duration(payload.ExpiresInHours) * time.Hour
+	}
+
+	user := getUserFromContext(r)
+	invite, code, err := s.store.CreateInvite(payload.Role, payload.MaxUses, lifetime, user.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to create invite")
+		return
+	}
+	invite.CreatedByUsername = user.Username
+	RespondWithJSON(w, http.StatusCreated, map[string]interface{}{
+		"invite":     invite,
+		"code":       code,
+		"invite_url": "/login?invite=" + code,
+	})
+}
+
+// handleAdminDeleteInvite revokes an invite so it can no longer be used.
+func (s *Server) handleAdminDeleteInvite(w http.ResponseWriter, r *http.Request) {
+	inviteID, err := strconv.ParseInt(chi.URLParam(r, "inviteID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid invite ID")
+		return
+	}
+	if err := s.store.DeleteInvite(inviteID); err != nil {
+		if errors.Is(err, store.ErrInviteNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Invite not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Failed to delete invite")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAdminApproveUser lets an account created through open registration log in.
+func (s *Server) handleAdminApproveUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	target, err := s.store.GetUserByID(userID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	// Pending users have no permissions yet, so approving counts as granting their role.
+	if !s.canGrantRole(w, r, target.Role) {
+		return
+	}
+	if err := s.store.ApproveUser(userID); err != nil {
+		if errors.Is(err, store.ErrUserNotPending) {
+			RespondWithError(w, http.StatusConflict, "User is not waiting for approval")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Failed to approve user")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,152 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/config"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestInviteHandlers(t *testing.T) {
+	var cfg *config.Config
+	server, _, _ := testutil.SetupTestServerWithConfig(t, func(c *config.Config) { cfg = c })
+	router := server.Router()
+	adminCookie := testutil.CookieForUser(t, server, "boss", "password", "admin")
+	cookie := testutil.CookieForUser(t, server, "reader", "password", "user")
+
+	do := func(method, path, body string, c *http.Cookie) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.RemoteAddr = "192.0.2.20:40000"
+		if c != nil {
+			req.AddCookie(c)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	register := func(username, code string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"username":%q,"password":"secret","invite_code":%q}`, username, code)
+		return do("POST", "/api/users/register", body, nil)
+	}
+
+	var code string
+	t.Run("Create Invite", func(t *testing.T) {
+		if rr := do("POST", "/api/admin/invites", `{}`, cookie); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected non-admins to be forbidden, got %d", rr.Code)
+		}
+		if rr := do("POST", "/api/admin/invites", `{"role":"nope"}`, adminCookie); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an unknown role, got %d", rr.Code)
+		}
+		rr := do("POST", "/api/admin/invites", `{"role":"user","max_uses":1,"expires_in_hours":2}`, adminCookie)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d %s", rr.Code, rr.Body.String())
+		}
+		var result struct {
+			Code      string `json:"code"`
+			InviteURL string `json:"invite_url"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &result)
+		if result.Code == "" || result.InviteURL != "/login?invite="+result.Code {
+			t.Fatalf("Unexpected invite response: %s", rr.Body.String())
+		}
+		code = result.Code
+
+		rr = do("GET", "/api/admin/invites", "", adminCookie)
+		var invites []map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &invites)
+		if len(invites) != 1 || invites[0]["created_by_username"] != "boss" || invites[0]["code"] != nil {
+			t.Errorf("Expected one invite without its code, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Invite Roles Are Limited", func(t *testing.T) {
+		if _, err := server.Store().CreateRole("usermanager", "", []string{models.PermReadLibrary, models.PermManageUsers}); err != nil {
+			t.Fatalf("CreateRole failed: %v", err)
+		}
+		managerCookie := testutil.CookieForUser(t, server, "manager", "password", "usermanager")
+		for _, role := range []string{"admin", "user"} {
+			if rr := do("POST", "/api/admin/invites", `{"role":"`+role+`"}`, managerCookie); rr.Code != http.StatusForbidden {
+				t.Errorf("Expected an invite for %s to be refused, got %d", role, rr.Code)
+			}
+		}
+	})
+
+	t.Run("Register With Invite", func(t *testing.T) {
+		if rr := register("newbie", ""); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 without an invite while registration is closed, got %d", rr.Code)
+		}
+		if rr := register("newbie", "wrong"); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a wrong code, got %d", rr.Code)
+		}
+		// A wrong code does not reveal that the username exists
+		if rr := register("reader", "wrong"); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a wrong code with a taken username, got %d", rr.Code)
+		}
+		if rr := register("reader", code); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 for a taken username, got %d", rr.Code)
+		}
+		rr := register("newbie", code)
+		if rr.Code != http.StatusCreated || !bytes.Contains(rr.Body.Bytes(), []byte(`"active"`)) {
+			t.Fatalf("Expected an active account, got %d %s", rr.Code, rr.Body.String())
+		}
+		if len(rr.Result().Cookies()) == 0 {
+			t.Error("Expected the new user to be logged in")
+		}
+		if rr := register("another", code); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected a used up invite to be rejected, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Open Registration", func(t *testing.T) {
+		cfg.Registration.Open = true
+		defer func() { cfg.Registration.Open = false }()
+
+		rr := do("GET", "/api/config", "", nil)
+		if !bytes.Contains(rr.Body.Bytes(), []byte(`"registration_open":true`)) {
+			t.Errorf("Expected registration_open in config, got %s", rr.Body.String())
+		}
+
+		rr = register("walkin", "")
+		if rr.Code != http.StatusCreated || !bytes.Contains(rr.Body.Bytes(), []byte(`"pending"`)) {
+			t.Fatalf("Expected a pending account, got %d %s", rr.Code, rr.Body.String())
+		}
+		login := `{"username":"walkin","password":"secret"}`
+		if rr := do("POST", "/api/users/login", login, nil); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected pending users to be refused, got %d", rr.Code)
+		}
+
+		user, _ := server.Store().GetUserByUsername("walkin")
+		path := fmt.Sprintf("/api/admin/users/%d/approve", user.ID)
+		// Approving grants the account its role, so user managers need all of its permissions
+		approverCookie := testutil.CookieForUser(t, server, "approver", "password", "usermanager")
+		if rr := do("POST", path, "", approverCookie); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected approving a role with more permissions to be refused, got %d", rr.Code)
+		}
+		if rr := do("POST", path, "", adminCookie); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected 204, got %d %s", rr.Code, rr.Body.String())
+		}
+		if rr := do("POST", path, "", adminCookie); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 approving twice, got %d", rr.Code)
+		}
+		if rr := do("POST", "/api/users/login", login, nil); rr.Code != http.StatusOK {
+			t.Errorf("Expected approved users to log in, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Revoke Invite", func(t *testing.T) {
+		invites, _ := server.Store().ListInvites()
+		path := fmt.Sprintf("/api/admin/invites/%d", invites[0].ID)
+		if rr := do("DELETE", path, "", adminCookie); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected 204, got %d", rr.Code)
+		}
+		if rr := do("DELETE", path, "", adminCookie); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404, got %d", rr.Code)
+		}
+	})
+}
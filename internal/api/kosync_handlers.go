@@ -0,0 +1,182 @@
+package api
+
+// This file implements the KOReader sync server protocol (kosync), so KOReader's built-in
+// "Progress sync" plugin can be pointed at mango-go. Documents are matched to chapters by
+// content hash or by KOReader's own partial MD5 of the file, and pushed positions are mapped
+// onto the same progress the web reader uses.
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+)
+
+// kosync error codes, as defined by the reference koreader-sync-server.
+const (
+	kosyncErrUnauthorized         = 2001
+	kosyncErrInvalidFields        = 2003
+	kosyncErrDocumentMissing      = 2004
+	kosyncErrRegistrationDisabled = 2005
+)
+
+// kosyncDevice is reported as the device for positions that come from mango-go itself.
+const kosyncDevice = "mango-go"
+
+// kosyncProgress is the body of PUT /syncs/progress and the response of GET /syncs/progress.
+type kosyncProgress struct {
+	Document   string   `json:"document,omitempty"`
+	Progress   string   `json:"progress,omitempty"`
+	Percentage *float64 `json:"percentage,omitempty"`
+	Device     string   `json:"device,omitempty"`
+	DeviceID   string   `json:"device_id,omitempty"`
+	Timestamp  int64    `json:"timestamp,omitempty"`
+}
+
+func respondWithKOSyncError(w http.ResponseWriter, status, code int, message string) {
+	RespondWithJSON(w, status, map[string]interface{}{"code": code, "message": message})
+}
+
+// handleKOSyncCreateUser answers KOReader's "Register" button. Accounts are managed by mango-go,
+// so no accounts are created. The answer is the same for every username, so it does not reveal
+// which accounts exist.
+func (s *Server) handleKOSyncCreateUser(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Username == "" || payload.Password == "" {
+		respondWithKOSyncError(w, http.StatusForbidden, kosyncErrInvalidFields, "Invalid request")
+		return
+	}
+	respondWithKOSyncError(w, http.StatusPaymentRequired, kosyncErrRegistrationDisabled, "User registration is disabled.")
+}
+
+// handleKOSyncAuth answers KOReader's "Login" button; KOSyncAuthMiddleware has already checked the key.
+func (s *Server) handleKOSyncAuth(w http.ResponseWriter, r *http.Request) {
+	RespondWithJSON(w, http.StatusOK, map[string]string{"authorized": "OK"})
+}
+
+// handleKOSyncPutProgress stores a device's position and, for library chapters, updates the
+// user's reading progress to match.
+func (s *Server) handleKOSyncPutProgress(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+
+	var payload kosyncProgress
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		respondWithKOSyncError(w, http.StatusForbidden, kosyncErrInvalidFields, "Invalid request")
+		return
+	}
+	if payload.Document == "" {
+		respondWithKOSyncError(w, http.StatusForbidden, kosyncErrDocumentMissing, "Field 'document' not provided.")
+		return
+	}
+	if payload.Percentage == nil || payload.Progress == "" || payload.Device == "" {
+		respondWithKOSyncError(w, http.StatusForbidden, kosyncErrInvalidFields, "Invalid request")
+		return
+	}
+
+	now := time.Now()
+	err := s.store.SaveKOSyncProgress(user.ID, &store.KOSyncProgress{
+		Document:   payload.Document,
+		Progress:   payload.Progress,
+		Percentage: *payload.Percentage,
+		Device:     payload.Device,
+		DeviceID:   payload.DeviceID,
+		UpdatedAt:  now,
+	})
+	if err != nil {
+		log.Printf("Failed to save kosync progress for %s: %v", payload.Document, err)
+		RespondWithError(w, http.StatusInternalServerError, "Failed to save progress")
+		return
+	}
+
+	if chapter := s.kosyncChapter(payload.Document, user); chapter != nil {
+		progress := kosyncPercent(*payload.Percentage)
+		// Re-reading a finished chapter should not mark it unread again.
+		read := chapter.Read || progress >= 99
+		if err := s.store.UpdateChapterProgress(chapter.ID, user.ID, progress, read); err != nil {
+			log.Printf("Failed to update progress for chapter %d: %v", chapter.ID, err)
+		}
+	}
+
+	RespondWithJSON(w, http.StatusOK, kosyncProgress{Document: payload.Document, Timestamp: now.Unix()})
+}
+
+// handleKOSyncGetProgress returns the position to resume from. The device's own record is
+// returned while it still matches the library progress; if the chapter was read elsewhere since,
+// the library progress is returned as a page number instead.
+func (s *Server) handleKOSyncGetProgress(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	document := chi.URLParam(r, "document")
+
+	saved, err := s.store.GetKOSyncProgress(user.ID, document)
+	if err != nil {
+		log.Printf("Failed to load kosync progress for %s: %v", document, err)
+		RespondWithError(w, http.StatusInternalServerError, "Failed to load progress")
+		return
+	}
+
+	chapter := s.kosyncChapter(document, user)
+	if chapter != nil && (saved == nil || kosyncPercent(saved.Percentage) != chapter.ProgressPercent) {
+		if chapter.ProgressPercent == 0 && !chapter.Read {
+			RespondWithJSON(w, http.StatusOK, kosyncProgress{})
+			return
+		}
+		percentage := float64(chapter.ProgressPercent) / 100
+		RespondWithJSON(w, http.StatusOK, kosyncProgress{
+			Document:   document,
+			Progress:   strconv.Itoa(lastReadPage(chapter.ProgressPercent, chapter.PageCount) + 1),
+			Percentage: &percentage,
+			Device:     kosyncDevice,
+			DeviceID:   kosyncDevice,
+		})
+		return
+	}
+
+	if saved == nil {
+		RespondWithJSON(w, http.StatusOK, kosyncProgress{})
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, kosyncProgress{
+		Document:   saved.Document,
+		Progress:   saved.Progress,
+		Percentage: &saved.Percentage,
+		Device:     saved.Device,
+		DeviceID:   saved.DeviceID,
+		Timestamp:  saved.UpdatedAt.Unix(),
+	})
+}
+
+// kosyncChapter returns the library chapter for a KOReader document, or nil if it is not one
+// or is hidden from the user.
+func (s *Server) kosyncChapter(document string, user *models.User) *models.Chapter {
+	chapterID, err := s.store.GetChapterIDByDocument(document)
+	if err != nil {
+		if !errors.Is(err, store.ErrChapterNotFound) {
+			log.Printf("Failed to look up kosync document %s: %v", document, err)
+		}
+		return nil
+	}
+	if ok, err := s.store.CanAccessChapter(user.ID, chapterID); err != nil || !ok {
+		return nil
+	}
+	chapter, err := s.store.GetChapterByID(chapterID, user.ID)
+	if err != nil {
+		log.Printf("Failed to load chapter %d for kosync: %v", chapterID, err)
+		return nil
+	}
+	return chapter
+}
+
+// kosyncPercent converts KOReader's 0-1 percentage into the web reader's whole percent.
+func kosyncPercent(percentage float64) int {
+	return int(math.Max(0, math.Min(100, math.Round(percentage*100))))
+}
@@ -0,0 +1,141 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestKOSync(t *testing.T) {
+	server, db, _ := testutil.SetupTestServer(t)
+	router := server.Router()
+	testutil.PersistOneFolderAndChapter(t, db)
+	// Logging in records the KOReader sync key for the password
+	testutil.CookieForUser(t, server, "reader", "password", "user")
+	user, _ := server.Store().GetUserByUsername("reader")
+	key := auth.KOSyncKey("password")
+
+	do := func(method, path, body, username, key string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Accept", "application/vnd.koreader.v1+json")
+		if username != "" {
+			req.Header.Set("x-auth-user", username)
+			req.Header.Set("x-auth-key", key)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	decode := func(rr *httptest.ResponseRecorder) map[string]interface{} {
+		t.Helper()
+		var body map[string]interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Invalid JSON response: %v\n%s", err, rr.Body.String())
+		}
+		return body
+	}
+
+	t.Run("Auth", func(t *testing.T) {
+		rr := do("GET", "/kosync/users/auth", "", "reader", key)
+		if rr.Code != http.StatusOK || decode(rr)["authorized"] != "OK" {
+			t.Fatalf("Expected authorized, got %d %s", rr.Code, rr.Body.String())
+		}
+
+		rr = do("GET", "/kosync/users/auth", "", "reader", auth.KOSyncKey("wrong"))
+		if rr.Code != http.StatusUnauthorized || decode(rr)["code"] != float64(2001) {
+			t.Errorf("Expected kosync 401, got %d %s", rr.Code, rr.Body.String())
+		}
+		if rr := do("GET", "/kosync/users/auth", "", "", ""); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without headers, got %d", rr.Code)
+		}
+
+		// The password-derived key is not enough for accounts with two-factor authentication
+		testutil.CookieForUser(t, server, "guarded", "password", "user")
+		guarded, _ := server.Store().GetUserByUsername("guarded")
+		if err := server.Store().EnableUserTOTP(guarded.ID, 0, nil); err != nil {
+			t.Fatalf("Failed to enable 2FA: %v", err)
+		}
+		if rr := do("GET", "/kosync/users/auth", "", "guarded", key); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for an account with 2FA, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Create User", func(t *testing.T) {
+		// Existing and unknown usernames get the same answer
+		for _, username := range []string{"reader", "newbie"} {
+			rr := do("POST", "/kosync/users/create", `{"username":"`+username+`","password":"`+key+`"}`, "", "")
+			if rr.Code != http.StatusPaymentRequired || decode(rr)["code"] != float64(2005) {
+				t.Errorf("Expected registration disabled for %s, got %d %s", username, rr.Code, rr.Body.String())
+			}
+		}
+		if _, err := server.Store().GetUserByUsername("newbie"); err == nil {
+			t.Error("kosync registration must not create accounts")
+		}
+	})
+
+	t.Run("Library Document", func(t *testing.T) {
+		rr := do("GET", "/kosync/syncs/progress/hash1", "", "reader", key)
+		if rr.Code != http.StatusOK || len(decode(rr)) != 0 {
+			t.Fatalf("Expected empty progress, got %d %s", rr.Code, rr.Body.String())
+		}
+
+		body := `{"document":"hash1","progress":"1","percentage":0.5,"device":"Kobo","device_id":"abc"}`
+		rr = do("PUT", "/kosync/syncs/progress", body, "reader", key)
+		if rr.Code != http.StatusOK || decode(rr)["document"] != "hash1" {
+			t.Fatalf("Expected progress saved, got %d %s", rr.Code, rr.Body.String())
+		}
+		chapter, _ := server.Store().GetChapterByID(1, user.ID)
+		if chapter.ProgressPercent != 50 || chapter.Read {
+			t.Errorf("Expected 50%% chapter progress, got %d (read=%v)", chapter.ProgressPercent, chapter.Read)
+		}
+
+		got := decode(do("GET", "/kosync/syncs/progress/hash1", "", "reader", key))
+		if got["progress"] != "1" || got["percentage"] != 0.5 || got["device"] != "Kobo" || got["device_id"] != "abc" {
+			t.Errorf("Expected the device's own record, got %v", got)
+		}
+
+		// Reading on the web moves the library progress past the device's record
+		if err := server.Store().UpdateChapterProgress(1, user.ID, 100, true); err != nil {
+			t.Fatalf("Failed to update progress: %v", err)
+		}
+		got = decode(do("GET", "/kosync/syncs/progress/hash1", "", "reader", key))
+		if got["progress"] != "2" || got["percentage"] != 1.0 || got["device"] != "mango-go" {
+			t.Errorf("Expected the library progress, got %v", got)
+		}
+	})
+
+	t.Run("KOReader Digest", func(t *testing.T) {
+		if err := server.Store().UpdateChapterKOReaderHash(1, "partialmd5"); err != nil {
+			t.Fatalf("Failed to set KOReader hash: %v", err)
+		}
+		body := `{"document":"partialmd5","progress":"1","percentage":0.5,"device":"Kobo"}`
+		if rr := do("PUT", "/kosync/syncs/progress", body, "reader", key); rr.Code != http.StatusOK {
+			t.Fatalf("Expected progress saved, got %d", rr.Code)
+		}
+		chapter, _ := server.Store().GetChapterByID(1, user.ID)
+		if chapter.ProgressPercent != 50 || !chapter.Read {
+			t.Errorf("Expected 50%% progress that stays read, got %d (read=%v)", chapter.ProgressPercent, chapter.Read)
+		}
+	})
+
+	t.Run("Other Document", func(t *testing.T) {
+		body := `{"document":"elsewhere","progress":"/body/DocFragment[3]","percentage":0.25,"device":"Kobo"}`
+		if rr := do("PUT", "/kosync/syncs/progress", body, "reader", key); rr.Code != http.StatusOK {
+			t.Fatalf("Expected progress saved, got %d", rr.Code)
+		}
+		got := decode(do("GET", "/kosync/syncs/progress/elsewhere", "", "reader", key))
+		if got["progress"] != "/body/DocFragment[3]" || got["percentage"] != 0.25 {
+			t.Errorf("Expected the stored record, got %v", got)
+		}
+
+		rr := do("PUT", "/kosync/syncs/progress", `{"progress":"1","percentage":0.1,"device":"Kobo"}`, "reader", key)
+		if rr.Code != http.StatusForbidden || decode(rr)["code"] != float64(2004) {
+			t.Errorf("Expected missing document error, got %d %s", rr.Code, rr.Body.String())
+		}
+	})
+}
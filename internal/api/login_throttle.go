@@ -0,0 +1,188 @@
+package api
+
+// This file limits password guessing on the login endpoints: exponential backoff per account
+// and per IP address, temporary account lockout, and the admin endpoints to review failed
+// attempts and unlock accounts. The state lives in the database, so it survives restarts.
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+// loginBackoff returns how long to wait before the next attempt after the given number of
+// consecutive failures: the base delay, doubled for each further failure, up to the maximum.
+func loginBackoff(base, max time.Duration, failures int) time.Duration {
+	if base <= 0 || failures <= 0 {
+		return 0
+	}
+	if failures > 30 {
+		failures = 30 // keeps the doubling within time.Duration's range
+	}
+	delay := time.Duration(float64(base) * math.Pow(2, float64(failures-1)))
+	if max > 0 && (delay > max || delay <= 0) {
+		return max
+	}
+	return delay
+}
+
+// respondTooManyAttempts rejects a login attempt that came too soon or while locked out.
+func respondTooManyAttempts(w http.ResponseWriter, status int, wait time.Duration, message string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	RespondWithError(w, status, message)
+}
+
+// ipLoginBlocked checks the request's IP address against the per-IP limits, answering the
+// request if it must wait.
+func (s *Server) ipLoginBlocked(w http.ResponseWriter, r *http.Request) bool {
+	cfg := s.app.Config().Login
+	if cfg.WindowMinutes <= 0 || (cfg.IPMaxFailures <= 0 && cfg.BackoffSeconds <= 0) {
+		return false
+	}
+	window := time.Duration(cfg.WindowMinutes) * time.Minute
+	failures, last, err := s.store.FailedLoginsFromIP(clientIP(r), time.Now().Add(-window))
+	if err != nil {
+		log.Printf("Failed to check login attempts: %v", err)
+		return false
+	}
+	if failures == 0 {
+		return false
+	}
+
+	wait := time.Until(last.Add(loginBackoff(seconds(cfg.BackoffSeconds), seconds(cfg.MaxBackoffSeconds), failures)))
+	if cfg.IPMaxFailures > 0 && failures >= cfg.IPMaxFailures {
+		wait = time.Until(last.Add(window))
+	}
+	if wait > 0 {
+		respondTooManyAttempts(w, http.StatusTooManyRequests, wait, "Too many failed login attempts, please try again later")
+		return true
+	}
+	return false
+}
+
+// accountLoginBlocked checks whether an account is locked or in backoff, answering the
+// request if so.
+func (s *Server) accountLoginBlocked(w http.ResponseWriter, userID int64) bool {
+	state, err := s.store.GetUserLoginState(userID)
+	if err != nil {
+		log.Printf("Failed to check login state for user %d: %v", userID, err)
+		return false
+	}
+	if state.LockedUntil != nil {
+		respondTooManyAttempts(w, http.StatusLocked, time.Until(*state.LockedUntil), "Account is temporarily locked after too many failed logins")
+		return true
+	}
+	if state.LastFailedAt == nil {
+		return false
+	}
+	cfg := s.app.Config().Login
+	wait := time.Until(state.LastFailedAt.Add(loginBackoff(seconds(cfg.BackoffSeconds), seconds(cfg.MaxBackoffSeconds), state.FailedCount)))
+	if wait > 0 {
+		respondTooManyAttempts(w, http.StatusTooManyRequests, wait, "Too many failed login attempts, please try again later")
+		return true
+	}
+	return false
+}
+
+// recordFailedLogin records a rejected password or code and locks the account once it reaches
+// the configured number of consecutive failures. userID is 0 for unknown usernames.
+func (s *Server) recordFailedLogin(r *http.Request, userID int64, username string) {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	failures, err := s.store.RecordFailedLogin(userID, username, clientIP(r), userAgent)
+	if err != nil {
+		log.Printf("Failed to record failed login for %q: %v", username, err)
+		return
+	}
+	cfg := s.app.Config().Login
+	if userID == 0 || cfg.MaxFailures <= 0 || failures < cfg.MaxFailures {
+		return
+	}
+	lockout := time.Duration(cfg.LockoutMinutes) * time.Minute
+	if err := s.store.LockUser(userID, time.Now().Add(lockout)); err != nil {
+		log.Printf("Failed to lock user %d: %v", userID, err)
+		return
+	}
+	log.Printf("Locked account %q for %v after %d failed logins (last from %s)", username, lockout, failures, clientIP(r))
+}
+
+// credentialsUser checks credentials that clients send with every request (OPDS Basic auth,
+// KOReader sync) against cache, under the same limits as the login page: failures are recorded,
+// and blocked addresses and accounts are refused. It returns the user, or nil with answered
+// reporting whether a blocked request has already been answered.
+func (s *Server) credentialsUser(w http.ResponseWriter, r *http.Request, cache *basicAuthCache, username, secret string) (user *models.User, answered bool) {
+	if s.ipLoginBlocked(w, r) {
+		return nil, true
+	}
+	var accountID int64
+	if account, err := s.store.GetUserByUsername(username); err == nil {
+		accountID = account.ID
+		if s.accountLoginBlocked(w, accountID) {
+			return nil, true
+		}
+	}
+	user = cache.authenticate(s.store, username, secret)
+	if user == nil {
+		s.recordFailedLogin(r, accountID, username)
+		return nil, false
+	}
+	// Only write when there is something to clear, as this runs for every request.
+	if state, err := s.store.GetUserLoginState(user.ID); err == nil && state.FailedCount > 0 {
+		s.resetLoginFailures(user.ID)
+	}
+	return user, false
+}
+
+// resetLoginFailures clears an account's failures after a successful login.
+func (s *Server) resetLoginFailures(userID int64) {
+	if err := s.store.ResetLoginFailures(userID); err != nil {
+		log.Printf("Failed to reset login failures for user %d: %v", userID, err)
+	}
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
+
+// handleAdminUnlockUser lifts a lockout and clears the account's failure count.
+func (s *Server) handleAdminUnlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	target, err := s.store.GetUserByID(userID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	// Otherwise a user manager could keep lifting the lockout of an admin account under attack.
+	if !canManageUser(w, r, target) {
+		return
+	}
+	if err := s.store.ResetLoginFailures(userID); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to unlock user")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAdminListFailedLogins lists failed login attempts, newest first. It can be narrowed to
+// one username and is paginated with page and per_page.
+func (s *Server) handleAdminListFailedLogins(w http.ResponseWriter, r *http.Request) {
+	page, perPage, _, _, _ := getListParams(r)
+	attempts, err := s.store.ListFailedLogins(r.URL.Query().Get("username"), perPage, (page-1)*perPage)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list failed logins: %v", err))
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, attempts)
+}
@@ -0,0 +1,201 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/config"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestLoginThrottling(t *testing.T) {
+	server, db, _ := testutil.SetupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Login = config.LoginConfig{
+			MaxFailures:       3,
+			LockoutMinutes:    15,
+			IPMaxFailures:     5,
+			WindowMinutes:     15,
+			BackoffSeconds:    60,
+			MaxBackoffSeconds: 60,
+		}
+		cfg.TrustedProxies = []string{"10.0.0.1"}
+	})
+	router := server.Router()
+	adminCookie := testutil.CookieForUser(t, server, "boss", "password", "admin")
+	testutil.CookieForUser(t, server, "reader", "password", "user")
+	reader, _ := server.Store().GetUserByUsername("reader")
+
+	// loginVia logs in from the TCP peer address peer, with an X-Forwarded-For header unless
+	// forwardedFor is empty.
+	loginVia := func(username, password, peer, forwardedFor string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"username":%q,"password":%q}`, username, password)
+		req, _ := http.NewRequest("POST", "/api/users/login", bytes.NewBufferString(body))
+		req.RemoteAddr = peer + ":40000"
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	login := func(username, password, ip string) *httptest.ResponseRecorder {
+		return loginVia(username, password, ip, "")
+	}
+	// skipBackoff pretends the last failures happened long ago, so the next attempt is allowed.
+	skipBackoff := func() {
+		db.Exec("UPDATE users SET last_failed_login_at = datetime('now', '-1 hour')")
+		db.Exec("UPDATE failed_logins SET attempted_at = datetime('now', '-1 hour')")
+	}
+
+	t.Run("Backoff", func(t *testing.T) {
+		if rr := login("reader", "wrong", "198.51.100.1"); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401, got %d", rr.Code)
+		}
+		// Even the right password has to wait, from another address too
+		rr := login("reader", "password", "198.51.100.2")
+		if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+			t.Fatalf("Expected 429 with Retry-After, got %d", rr.Code)
+		}
+		skipBackoff()
+		if rr := login("reader", "password", "198.51.100.2"); rr.Code != http.StatusOK {
+			t.Fatalf("Expected a login after the backoff, got %d", rr.Code)
+		}
+		if state, _ := server.Store().GetUserLoginState(reader.ID); state.FailedCount != 0 {
+			t.Errorf("Expected a successful login to clear failures, got %d", state.FailedCount)
+		}
+	})
+
+	t.Run("Lockout", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			skipBackoff()
+			login("reader", "wrong", fmt.Sprintf("198.51.100.%d", 10+i))
+		}
+		skipBackoff()
+		if rr := login("reader", "password", "198.51.100.20"); rr.Code != http.StatusLocked {
+			t.Fatalf("Expected the account to be locked, got %d", rr.Code)
+		}
+
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/users", nil)
+		req.AddCookie(adminCookie)
+		router.ServeHTTP(rr, req)
+		var users []map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &users)
+		for _, u := range users {
+			if u["username"] == "reader" && u["locked_until"] == nil {
+				t.Error("Expected the user list to show the lock")
+			}
+		}
+
+		rr = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", fmt.Sprintf("/api/admin/users/%d/unlock", reader.ID), nil)
+		req.AddCookie(adminCookie)
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected 204, got %d", rr.Code)
+		}
+		if rr := login("reader", "password", "198.51.100.20"); rr.Code != http.StatusOK {
+			t.Errorf("Expected a login after unlocking, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Per IP Limit", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			skipBackoff()
+			login(fmt.Sprintf("nobody%d", i), "wrong", "192.0.2.50")
+		}
+		db.Exec("UPDATE failed_logins SET attempted_at = datetime('now', '-2 minutes')")
+		if rr := login("boss", "password", "192.0.2.50"); rr.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected the address to be blocked, got %d", rr.Code)
+		}
+		if rr := login("boss", "password", "192.0.2.51"); rr.Code != http.StatusOK {
+			t.Errorf("Expected other addresses to be unaffected, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Forwarded Addresses", func(t *testing.T) {
+		// Clients cannot escape the per-IP limit by sending a different X-Forwarded-For each time
+		for i := 0; i < 5; i++ {
+			skipBackoff()
+			loginVia(fmt.Sprintf("nobody%d", i), "wrong", "192.0.2.60", fmt.Sprintf("203.0.113.%d", i))
+		}
+		db.Exec("UPDATE failed_logins SET attempted_at = datetime('now', '-2 minutes')")
+		if rr := loginVia("boss", "password", "192.0.2.60", "203.0.113.99"); rr.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected the peer address to be blocked, got %d", rr.Code)
+		}
+
+		// Behind a trusted proxy, the client it reports is limited rather than the proxy
+		for i := 0; i < 5; i++ {
+			skipBackoff()
+			loginVia(fmt.Sprintf("nobody%d", i), "wrong", "10.0.0.1", "203.0.113.99, 192.0.2.70")
+		}
+		db.Exec("UPDATE failed_logins SET attempted_at = datetime('now', '-2 minutes')")
+		if rr := loginVia("boss", "password", "10.0.0.1", "192.0.2.70"); rr.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected the forwarded client to be blocked, got %d", rr.Code)
+		}
+		if rr := loginVia("boss", "password", "10.0.0.1", "192.0.2.71"); rr.Code != http.StatusOK {
+			t.Errorf("Expected other clients of the proxy to be unaffected, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Failed Attempt Record", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/admin/failed-logins?username=reader&per_page=2", nil)
+		req.AddCookie(adminCookie)
+		router.ServeHTTP(rr, req)
+		var attempts []map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &attempts)
+		if rr.Code != http.StatusOK || len(attempts) != 2 {
+			t.Fatalf("Expected 2 attempts, got %d %s", rr.Code, rr.Body.String())
+		}
+		if attempts[0]["username"] != "reader" || attempts[0]["ip_address"] == "" {
+			t.Errorf("Unexpected attempt %+v", attempts[0])
+		}
+	})
+	t.Run("OPDS And KOReader Sync", func(t *testing.T) {
+		if err := server.Store().ResetLoginFailures(reader.ID); err != nil {
+			t.Fatalf("Failed to reset failures: %v", err)
+		}
+		opds := func(password, ip string) int {
+			req, _ := http.NewRequest("GET", "/opds/", nil)
+			req.SetBasicAuth("reader", password)
+			req.RemoteAddr = ip + ":40000"
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			return rr.Code
+		}
+		kosync := func(password, ip string) int {
+			req, _ := http.NewRequest("GET", "/kosync/users/auth", nil)
+			req.Header.Set("x-auth-user", "reader")
+			req.Header.Set("x-auth-key", auth.KOSyncKey(password))
+			req.RemoteAddr = ip + ":40000"
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			return rr.Code
+		}
+
+		skipBackoff()
+		if code := opds("wrong", "198.51.100.30"); code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401, got %d", code)
+		}
+		if code := opds("password", "198.51.100.31"); code != http.StatusTooManyRequests {
+			t.Errorf("Expected OPDS to wait out the backoff, got %d", code)
+		}
+		for i := 0; i < 2; i++ {
+			skipBackoff()
+			kosync("wrong", fmt.Sprintf("198.51.100.%d", 32+i))
+		}
+		skipBackoff()
+		if code := opds("password", "198.51.100.34"); code != http.StatusLocked {
+			t.Errorf("Expected OPDS to refuse a locked account, got %d", code)
+		}
+		if code := kosync("password", "198.51.100.34"); code != http.StatusLocked {
+			t.Errorf("Expected KOReader sync to refuse a locked account, got %d", code)
+		}
+	})
+}
@@ -1,12 +1,20 @@
 package api
 
-// This file contains the middleware for handling authentication and role-based authorization.
+// This file contains the middleware for handling authentication and permission-based authorization.
 
 import (
 	"context"
+	"crypto/sha256"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/vrsandeep/mango-go/internal/auth"
 	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
 )
 
 // contextKey is a private type to prevent collisions with other context keys.
@@ -14,20 +22,34 @@ type contextKey string
 
 const userContextKey = contextKey("user")
 
+// readOnlyTokenContextKey marks requests authenticated with a read-only API token.
+const readOnlyTokenContextKey = contextKey("read-only-token")
+
 // AuthMiddleware is a middleware that verifies a user's session.
 // If the session is valid, it retrieves the user's details from the database
 // and injects them into the request's context for downstream handlers to use.
+// Personal API tokens sent as "Authorization: Bearer" are accepted in place of the cookie, as is
+// the username header set by a trusted authenticating proxy when proxy auth is enabled.
 func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("session_token")
-		if err != nil {
+		if token, ok := bearerToken(r); ok {
+			s.serveWithAPIToken(w, r, token, next)
+			return
+		}
+		if user := s.userFromProxyHeaders(r); user != nil {
+			ctx := context.WithValue(r.Context(), userContextKey, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		if _, err := r.Cookie(sessionCookieName); err != nil {
 			// If no cookie is present, the user is unauthorized.
 			RespondWithError(w, http.StatusUnauthorized, "Unauthorized: No session token")
 			return
 		}
 
-		user, err := s.store.GetUserFromSession(cookie.Value)
-		if err != nil {
+		user := s.userFromSessionCookie(w, r)
+		if user == nil {
 			// If the token is invalid or expired, the user is unauthorized.
 			RespondWithError(w, http.StatusUnauthorized, "Unauthorized: Invalid session")
 			return
@@ -40,23 +62,238 @@ func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// AdminOnlyMiddleware is a middleware that ensures only users with the 'admin' role can access a route.
-// It must be chained *after* the AuthMiddleware.
-func (s *Server) AdminOnlyMiddleware(next http.Handler) http.Handler {
+// serveWithAPIToken authenticates a request with a personal API token.
+func (s *Server) serveWithAPIToken(w http.ResponseWriter, r *http.Request, token string, next http.Handler) {
+	user, apiToken, err := s.store.GetUserFromAPIToken(token)
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "Unauthorized: Invalid API token")
+		return
+	}
+	serveAPITokenUser(w, r, user, apiToken, next)
+}
+
+// serveAPITokenUser serves a request authenticated with a valid API token. Read-only tokens are
+// limited to safe methods, and are marked in the context so handlers that record state on
+// reads, such as OPDS page streaming, can skip it.
+func serveAPITokenUser(w http.ResponseWriter, r *http.Request, user *models.User, apiToken *models.APIToken, next http.Handler) {
+	if apiToken.ReadOnly && r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+		RespondWithError(w, http.StatusForbidden, "Forbidden: Read-only API token")
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	ctx = context.WithValue(ctx, readOnlyTokenContextKey, apiToken.ReadOnly)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// isReadOnlyToken reports whether the request was authenticated with a read-only API token.
+func isReadOnlyToken(r *http.Request) bool {
+	readOnly, _ := r.Context().Value(readOnlyTokenContextKey).(bool)
+	return readOnly
+}
+
+// bearerToken returns the token from an "Authorization: Bearer" header, if there is one.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// BasicAuthMiddleware authenticates clients that cannot hold a session cookie, such as OPDS
+// readers, using HTTP Basic credentials checked against the users table. A valid session cookie
+// is accepted too, so the feeds can also be opened from a logged-in browser, as are API tokens.
+// Wrong passwords count as failed logins, and are throttled like those on the login page.
+func (s *Server) BasicAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		user := getUserFromContext(r)
+		if token, ok := bearerToken(r); ok {
+			s.serveWithAPIToken(w, r, token, next)
+			return
+		}
+
+		var user *models.User
+		if username, password, ok := r.BasicAuth(); ok {
+			if strings.HasPrefix(password, store.APITokenPrefix) {
+				// Clients that only speak Basic auth can send an API token as the password.
+				if tokenUser, apiToken, err := s.store.GetUserFromAPIToken(password); err == nil && tokenUser.Username == username {
+					serveAPITokenUser(w, r, tokenUser, apiToken, next)
+					return
+				}
+			} else if u, answered := s.credentialsUser(w, r, s.basicAuth, username, password); answered {
+				return
+			} else if u != nil && !u.TOTPEnabled {
+				// A password alone is not enough for accounts with two-factor authentication.
+				user = u
+			}
+		} else {
+			user = s.userFromSessionCookie(w, r)
+		}
 
-		// This should theoretically not happen if AuthMiddleware is used first, but it's a safe check.
 		if user == nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="mango-go", charset="UTF-8"`)
 			RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 
-		if user.Role != "admin" {
-			RespondWithError(w, http.StatusForbidden, "Forbidden: Administrator access required")
+		ctx := context.WithValue(r.Context(), userContextKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// KOSyncAuthMiddleware authenticates KOReader's progress sync requests. KOReader sends the
+// username in x-auth-user and the MD5 of the password in x-auth-key. Only users allowed to
+// read the library are accepted. The key is derived from the password alone, so accounts with
+// two-factor authentication are refused, as they are for Basic auth, and wrong keys are
+// throttled like wrong passwords.
+func (s *Server) KOSyncAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var user *models.User
+		username, key := r.Header.Get("x-auth-user"), r.Header.Get("x-auth-key")
+		if username != "" && key != "" {
+			u, answered := s.credentialsUser(w, r, s.kosyncAuth, username, strings.ToLower(key))
+			if answered {
+				return
+			}
+			if u != nil && !u.TOTPEnabled {
+				user = u
+			}
+		}
+		// KOReader cannot show a permission error, so users who may not read are unauthorized.
+		if user == nil || !user.HasPermission(models.PermReadLibrary) {
+			respondWithKOSyncError(w, http.StatusUnauthorized, kosyncErrUnauthorized, "Unauthorized")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userContextKey, user)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// basicAuthCacheTTL is how long verified Basic credentials are remembered. Clients send them
+// with every request, and a bcrypt check per page would make reading unusably slow.
+const basicAuthCacheTTL = 5 * time.Minute
+
+// basicAuthCache remembers recently verified Basic credentials, keyed by a hash of the
+// username and password so plaintext passwords are never held in memory.
+type basicAuthCache struct {
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]basicAuthEntry
+	// hashOf returns the stored bcrypt hash the secret is checked against.
+	hashOf func(*models.User) string
+}
+
+type basicAuthEntry struct {
+	userID     int64
+	secretHash string // the hash the secret was verified against
+	expires    time.Time
+}
+
+// newBasicAuthCache returns a cache that verifies secrets against the hash returned by hashOf,
+// e.g. the password hash for Basic auth or the KOReader sync key hash for kosync.
+func newBasicAuthCache(hashOf func(*models.User) string) *basicAuthCache {
+	return &basicAuthCache{entries: make(map[[sha256.Size]byte]basicAuthEntry), hashOf: hashOf}
+}
+
+// authenticate returns the user for the credentials, or nil if they are invalid.
+func (c *basicAuthCache) authenticate(st *store.Store, username, secret string) *models.User {
+	key := sha256.Sum256([]byte(username + "\x00" + secret))
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		// Re-read the user so role changes apply immediately; a changed secret invalidates the entry.
+		if user, err := st.GetUserByID(entry.userID); err == nil && c.hashOf(user) == entry.secretHash {
+			return user
+		}
+	}
+
+	user, err := st.GetUserByUsername(username)
+	if err != nil || c.hashOf(user) == "" || !auth.CheckPasswordHash(secret, c.hashOf(user)) {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+		return nil
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = basicAuthEntry{userID: user.ID, secretHash: c.hashOf(user), expires: now.Add(basicAuthCacheTTL)}
+	c.mu.Unlock()
+	return user
+}
+
+// RequirePermission returns a middleware that only lets through users whose role grants at
+// least one of the given permissions. It must be chained *after* the authentication middleware.
+func (s *Server) RequirePermission(perms ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := getUserFromContext(r)
+
+			// This should theoretically not happen if AuthMiddleware is used first, but it's a safe check.
+			if user == nil {
+				RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+
+			if !user.HasPermission(perms...) {
+				RespondWithError(w, http.StatusForbidden, "Forbidden: Missing permission "+strings.Join(perms, " or "))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// contentAccessChecks maps URL parameters to the store check for the item they name.
+var contentAccessChecks = []struct {
+	param string
+	check func(st *store.Store, userID, id int64) (bool, error)
+}{
+	{"folderID", (*store.Store).CanAccessFolder},
+	{"chapterID", (*store.Store).CanAccessChapter},
+	{"tagID", (*store.Store).CanAccessTag},
+}
+
+// ContentAccessMiddleware responds with 404 Not Found when a folder, chapter or tag named in the
+// URL is hidden from the user by their content restrictions, so hidden items cannot be reached
+// by ID. It must be chained *after* the authentication middleware, on routes whose URL
+// parameters are already resolved.
+func (s *Server) ContentAccessMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getUserFromContext(r)
+		if user == nil {
+			RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 
+		for _, c := range contentAccessChecks {
+			id, err := strconv.ParseInt(chi.URLParam(r, c.param), 10, 64)
+			if err != nil {
+				continue // missing or malformed; the handler reports it
+			}
+			if c.param == "folderID" && id == 0 {
+				continue // the library root
+			}
+			ok, err := c.check(s.store, user.ID, id)
+			if err != nil {
+				RespondWithError(w, http.StatusInternalServerError, "Failed to check access")
+				return
+			}
+			if !ok {
+				RespondWithError(w, http.StatusNotFound, "Not found")
+				return
+			}
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
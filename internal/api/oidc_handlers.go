@@ -0,0 +1,404 @@
+package api
+
+// This file implements single sign-on through an OpenID Connect provider, using the
+// authorization code flow with PKCE. Users are provisioned on first login and then get
+// the same session cookie as a password login. A signed-in user can link their existing account
+// to an identity; accounts are never linked by matching usernames.
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/vrsandeep/mango-go/internal/config"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+	"golang.org/x/oauth2"
+)
+
+// oidcStateTTL is how long a user has to complete a login at the provider.
+const oidcStateTTL = 10 * time.Minute
+
+// oidcStateCookie binds a pending login to the browser that started it.
+const oidcStateCookie = "oidc_state"
+
+// maxOIDCUsernameSuffix bounds the numbered usernames tried when a new identity's username is
+// already taken.
+const maxOIDCUsernameSuffix = 100
+
+// oidcLogin holds the provider connection and the logins that are waiting for a callback.
+type oidcLogin struct {
+	cfg config.OIDCConfig
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+	pending  map[string]oidcPendingLogin // keyed by state
+}
+
+type oidcPendingLogin struct {
+	verifier   string // PKCE code verifier
+	nonce      string
+	linkUserID int64 // the signed-in user linking their account, or 0 for a login
+	expires    time.Time
+}
+
+func newOIDCLogin(cfg config.OIDCConfig) *oidcLogin {
+	return &oidcLogin{cfg: cfg, pending: make(map[string]oidcPendingLogin)}
+}
+
+// discover fetches the provider's metadata on first use, so mango-go still starts when the
+// provider is unreachable.
+func (o *oidcLogin) discover(ctx context.Context) (*oidc.Provider, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.provider != nil {
+		return o.provider, nil
+	}
+	provider, err := oidc.NewProvider(ctx, o.cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	o.provider = provider
+	return provider, nil
+}
+
+func (o *oidcLogin) oauth2Config(provider *oidc.Provider, redirectURL string) *oauth2.Config {
+	scopes := o.cfg.Scopes
+	if !slices.Contains(scopes, oidc.ScopeOpenID) {
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+	}
+	return &oauth2.Config{
+		ClientID:     o.cfg.ClientID,
+		ClientSecret: o.cfg.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURL,
+		Scopes:       scopes,
+	}
+}
+
+// oidcRedirectURL returns the callback URL sent to the provider: redirect_url if it is set, or
+// else one derived from the request. X-Forwarded-Proto is only believed from trusted proxies,
+// like the other forwarded headers.
+func (s *Server) oidcRedirectURL(r *http.Request) string {
+	if s.oidc.cfg.RedirectURL != "" {
+		return s.oidc.cfg.RedirectURL
+	}
+	scheme := "http"
+	if r.TLS != nil || (s.trustedProxies.contains(peerIP(r)) && r.Header.Get("X-Forwarded-Proto") == "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/api/auth/oidc/callback"
+}
+
+// start records a new pending login and returns its state.
+func (o *oidcLogin) start(verifier, nonce string, linkUserID int64) (string, error) {
+	state, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	now := time.Now()
+	for k, p := range o.pending {
+		if now.After(p.expires) {
+			delete(o.pending, k)
+		}
+	}
+	o.pending[state] = oidcPendingLogin{verifier: verifier, nonce: nonce, linkUserID: linkUserID, expires: now.Add(oidcStateTTL)}
+	return state, nil
+}
+
+// finish removes and returns the pending login for a state. Each state can be used once.
+func (o *oidcLogin) finish(state string) (oidcPendingLogin, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	p, ok := o.pending[state]
+	delete(o.pending, state)
+	if !ok || time.Now().After(p.expires) {
+		return oidcPendingLogin{}, false
+	}
+	return p, true
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// handleOIDCLogin sends the browser to the identity provider.
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	s.redirectToProvider(w, r, 0)
+}
+
+// handleOIDCLink sends the signed-in user to the identity provider to link their account to the
+// identity they log in with there.
+func (s *Server) handleOIDCLink(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	if _, err := r.Cookie(sessionCookieName); err != nil {
+		RespondWithError(w, http.StatusForbidden, "Linking an identity requires a browser session")
+		return
+	}
+	s.redirectToProvider(w, r, user.ID)
+}
+
+// redirectToProvider starts a login, or with a linkUserID a link, at the identity provider.
+func (s *Server) redirectToProvider(w http.ResponseWriter, r *http.Request, linkUserID int64) {
+	if s.oidc == nil {
+		RespondWithError(w, http.StatusNotFound, "OIDC login is not configured")
+		return
+	}
+	provider, err := s.oidc.discover(r.Context())
+	if err != nil {
+		log.Printf("OIDC discovery failed for %s: %v", s.oidc.cfg.Issuer, err)
+		RespondWithError(w, http.StatusBadGateway, "Identity provider is unavailable")
+		return
+	}
+
+	verifier := oauth2.GenerateVerifier()
+	nonce, err := randomHex(16)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to start login")
+		return
+	}
+	state, err := s.oidc.start(verifier, nonce, linkUserID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to start login")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	authURL := s.oidc.oauth2Config(provider, s.oidcRedirectURL(r)).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handleOIDCCallback completes a login: it exchanges the code, verifies the ID token, provisions
+// the user and starts a session. Failures send the browser back to the login page with a message.
+// A link started by handleOIDCLink instead links the identity to the signed-in user.
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	fail := func(message string) {
+		http.Redirect(w, r, "/login?sso_error="+url.QueryEscape(message), http.StatusFound)
+	}
+	if s.oidc == nil {
+		RespondWithError(w, http.StatusNotFound, "OIDC login is not configured")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", Path: "/api/auth/oidc", MaxAge: -1})
+
+	query := r.URL.Query()
+	if errCode := query.Get("error"); errCode != "" {
+		log.Printf("OIDC provider returned an error: %s %s", errCode, query.Get("error_description"))
+		fail("The identity provider did not complete the login")
+		return
+	}
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if state == "" || err != nil || cookie.Value != state {
+		fail("Login session mismatch, please try again")
+		return
+	}
+	pending, ok := s.oidc.finish(state)
+	if !ok {
+		fail("Login expired, please try again")
+		return
+	}
+
+	provider, err := s.oidc.discover(r.Context())
+	if err != nil {
+		log.Printf("OIDC discovery failed for %s: %v", s.oidc.cfg.Issuer, err)
+		fail("Identity provider is unavailable")
+		return
+	}
+	oauthCfg := s.oidc.oauth2Config(provider, s.oidcRedirectURL(r))
+	token, err := oauthCfg.Exchange(r.Context(), query.Get("code"), oauth2.VerifierOption(pending.verifier))
+	if err != nil {
+		log.Printf("OIDC code exchange failed: %v", err)
+		fail("Login failed at the identity provider")
+		return
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		log.Printf("OIDC token response has no id_token")
+		fail("Login failed at the identity provider")
+		return
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: s.oidc.cfg.ClientID}).Verify(r.Context(), rawIDToken)
+	if err != nil || idToken.Nonce != pending.nonce {
+		log.Printf("OIDC ID token rejected: %v", err)
+		fail("Login failed: invalid identity token")
+		return
+	}
+
+	claims := map[string]interface{}{}
+	if err := idToken.Claims(&claims); err != nil {
+		fail("Login failed: invalid identity token")
+		return
+	}
+	// Some providers only put profile and group claims in the userinfo response.
+	if _, ok := claims[s.oidc.cfg.GroupsClaim]; !ok || claimString(claims, s.oidc.cfg.UsernameClaim) == "" {
+		if info, err := provider.UserInfo(r.Context(), oauth2.StaticTokenSource(token)); err == nil {
+			extra := map[string]interface{}{}
+			if info.Claims(&extra) == nil {
+				for k, v := range extra {
+					if _, exists := claims[k]; !exists {
+						claims[k] = v
+					}
+				}
+			}
+		}
+	}
+
+	if pending.linkUserID != 0 {
+		s.finishOIDCLink(w, r, pending.linkUserID, idToken.Subject)
+		return
+	}
+
+	user, err := s.provisionOIDCUser(idToken.Subject, claims)
+	if err != nil {
+		log.Printf("OIDC login for subject %s failed: %v", idToken.Subject, err)
+		fail(err.Error())
+		return
+	}
+
+	if err := s.startSession(w, r, user.ID); err != nil {
+		fail("Failed to create session")
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// finishOIDCLink links an identity to the user who started the link, as long as the browser is
+// still signed in as that user and the identity is not linked to another account.
+func (s *Server) finishOIDCLink(w http.ResponseWriter, r *http.Request, userID int64, subject string) {
+	fail := func(message string) {
+		http.Redirect(w, r, "/?sso_error="+url.QueryEscape(message), http.StatusFound)
+	}
+	if user := s.userFromSessionCookie(w, r); user == nil || user.ID != userID {
+		fail("Sign in again to link your account")
+		return
+	}
+	if other, err := s.store.GetUserByOIDCSubject(subject); err == nil && other.ID != userID {
+		fail("This identity is already linked to another account")
+		return
+	} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		fail("Failed to link account")
+		return
+	}
+	if err := s.store.LinkUserOIDCSubject(userID, subject); err != nil {
+		if errors.Is(err, store.ErrUserAlreadyLinked) {
+			fail("Your account is already linked to another identity")
+			return
+		}
+		fail("Failed to link account")
+		return
+	}
+	log.Printf("Linked user %d to OIDC subject %s", userID, subject)
+	http.Redirect(w, r, "/?sso_linked=1", http.StatusFound)
+}
+
+// provisionOIDCUser returns the account for an OIDC identity, creating it on first login.
+// Existing local accounts are never taken over: when the username is taken, the new account
+// gets a numbered one instead. When an admin group is configured, membership of it is synced
+// to the admin role on every login.
+func (s *Server) provisionOIDCUser(subject string, claims map[string]interface{}) (*models.User, error) {
+	cfg := s.oidc.cfg
+	groups := claimStrings(claims, cfg.GroupsClaim)
+
+	user, err := s.store.GetUserByOIDCSubject(subject)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to look up user")
+	}
+	if user == nil {
+		username := claimString(claims, cfg.UsernameClaim)
+		if username == "" {
+			username = claimString(claims, "email")
+		}
+		if username == "" {
+			username = subject
+		}
+
+		username, err = s.freeUsername(username)
+		if err != nil {
+			return nil, err
+		}
+		newRole := groupRole(groups, cfg.AdminGroup)
+		// An empty password hash never matches, so the account can only log in through OIDC.
+		user, err = s.store.CreateUser(username, "", newRole)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user %s", username)
+		}
+		user.Role = newRole
+		if err := s.store.LinkUserOIDCSubject(user.ID, subject); err != nil {
+			return nil, fmt.Errorf("failed to link user %s", username)
+		}
+	}
+
+	if user, err = s.syncAdminGroup(user, groups, cfg.AdminGroup); err != nil {
+		return nil, fmt.Errorf("failed to update role")
+	}
+	return user, nil
+}
+
+// freeUsername returns username, or if it is taken the first free one of username-2,
+// username-3 and so on.
+func (s *Server) freeUsername(username string) (string, error) {
+	for n := 1; n <= maxOIDCUsernameSuffix; n++ {
+		candidate := username
+		if n > 1 {
+			candidate += "-" + strconv.Itoa(n)
+		}
+		_, err := s.store.GetUserByUsername(candidate)
+		if errors.Is(err, sql.ErrNoRows) {
+			return candidate, nil
+		} else if err != nil {
+			return "", fmt.Errorf("failed to look up user")
+		}
+	}
+	return "", fmt.Errorf("no free username for %s", username)
+}
+
+// claimString returns a string claim, or "" if it is missing or not a string.
+func claimString(claims map[string]interface{}, name string) string {
+	v, _ := claims[name].(string)
+	return strings.TrimSpace(v)
+}
+
+// claimStrings returns a claim that may be a single string or a list of strings.
+func claimStrings(claims map[string]interface{}, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
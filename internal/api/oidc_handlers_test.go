@@ -0,0 +1,220 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/config"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestOIDCLogin(t *testing.T) {
+	provider := testutil.NewFakeOIDCProvider(t)
+	server, _, _ := testutil.SetupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.OIDC = provider.Config()
+		cfg.OIDC.AdminGroup = "mango-admins"
+	})
+	router := server.Router()
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	var startFlow func(t *testing.T, path string, session *http.Cookie) *httptest.ResponseRecorder
+
+	// login runs the browser side of the flow and returns the callback response.
+	login := func(t *testing.T) *httptest.ResponseRecorder {
+		t.Helper()
+		return startFlow(t, "/api/auth/oidc/login", nil)
+	}
+	startFlow = func(t *testing.T, path string, session *http.Cookie) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest("GET", "http://mango.test"+path, nil)
+		if session != nil {
+			req.AddCookie(session)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusFound {
+			t.Fatalf("Expected a redirect to the provider, got %d %s", rr.Code, rr.Body.String())
+		}
+		authURL, _ := url.Parse(rr.Header().Get("Location"))
+		if authURL.Query().Get("code_challenge_method") != "S256" || authURL.Query().Get("nonce") == "" {
+			t.Fatalf("Expected a PKCE authorization request, got %s", authURL)
+		}
+		if got := authURL.Query().Get("redirect_uri"); got != "http://mango.test/api/auth/oidc/callback" {
+			t.Errorf("Unexpected redirect_uri %s", got)
+		}
+		var stateCookie *http.Cookie
+		for _, c := range rr.Result().Cookies() {
+			if c.Name == "oidc_state" {
+				stateCookie = c
+			}
+		}
+
+		resp, err := noRedirect.Get(authURL.String())
+		if err != nil {
+			t.Fatalf("Authorization request failed: %v", err)
+		}
+		resp.Body.Close()
+		callback, _ := url.Parse(resp.Header.Get("Location"))
+
+		req = httptest.NewRequest("GET", callback.RequestURI(), nil)
+		req.AddCookie(stateCookie)
+		if session != nil {
+			req.AddCookie(session)
+		}
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	sessionCookie := func(rr *httptest.ResponseRecorder) *http.Cookie {
+		for _, c := range rr.Result().Cookies() {
+			if c.Name == "session_token" && c.Value != "" {
+				return c
+			}
+		}
+		return nil
+	}
+
+	t.Run("Config Advertises SSO", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/config", nil))
+		if !strings.Contains(rr.Body.String(), `"oidc_enabled":true`) {
+			t.Errorf("Expected oidc_enabled in config, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Provisions User", func(t *testing.T) {
+		provider.SetIdentity("sub-alice", map[string]interface{}{
+			"preferred_username": "alice",
+			"groups":             []string{"readers"},
+		})
+		rr := login(t)
+		if rr.Code != http.StatusFound || rr.Header().Get("Location") != "/" {
+			t.Fatalf("Expected a redirect home, got %d %s", rr.Code, rr.Header().Get("Location"))
+		}
+		cookie := sessionCookie(rr)
+		if cookie == nil {
+			t.Fatal("Expected a session cookie")
+		}
+
+		req := httptest.NewRequest("GET", "/api/users/me", nil)
+		req.AddCookie(cookie)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var me struct {
+			Username string `json:"username"`
+			Role     string `json:"role"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &me)
+		if me.Username != "alice" || me.Role != "user" {
+			t.Errorf("Expected user alice, got %+v", me)
+		}
+	})
+
+	t.Run("Syncs Admin Role", func(t *testing.T) {
+		provider.SetIdentity("sub-alice", map[string]interface{}{
+			"preferred_username": "alice-renamed",
+			"groups":             []string{"readers", "mango-admins"},
+		})
+		if rr := login(t); sessionCookie(rr) == nil {
+			t.Fatalf("Expected login to succeed, got %s", rr.Header().Get("Location"))
+		}
+		user, err := server.Store().GetUserByUsername("alice")
+		if err != nil || user.Role != "admin" {
+			t.Errorf("Expected the same account to become admin, got %+v (%v)", user, err)
+		}
+	})
+
+	t.Run("Keeps Roles Assigned By Admins", func(t *testing.T) {
+		alice, _ := server.Store().GetUserByUsername("alice")
+		server.Store().CreateRole("reader", "", []string{models.PermReadLibrary})
+		if err := server.Store().UpdateUser(alice.ID, alice.Username, "reader"); err != nil {
+			t.Fatalf("UpdateUser failed: %v", err)
+		}
+		provider.SetIdentity("sub-alice", map[string]interface{}{"groups": []string{"readers"}})
+		if rr := login(t); sessionCookie(rr) == nil {
+			t.Fatalf("Expected login to succeed, got %s", rr.Header().Get("Location"))
+		}
+		if user, _ := server.Store().GetUserByID(alice.ID); user.Role != "reader" {
+			t.Errorf("Expected the assigned role to be kept, got %s", user.Role)
+		}
+	})
+
+	t.Run("Never Takes Over Existing Account", func(t *testing.T) {
+		hash, _ := auth.HashPassword("password")
+		local, _ := server.Store().CreateUser("bob", hash, "user")
+		provider.SetIdentity("sub-bob", map[string]interface{}{"preferred_username": "bob"})
+		if rr := login(t); sessionCookie(rr) == nil {
+			t.Fatalf("Expected login to succeed, got %s", rr.Header().Get("Location"))
+		}
+		created, err := server.Store().GetUserByOIDCSubject("sub-bob")
+		if err != nil || created.ID == local.ID || created.Username != "bob-2" {
+			t.Errorf("Expected a new account bob-2, got %+v (%v)", created, err)
+		}
+	})
+
+	t.Run("Links Signed-In Account", func(t *testing.T) {
+		cookie := testutil.CookieForUser(t, server, "carol", "password", "user")
+		carol, _ := server.Store().GetUserByUsername("carol")
+		provider.SetIdentity("sub-carol", map[string]interface{}{"preferred_username": "someone-else"})
+
+		// Without a session the link cannot be started
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/users/me/oidc/link", nil))
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401 without a session, got %d", rr.Code)
+		}
+
+		rr = startFlow(t, "/api/users/me/oidc/link", cookie)
+		if rr.Code != http.StatusFound || rr.Header().Get("Location") != "/?sso_linked=1" {
+			t.Fatalf("Expected the link to succeed, got %d %s", rr.Code, rr.Header().Get("Location"))
+		}
+		linked, err := server.Store().GetUserByOIDCSubject("sub-carol")
+		if err != nil || linked.ID != carol.ID {
+			t.Errorf("Expected carol's account to be linked, got %+v (%v)", linked, err)
+		}
+		if rr := login(t); sessionCookie(rr) == nil {
+			t.Errorf("Expected SSO login into carol's account, got %s", rr.Header().Get("Location"))
+		}
+
+		// An identity already linked to another account is refused
+		dave := testutil.CookieForUser(t, server, "dave", "password", "user")
+		rr = startFlow(t, "/api/users/me/oidc/link", dave)
+		if !strings.HasPrefix(rr.Header().Get("Location"), "/?sso_error=") {
+			t.Errorf("Expected linking a taken identity to fail, got %s", rr.Header().Get("Location"))
+		}
+	})
+
+	t.Run("Ignores Forwarded Scheme From Untrusted Peers", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://mango.test/api/auth/oidc/login", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		authURL, _ := url.Parse(rr.Header().Get("Location"))
+		if got := authURL.Query().Get("redirect_uri"); got != "http://mango.test/api/auth/oidc/callback" {
+			t.Errorf("Expected X-Forwarded-Proto to be ignored, got redirect_uri %s", got)
+		}
+	})
+
+	t.Run("Rejects Mismatched State", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/auth/oidc/callback?code=abc&state=forged", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if sessionCookie(rr) != nil || !strings.HasPrefix(rr.Header().Get("Location"), "/login?sso_error=") {
+			t.Errorf("Expected a forged callback to be refused, got %d %s", rr.Code, rr.Header().Get("Location"))
+		}
+	})
+}
+
+func TestOIDCLoginDisabled(t *testing.T) {
+	server, _, _ := testutil.SetupTestServer(t)
+	rr := httptest.NewRecorder()
+	server.Router().ServeHTTP(rr, httptest.NewRequest("GET", "/api/auth/oidc/login", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 when OIDC is not configured, got %d", rr.Code)
+	}
+}
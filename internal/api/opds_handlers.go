@@ -0,0 +1,633 @@
+package api
+
+// This file serves the library as an OPDS 1.2 catalog (https://specs.opds.io/opds-1.2)
+// for e-readers and reading apps such as KOReader, Panels and Chunky, including the
+// Page Streaming Extension (https://github.com/anansi-project/opds-pse) for reading
+// chapters page by page without downloading them.
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vrsandeep/mango-go/internal/library"
+	"github.com/vrsandeep/mango-go/internal/library/chapterfiles"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+)
+
+const (
+	opdsNavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	opdsAcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+
+	opdsRelAcquisition = "http://opds-spec.org/acquisition"
+	opdsRelImage       = "http://opds-spec.org/image"
+	opdsRelThumbnail   = "http://opds-spec.org/image/thumbnail"
+	opdsRelPSEStream   = "http://vaemendis.net/opds-pse/stream"
+
+	// opdsPerPage is the number of entries per feed page; further pages are linked with rel="next".
+	opdsPerPage = 50
+	// opdsSectionLimit caps the "Continue Reading" and "Recently Added" feeds.
+	opdsSectionLimit = 50
+)
+
+// opdsFeed is an Atom feed carrying OPDS catalog entries.
+type opdsFeed struct {
+	XMLName  xml.Name    `xml:"feed"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	XmlnsDC  string      `xml:"xmlns:dcterms,attr"`
+	XmlnsPSE string      `xml:"xmlns:pse,attr"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Updated  string      `xml:"updated"`
+	Author   *opdsAuthor `xml:"author,omitempty"`
+	Links    []opdsLink  `xml:"link"`
+	Entries  []opdsEntry `xml:"entry"`
+}
+
+type opdsAuthor struct {
+	Name string `xml:"name"`
+}
+
+type opdsEntry struct {
+	Title    string       `xml:"title"`
+	ID       string       `xml:"id"`
+	Updated  string       `xml:"updated"`
+	Language string       `xml:"dcterms:language,omitempty"`
+	Content  *opdsContent `xml:"content,omitempty"`
+	Links    []opdsLink   `xml:"link"`
+}
+
+type opdsContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type opdsLink struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+	// OPDS-PSE stream attributes: the page count and the last page read (0-based).
+	PSECount    int  `xml:"pse:count,attr,omitempty"`
+	PSELastRead *int `xml:"pse:lastRead,attr,omitempty"`
+}
+
+func newOPDSFeed(id, title, selfHref, kind string) *opdsFeed {
+	return &opdsFeed{
+		Xmlns:    "http://www.w3.org/2005/Atom",
+		XmlnsDC:  "http://purl.org/dc/terms/",
+		XmlnsPSE: "http://vaemendis.net/opds-pse/ns",
+		ID:       id,
+		Title:    title,
+		Updated:  opdsTime(time.Now()),
+		Author:   &opdsAuthor{Name: "mango-go"},
+		Links: []opdsLink{
+			{Rel: "self", Href: selfHref, Type: kind},
+			{Rel: "start", Href: "/opds", Type: opdsNavigationType},
+		},
+	}
+}
+
+func opdsTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// respondWithOPDS writes an OPDS feed with the given catalog content type.
+func respondWithOPDS(w http.ResponseWriter, kind string, feed *opdsFeed) {
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to marshal feed")
+		return
+	}
+	w.Header().Set("Content-Type", kind+";charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+// opdsNavigationEntry links to another catalog feed.
+func opdsNavigationEntry(id, title, href, kind string, updated time.Time) opdsEntry {
+	return opdsEntry{
+		Title:   title,
+		ID:      id,
+		Updated: opdsTime(updated),
+		Links:   []opdsLink{{Rel: "subsection", Href: href, Type: kind}},
+	}
+}
+
+func opdsFolderEntry(folder *models.Folder) opdsEntry {
+	entry := opdsNavigationEntry(
+		fmt.Sprintf("urn:mango-go:folder:%d", folder.ID), folder.Name,
+		fmt.Sprintf("/opds/folders/%d", folder.ID), opdsAcquisitionType, folder.UpdatedAt)
+	if folder.Thumbnail != "" {
+		cover := fmt.Sprintf("/opds/folders/%d/cover", folder.ID)
+		entry.Links = append(entry.Links,
+			opdsLink{Rel: opdsRelImage, Href: cover, Type: "image/jpeg"},
+			opdsLink{Rel: opdsRelThumbnail, Href: cover, Type: "image/jpeg"})
+	}
+	return entry
+}
+
+func opdsChapterEntry(chapter *models.Chapter) opdsEntry {
+	entry := opdsEntry{
+		Title:   store.GetChapterTitle(chapter),
+		ID:      fmt.Sprintf("urn:mango-go:chapter:%d", chapter.ID),
+		Updated: opdsTime(chapter.UpdatedAt),
+		Links: []opdsLink{{
+			Rel:  opdsRelAcquisition,
+			Href: fmt.Sprintf("/opds/chapters/%d/download", chapter.ID),
+			Type: chapterFileMimeType(chapter.Path),
+		}},
+	}
+	if chapter.PageCount > 0 {
+		lastRead := lastReadPage(chapter.ProgressPercent, chapter.PageCount)
+		entry.Links = append(entry.Links, opdsLink{
+			Rel:         opdsRelPSEStream,
+			Href:        fmt.Sprintf("/opds/chapters/%d/pages/{pageNumber}?maxWidth={maxWidth}", chapter.ID),
+			Type:        "image/jpeg",
+			PSECount:    chapter.PageCount,
+			PSELastRead: &lastRead,
+		})
+	}
+	if chapter.ComicInfo != nil {
+		entry.Language = chapter.ComicInfo.LanguageISO
+	}
+	if chapter.PageCount > 0 {
+		entry.Content = &opdsContent{Type: "text", Text: fmt.Sprintf("%d pages", chapter.PageCount)}
+	}
+	if chapter.Thumbnail != "" {
+		cover := fmt.Sprintf("/opds/chapters/%d/cover", chapter.ID)
+		entry.Links = append(entry.Links,
+			opdsLink{Rel: opdsRelImage, Href: cover, Type: "image/jpeg"},
+			opdsLink{Rel: opdsRelThumbnail, Href: cover, Type: "image/jpeg"})
+	}
+	return entry
+}
+
+// chapterFileMimeType returns the MIME type an OPDS client should expect when downloading a chapter.
+// Image directories are sent as CBZ.
+func chapterFileMimeType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".cbz":
+		return "application/vnd.comicbook+zip"
+	case ".cbr":
+		return "application/vnd.comicbook-rar"
+	case ".zip":
+		return "application/zip"
+	case ".rar":
+		return "application/vnd.rar"
+	case ".7z", ".cb7":
+		return "application/x-7z-compressed"
+	case ".pdf":
+		return "application/pdf"
+	case ".epub":
+		return "application/epub+zip"
+	}
+	if chapterfiles.IsImageDirectory(path) {
+		return "application/vnd.comicbook+zip"
+	}
+	return "application/octet-stream"
+}
+
+// opdsPage reads the 1-based "page" query parameter.
+func opdsPage(r *http.Request) int {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	return page
+}
+
+// addOPDSPagination adds previous/next links to a paged feed.
+func addOPDSPagination(feed *opdsFeed, baseHref, kind string, page, total int) {
+	if page > 1 {
+		feed.Links = append(feed.Links, opdsLink{Rel: "previous", Href: fmt.Sprintf("%s?page=%d", baseHref, page-1), Type: kind})
+	}
+	if page*opdsPerPage < total {
+		feed.Links = append(feed.Links, opdsLink{Rel: "next", Href: fmt.Sprintf("%s?page=%d", baseHref, page+1), Type: kind})
+	}
+}
+
+// handleOPDSRoot serves the catalog's start page.
+func (s *Server) handleOPDSRoot(w http.ResponseWriter, r *http.Request) {
+	feed := newOPDSFeed("urn:mango-go:root", "mango-go", "/opds", opdsNavigationType)
+	now := time.Now()
+	feed.Entries = []opdsEntry{
+		opdsNavigationEntry("urn:mango-go:library", "Library", "/opds/folders/0", opdsNavigationType, now),
+		opdsNavigationEntry("urn:mango-go:continue-reading", "Continue Reading", "/opds/continue-reading", opdsAcquisitionType, now),
+		opdsNavigationEntry("urn:mango-go:recently-added", "Recently Added", "/opds/recently-added", opdsAcquisitionType, now),
+		opdsNavigationEntry("urn:mango-go:tags", "Tags", "/opds/tags", opdsNavigationType, now),
+	}
+	respondWithOPDS(w, opdsNavigationType, feed)
+}
+
+// handleOPDSFolder lists a folder's subfolders and chapters. Folder 0 is the library root.
+func (s *Server) handleOPDSFolder(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	folderID, err := strconv.ParseInt(chi.URLParam(r, "folderID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid folder ID")
+		return
+	}
+
+	page := opdsPage(r)
+	opts := store.ListItemsOptions{
+		UserID:   user.ID,
+		ParentID: &folderID,
+		Page:     page,
+		PerPage:  opdsPerPage,
+	}
+	if folderID != 0 {
+		// Honour the sort order the user picked for this folder in the web UI
+		if settings, err := s.store.GetFolderSettings(folderID, user.ID); err == nil {
+			opts.SortBy, opts.SortDir = settings.SortBy, settings.SortDir
+		}
+	}
+	folder, subfolders, chapters, total, err := s.store.ListItems(opts)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "Folder not found")
+		return
+	}
+
+	title := "Library"
+	if folder != nil {
+		title = folder.Name
+	}
+	// Folders holding chapters are acquisition feeds; folders of folders are navigation feeds.
+	kind := opdsNavigationType
+	if len(chapters) > 0 {
+		kind = opdsAcquisitionType
+	}
+	href := fmt.Sprintf("/opds/folders/%d", folderID)
+	feed := newOPDSFeed(fmt.Sprintf("urn:mango-go:folder:%d", folderID), title, href, kind)
+	if folder != nil {
+		up := "/opds/folders/0"
+		if folder.ParentID != nil {
+			up = fmt.Sprintf("/opds/folders/%d", *folder.ParentID)
+		}
+		feed.Links = append(feed.Links, opdsLink{Rel: "up", Href: up, Type: opdsNavigationType})
+	}
+	addOPDSPagination(feed, href, kind, page, total)
+
+	for _, sub := range subfolders {
+		feed.Entries = append(feed.Entries, opdsFolderEntry(sub))
+	}
+	for _, chapter := range chapters {
+		feed.Entries = append(feed.Entries, opdsChapterEntry(chapter))
+	}
+	respondWithOPDS(w, kind, feed)
+}
+
+// handleOPDSTags lists all tags that are in use.
+func (s *Server) handleOPDSTags(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	tags, err := s.store.ListTagsWithCounts(user.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve tags")
+		return
+	}
+	feed := newOPDSFeed("urn:mango-go:tags", "Tags", "/opds/tags", opdsNavigationType)
+	now := time.Now()
+	for _, tag := range tags {
+		entry := opdsNavigationEntry(fmt.Sprintf("urn:mango-go:tag:%d", tag.ID), tag.Name,
+			fmt.Sprintf("/opds/tags/%d", tag.ID), opdsNavigationType, now)
+		entry.Content = &opdsContent{Type: "text", Text: fmt.Sprintf("%d series", tag.FolderCount)}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	respondWithOPDS(w, opdsNavigationType, feed)
+}
+
+// handleOPDSTag lists the folders carrying a tag.
+func (s *Server) handleOPDSTag(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	tagID, err := strconv.ParseInt(chi.URLParam(r, "tagID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid tag ID")
+		return
+	}
+	tag, err := s.store.GetTagByID(tagID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "Tag not found")
+		return
+	}
+
+	page := opdsPage(r)
+	var root int64
+	_, folders, _, total, err := s.store.ListItems(store.ListItemsOptions{
+		UserID:   user.ID,
+		ParentID: &root,
+		TagID:    &tagID,
+		Page:     page,
+		PerPage:  opdsPerPage,
+	})
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve folders")
+		return
+	}
+
+	href := fmt.Sprintf("/opds/tags/%d", tagID)
+	feed := newOPDSFeed(fmt.Sprintf("urn:mango-go:tag:%d", tagID), tag.Name, href, opdsNavigationType)
+	feed.Links = append(feed.Links, opdsLink{Rel: "up", Href: "/opds/tags", Type: opdsNavigationType})
+	addOPDSPagination(feed, href, opdsNavigationType, page, total)
+	for _, folder := range folders {
+		feed.Entries = append(feed.Entries, opdsFolderEntry(folder))
+	}
+	respondWithOPDS(w, opdsNavigationType, feed)
+}
+
+// handleOPDSContinueReading lists the chapters the user is part-way through.
+func (s *Server) handleOPDSContinueReading(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	items, err := s.store.GetContinueReading(user.ID, opdsSectionLimit)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve continue reading")
+		return
+	}
+	feed := newOPDSFeed("urn:mango-go:continue-reading", "Continue Reading", "/opds/continue-reading", opdsAcquisitionType)
+	feed.Entries = s.opdsHomeSectionEntries(user.ID, items)
+	respondWithOPDS(w, opdsAcquisitionType, feed)
+}
+
+// handleOPDSRecentlyAdded lists recently added chapters, grouped by series like the home page.
+func (s *Server) handleOPDSRecentlyAdded(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	items, err := s.store.GetRecentlyAdded(user.ID, opdsSectionLimit)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve recently added")
+		return
+	}
+	feed := newOPDSFeed("urn:mango-go:recently-added", "Recently Added", "/opds/recently-added", opdsAcquisitionType)
+	feed.Entries = s.opdsHomeSectionEntries(user.ID, items)
+	respondWithOPDS(w, opdsAcquisitionType, feed)
+}
+
+// opdsHomeSectionEntries turns home page items into entries: single chapters become acquisition
+// entries, and series cards (several new chapters) link to the series folder.
+func (s *Server) opdsHomeSectionEntries(userID int64, items []*models.HomeSectionItem) []opdsEntry {
+	var entries []opdsEntry
+	for _, item := range items {
+		if item.ChapterID != nil {
+			chapter, err := s.store.GetChapterByID(*item.ChapterID, userID)
+			if err != nil {
+				continue
+			}
+			entry := opdsChapterEntry(chapter)
+			entry.Title = item.SeriesTitle + " - " + entry.Title
+			entries = append(entries, entry)
+			continue
+		}
+		folder, err := s.store.GetFolder(item.SeriesID)
+		if err != nil {
+			continue
+		}
+		entry := opdsFolderEntry(folder)
+		if item.NewChapterCount > 0 {
+			entry.Content = &opdsContent{Type: "text", Text: fmt.Sprintf("%d new chapters", item.NewChapterCount)}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// handleOPDSDownloadChapter sends the original chapter file. Image directories are zipped into a CBZ on the fly.
+func (s *Server) handleOPDSDownloadChapter(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	chapterID, err := strconv.ParseInt(chi.URLParam(r, "chapterID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chapter ID")
+		return
+	}
+	chapter, err := s.store.GetChapterByID(chapterID, user.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "Chapter not found")
+		return
+	}
+
+	info, err := os.Stat(chapter.Path)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "Chapter file not found")
+		return
+	}
+
+	if info.IsDir() {
+		// Only the chapter's pages are zipped, in reading order, leaving out OS clutter such as
+		// macOS ._ files that readers would show as broken pages.
+		pages, _, err := chapterfiles.InspectChapterFile(r.Context(), chapter.Path)
+		if err != nil {
+			RespondWithError(w, http.StatusInternalServerError, "Failed to read chapter")
+			return
+		}
+		name := filepath.Base(chapter.Path) + ".cbz"
+		w.Header().Set("Content-Type", chapterFileMimeType(chapter.Path))
+		w.Header().Set("Content-Disposition", contentDisposition(name))
+		if err := writeDirectoryAsZip(w, chapter.Path, pages); err != nil {
+			log.Printf("Error streaming chapter directory %s: %v", chapter.Path, err)
+		}
+		return
+	}
+
+	file, err := os.Open(chapter.Path)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "Chapter file not found")
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Content-Type", chapterFileMimeType(chapter.Path))
+	w.Header().Set("Content-Disposition", contentDisposition(filepath.Base(chapter.Path)))
+	http.ServeContent(w, r, filepath.Base(chapter.Path), info.ModTime(), file)
+}
+
+// contentDisposition builds an attachment header that survives non-ASCII file names.
+func contentDisposition(name string) string {
+	ascii := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, name)
+	return fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, ascii, url.PathEscape(name))
+}
+
+// writeDirectoryAsZip streams the pages of an image directory chapter, in order, as an
+// uncompressed zip archive.
+func writeDirectoryAsZip(w io.Writer, dir string, pages []*models.Page) error {
+	zw := zip.NewWriter(w)
+	for _, page := range pages {
+		f, err := os.Open(filepath.Join(dir, page.FileName))
+		if err != nil {
+			return err
+		}
+		// Images are already compressed, so store them as-is.
+		dst, err := zw.CreateHeader(&zip.FileHeader{Name: page.FileName, Method: zip.Store})
+		if err == nil {
+			_, err = io.Copy(dst, f)
+		}
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// handleOPDSStreamPage serves one page for the OPDS-PSE stream link. Page numbers are 0-based, and
+// maxWidth (optional) scales wide pages down. Fetching a page past the user's progress records it,
+// using the same percentage the web reader stores, so both readers resume at the same page.
+// Revalidating a cached page with a conditional request does not.
+func (s *Server) handleOPDSStreamPage(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	chapterID, err := strconv.ParseInt(chi.URLParam(r, "chapterID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chapter ID")
+		return
+	}
+	pageIndex, err := strconv.Atoi(chi.URLParam(r, "pageNumber"))
+	if err != nil || pageIndex < 0 {
+		RespondWithError(w, http.StatusBadRequest, "Invalid page number")
+		return
+	}
+	maxWidth := 0
+	if v := r.URL.Query().Get("maxWidth"); v != "" {
+		// Clients that don't substitute the template send "{maxWidth}" verbatim; treat it as unset.
+		maxWidth, _ = strconv.Atoi(v)
+	}
+
+	chapter, err := s.store.GetChapterByID(chapterID, user.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "Chapter not found")
+		return
+	}
+
+	// maxWidth is served like the web reader's width parameter, from the same page cache.
+	var transform library.PageTransform
+	if maxWidth > 0 {
+		transform.Width = min(maxWidth, maxPageDimension)
+	}
+	if s.servePage(w, r, user.ID, chapter, pageIndex, transform) {
+		s.recordOPDSPageProgress(r, chapter, user.ID, pageIndex)
+	}
+}
+
+// recordOPDSPageProgress stores a page fetched through the stream link as the user's progress.
+// Readers fetch pages ahead and revisit earlier ones, so progress only ever moves forward here;
+// clients that send an explicit position, like the web reader and KOReader, can move it back.
+// Read-only API tokens never write progress.
+func (s *Server) recordOPDSPageProgress(r *http.Request, chapter *models.Chapter, userID int64, pageIndex int) {
+	if chapter.PageCount == 0 || isReadOnlyToken(r) {
+		return
+	}
+	progress := pageProgressPercent(pageIndex, chapter.PageCount)
+	if progress <= chapter.ProgressPercent {
+		return
+	}
+	// Re-reading a finished chapter should not mark it unread again.
+	read := chapter.Read || progress >= 99
+	if err := s.store.UpdateChapterProgress(chapter.ID, userID, progress, read); err != nil {
+		log.Printf("Failed to update progress for chapter %d: %v", chapter.ID, err)
+	}
+}
+
+// pageProgressPercent converts a 0-based page index into the percentage the web reader stores
+// for that page.
+func pageProgressPercent(pageIndex, pageCount int) int {
+	if pageCount <= 0 {
+		return 0
+	}
+	if pageIndex >= pageCount {
+		pageIndex = pageCount - 1
+	}
+	return (pageIndex + 1) * 100 / pageCount
+}
+
+// lastReadPage converts a stored progress percentage back into a 0-based page index, rounding up
+// like the web reader does when it resumes.
+func lastReadPage(progressPercent, pageCount int) int {
+	page := (progressPercent*pageCount + 99) / 100
+	if page > pageCount {
+		page = pageCount
+	}
+	if page < 1 {
+		return 0
+	}
+	return page - 1
+}
+
+// handleOPDSFolderCover serves a folder's thumbnail as an image.
+func (s *Server) handleOPDSFolderCover(w http.ResponseWriter, r *http.Request) {
+	folderID, err := strconv.ParseInt(chi.URLParam(r, "folderID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid folder ID")
+		return
+	}
+	folder, err := s.store.GetFolder(folderID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "Folder not found")
+		return
+	}
+	s.serveThumbnail(w, r, folder.Thumbnail)
+}
+
+// handleOPDSChapterCover serves a chapter's thumbnail as an image.
+func (s *Server) handleOPDSChapterCover(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	chapterID, err := strconv.ParseInt(chi.URLParam(r, "chapterID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid chapter ID")
+		return
+	}
+	chapter, err := s.store.GetChapterByID(chapterID, user.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "Chapter not found")
+		return
+	}
+	s.serveThumbnail(w, r, chapter.Thumbnail)
+}
+
+// serveThumbnail writes a chapter's or folder's thumbnail, which is either a thumbnail file or a
+// data URI stored before thumbnails were kept as files.
+func (s *Server) serveThumbnail(w http.ResponseWriter, r *http.Request, thumbnail string) {
+	if hash, ok := library.ThumbnailHash(thumbnail); ok {
+		s.serveThumbnailFile(w, r, hash)
+		return
+	}
+	serveDataURI(w, r, thumbnail)
+}
+
+// serveDataURI writes the image held in a base64 data URI thumbnail. Its ETag is a hash of the
+// image, so conditional requests are answered with 304 Not Modified.
+func serveDataURI(w http.ResponseWriter, r *http.Request, dataURI string) {
+	meta, payload, ok := strings.Cut(strings.TrimPrefix(dataURI, "data:"), ",")
+	mimeType, isBase64 := strings.CutSuffix(meta, ";base64")
+	if !ok || !isBase64 || !strings.HasPrefix(mimeType, "image/") {
+		RespondWithError(w, http.StatusNotFound, "Cover not found")
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "Cover not found")
+		return
+	}
+	sum := sha256.Sum256(data)
+	w.Header().Set("Content-Type", mimeType)
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
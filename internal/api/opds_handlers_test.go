@@ -0,0 +1,276 @@
+package api_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+// opdsTestFeed decodes the parts of an OPDS feed the tests look at.
+type opdsTestFeed struct {
+	Title   string `xml:"title"`
+	Entries []struct {
+		Title string `xml:"title"`
+		ID    string `xml:"id"`
+		Links []struct {
+			Rel      string `xml:"rel,attr"`
+			Href     string `xml:"href,attr"`
+			Type     string `xml:"type,attr"`
+			Count    string `xml:"http://vaemendis.net/opds-pse/ns count,attr"`
+			LastRead string `xml:"http://vaemendis.net/opds-pse/ns lastRead,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+func TestOPDSCatalog(t *testing.T) {
+	server, db, _ := testutil.SetupTestServer(t)
+	router := server.Router()
+	testutil.PersistOneFolderAndChapter(t, db)
+	// Creates the user the Basic credentials below refer to
+	testutil.CookieForUser(t, server, "reader", "password", "user")
+
+	get := func(path string, withAuth bool) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		if withAuth {
+			req.SetBasicAuth("reader", "password")
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	decode := func(rr *httptest.ResponseRecorder) opdsTestFeed {
+		t.Helper()
+		var feed opdsTestFeed
+		if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+			t.Fatalf("Invalid OPDS feed: %v\n%s", err, rr.Body.String())
+		}
+		return feed
+	}
+
+	t.Run("Requires Basic Auth", func(t *testing.T) {
+		rr := get("/opds", false)
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401 without credentials, got %d", rr.Code)
+		}
+		if !strings.HasPrefix(rr.Header().Get("WWW-Authenticate"), "Basic") {
+			t.Errorf("Expected a Basic challenge, got %q", rr.Header().Get("WWW-Authenticate"))
+		}
+
+		req, _ := http.NewRequest("GET", "/opds", nil)
+		req.SetBasicAuth("reader", "wrong")
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 with a wrong password, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Root", func(t *testing.T) {
+		rr := get("/opds", true)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+		if !strings.Contains(rr.Header().Get("Content-Type"), "profile=opds-catalog") {
+			t.Errorf("Unexpected content type %q", rr.Header().Get("Content-Type"))
+		}
+		feed := decode(rr)
+		if len(feed.Entries) != 4 {
+			t.Errorf("Expected 4 root entries, got %d", len(feed.Entries))
+		}
+	})
+
+	t.Run("Folder Hierarchy", func(t *testing.T) {
+		feed := decode(get("/opds/folders/0", true))
+		if len(feed.Entries) != 1 || feed.Entries[0].Title != "Folder 1" {
+			t.Fatalf("Expected the root folder entry, got %+v", feed.Entries)
+		}
+		if feed.Entries[0].Links[0].Href != "/opds/folders/1" {
+			t.Errorf("Expected a link to folder 1, got %s", feed.Entries[0].Links[0].Href)
+		}
+
+		feed = decode(get("/opds/folders/1", true))
+		if len(feed.Entries) != 1 {
+			t.Fatalf("Expected one chapter entry, got %d", len(feed.Entries))
+		}
+		var acquisition string
+		for _, l := range feed.Entries[0].Links {
+			if l.Rel == "http://opds-spec.org/acquisition" {
+				acquisition = l.Href
+				if l.Type != "application/vnd.comicbook+zip" {
+					t.Errorf("Expected CBZ MIME type, got %s", l.Type)
+				}
+			}
+		}
+		if acquisition != "/opds/chapters/1/download" {
+			t.Errorf("Expected acquisition link, got %q", acquisition)
+		}
+
+		if rr := get("/opds/folders/999", true); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for a missing folder, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Page Streaming", func(t *testing.T) {
+		streamLink := func() (href, count, lastRead string) {
+			feed := decode(get("/opds/folders/1", true))
+			for _, l := range feed.Entries[0].Links {
+				if l.Rel == "http://vaemendis.net/opds-pse/stream" {
+					return l.Href, l.Count, l.LastRead
+				}
+			}
+			t.Fatal("Chapter entry has no PSE stream link")
+			return
+		}
+		href, count, lastRead := streamLink()
+		if href != "/opds/chapters/1/pages/{pageNumber}?maxWidth={maxWidth}" || count != "2" || lastRead != "0" {
+			t.Fatalf("Unexpected stream link %q count=%s lastRead=%s", href, count, lastRead)
+		}
+
+		// Pages are 0-based; an unsubstituted maxWidth is ignored
+		rr := get("/opds/chapters/1/pages/0?maxWidth={maxWidth}", true)
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "image/jpeg" {
+			t.Fatalf("Expected the first page, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+		}
+		user, _ := server.Store().GetUserByUsername("reader")
+		chapter, _ := server.Store().GetChapterByID(1, user.ID)
+		if chapter.ProgressPercent != 50 || chapter.Read {
+			t.Errorf("Expected 50%% progress after page 1 of 2, got %d (read=%v)", chapter.ProgressPercent, chapter.Read)
+		}
+		if _, _, lastRead := streamLink(); lastRead != "0" {
+			t.Errorf("Expected lastRead 0, got %s", lastRead)
+		}
+
+		lastPage := get("/opds/chapters/1/pages/1?maxWidth=600", true)
+		if lastPage.Code != http.StatusOK {
+			t.Fatalf("Expected the last page, got %d", lastPage.Code)
+		}
+		chapter, _ = server.Store().GetChapterByID(1, user.ID)
+		if chapter.ProgressPercent != 100 || !chapter.Read {
+			t.Errorf("Expected the chapter to be read, got %d (read=%v)", chapter.ProgressPercent, chapter.Read)
+		}
+		if _, _, lastRead := streamLink(); lastRead != "1" {
+			t.Errorf("Expected lastRead 1, got %s", lastRead)
+		}
+
+		// Going back a page does not move progress back
+		if rr := get("/opds/chapters/1/pages/0", true); rr.Code != http.StatusOK {
+			t.Fatalf("Expected the first page, got %d", rr.Code)
+		}
+		if _, _, lastRead := streamLink(); lastRead != "1" {
+			t.Errorf("Expected lastRead to stay 1, got %s", lastRead)
+		}
+
+		// Revalidating a cached page is not reading it
+		server.Store().UpdateChapterProgress(1, user.ID, 0, false)
+		if etag := lastPage.Header().Get("ETag"); etag != "" {
+			req, _ := http.NewRequest("GET", "/opds/chapters/1/pages/1?maxWidth=600", nil)
+			req.SetBasicAuth("reader", "password")
+			req.Header.Set("If-None-Match", etag)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != http.StatusNotModified {
+				t.Fatalf("Expected 304, got %d", rr.Code)
+			}
+			if chapter, _ := server.Store().GetChapterByID(1, user.ID); chapter.ProgressPercent != 0 {
+				t.Errorf("Expected a 304 to leave progress alone, got %d", chapter.ProgressPercent)
+			}
+		}
+
+		// Widths past the page endpoint's limit are clamped rather than refused
+		if rr := get("/opds/chapters/1/pages/0?maxWidth=1000000", true); rr.Code != http.StatusOK {
+			t.Errorf("Expected a huge maxWidth to be clamped, got %d", rr.Code)
+		}
+
+		if rr := get("/opds/chapters/1/pages/2", true); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 past the last page, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		rr := get("/opds/chapters/1/download", true)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+		if !strings.Contains(rr.Header().Get("Content-Disposition"), `filename="ch1.cbz"`) {
+			t.Errorf("Unexpected Content-Disposition %q", rr.Header().Get("Content-Disposition"))
+		}
+		if !strings.HasPrefix(rr.Body.String(), "PK") {
+			t.Error("Expected the original zip file")
+		}
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		if _, err := server.Store().AddTagToFolder(1, "action"); err != nil {
+			t.Fatalf("Failed to tag folder: %v", err)
+		}
+		feed := decode(get("/opds/tags", true))
+		if len(feed.Entries) != 1 || feed.Entries[0].Title != "action" {
+			t.Fatalf("Expected the action tag, got %+v", feed.Entries)
+		}
+		feed = decode(get(feed.Entries[0].Links[0].Href, true))
+		if len(feed.Entries) != 1 || feed.Entries[0].Title != "Folder 1" {
+			t.Errorf("Expected the tagged folder, got %+v", feed.Entries)
+		}
+	})
+
+	t.Run("Home Sections", func(t *testing.T) {
+		user, _ := server.Store().GetUserByUsername("reader")
+		if err := server.Store().UpdateChapterProgress(1, user.ID, 50, false); err != nil {
+			t.Fatalf("Failed to update progress: %v", err)
+		}
+		feed := decode(get("/opds/continue-reading", true))
+		if len(feed.Entries) != 1 || feed.Entries[0].ID != "urn:mango-go:chapter:1" {
+			t.Errorf("Expected the started chapter, got %+v", feed.Entries)
+		}
+		feed = decode(get("/opds/recently-added", true))
+		if len(feed.Entries) != 1 {
+			t.Errorf("Expected one recently added entry, got %+v", feed.Entries)
+		}
+	})
+}
+
+func TestOPDSDownloadImageDirectory(t *testing.T) {
+	server, _, _ := testutil.SetupTestServer(t)
+	testutil.CookieForUser(t, server, "reader", "password", "user")
+
+	dir := filepath.Join(t.TempDir(), "Chapter 1")
+	os.Mkdir(dir, 0755)
+	for _, name := range []string{"10.jpg", "2.jpg", "._2.jpg", "Thumbs.db", "desktop.ini"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	folder, _ := server.Store().CreateFolder(filepath.Dir(dir), "Series", nil)
+	chapter, err := server.Store().CreateChapter(folder.ID, dir, "hash", 2, "")
+	if err != nil {
+		t.Fatalf("CreateChapter failed: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/opds/chapters/%d/download", chapter.ID), nil)
+	req.SetBasicAuth("reader", "password")
+	rr := httptest.NewRecorder()
+	server.Router().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Fatalf("Expected a zip archive: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "2.jpg,10.jpg" {
+		t.Errorf("Expected only the pages in reading order, got %v", names)
+	}
+}
@@ -0,0 +1,230 @@
+package api
+
+// This file reads pages ahead of the reader. When a page is requested, the next few pages, and
+// near the end of a chapter the first pages of the next one, are extracted in the background
+// into a memory cache, so chapters that are slow to extract (CBR, 7z, PDF) don't stall on every
+// page turn.
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/config"
+	"github.com/vrsandeep/mango-go/internal/library/chapterfiles"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+)
+
+// prefetchTimeout bounds how long one read-ahead may take.
+const prefetchTimeout = time.Minute
+
+type prefetchedPage struct {
+	key      string
+	data     []byte
+	fileName string
+}
+
+// prefetcher reads pages ahead into an LRU cache bounded by size, and counts how often page
+// requests are served from it. A nil prefetcher reads every page from its chapter file.
+type prefetcher struct {
+	cfg   config.PrefetchConfig
+	store *store.Store
+
+	mu       sync.Mutex
+	size     int64
+	maxBytes int64
+	lru      *list.List // of *prefetchedPage, most recently used first
+	pages    map[string]*list.Element
+	running  map[int64]int // read-aheads in progress per user
+
+	hits       atomic.Int64 // page requests served from the cache
+	misses     atomic.Int64 // page requests read from the chapter file
+	prefetched atomic.Int64 // pages read ahead
+	skipped    atomic.Int64 // read-aheads skipped because the user had too many running
+}
+
+// newPrefetcher returns a prefetcher, or nil when prefetching is disabled.
+func newPrefetcher(cfg config.PrefetchConfig, st *store.Store) *prefetcher {
+	if cfg.Pages <= 0 || cfg.CacheSizeMB <= 0 {
+		return nil
+	}
+	if cfg.PerUser < 1 {
+		cfg.PerUser = 1
+	}
+	return &prefetcher{
+		cfg:      cfg,
+		store:    st,
+		maxBytes: int64(cfg.CacheSizeMB) << 20,
+		lru:      list.New(),
+		pages:    make(map[string]*list.Element),
+		running:  make(map[int64]int),
+	}
+}
+
+// prefetchKey identifies a page of a chapter file as it was when the page was read, so pages of
+// a replaced file are not served.
+func prefetchKey(chapter *models.Chapter, pageIndex int) string {
+	var mtime, size int64
+	if chapter.FileMtime != nil {
+		mtime = chapter.FileMtime.UnixNano()
+	}
+	if chapter.FileSize != nil {
+		size = *chapter.FileSize
+	}
+	return fmt.Sprintf("%s|%d|%d|%d", chapter.Path, mtime, size, pageIndex)
+}
+
+// page returns a page from the cache, or reads it from the chapter file.
+func (p *prefetcher) page(ctx context.Context, chapter *models.Chapter, pageIndex int) ([]byte, string, error) {
+	if p == nil {
+		return chapterfiles.GetChapterPage(ctx, chapter.Path, pageIndex)
+	}
+	p.mu.Lock()
+	elem, ok := p.pages[prefetchKey(chapter, pageIndex)]
+	if ok {
+		p.lru.MoveToFront(elem)
+	}
+	p.mu.Unlock()
+	if ok {
+		p.hits.Add(1)
+		page := elem.Value.(*prefetchedPage)
+		return page.data, page.fileName, nil
+	}
+	p.misses.Add(1)
+	return chapterfiles.GetChapterPage(ctx, chapter.Path, pageIndex)
+}
+
+// readAhead starts reading the pages after pageIndex in the background, unless the user already
+// has PerUser read-aheads running.
+func (p *prefetcher) readAhead(userID int64, chapter *models.Chapter, pageIndex int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	if p.running[userID] >= p.cfg.PerUser {
+		p.mu.Unlock()
+		p.skipped.Add(1)
+		return
+	}
+	p.running[userID]++
+	p.mu.Unlock()
+
+	go func() {
+		defer func() {
+			p.mu.Lock()
+			if p.running[userID]--; p.running[userID] == 0 {
+				delete(p.running, userID)
+			}
+			p.mu.Unlock()
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), prefetchTimeout)
+		defer cancel()
+
+		last := pageIndex + p.cfg.Pages
+		for i := pageIndex + 1; i <= last && i < chapter.PageCount; i++ {
+			p.warm(ctx, chapter, i)
+		}
+		if p.cfg.NextChapterPages <= 0 || last < chapter.PageCount-1 {
+			return
+		}
+		next := p.nextChapter(chapter, userID)
+		for i := 0; next != nil && i < p.cfg.NextChapterPages && i < next.PageCount; i++ {
+			p.warm(ctx, next, i)
+		}
+	}()
+}
+
+// nextChapter returns the chapter after chapter in its folder, or nil if there is none.
+func (p *prefetcher) nextChapter(chapter *models.Chapter, userID int64) *models.Chapter {
+	neighbors, err := p.store.GetChapterNeighbors(chapter.FolderID, chapter.ID, userID)
+	if err != nil || neighbors["next"] == nil {
+		return nil
+	}
+	next, err := p.store.GetChapterByID(*neighbors["next"], userID)
+	if err != nil || !chapterfiles.IsSupportedChapterPath(next.Path) {
+		return nil
+	}
+	return next
+}
+
+// warm reads a page into the cache unless it is there already.
+func (p *prefetcher) warm(ctx context.Context, chapter *models.Chapter, pageIndex int) {
+	key := prefetchKey(chapter, pageIndex)
+	p.mu.Lock()
+	_, ok := p.pages[key]
+	p.mu.Unlock()
+	if ok {
+		return
+	}
+	data, fileName, err := chapterfiles.GetChapterPage(ctx, chapter.Path, pageIndex)
+	if err != nil {
+		log.Printf("Failed to prefetch page %d of %s: %v", pageIndex, chapter.Path, err)
+		return
+	}
+	p.prefetched.Add(1)
+	p.put(&prefetchedPage{key: key, data: data, fileName: fileName})
+}
+
+// put adds a page to the cache, dropping the least recently used pages beyond its size limit.
+func (p *prefetcher) put(page *prefetchedPage) {
+	size := int64(len(page.data))
+	if size > p.maxBytes {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.pages[page.key]; ok {
+		return
+	}
+	p.pages[page.key] = p.lru.PushFront(page)
+	p.size += size
+	for p.size > p.maxBytes {
+		oldest := p.lru.Remove(p.lru.Back()).(*prefetchedPage)
+		delete(p.pages, oldest.key)
+		p.size -= int64(len(oldest.data))
+	}
+}
+
+// prefetchStats reports how well reading ahead works.
+type prefetchStats struct {
+	Enabled     bool    `json:"enabled"`
+	Hits        int64   `json:"hits"`
+	Misses      int64   `json:"misses"`
+	HitRate     float64 `json:"hit_rate"` // share of page requests served from the cache
+	Prefetched  int64   `json:"prefetched"`
+	Skipped     int64   `json:"skipped"`
+	CachedPages int     `json:"cached_pages"`
+	CachedBytes int64   `json:"cached_bytes"`
+}
+
+func (p *prefetcher) stats() prefetchStats {
+	if p == nil {
+		return prefetchStats{}
+	}
+	stats := prefetchStats{
+		Enabled:    true,
+		Hits:       p.hits.Load(),
+		Misses:     p.misses.Load(),
+		Prefetched: p.prefetched.Load(),
+		Skipped:    p.skipped.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	p.mu.Lock()
+	stats.CachedPages = p.lru.Len()
+	stats.CachedBytes = p.size
+	p.mu.Unlock()
+	return stats
+}
+
+// handleAdminPrefetchStats returns the prefetch cache's hit rate and size.
+func (s *Server) handleAdminPrefetchStats(w http.ResponseWriter, r *http.Request) {
+	RespondWithJSON(w, http.StatusOK, s.prefetch.stats())
+}
@@ -0,0 +1,77 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/config"
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestPagePrefetch(t *testing.T) {
+	server, db, _ := testutil.SetupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Prefetch = config.PrefetchConfig{Pages: 2, NextChapterPages: 1, CacheSizeMB: 10, PerUser: 1}
+	})
+	router := server.Router()
+	dir := t.TempDir()
+	st := store.New(db)
+	folder, _ := st.CreateFolder(dir, "Folder", nil)
+	ch1 := testutil.CreateTestCBZ(t, dir, "ch1.cbz", []string{"p1.png", "p2.png", "p3.png"})
+	ch2 := testutil.CreateTestCBZ(t, dir, "ch2.cbz", []string{"p1.png", "p2.png"})
+	if _, err := st.CreateChapter(folder.ID, ch1, "hash1", 3, ""); err != nil {
+		t.Fatalf("Failed to create chapter: %v", err)
+	}
+	if _, err := st.CreateChapter(folder.ID, ch2, "hash2", 2, ""); err != nil {
+		t.Fatalf("Failed to create chapter: %v", err)
+	}
+	userCookie := testutil.CookieForUser(t, server, "reader", "password", "user")
+	adminCookie := testutil.CookieForUser(t, server, "admin", "password", "admin")
+	get := func(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.AddCookie(cookie)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	var stats struct {
+		Hits, Misses, Prefetched int64
+		HitRate                  float64 `json:"hit_rate"`
+	}
+	loadStats := func() {
+		t.Helper()
+		rr := get("/api/admin/prefetch/stats", adminCookie)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected stats, got %d", rr.Code)
+		}
+		json.Unmarshal(rr.Body.Bytes(), &stats)
+	}
+
+	// Page 2 of 3 is near the end, so page 3 and the next chapter's first page are read ahead.
+	if rr := get("/api/chapters/1/pages/2", userCookie); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the page, got %d", rr.Code)
+	}
+	for deadline := time.Now().Add(5 * time.Second); stats.Prefetched < 2; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 2 pages to be prefetched, got %d", stats.Prefetched)
+		}
+		loadStats()
+	}
+
+	for _, path := range []string{"/api/chapters/1/pages/3", "/api/chapters/2/pages/1"} {
+		if rr := get(path, userCookie); rr.Code != http.StatusOK {
+			t.Fatalf("Expected %s, got %d", path, rr.Code)
+		}
+	}
+	loadStats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.HitRate < 0.66 || stats.HitRate > 0.67 {
+		t.Errorf("Expected 2 hits and 1 miss, got %+v", stats)
+	}
+
+	if rr := get("/api/admin/prefetch/stats", userCookie); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected non-admins to be refused, got %d", rr.Code)
+	}
+}
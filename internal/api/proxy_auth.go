@@ -0,0 +1,122 @@
+package api
+
+// This file implements authentication by a trusted reverse proxy. Forward-auth proxies such as
+// Authelia and Authentik log the user in and pass the username on in a header; mango-go accepts
+// that header only when the request comes directly from one of the configured proxies.
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/vrsandeep/mango-go/internal/config"
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+// proxyAuth holds the parsed proxy authentication settings.
+type proxyAuth struct {
+	cfg     config.ProxyAuthConfig
+	trusted trustedNetworks
+}
+
+// newProxyAuth parses the trusted proxy list. Invalid entries are logged and skipped.
+func newProxyAuth(cfg config.ProxyAuthConfig) *proxyAuth {
+	p := &proxyAuth{cfg: cfg, trusted: parseTrustedNetworks(cfg.TrustedProxies)}
+	if len(p.trusted) == 0 {
+		log.Printf("Proxy authentication is enabled but no valid trusted proxies are configured; it will not be used")
+	}
+	return p
+}
+
+// trustedPeer reports whether the request came directly from a trusted proxy. It uses the TCP
+// peer address recorded before X-Forwarded-For is applied, so clients cannot spoof it.
+func (p *proxyAuth) trustedPeer(r *http.Request) bool {
+	return p.trusted.contains(peerIP(r))
+}
+
+// userFromProxyHeaders returns the user named by a trusted proxy, creating the account on first
+// sight. It returns nil when the request did not come through a trusted proxy with the header set.
+func (s *Server) userFromProxyHeaders(r *http.Request) *models.User {
+	if s.proxyAuth == nil || !s.proxyAuth.trustedPeer(r) {
+		return nil
+	}
+	cfg := s.proxyAuth.cfg
+	username := strings.TrimSpace(r.Header.Get(cfg.UserHeader))
+	if username == "" {
+		return nil
+	}
+
+	var groups []string
+	for _, g := range strings.Split(r.Header.Get(cfg.GroupsHeader), ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
+	}
+	user, err := s.store.GetUserByUsername(username)
+	if err != nil {
+		newRole := groupRole(groups, cfg.AdminGroup)
+		// An empty password hash never matches, so the account can only be used through the proxy.
+		if _, err := s.store.CreateUser(username, "", newRole); err != nil {
+			log.Printf("Proxy auth: could not create user %s: %v", username, err)
+		} else {
+			log.Printf("Proxy auth: created user %s (%s)", username, newRole)
+		}
+		// Re-read in case a concurrent request created the account first.
+		if user, err = s.store.GetUserByUsername(username); err != nil {
+			return nil
+		}
+	}
+
+	if user, err = s.syncAdminGroup(user, groups, cfg.AdminGroup); err != nil {
+		log.Printf("Proxy auth: could not update role for %s: %v", username, err)
+	}
+	return user
+}
+
+// groupRole returns the role a new account gets from its groups: "admin" for members of
+// adminGroup and "user" for everyone else.
+func groupRole(groups []string, adminGroup string) string {
+	if adminGroup != "" && slices.Contains(groups, adminGroup) {
+		return models.RoleAdmin
+	}
+	return models.RoleUser
+}
+
+// syncAdminGroup keeps the admin role in line with membership of adminGroup: members are made
+// admins, and admins who left the group get the user role. Other roles, which admins assign
+// themselves, are left alone, and the last admin is never demoted. Nothing changes when no admin
+// group is configured. It returns the user as updated, or unchanged along with an error.
+func (s *Server) syncAdminGroup(user *models.User, groups []string, adminGroup string) (*models.User, error) {
+	if adminGroup == "" {
+		return user, nil
+	}
+	member := slices.Contains(groups, adminGroup)
+	var role string
+	switch {
+	case member && user.Role != models.RoleAdmin:
+		role = models.RoleAdmin
+	case !member && user.Role == models.RoleAdmin:
+		admins, err := s.store.GetRole(models.RoleAdmin)
+		if err != nil {
+			return user, err
+		}
+		if admins.UserCount <= 1 {
+			log.Printf("Not demoting %s, who left the admin group, as the last admin", user.Username)
+			return user, nil
+		}
+		role = models.RoleUser
+	default:
+		return user, nil
+	}
+
+	if err := s.store.UpdateUser(user.ID, user.Username, role); err != nil {
+		return user, err
+	}
+	// Re-read to pick up the new role's permissions.
+	updated, err := s.store.GetUserByID(user.ID)
+	if err != nil {
+		return user, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,104 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/config"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestProxyAuth(t *testing.T) {
+	server, _, _ := testutil.SetupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.ProxyAuth = config.ProxyAuthConfig{
+			Enabled:        true,
+			UserHeader:     "Remote-User",
+			GroupsHeader:   "Remote-Groups",
+			TrustedProxies: []string{"10.0.0.0/8", "192.168.1.5"},
+			AdminGroup:     "admins",
+		}
+	})
+	router := server.Router()
+
+	getMe := func(remoteAddr string, headers map[string]string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("GET", "/api/users/me", nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var body map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &body)
+		return rr.Code, body
+	}
+
+	t.Run("Creates User From Trusted Proxy", func(t *testing.T) {
+		code, me := getMe("10.1.2.3:4567", map[string]string{"Remote-User": "carol", "Remote-Groups": "family"})
+		if code != http.StatusOK || me["username"] != "carol" || me["role"] != "user" {
+			t.Fatalf("Expected carol to be created, got %d %v", code, me)
+		}
+		code, me = getMe("192.168.1.5:4567", map[string]string{"Remote-User": "carol", "Remote-Groups": "family, admins"})
+		if code != http.StatusOK || me["role"] != "admin" {
+			t.Errorf("Expected carol to become admin, got %d %v", code, me)
+		}
+	})
+
+	t.Run("Only Manages The Admin Role", func(t *testing.T) {
+		// carol is the only admin, so leaving the group does not demote her
+		code, me := getMe("10.1.2.3:4567", map[string]string{"Remote-User": "carol", "Remote-Groups": "family"})
+		if code != http.StatusOK || me["role"] != "admin" {
+			t.Errorf("Expected the last admin to stay admin, got %d %v", code, me)
+		}
+		testutil.CookieForUser(t, server, "boss", "password", "admin")
+		code, me = getMe("10.1.2.3:4567", map[string]string{"Remote-User": "carol", "Remote-Groups": "family"})
+		if code != http.StatusOK || me["role"] != "user" {
+			t.Errorf("Expected carol to be demoted after leaving the group, got %d %v", code, me)
+		}
+
+		// Roles assigned by an admin are kept
+		if _, err := server.Store().CreateRole("reader", "", []string{models.PermReadLibrary}); err != nil {
+			t.Fatalf("CreateRole failed: %v", err)
+		}
+		testutil.CookieForUser(t, server, "dave", "password", "reader")
+		code, me = getMe("10.1.2.3:4567", map[string]string{"Remote-User": "dave", "Remote-Groups": "family"})
+		if code != http.StatusOK || me["role"] != "reader" {
+			t.Errorf("Expected dave to keep the assigned role, got %d %v", code, me)
+		}
+	})
+
+	t.Run("Ignores Untrusted Sources", func(t *testing.T) {
+		if code, _ := getMe("203.0.113.7:4567", map[string]string{"Remote-User": "mallory"}); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 from an untrusted address, got %d", code)
+		}
+		// X-Real-IP must not make an untrusted client look like the proxy
+		headers := map[string]string{"Remote-User": "mallory", "X-Real-IP": "10.0.0.1", "X-Forwarded-For": "10.0.0.1"}
+		if code, _ := getMe("203.0.113.7:4567", headers); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 with spoofed forwarding headers, got %d", code)
+		}
+		if _, err := server.Store().GetUserByUsername("mallory"); err == nil {
+			t.Error("Untrusted headers must not create users")
+		}
+	})
+
+	t.Run("Falls Back To Session", func(t *testing.T) {
+		if code, _ := getMe("10.1.2.3:4567", nil); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without the header or a cookie, got %d", code)
+		}
+	})
+}
+
+func TestProxyAuthDisabled(t *testing.T) {
+	server, _, _ := testutil.SetupTestServer(t)
+	req := httptest.NewRequest("GET", "/api/users/me", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("Remote-User", "admin")
+	rr := httptest.NewRecorder()
+	server.Router().ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the header to be ignored when proxy auth is off, got %d", rr.Code)
+	}
+}
@@ -2,27 +2,47 @@ package api
 
 import (
 	"encoding/json"
-	"io"
+	"errors"
 	"log"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/outbound"
 )
 
 // handleProxyResource proxies a resource request with appropriate headers
 // This is useful for resources that require specific headers (e.g., Referer for webtoons)
 // or to bypass CORS restrictions
 //
+// Only URLs signed by the server (see auth.URLSigner.ResourceProxyURL) are accepted, so the
+// endpoint cannot be used as an open proxy. Plugins get them from mango.utils.proxyURL.
+//
 // Query parameters:
 //   - url: (required) The resource URL to proxy
 //   - referer: (optional) Referer header value
 //   - user-agent: (optional) User-Agent header value
 //   - origin: (optional) Origin header value
 //   - headers: (optional) JSON object with additional custom headers
+//   - expires, sig: (required) expiry and signature added when the URL is signed
 func (s *Server) handleProxyResource(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 
+	if s.urlSigner == nil {
+		RespondWithError(w, http.StatusServiceUnavailable, "Resource proxy is unavailable")
+		return
+	}
+	if err := s.urlSigner.Verify(auth.ResourceProxyPath, query, time.Now()); err != nil {
+		if errors.Is(err, auth.ErrURLExpired) {
+			RespondWithError(w, http.StatusForbidden, "Resource URL has expired")
+		} else {
+			RespondWithError(w, http.StatusForbidden, "Invalid resource URL signature")
+		}
+		return
+	}
+
 	// Get the resource URL from query parameter
 	resourceURL := query.Get("url")
 	if resourceURL == "" {
@@ -43,13 +63,14 @@ func (s *Server) handleProxyResource(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Security: Optional - restrict to allowed domains (can be configured)
-	// For now, we allow all domains but could add a whitelist
-
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	// Security: internal addresses and denied hosts are blocked by the outbound policy, which
+	// checks again after DNS resolution and on every redirect
+	policy := s.app.Outbound()
+	if err := policy.CheckURL(parsedURL); err != nil {
+		RespondWithError(w, http.StatusForbidden, "Destination is not allowed")
+		return
 	}
+	client := policy.Client(30 * time.Second)
 
 	// Create request
 	req, err := http.NewRequest("GET", resourceURL, nil)
@@ -85,6 +106,11 @@ func (s *Server) handleProxyResource(w http.ResponseWriter, r *http.Request) {
 
 	// Execute the request
 	resp, err := client.Do(req)
+	if errors.Is(err, outbound.ErrBlockedDestination) {
+		log.Printf("Blocked proxied resource request: %v", err)
+		RespondWithError(w, http.StatusForbidden, "Destination is not allowed")
+		return
+	}
 	if err != nil {
 		log.Printf("Error fetching proxied resource: %v", err)
 		RespondWithError(w, http.StatusBadGateway, "Failed to fetch resource")
@@ -98,6 +124,13 @@ func (s *Server) handleProxyResource(w http.ResponseWriter, r *http.Request) {
 		RespondWithError(w, http.StatusBadGateway, "Resource server returned error")
 		return
 	}
+	// Read the body before responding so oversized resources can still be refused
+	data, err := policy.ReadBody(resp.Body)
+	if err != nil {
+		log.Printf("Error reading proxied resource %s: %v", resourceURL, err)
+		RespondWithError(w, http.StatusBadGateway, "Failed to read resource")
+		return
+	}
 
 	// Copy content type from the original response
 	contentType := resp.Header.Get("Content-Type")
@@ -127,11 +160,8 @@ func (s *Server) handleProxyResource(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Copy the resource data to the response
-	_, err = io.Copy(w, resp.Body)
-	if err != nil {
-		log.Printf("Error copying proxied resource data: %v", err)
-		// Response already started, can't send error
-		return
+	if _, err := w.Write(data); err != nil {
+		log.Printf("Error writing proxied resource data: %v", err)
 	}
 }
 
@@ -4,12 +4,32 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/vrsandeep/mango-go/internal/api"
+	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/config"
+	"github.com/vrsandeep/mango-go/internal/store"
 	"github.com/vrsandeep/mango-go/internal/testutil"
 )
 
+// signProxyURL signs a resource proxy query the way plugins get it from mango.utils.proxyURL.
+func signProxyURL(t *testing.T, server *api.Server, rawQuery string, ttl time.Duration) string {
+	t.Helper()
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatalf("Invalid proxy query %q: %v", rawQuery, err)
+	}
+	key, err := server.Store().GetOrCreateSecret(store.ResourceProxySecret, 32)
+	if err != nil {
+		t.Fatalf("Failed to load the proxy signing key: %v", err)
+	}
+	return auth.NewURLSigner(key).Sign(auth.ResourceProxyPath, values, time.Now().Add(ttl))
+}
+
 // setupMockResourceServer creates a mock HTTP server that simulates external resources
 func setupMockResourceServer() *httptest.Server {
 	mux := http.NewServeMux()
@@ -69,7 +89,7 @@ func TestHandleProxyResource(t *testing.T) {
 
 	t.Run("Success - Image with Referer", func(t *testing.T) {
 		imageURL := mockResourceServer.URL + "/image.jpg"
-		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s&referer=https://example.com/", imageURL), nil)
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, fmt.Sprintf("url=%s&referer=https://example.com/", imageURL), time.Hour), nil)
 		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
@@ -90,7 +110,7 @@ func TestHandleProxyResource(t *testing.T) {
 
 	t.Run("Success - JSON Resource", func(t *testing.T) {
 		jsonURL := mockResourceServer.URL + "/api/data.json"
-		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s", jsonURL), nil)
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, fmt.Sprintf("url=%s", jsonURL), time.Hour), nil)
 		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
@@ -108,7 +128,7 @@ func TestHandleProxyResource(t *testing.T) {
 
 	t.Run("Success - HTML Resource", func(t *testing.T) {
 		htmlURL := mockResourceServer.URL + "/page.html"
-		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s", htmlURL), nil)
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, fmt.Sprintf("url=%s", htmlURL), time.Hour), nil)
 		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
@@ -124,7 +144,7 @@ func TestHandleProxyResource(t *testing.T) {
 	t.Run("Success - Custom Headers via JSON", func(t *testing.T) {
 		protectedURL := mockResourceServer.URL + "/protected"
 		headersJSON := `{"X-Custom-Header":"secret-value"}`
-		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s&headers=%s", protectedURL, headersJSON), nil)
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, fmt.Sprintf("url=%s&headers=%s", protectedURL, headersJSON), time.Hour), nil)
 		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
@@ -139,7 +159,7 @@ func TestHandleProxyResource(t *testing.T) {
 
 	t.Run("Success - Multiple Headers", func(t *testing.T) {
 		imageURL := mockResourceServer.URL + "/image.jpg"
-		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s&referer=https://example.com/&user-agent=TestBot/1.0&origin=https://example.com", imageURL), nil)
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, fmt.Sprintf("url=%s&referer=https://example.com/&user-agent=TestBot/1.0&origin=https://example.com", imageURL), time.Hour), nil)
 		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
@@ -149,73 +169,58 @@ func TestHandleProxyResource(t *testing.T) {
 		}
 	})
 
-	t.Run("Success - No Auth Cookie (External Request)", func(t *testing.T) {
+	t.Run("Unauthorized - No Auth Cookie", func(t *testing.T) {
 		imageURL := mockResourceServer.URL + "/image.jpg"
-		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s&referer=https://example.com/", imageURL), nil)
-		// No cookie added - endpoint should work without authentication
-		// Set RemoteAddr to simulate external (non-localhost) request
-		req.RemoteAddr = "192.168.1.100:54321"
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, fmt.Sprintf("url=%s&referer=https://example.com/", imageURL), time.Hour), nil)
+		// Localhost requests get no special treatment
+		req.RemoteAddr = "127.0.0.1:54321"
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
 
-		if status := rr.Code; status != http.StatusOK {
-			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
-		}
-		if contentType := rr.Header().Get("Content-Type"); contentType != "image/jpeg" {
-			t.Errorf("handler returned wrong content type: got %v want %v", contentType, "image/jpeg")
-		}
-		if body := rr.Body.String(); body != "fake-image-data" {
-			t.Errorf("handler returned wrong body: got %v want %v", body, "fake-image-data")
+		if status := rr.Code; status != http.StatusUnauthorized {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusUnauthorized)
 		}
 	})
 
-	t.Run("Success - Localhost Request Without Auth", func(t *testing.T) {
+	t.Run("Forbidden - Unsigned URL", func(t *testing.T) {
 		imageURL := mockResourceServer.URL + "/image.jpg"
 		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s&referer=https://example.com/", imageURL), nil)
-		// No cookie added - localhost requests should work without auth
-		// httptest doesn't set RemoteAddr, so empty RemoteAddr is treated as localhost
+		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
 
-		if status := rr.Code; status != http.StatusOK {
-			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
-		}
-		if contentType := rr.Header().Get("Content-Type"); contentType != "image/jpeg" {
-			t.Errorf("handler returned wrong content type: got %v want %v", contentType, "image/jpeg")
-		}
-		if body := rr.Body.String(); body != "fake-image-data" {
-			t.Errorf("handler returned wrong body: got %v want %v", body, "fake-image-data")
+		if status := rr.Code; status != http.StatusForbidden {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusForbidden)
 		}
 	})
 
-	t.Run("Success - Localhost Request With Explicit 127.0.0.1", func(t *testing.T) {
-		imageURL := mockResourceServer.URL + "/image.jpg"
-		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s&referer=https://example.com/", imageURL), nil)
-		// Set RemoteAddr to 127.0.0.1 to explicitly test localhost detection
-		req.RemoteAddr = "127.0.0.1:54321"
+	t.Run("Forbidden - Tampered URL", func(t *testing.T) {
+		signed := signProxyURL(t, server, "url="+mockResourceServer.URL+"/image.jpg&referer=https://example.com/", time.Hour)
+		tampered := strings.Replace(signed, "image.jpg", "page.html", 1)
+		req, _ := http.NewRequest("GET", tampered, nil)
+		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
 
-		if status := rr.Code; status != http.StatusOK {
-			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		if status := rr.Code; status != http.StatusForbidden {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusForbidden)
 		}
 	})
 
-	t.Run("Success - Localhost Request With Explicit [::1]", func(t *testing.T) {
+	t.Run("Forbidden - Expired URL", func(t *testing.T) {
 		imageURL := mockResourceServer.URL + "/image.jpg"
-		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s&referer=https://example.com/", imageURL), nil)
-		// Set RemoteAddr to IPv6 localhost to test IPv6 localhost detection
-		req.RemoteAddr = "[::1]:54321"
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, fmt.Sprintf("url=%s&referer=https://example.com/", imageURL), -time.Minute), nil)
+		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
 
-		if status := rr.Code; status != http.StatusOK {
-			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		if status := rr.Code; status != http.StatusForbidden {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusForbidden)
 		}
 	})
 
 	t.Run("Bad Request - Missing URL", func(t *testing.T) {
-		req, _ := http.NewRequest("GET", "/api/proxy/resource", nil)
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, "", time.Hour), nil)
 		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
@@ -226,7 +231,7 @@ func TestHandleProxyResource(t *testing.T) {
 	})
 
 	t.Run("Bad Request - Invalid URL", func(t *testing.T) {
-		req, _ := http.NewRequest("GET", "/api/proxy/resource?url=not-a-valid-url", nil)
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, "url=not-a-valid-url", time.Hour), nil)
 		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
@@ -237,7 +242,7 @@ func TestHandleProxyResource(t *testing.T) {
 	})
 
 	t.Run("Bad Request - Non-HTTP URL", func(t *testing.T) {
-		req, _ := http.NewRequest("GET", "/api/proxy/resource?url=file:///etc/passwd", nil)
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, "url=file:///etc/passwd", time.Hour), nil)
 		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
@@ -249,7 +254,7 @@ func TestHandleProxyResource(t *testing.T) {
 
 	t.Run("Bad Gateway - Resource Returns Error", func(t *testing.T) {
 		errorURL := mockResourceServer.URL + "/error"
-		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s", errorURL), nil)
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, fmt.Sprintf("url=%s", errorURL), time.Hour), nil)
 		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
@@ -284,7 +289,7 @@ func TestHandleProxyResource(t *testing.T) {
 				defer mockServer.Close()
 
 				reqURL := mockServer.URL + tc.url
-				req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s", reqURL), nil)
+				req, _ := http.NewRequest("GET", signProxyURL(t, server, fmt.Sprintf("url=%s", reqURL), time.Hour), nil)
 				req.AddCookie(cookie)
 				rr := httptest.NewRecorder()
 				router.ServeHTTP(rr, req)
@@ -334,7 +339,7 @@ func TestHandleProxyResource(t *testing.T) {
 		defer mockServer.Close()
 
 		imageURL := mockServer.URL + "/test"
-		req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s&referer=https://test.com/&user-agent=TestBot/1.0&origin=https://test.com", imageURL), nil)
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, fmt.Sprintf("url=%s&referer=https://test.com/&user-agent=TestBot/1.0&origin=https://test.com", imageURL), time.Hour), nil)
 		req.AddCookie(cookie)
 		rr := httptest.NewRecorder()
 		router.ServeHTTP(rr, req)
@@ -348,6 +353,48 @@ func TestHandleProxyResource(t *testing.T) {
 	})
 }
 
+func TestHandleProxyResourceOutboundPolicy(t *testing.T) {
+	mockResourceServer := setupMockResourceServer()
+	defer mockResourceServer.Close()
+	big := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 2<<20))
+	}))
+	defer big.Close()
+
+	proxyGet := func(t *testing.T, outboundCfg config.OutboundConfig, target string) int {
+		server, _, _ := testutil.SetupTestServerWithConfig(t, func(cfg *config.Config) {
+			cfg.Outbound = outboundCfg
+		})
+		cookie := testutil.CookieForUser(t, server, "testuser", "password", "user")
+		req, _ := http.NewRequest("GET", signProxyURL(t, server, "url="+url.QueryEscape(target), time.Hour), nil)
+		req.AddCookie(cookie)
+		rr := httptest.NewRecorder()
+		server.Router().ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Without the tests' loopback exemption, the default policy applies
+	for _, target := range []string{
+		mockResourceServer.URL + "/page.html",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/",
+		"http://localhost/",
+	} {
+		t.Run("Blocks "+target, func(t *testing.T) {
+			if code := proxyGet(t, config.OutboundConfig{}, target); code != http.StatusForbidden {
+				t.Errorf("Expected %s to be blocked, got %d", target, code)
+			}
+		})
+	}
+
+	t.Run("Response Size Cap", func(t *testing.T) {
+		cfg := config.OutboundConfig{Allow: []string{"127.0.0.1"}, MaxResponseMB: 1}
+		if code := proxyGet(t, cfg, big.URL+"/big.jpg"); code != http.StatusBadGateway {
+			t.Errorf("Expected a response over the cap to be refused, got %d", code)
+		}
+	})
+}
+
 func TestInferContentType(t *testing.T) {
 	// This tests the helper function indirectly through the proxy handler
 	testCases := []struct {
@@ -386,7 +433,7 @@ func TestInferContentType(t *testing.T) {
 			defer mockServer.Close()
 
 			reqURL := mockServer.URL + "/test" + tc.urlSuffix
-			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/proxy/resource?url=%s", reqURL), nil)
+			req, _ := http.NewRequest("GET", signProxyURL(t, server, fmt.Sprintf("url=%s", reqURL), time.Hour), nil)
 			req.AddCookie(cookie)
 			rr := httptest.NewRecorder()
 			router.ServeHTTP(rr, req)
@@ -9,13 +9,17 @@ import (
 	"io/fs"
 	"log"
 	"net/http"
+	"runtime"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/vrsandeep/mango-go/internal/anilist"
 	"github.com/vrsandeep/mango-go/internal/assets"
+	"github.com/vrsandeep/mango-go/internal/auth"
 	"github.com/vrsandeep/mango-go/internal/core"
+	"github.com/vrsandeep/mango-go/internal/diskcache"
+	"github.com/vrsandeep/mango-go/internal/models"
 	"github.com/vrsandeep/mango-go/internal/store"
 )
 
@@ -31,6 +35,16 @@ type Server struct {
 	store           *store.Store
 	homeStore       HomeStore
 	anilistSearcher AnilistSearcher
+	basicAuth       *basicAuthCache
+	kosyncAuth      *basicAuthCache
+	oidc            *oidcLogin // nil unless OIDC login is configured
+	proxyAuth       *proxyAuth // nil unless proxy header authentication is enabled
+	twoFactor       *twoFactorChallenges
+	urlSigner       *auth.URLSigner  // nil if the signing key could not be loaded
+	pageCache       *diskcache.Cache // resized pages; nil when the cache is disabled
+	transcodes      chan struct{}    // limits how many pages are resized at once
+	prefetch        *prefetcher      // nil when prefetching is disabled
+	trustedProxies  trustedNetworks  // proxies whose forwarded client addresses are believed
 }
 
 // Store returns the store instance.
@@ -51,11 +65,53 @@ func (s *Server) SetAnilistSearcher(searcher AnilistSearcher) {
 // NewServer creates a new Server instance.
 func NewServer(app *core.App) *Server {
 	storeInstance := store.New(app.DB())
+	var oidcAuth *oidcLogin
+	var proxy *proxyAuth
+	var pageCache *diskcache.Cache
+	var prefetch *prefetcher
+	var trustedProxies trustedNetworks
+	if cfg := app.Config(); cfg != nil {
+		trustedProxies = parseTrustedNetworks(cfg.TrustedProxies)
+		if cfg.ProxyAuth.Enabled {
+			// The proxy that authenticates users also reports their address.
+			trustedProxies = append(trustedProxies, parseTrustedNetworks(cfg.ProxyAuth.TrustedProxies)...)
+		}
+		if cfg.OIDC.Enabled() {
+			oidcAuth = newOIDCLogin(cfg.OIDC)
+		}
+		if cfg.ProxyAuth.Enabled {
+			proxy = newProxyAuth(cfg.ProxyAuth)
+		}
+		if cfg.PageCache.MaxSizeMB > 0 {
+			cache, err := diskcache.New(cfg.PageCacheDir(), int64(cfg.PageCache.MaxSizeMB)<<20)
+			if err != nil {
+				log.Printf("Warning: page cache disabled, failed to open %s: %v", cfg.PageCacheDir(), err)
+			}
+			pageCache = cache
+		}
+		prefetch = newPrefetcher(cfg.Prefetch, storeInstance)
+	}
+	var signer *auth.URLSigner
+	if key, err := storeInstance.GetOrCreateSecret(store.ResourceProxySecret, 32); err == nil {
+		signer = auth.NewURLSigner(key)
+	} else {
+		log.Printf("Warning: resource proxy disabled, failed to load its signing key: %v", err)
+	}
 	return &Server{
-		app:       app,
-		db:        app.DB(),
-		store:     storeInstance,
-		homeStore: storeInstance, // Use the concrete store by default
+		app:            app,
+		db:             app.DB(),
+		store:          storeInstance,
+		homeStore:      storeInstance, // Use the concrete store by default
+		basicAuth:      newBasicAuthCache(func(u *models.User) string { return u.PasswordHash }),
+		kosyncAuth:     newBasicAuthCache(func(u *models.User) string { return u.KOSyncKeyHash }),
+		oidc:           oidcAuth,
+		proxyAuth:      proxy,
+		twoFactor:      newTwoFactorChallenges(),
+		urlSigner:      signer,
+		pageCache:      pageCache,
+		transcodes:     make(chan struct{}, runtime.NumCPU()),
+		prefetch:       prefetch,
+		trustedProxies: trustedProxies,
 	}
 }
 
@@ -64,116 +120,236 @@ func (s *Server) Router() http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(RememberPeerAddr) // before RealIP, for trusted proxy checks
+	r.Use(s.RealIP)
 	r.Use(middleware.Logger)    // Logs requests to the console
 	r.Use(middleware.Recoverer) // Recovers from panics
 	r.Use(middleware.Timeout(60 * time.Second))
 
 	// API routes
 	r.Post("/api/users/login", s.handleLogin)
+	r.Post("/api/users/login/2fa", s.handleLoginTwoFactor)
+	r.Post("/api/users/reset-password", s.handleResetPassword)
+	r.With(s.Audit("user.register")).Post("/api/users/register", s.handleRegister)
+	r.Get("/api/auth/oidc/login", s.handleOIDCLogin)
+	r.Get("/api/auth/oidc/callback", s.handleOIDCCallback)
 	r.Get("/api/version", s.handleGetVersion)
 	r.Get("/api/config", s.handleGetConfig)
 
-	// Resource Proxy (for resources that require special headers, e.g., Referer for webtoons)
-	r.Get("/api/proxy/resource", s.handleProxyResource)
 	r.Group(func(r chi.Router) {
 		r.Use(s.AuthMiddleware)
 
+		// Resource Proxy (for resources that require special headers, e.g., Referer for webtoons).
+		// Only URLs signed by the server are accepted.
+		r.With(s.RequirePermission(models.PermManageDownloads)).Get(auth.ResourceProxyPath, s.handleProxyResource)
+
+		// Job progress updates; job events are only sent to users who can run jobs
+		r.Get("/ws/admin/progress", s.handleWebSocket)
+
 		r.Post("/api/users/logout", s.handleLogout)
 		r.Get("/api/users/me", s.handleGetMe)
+		r.Put("/api/users/me", s.handleUpdateMe)
+
+		// Personal API tokens
+		r.Get("/api/users/me/tokens", s.handleListAPITokens)
+		r.Post("/api/users/me/tokens", s.handleCreateAPIToken)
+		r.Delete("/api/users/me/tokens/{tokenID}", s.handleDeleteAPIToken)
+
+		// Sessions
+		r.Get("/api/users/me/sessions", s.handleListSessions)
+		r.Delete("/api/users/me/sessions", s.handleRevokeOtherSessions)
+		r.Delete("/api/users/me/sessions/{sessionID}", s.handleRevokeSession)
+
+		// Two-factor authentication
+		r.Get("/api/users/me/2fa", s.handleGetTwoFactorStatus)
+		r.Post("/api/users/me/2fa/enroll", s.handleEnrollTwoFactor)
+		r.Post("/api/users/me/2fa/confirm", s.handleConfirmTwoFactor)
+		r.Post("/api/users/me/2fa/recovery-codes", s.handleRegenerateRecoveryCodes)
+		r.Post("/api/users/me/2fa/disable", s.handleDisableTwoFactor)
+
+		// Single sign-on
+		r.Get("/api/users/me/oidc/link", s.handleOIDCLink)
 
 		r.Route("/api", func(r chi.Router) {
-			r.Get("/home", s.handleGetHomePageData)
-
-			// Browse Routes
-			r.Get("/browse", s.handleBrowseFolder)
-			r.Get("/browse/breadcrumb", s.handleGetBreadcrumb)
-			r.Get("/folders", s.handleListAllFolders)
-			r.Get("/folders/search", s.handleSearchFolders)
-
-			r.Get("/folders/{folderID}/settings", s.handleGetFolderSettings)
-			r.Post("/folders/{folderID}/settings", s.handleUpdateFolderSettings)
-			r.Post("/folders/{folderID}/mark-all-as", s.handleMarkFolderAs)
-			r.Post("/folders/{folderID}/cover", s.handleUploadFolderCover)
-			r.Get("/folders/{folderID}/anilist", s.handleGetFolderAnilist)
-			r.Post("/folders/{folderID}/anilist", s.handlePostFolderAnilist)
-			r.Get("/folders/{folderID}/chapters/{chapterID}/neighbors", s.handleGetChapterNeighbors)
-
-			r.Get("/chapters/{chapterID}", s.handleGetChapterDetails)
-			r.Post("/chapters/{chapterID}/progress", s.handleUpdateProgress)
-			r.Get("/chapters/{chapterID}/pages/{pageNumber}", s.handleGetPage)
+			r.Group(func(r chi.Router) {
+				r.Use(s.RequirePermission(models.PermReadLibrary))
+				r.Use(s.ContentAccessMiddleware)
+
+				r.Get("/home", s.handleGetHomePageData)
+
+				// Browse Routes
+				r.Get("/browse", s.handleBrowseFolder)
+				r.Get("/browse/breadcrumb", s.handleGetBreadcrumb)
+				r.Get("/folders", s.handleListAllFolders)
+				r.Get("/folders/search", s.handleSearchFolders)
+				r.Get("/thumbnails/{hash}", s.handleGetThumbnail)
+
+				r.Get("/folders/{folderID}/settings", s.handleGetFolderSettings)
+				r.Post("/folders/{folderID}/settings", s.handleUpdateFolderSettings)
+				r.With(s.Audit("folder.mark_all")).Post("/folders/{folderID}/mark-all-as", s.handleMarkFolderAs)
+				r.Get("/folders/{folderID}/anilist", s.handleGetFolderAnilist)
+				r.Get("/folders/{folderID}/chapters/{chapterID}/neighbors", s.handleGetChapterNeighbors)
+
+				r.Get("/chapters/{chapterID}", s.handleGetChapterDetails)
+				r.Post("/chapters/{chapterID}/progress", s.handleUpdateProgress)
+				r.Get("/chapters/{chapterID}/pages/{pageNumber}", s.handleGetPage)
+
+				// Tag Endpoints
+				r.Get("/tags", s.handleListTags)
+				r.Get("/tags/{tagID}", s.handleGetTagDetails) // To get a single tag's name
+				r.Get("/tags/{tagID}/folders", s.handleListFoldersByTag)
+			})
 
 			// Folder Tagging Routes
-			r.Post("/folders/{folderID}/tags", s.handleAddTagToFolder)
-			r.Delete("/folders/{folderID}/tags/{tagID}", s.handleRemoveTagFromFolder)
+			r.Group(func(r chi.Router) {
+				r.Use(s.RequirePermission(models.PermManageTags))
+				r.Use(s.ContentAccessMiddleware)
 
-			// Tag Endpoints
-			r.Get("/tags", s.handleListTags)
-			r.Get("/tags/{tagID}", s.handleGetTagDetails) // To get a single tag's name
-			r.Get("/tags/{tagID}/folders", s.handleListFoldersByTag)
+				r.Post("/folders/{folderID}/tags", s.handleAddTagToFolder)
+				r.Delete("/folders/{folderID}/tags/{tagID}", s.handleRemoveTagFromFolder)
+			})
+
+			// Cover and metadata Routes
+			r.Group(func(r chi.Router) {
+				r.Use(s.RequirePermission(models.PermUploadCovers))
+				r.Use(s.ContentAccessMiddleware)
+
+				r.Post("/folders/{folderID}/cover", s.handleUploadFolderCover)
+				r.Post("/folders/{folderID}/anilist", s.handlePostFolderAnilist)
+			})
 
-			// Admin Job Triggers
 			r.Route("/admin", func(r chi.Router) {
-				r.Use(s.AdminOnlyMiddleware)
-
-				r.Get("/jobs/status", s.handleGetAdminJobsStatus)
-				r.Post("/jobs/run", s.handleRunAdminJob)
-
-				// Bad Files Management Routes
-				r.Get("/bad-files", s.handleGetBadFiles)
-				r.Get("/bad-files/count", s.handleGetBadFilesCount)
-				r.Get("/bad-files/download", s.handleDownloadBadFilesCSV)
-				r.Delete("/bad-files", s.handleDeleteBadFile)
-
-				// New User Management Routes
-				r.Get("/users", s.handleAdminListUsers)
-				r.Post("/users", s.handleAdminCreateUser)
-				r.Put("/users/{userID}", s.handleAdminUpdateUser)
-				r.Delete("/users/{userID}", s.handleAdminDeleteUser)
-
-				// Plugin Management Routes
-				r.Post("/plugins/reload", s.handleReloadAllPlugins)
-				r.Post("/plugins/{pluginID}/reload", s.handleReloadPlugin)
-				r.Delete("/plugins/{pluginID}", s.handleUnloadPlugin)
-
-				// Plugin Repository Management Routes (Admin only)
-				r.Post("/plugin-repositories", s.handleCreateRepository)
-				r.Delete("/plugin-repositories/{repositoryID}", s.handleDeleteRepository)
-				r.Post("/plugin-repositories/install", s.handleInstallPlugin)
-				r.Post("/plugin-repositories/update", s.handleUpdatePlugin)
-				r.Post("/plugin-repositories/check-updates", s.handleCheckUpdates)
+				// Admin Job Triggers
+				r.Group(func(r chi.Router) {
+					r.Use(s.RequirePermission(models.PermRunJobs))
+
+					r.Get("/jobs/status", s.handleGetAdminJobsStatus)
+					r.Get("/prefetch/stats", s.handleAdminPrefetchStats)
+					r.With(s.Audit("job.run")).Post("/jobs/run", s.handleRunAdminJob)
+
+					// Bad Files Management Routes
+					r.Get("/bad-files", s.handleGetBadFiles)
+					r.Get("/bad-files/count", s.handleGetBadFilesCount)
+					r.Get("/bad-files/download", s.handleDownloadBadFilesCSV)
+					r.With(s.Audit("bad_file.delete")).Delete("/bad-files", s.handleDeleteBadFile)
+				})
+
+				// User and Role Management Routes
+				r.Group(func(r chi.Router) {
+					r.Use(s.RequirePermission(models.PermManageUsers))
+
+					r.Get("/users", s.handleAdminListUsers)
+					r.With(s.Audit("user.create")).Post("/users", s.handleAdminCreateUser)
+					r.With(s.Audit("user.update")).Put("/users/{userID}", s.handleAdminUpdateUser)
+					r.With(s.Audit("user.delete")).Delete("/users/{userID}", s.handleAdminDeleteUser)
+					r.With(s.Audit("user.reset_2fa")).Delete("/users/{userID}/2fa", s.handleAdminResetTwoFactor)
+					r.With(s.Audit("user.revoke_sessions")).Delete("/users/{userID}/sessions", s.handleAdminRevokeUserSessions)
+					r.With(s.Audit("user.unlock")).Post("/users/{userID}/unlock", s.handleAdminUnlockUser)
+					r.With(s.Audit("user.password_reset")).Post("/users/{userID}/password-reset", s.handleAdminCreatePasswordReset)
+					r.Get("/users/{userID}/restrictions", s.handleAdminGetUserRestrictions)
+					r.With(s.Audit("user.restrictions")).Put("/users/{userID}/restrictions", s.handleAdminUpdateUserRestrictions)
+					r.With(s.Audit("user.approve")).Post("/users/{userID}/approve", s.handleAdminApproveUser)
+					r.Get("/invites", s.handleAdminListInvites)
+					r.With(s.Audit("invite.create")).Post("/invites", s.handleAdminCreateInvite)
+					r.With(s.Audit("invite.delete")).Delete("/invites/{inviteID}", s.handleAdminDeleteInvite)
+					r.Get("/failed-logins", s.handleAdminListFailedLogins)
+					r.Get("/audit", s.handleAdminListAudit)
+
+					r.Get("/permissions", s.handleAdminListPermissions)
+					r.Get("/roles", s.handleAdminListRoles)
+					r.With(s.Audit("role.create")).Post("/roles", s.handleAdminCreateRole)
+					r.With(s.Audit("role.update")).Put("/roles/{roleName}", s.handleAdminUpdateRole)
+					r.With(s.Audit("role.delete")).Delete("/roles/{roleName}", s.handleAdminDeleteRole)
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(s.RequirePermission(models.PermManagePlugins))
+
+					// Plugin Management Routes
+					r.With(s.Audit("plugin.reload_all")).Post("/plugins/reload", s.handleReloadAllPlugins)
+					r.With(s.Audit("plugin.reload")).Post("/plugins/{pluginID}/reload", s.handleReloadPlugin)
+					r.With(s.Audit("plugin.unload")).Delete("/plugins/{pluginID}", s.handleUnloadPlugin)
+
+					// Plugin Repository Management Routes
+					r.With(s.Audit("plugin_repository.create")).Post("/plugin-repositories", s.handleCreateRepository)
+					r.With(s.Audit("plugin_repository.delete")).Delete("/plugin-repositories/{repositoryID}", s.handleDeleteRepository)
+					r.With(s.Audit("plugin.install")).Post("/plugin-repositories/install", s.handleInstallPlugin)
+					r.With(s.Audit("plugin.update")).Post("/plugin-repositories/update", s.handleUpdatePlugin)
+					r.Post("/plugin-repositories/check-updates", s.handleCheckUpdates)
+				})
 			})
 
-			// Downloader Routes
-			r.Get("/providers", s.handleListProviders)
-			r.Get("/providers/{providerID}/search", s.handleProviderSearch)
-			r.Get("/providers/{providerID}/series/{seriesIdentifier}", s.handleProviderGetChapters)
-			r.Post("/downloads/queue", s.handleAddChaptersToQueue)
-			r.Get("/downloads/queue", s.handleGetDownloadQueue)
-			r.Post("/downloads/action", s.handleQueueAction)
-			r.Post("/downloads/queue/{itemID}/action", s.handleQueueItemAction)
-
-			// Subscription Routes
-			r.Post("/subscriptions", s.handleSubscribeToSeries)
-			r.Get("/subscriptions", s.handleListSubscriptions)
-			r.Put("/subscriptions/{subID}/folder-path", s.handleUpdateSubscriptionFolderPath)
-			r.Post("/subscriptions/{subID}/recheck", s.handleRecheckSubscription)
-			r.Post("/subscriptions/recheck-all", s.handleRecheckAllSubscriptions)
-			r.Delete("/subscriptions/{subID}", s.handleDeleteSubscription)
-
-			// Plugin Management Routes
-			r.Get("/plugins", s.handleListPlugins)
-			r.Get("/plugins/{pluginID}", s.handleGetPluginInfo)
+			r.Group(func(r chi.Router) {
+				r.Use(s.RequirePermission(models.PermManageDownloads))
+
+				// Downloader Routes
+				r.Get("/providers", s.handleListProviders)
+				r.Get("/providers/{providerID}/search", s.handleProviderSearch)
+				r.Get("/providers/{providerID}/series/{seriesIdentifier}", s.handleProviderGetChapters)
+				r.With(s.Audit("download.queue")).Post("/downloads/queue", s.handleAddChaptersToQueue)
+				r.Get("/downloads/queue", s.handleGetDownloadQueue)
+				r.With(s.Audit("download.queue_action")).Post("/downloads/action", s.handleQueueAction)
+				r.With(s.Audit("download.item_action")).Post("/downloads/queue/{itemID}/action", s.handleQueueItemAction)
+
+				// Subscription Routes
+				r.With(s.Audit("subscription.create")).Post("/subscriptions", s.handleSubscribeToSeries)
+				r.Get("/subscriptions", s.handleListSubscriptions)
+				r.With(s.Audit("subscription.update_folder")).Put("/subscriptions/{subID}/folder-path", s.handleUpdateSubscriptionFolderPath)
+				r.With(s.Audit("subscription.recheck")).Post("/subscriptions/{subID}/recheck", s.handleRecheckSubscription)
+				r.With(s.Audit("subscription.recheck_all")).Post("/subscriptions/recheck-all", s.handleRecheckAllSubscriptions)
+				r.With(s.Audit("subscription.delete")).Delete("/subscriptions/{subID}", s.handleDeleteSubscription)
+			})
+
+			// Plugin Management Routes (the downloader lists plugins too)
+			r.Group(func(r chi.Router) {
+				r.Use(s.RequirePermission(models.PermManageDownloads, models.PermManagePlugins))
+
+				r.Get("/plugins", s.handleListPlugins)
+				r.Get("/plugins/{pluginID}", s.handleGetPluginInfo)
+			})
 
 			// Plugin Repository Routes
-			r.Get("/plugin-repositories", s.handleListRepositories)
-			r.Get("/plugin-repositories/{repositoryID}/plugins", s.handleGetRepositoryPlugins)
+			r.Group(func(r chi.Router) {
+				r.Use(s.RequirePermission(models.PermManagePlugins))
+
+				r.Get("/plugin-repositories", s.handleListRepositories)
+				r.Get("/plugin-repositories/{repositoryID}/plugins", s.handleGetRepositoryPlugins)
+			})
 		})
 	})
 
-	// WebSocket route
-	r.Get("/ws/admin/progress", func(w http.ResponseWriter, r *http.Request) {
-		s.app.WsHub().ServeWs(w, r)
+	// OPDS catalog for e-readers and reading apps (HTTP Basic auth)
+	r.Route("/opds", func(r chi.Router) {
+		r.Use(s.BasicAuthMiddleware)
+		r.Use(s.RequirePermission(models.PermReadLibrary))
+
+		// Grouped so the access checks run after the URL parameters are resolved
+		r.Group(func(r chi.Router) {
+			r.Use(s.ContentAccessMiddleware)
+
+			r.Get("/", s.handleOPDSRoot)
+			r.Get("/folders/{folderID}", s.handleOPDSFolder)
+			r.Get("/folders/{folderID}/cover", s.handleOPDSFolderCover)
+			r.Get("/tags", s.handleOPDSTags)
+			r.Get("/tags/{tagID}", s.handleOPDSTag)
+			r.Get("/continue-reading", s.handleOPDSContinueReading)
+			r.Get("/recently-added", s.handleOPDSRecentlyAdded)
+			r.Get("/chapters/{chapterID}/download", s.handleOPDSDownloadChapter)
+			r.Get("/chapters/{chapterID}/pages/{pageNumber}", s.handleOPDSStreamPage)
+			r.Get("/chapters/{chapterID}/cover", s.handleOPDSChapterCover)
+		})
+	})
+
+	// KOReader progress sync (kosync protocol, x-auth-user/x-auth-key headers)
+	r.Route("/kosync", func(r chi.Router) {
+		r.Post("/users/create", s.handleKOSyncCreateUser)
+		r.Group(func(r chi.Router) {
+			r.Use(s.KOSyncAuthMiddleware)
+
+			r.Get("/users/auth", s.handleKOSyncAuth)
+			r.Put("/syncs/progress", s.handleKOSyncPutProgress)
+			r.Get("/syncs/progress/{document}", s.handleKOSyncGetProgress)
+		})
 	})
 
 	r.Get("/api/health", func(w http.ResponseWriter, r *http.Request) {
@@ -220,6 +396,7 @@ func (s *Server) Router() http.Handler {
 
 	r.Get("/", serveHTML("home.html"))
 	r.Get("/login", serveHTML("login.html"))
+	r.Get("/reset-password", serveHTML("reset_password.html"))
 	r.Get("/library", serveHTML("library.html"))
 	r.Get("/tags", serveHTML("tags.html"))
 	r.Get("/admin", serveHTML("admin.html"))
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+)
+
+const (
+	sessionCookieName = "session_token"
+	// sessionTouchInterval limits how often a session's last-seen time (and sliding expiry) is
+	// written, so browsing does not turn every request into a database write.
+	sessionTouchInterval = time.Minute
+	// maxUserAgentLength bounds the user agent stored with a session.
+	maxUserAgentLength = 512
+)
+
+// startSession logs the user in on this browser by creating a session and setting its cookie.
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, userID int64) error {
+	lifetime := s.app.Config().Sessions.Lifetime()
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	token, err := s.store.CreateSession(userID, lifetime, userAgent, clientIP(r))
+	if err != nil {
+		return err
+	}
+	setSessionCookie(w, r, token, time.Now().Add(lifetime))
+	return nil
+}
+
+// setSessionCookie hands a session token to the browser.
+func setSessionCookie(w http.ResponseWriter, r *http.Request, token string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Expires:  expires,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil, // Set secure flag if using HTTPS
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// userFromSessionCookie returns the user logged in with the request's session cookie, or nil.
+// It records the session's last use and, with sliding sessions, pushes its expiry (and the
+// cookie's) forward.
+func (s *Server) userFromSessionCookie(w http.ResponseWriter, r *http.Request) *models.User {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil
+	}
+	session, err := s.store.GetSession(cookie.Value)
+	if err != nil {
+		return nil
+	}
+	user, err := s.store.GetUserByID(session.UserID)
+	if err != nil {
+		return nil
+	}
+
+	if time.Since(session.LastSeenAt) >= sessionTouchInterval {
+		cfg := s.app.Config().Sessions
+		expires := session.ExpiresAt
+		if cfg.Sliding {
+			expires = time.Now().Add(cfg.Lifetime())
+		}
+		if err := s.store.TouchSession(session.ID, clientIP(r), expires); err != nil {
+			log.Printf("Failed to update session %d: %v", session.ID, err)
+		} else if cfg.Sliding {
+			setSessionCookie(w, r, cookie.Value, expires)
+		}
+	}
+	return user
+}
+
+// currentSessionID returns the ID of the session the request was made with, or 0.
+func (s *Server) currentSessionID(r *http.Request) int64 {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return 0
+	}
+	session, err := s.store.GetSession(cookie.Value)
+	if err != nil {
+		return 0
+	}
+	return session.ID
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	sessions, err := s.store.ListUserSessions(user.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to list sessions")
+		return
+	}
+	current := s.currentSessionID(r)
+	for _, session := range sessions {
+		session.Current = session.ID == current
+	}
+	RespondWithJSON(w, http.StatusOK, sessions)
+}
+
+// handleRevokeSession logs out one of the current user's sessions.
+func (s *Server) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	sessionID, err := strconv.ParseInt(chi.URLParam(r, "sessionID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+	if err := s.store.DeleteUserSession(user.ID, sessionID); err != nil {
+		if errors.Is(err, store.ErrSessionNotFound) {
+			RespondWithError(w, http.StatusNotFound, "Session not found")
+			return
+		}
+		RespondWithError(w, http.StatusInternalServerError, "Failed to revoke session")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRevokeOtherSessions logs out all of the current user's sessions except this one.
+func (s *Server) handleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	revoked, err := s.store.DeleteOtherUserSessions(user.ID, s.currentSessionID(r))
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]int64{"revoked": revoked})
+}
+
+// handleAdminRevokeUserSessions logs a user out everywhere.
+func (s *Server) handleAdminRevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	target, err := s.store.GetUserByID(userID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if !canManageUser(w, r, target) {
+		return
+	}
+	revoked, err := s.store.DeleteUserSessions(userID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]int64{"revoked": revoked})
+}
@@ -0,0 +1,137 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/config"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestSessionHandlers(t *testing.T) {
+	server, db, _ := testutil.SetupTestServerWithConfig(t, func(cfg *config.Config) {
+		cfg.Sessions.LifetimeHours = 2
+		cfg.Sessions.Sliding = true
+	})
+	router := server.Router()
+	adminCookie := testutil.CookieForUser(t, server, "boss", "password", "admin")
+
+	login := func(userAgent string) *http.Cookie {
+		t.Helper()
+		req, _ := http.NewRequest("POST", "/api/users/login", bytes.NewBufferString(`{"username":"reader","password":"password"}`))
+		req.Header.Set("User-Agent", userAgent)
+		req.RemoteAddr = "203.0.113.7:40000"
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		for _, c := range rr.Result().Cookies() {
+			if c.Name == "session_token" {
+				return c
+			}
+		}
+		t.Fatalf("Login failed: %d %s", rr.Code, rr.Body.String())
+		return nil
+	}
+	do := func(method, path string, c *http.Cookie) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		req.AddCookie(c)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	type session struct {
+		ID        int64     `json:"id"`
+		UserAgent string    `json:"user_agent"`
+		IPAddress string    `json:"ip_address"`
+		ExpiresAt time.Time `json:"expires_at"`
+		Current   bool      `json:"current"`
+	}
+	list := func(c *http.Cookie) []session {
+		t.Helper()
+		rr := do("GET", "/api/users/me/sessions", c)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+		var sessions []session
+		json.Unmarshal(rr.Body.Bytes(), &sessions)
+		return sessions
+	}
+
+	testutil.CookieForUser(t, server, "reader", "password", "user")
+	laptop := login("Laptop Browser")
+	phone := login("Phone Browser")
+
+	t.Run("List", func(t *testing.T) {
+		sessions := list(laptop)
+		var current *session
+		for i := range sessions {
+			if sessions[i].Current {
+				current = &sessions[i]
+			}
+		}
+		if len(sessions) != 3 || current == nil {
+			t.Fatalf("Expected 3 sessions with one current, got %+v", sessions)
+		}
+		if current.UserAgent != "Laptop Browser" || current.IPAddress != "203.0.113.7" {
+			t.Errorf("Unexpected current session %+v", current)
+		}
+	})
+
+	t.Run("Sliding Expiry", func(t *testing.T) {
+		db.Exec("UPDATE sessions SET last_seen_at = ?, expiry = ? WHERE token = ?",
+			time.Now().UTC().Add(-time.Hour), time.Now().UTC().Add(time.Hour), phone.Value)
+		rr := do("GET", "/api/users/me", phone)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+		if len(rr.Result().Cookies()) == 0 {
+			t.Error("Expected the cookie to be refreshed")
+		}
+		for _, s := range list(phone) {
+			if s.Current && time.Until(s.ExpiresAt) < 119*time.Minute {
+				t.Errorf("Expected the expiry to slide to two hours, got %v", time.Until(s.ExpiresAt))
+			}
+		}
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		var phoneID int64
+		for _, s := range list(phone) {
+			if s.Current {
+				phoneID = s.ID
+			}
+		}
+		if rr := do("DELETE", fmt.Sprintf("/api/users/me/sessions/%d", phoneID), adminCookie); rr.Code != http.StatusNotFound {
+			t.Errorf("Expected another user's session to be not found, got %d", rr.Code)
+		}
+		if rr := do("DELETE", fmt.Sprintf("/api/users/me/sessions/%d", phoneID), laptop); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected 204, got %d", rr.Code)
+		}
+		if rr := do("GET", "/api/users/me", phone); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected the revoked session to be logged out, got %d", rr.Code)
+		}
+
+		rr := do("DELETE", "/api/users/me/sessions", laptop)
+		if rr.Code != http.StatusOK || len(list(laptop)) != 1 {
+			t.Errorf("Expected only the current session to remain, got %d %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Admin Force Logout", func(t *testing.T) {
+		user, _ := server.Store().GetUserByUsername("reader")
+		path := fmt.Sprintf("/api/admin/users/%d/sessions", user.ID)
+		if rr := do("DELETE", path, laptop); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected non-admins to be forbidden, got %d", rr.Code)
+		}
+		if rr := do("DELETE", path, adminCookie); rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+		if rr := do("GET", "/api/users/me", laptop); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected the user to be logged out, got %d", rr.Code)
+		}
+	})
+}
@@ -9,7 +9,8 @@ import (
 )
 
 func (s *Server) handleListTags(w http.ResponseWriter, r *http.Request) {
-	tags, err := s.store.ListTagsWithCounts()
+	user := getUserFromContext(r)
+	tags, err := s.store.ListTagsWithCounts(user.ID)
 	if err != nil {
 		RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve tags")
 		return
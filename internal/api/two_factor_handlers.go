@@ -0,0 +1,341 @@
+package api
+
+// This file implements optional TOTP two-factor authentication: enrollment, recovery codes,
+// the second login step and an admin reset.
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+const (
+	// totpIssuer is the account label shown in authenticator apps.
+	totpIssuer = "mango-go"
+	// recoveryCodeCount is how many recovery codes are issued at a time.
+	recoveryCodeCount = 10
+	// twoFactorChallengeTTL is how long a user has to enter their code after the password.
+	twoFactorChallengeTTL = 5 * time.Minute
+	// twoFactorMaxAttempts is how many wrong codes a challenge survives.
+	twoFactorMaxAttempts = 5
+)
+
+// twoFactorChallenges tracks logins that passed the password step and are waiting for a code.
+type twoFactorChallenges struct {
+	mu      sync.Mutex
+	pending map[string]*twoFactorChallenge
+}
+
+type twoFactorChallenge struct {
+	userID   int64
+	expires  time.Time
+	attempts int
+}
+
+func newTwoFactorChallenges() *twoFactorChallenges {
+	return &twoFactorChallenges{pending: make(map[string]*twoFactorChallenge)}
+}
+
+// start creates a challenge for a user whose password was verified.
+func (c *twoFactorChallenges) start(userID int64) (string, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, p := range c.pending {
+		if now.After(p.expires) {
+			delete(c.pending, k)
+		}
+	}
+	c.pending[token] = &twoFactorChallenge{userID: userID, expires: now.Add(twoFactorChallengeTTL)}
+	return token, nil
+}
+
+// user returns the user a challenge belongs to and counts the attempt. Challenges are dropped
+// once they expire or run out of attempts.
+func (c *twoFactorChallenges) user(token string) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pending[token]
+	if !ok || time.Now().After(p.expires) {
+		delete(c.pending, token)
+		return 0, false
+	}
+	p.attempts++
+	if p.attempts >= twoFactorMaxAttempts {
+		delete(c.pending, token)
+	}
+	return p.userID, true
+}
+
+func (c *twoFactorChallenges) finish(token string) {
+	c.mu.Lock()
+	delete(c.pending, token)
+	c.mu.Unlock()
+}
+
+// verifyTOTP checks a code from the user's authenticator app, refusing codes already used.
+func (s *Server) verifyTOTP(userID int64, secret, code string) bool {
+	step, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false
+	}
+	accepted, err := s.store.AcceptTOTPStep(userID, step)
+	if err != nil {
+		log.Printf("Failed to record TOTP use for user %d: %v", userID, err)
+		return false
+	}
+	return accepted
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code.
+func (s *Server) verifySecondFactor(userID int64, code string) bool {
+	totp, err := s.store.GetUserTOTP(userID)
+	if err != nil || !totp.Enabled {
+		return false
+	}
+	if s.verifyTOTP(userID, totp.Secret, code) {
+		return true
+	}
+	used, err := s.store.UseRecoveryCode(userID, auth.HashRecoveryCode(code))
+	if err != nil {
+		log.Printf("Failed to check recovery code for user %d: %v", userID, err)
+	}
+	return used
+}
+
+// newRecoveryCodes generates recovery codes and their stored hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// handleLoginTwoFactor is the second login step for users with 2FA enabled.
+func (s *Server) handleLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Challenge string `json:"challenge"`
+		Code      string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	userID, ok := s.twoFactor.user(payload.Challenge)
+	if !ok {
+		RespondWithError(w, http.StatusUnauthorized, "Login expired, please log in again")
+		return
+	}
+	user, err := s.store.GetUserByID(userID)
+	if err != nil {
+		RespondWithError(w, http.StatusUnauthorized, "Login expired, please log in again")
+		return
+	}
+	if s.accountLoginBlocked(w, userID) {
+		return
+	}
+	if !s.verifySecondFactor(userID, payload.Code) {
+		s.recordFailedLogin(r, userID, user.Username)
+		RespondWithError(w, http.StatusUnauthorized, "Invalid authentication code")
+		return
+	}
+	s.twoFactor.finish(payload.Challenge)
+	s.resetLoginFailures(userID)
+
+	if err := s.startSession(w, r, userID); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to create session")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleGetTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	remaining, err := s.store.CountRecoveryCodes(user.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to load 2FA status")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"enabled":                  user.TOTPEnabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// handleEnrollTwoFactor generates a new secret for the user to add to an authenticator app.
+// 2FA is not enforced until the user confirms a code from it.
+func (s *Server) handleEnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	if user.TOTPEnabled {
+		RespondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to generate secret")
+		return
+	}
+	if err := s.store.SetUserTOTPSecret(user.ID, secret); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to save secret")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]string{
+		"secret":      secret,
+		"otpauth_uri": auth.TOTPAuthURI(totpIssuer, user.Username, secret),
+	})
+}
+
+// handleConfirmTwoFactor enables 2FA once the user proves their app produces valid codes, and
+// returns the recovery codes. They are only shown this once.
+func (s *Server) handleConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	var payload struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	totp, err := s.store.GetUserTOTP(user.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to load 2FA status")
+		return
+	}
+	if totp.Enabled {
+		RespondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+	if totp.Secret == "" {
+		RespondWithError(w, http.StatusBadRequest, "Start enrollment first")
+		return
+	}
+	step, ok := auth.ValidateTOTP(totp.Secret, payload.Code, time.Now())
+	if !ok {
+		RespondWithError(w, http.StatusBadRequest, "Invalid authentication code")
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to generate recovery codes")
+		return
+	}
+	if err := s.store.EnableUserTOTP(user.ID, step, hashes); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{"recovery_codes": codes})
+}
+
+// handleRegenerateRecoveryCodes replaces the user's recovery codes. It needs a current code.
+func (s *Server) handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	var payload struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	totp, err := s.store.GetUserTOTP(user.ID)
+	if err != nil || !totp.Enabled {
+		RespondWithError(w, http.StatusBadRequest, "Two-factor authentication is not enabled")
+		return
+	}
+	if !s.verifyTOTP(user.ID, totp.Secret, payload.Code) {
+		RespondWithError(w, http.StatusBadRequest, "Invalid authentication code")
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to generate recovery codes")
+		return
+	}
+	if err := s.store.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to save recovery codes")
+		return
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{"recovery_codes": codes})
+}
+
+// handleDisableTwoFactor turns off 2FA for the current user. It needs a current or recovery code.
+func (s *Server) handleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromContext(r)
+	var payload struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if !user.TOTPEnabled {
+		RespondWithError(w, http.StatusBadRequest, "Two-factor authentication is not enabled")
+		return
+	}
+	if !s.verifySecondFactor(user.ID, payload.Code) {
+		RespondWithError(w, http.StatusBadRequest, "Invalid authentication code")
+		return
+	}
+	if err := s.store.DisableUserTOTP(user.ID); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAdminResetTwoFactor turns off 2FA for a user who lost their device and recovery codes.
+func (s *Server) handleAdminResetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	target, err := s.store.GetUserByID(userID)
+	if err != nil {
+		RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+	if !canManageUser(w, r, target) {
+		return
+	}
+	if err := s.store.DisableUserTOTP(userID); err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to reset two-factor authentication")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loginRequiresTwoFactor answers a correct password for a 2FA user with a challenge for the
+// second step instead of a session.
+func (s *Server) loginRequiresTwoFactor(w http.ResponseWriter, user *models.User) bool {
+	if !user.TOTPEnabled {
+		return false
+	}
+	challenge, err := s.twoFactor.start(user.ID)
+	if err != nil {
+		RespondWithError(w, http.StatusInternalServerError, "Failed to start two-factor login")
+		return true
+	}
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"two_factor_required": true,
+		"challenge":           challenge,
+	})
+	return true
+}
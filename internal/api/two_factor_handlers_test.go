@@ -0,0 +1,176 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestTwoFactorHandlers(t *testing.T) {
+	server, _, _ := testutil.SetupTestServer(t)
+	router := server.Router()
+	cookie := testutil.CookieForUser(t, server, "careful", "password", "user")
+	adminCookie := testutil.CookieForUser(t, server, "boss", "password", "admin")
+
+	do := func(method, path, body string, c *http.Cookie) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		if c != nil {
+			req.AddCookie(c)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	login := func() (challenge string, rr *httptest.ResponseRecorder) {
+		rr = do("POST", "/api/users/login", `{"username":"careful","password":"password"}`, nil)
+		var body struct {
+			Required  bool   `json:"two_factor_required"`
+			Challenge string `json:"challenge"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &body)
+		return body.Challenge, rr
+	}
+	// codeAt returns the code for a time step, so tests can use a fresh step after each success.
+	codeAt := func(secret string, offset int64) string {
+		code, _ := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+offset)
+		return code
+	}
+
+	var secret string
+	var recoveryCodes []string
+
+	t.Run("Enroll And Confirm", func(t *testing.T) {
+		rr := do("POST", "/api/users/me/2fa/enroll", "", cookie)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+		}
+		var enroll struct {
+			Secret string `json:"secret"`
+			URI    string `json:"otpauth_uri"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &enroll)
+		secret = enroll.Secret
+		if secret == "" || enroll.URI == "" {
+			t.Fatalf("Expected a secret and URI, got %s", rr.Body.String())
+		}
+
+		// Not enforced until confirmed
+		if _, rr := login(); len(rr.Result().Cookies()) == 0 {
+			t.Fatal("Expected a session before 2FA is confirmed")
+		}
+
+		if rr := do("POST", "/api/users/me/2fa/confirm", `{"code":"000000x"}`, cookie); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a wrong code, got %d", rr.Code)
+		}
+		rr = do("POST", "/api/users/me/2fa/confirm", fmt.Sprintf(`{"code":%q}`, codeAt(secret, -1)), cookie)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+		}
+		var confirmed struct {
+			Codes []string `json:"recovery_codes"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &confirmed)
+		recoveryCodes = confirmed.Codes
+		if len(recoveryCodes) != 10 {
+			t.Fatalf("Expected 10 recovery codes, got %d", len(recoveryCodes))
+		}
+		if rr := do("POST", "/api/users/me/2fa/enroll", "", cookie); rr.Code != http.StatusConflict {
+			t.Errorf("Expected 409 when already enabled, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Login Requires Code", func(t *testing.T) {
+		challenge, rr := login()
+		if rr.Code != http.StatusOK || challenge == "" || len(rr.Result().Cookies()) != 0 {
+			t.Fatalf("Expected a challenge and no session, got %d %s", rr.Code, rr.Body.String())
+		}
+
+		// The code used to confirm enrollment cannot be replayed
+		body := fmt.Sprintf(`{"challenge":%q,"code":%q}`, challenge, codeAt(secret, -1))
+		if rr := do("POST", "/api/users/login/2fa", body, nil); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected a replayed code to be rejected, got %d", rr.Code)
+		}
+
+		body = fmt.Sprintf(`{"challenge":%q,"code":%q}`, challenge, codeAt(secret, 0))
+		rr = do("POST", "/api/users/login/2fa", body, nil)
+		if rr.Code != http.StatusOK || len(rr.Result().Cookies()) == 0 {
+			t.Fatalf("Expected a session, got %d %s", rr.Code, rr.Body.String())
+		}
+		if rr := do("POST", "/api/users/login/2fa", body, nil); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected a used challenge to be rejected, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Recovery Code", func(t *testing.T) {
+		challenge, _ := login()
+		body := fmt.Sprintf(`{"challenge":%q,"code":%q}`, challenge, recoveryCodes[0])
+		if rr := do("POST", "/api/users/login/2fa", body, nil); rr.Code != http.StatusOK {
+			t.Fatalf("Expected a recovery code to work, got %d", rr.Code)
+		}
+		challenge, _ = login()
+		body = fmt.Sprintf(`{"challenge":%q,"code":%q}`, challenge, recoveryCodes[0])
+		if rr := do("POST", "/api/users/login/2fa", body, nil); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected a used recovery code to be rejected, got %d", rr.Code)
+		}
+
+		rr := do("GET", "/api/users/me/2fa", "", cookie)
+		var status map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &status)
+		if status["enabled"] != true || status["recovery_codes_remaining"] != float64(9) {
+			t.Errorf("Unexpected status %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Attempts Are Limited", func(t *testing.T) {
+		challenge, _ := login()
+		body := fmt.Sprintf(`{"challenge":%q,"code":"bad"}`, challenge)
+		for i := 0; i < 5; i++ {
+			do("POST", "/api/users/login/2fa", body, nil)
+		}
+		body = fmt.Sprintf(`{"challenge":%q,"code":%q}`, challenge, recoveryCodes[1])
+		if rr := do("POST", "/api/users/login/2fa", body, nil); rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected the challenge to be used up, got %d", rr.Code)
+		}
+	})
+
+	t.Run("OPDS Basic Auth", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/opds", nil)
+		req.SetBasicAuth("careful", "password")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected the password alone to be rejected, got %d", rr.Code)
+		}
+
+		user, _ := server.Store().GetUserByUsername("careful")
+		_, token, _ := server.Store().CreateAPIToken(user.ID, "reader", true, nil)
+		req, _ = http.NewRequest("GET", "/opds", nil)
+		req.SetBasicAuth("careful", token)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected an API token as the password to work, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Admin Reset", func(t *testing.T) {
+		user, _ := server.Store().GetUserByUsername("careful")
+		path := fmt.Sprintf("/api/admin/users/%d/2fa", user.ID)
+		if rr := do("DELETE", path, "", cookie); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected non-admins to be forbidden, got %d", rr.Code)
+		}
+		if rr := do("DELETE", path, "", adminCookie); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected 204, got %d", rr.Code)
+		}
+		if _, rr := login(); len(rr.Result().Cookies()) == 0 {
+			t.Error("Expected a plain login after the reset")
+		}
+	})
+}
@@ -0,0 +1,15 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE chapters DROP COLUMN comicinfo_checked;
+ALTER TABLE chapters DROP COLUMN comicinfo_page_count;
+ALTER TABLE chapters DROP COLUMN comicinfo_manga;
+ALTER TABLE chapters DROP COLUMN comicinfo_language_iso;
+ALTER TABLE chapters DROP COLUMN comicinfo_genre;
+ALTER TABLE chapters DROP COLUMN comicinfo_writer;
+ALTER TABLE chapters DROP COLUMN comicinfo_title;
+ALTER TABLE chapters DROP COLUMN comicinfo_volume;
+ALTER TABLE chapters DROP COLUMN comicinfo_number;
+ALTER TABLE chapters DROP COLUMN comicinfo_series;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,18 @@
+PRAGMA foreign_keys = ON;
+
+-- Metadata parsed from a ComicInfo.xml embedded in the chapter file
+ALTER TABLE chapters ADD COLUMN comicinfo_series TEXT;
+ALTER TABLE chapters ADD COLUMN comicinfo_number TEXT;
+ALTER TABLE chapters ADD COLUMN comicinfo_volume INTEGER;
+ALTER TABLE chapters ADD COLUMN comicinfo_title TEXT;
+ALTER TABLE chapters ADD COLUMN comicinfo_writer TEXT;
+ALTER TABLE chapters ADD COLUMN comicinfo_genre TEXT;
+ALTER TABLE chapters ADD COLUMN comicinfo_language_iso TEXT;
+ALTER TABLE chapters ADD COLUMN comicinfo_manga TEXT;
+ALTER TABLE chapters ADD COLUMN comicinfo_page_count INTEGER;
+-- Set once the chapter file has been checked for ComicInfo.xml, so existing
+-- chapters are backfilled by the next sync even when the file is unchanged
+ALTER TABLE chapters ADD COLUMN comicinfo_checked BOOLEAN NOT NULL DEFAULT false;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,8 @@
+PRAGMA foreign_keys = ON;
+
+ALTER TABLE download_queue DROP COLUMN volume;
+ALTER TABLE download_queue DROP COLUMN chapter_number;
+ALTER TABLE download_queue DROP COLUMN language;
+ALTER TABLE download_queue DROP COLUMN group_id;
+ALTER TABLE download_queue DROP COLUMN group_name;
+ALTER TABLE download_queue DROP COLUMN published_at;
@@ -0,0 +1,13 @@
+PRAGMA foreign_keys = ON;
+
+-- Chapter metadata reported by the provider, embedded as ComicInfo.xml in downloaded CBZs
+ALTER TABLE download_queue ADD COLUMN volume TEXT;
+ALTER TABLE download_queue ADD COLUMN chapter_number TEXT;
+ALTER TABLE download_queue ADD COLUMN language TEXT;
+ALTER TABLE download_queue ADD COLUMN group_id TEXT;
+-- Scanlation group name, written as the ComicInfo.xml Translator
+ALTER TABLE download_queue ADD COLUMN group_name TEXT;
+ALTER TABLE download_queue ADD COLUMN published_at TIMESTAMP;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,9 @@
+PRAGMA foreign_keys = ON;
+
+DROP TABLE IF EXISTS kosync_progress;
+DROP INDEX IF EXISTS idx_chapters_koreader_hash;
+ALTER TABLE chapters DROP COLUMN koreader_hash;
+ALTER TABLE users DROP COLUMN kosync_key_hash;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,28 @@
+PRAGMA foreign_keys = ON;
+
+-- bcrypt hash of the MD5 hex digest of the user's password, which is what KOReader
+-- sends as its sync key. Set whenever the plaintext password is seen.
+ALTER TABLE users ADD COLUMN kosync_key_hash TEXT;
+
+-- KOReader's partial MD5 of the chapter file, so its default document
+-- identifiers can be matched alongside content_hash
+ALTER TABLE chapters ADD COLUMN koreader_hash TEXT;
+
+CREATE INDEX idx_chapters_koreader_hash ON chapters (koreader_hash);
+
+-- Last position pushed by a KOReader device, kept verbatim so the device gets
+-- back its own progress string (page number or XPointer)
+CREATE TABLE kosync_progress (
+    user_id INTEGER NOT NULL,
+    document TEXT NOT NULL,
+    progress TEXT NOT NULL,
+    percentage REAL NOT NULL,
+    device TEXT NOT NULL,
+    device_id TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, document),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Foreign key check
+PRAGMA foreign_key_check;
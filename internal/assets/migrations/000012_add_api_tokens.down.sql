@@ -0,0 +1,7 @@
+PRAGMA foreign_keys = ON;
+
+DROP INDEX IF EXISTS idx_api_tokens_user_id;
+DROP TABLE IF EXISTS api_tokens;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
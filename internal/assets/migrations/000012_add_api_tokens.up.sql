@@ -0,0 +1,20 @@
+PRAGMA foreign_keys = ON;
+
+-- Personal API tokens, sent as "Authorization: Bearer <token>". Only the SHA-256
+-- of the token is stored; the token itself is shown once when it is created.
+CREATE TABLE api_tokens (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    read_only BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_api_tokens_user_id ON api_tokens (user_id);
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,7 @@
+PRAGMA foreign_keys = ON;
+
+DROP INDEX IF EXISTS idx_users_oidc_subject;
+ALTER TABLE users DROP COLUMN oidc_subject;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
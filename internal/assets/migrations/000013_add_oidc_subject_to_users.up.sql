@@ -0,0 +1,10 @@
+PRAGMA foreign_keys = ON;
+
+-- The OpenID Connect "sub" claim of the identity linked to the account. Users
+-- created by OIDC login have an empty password_hash and cannot log in locally.
+ALTER TABLE users ADD COLUMN oidc_subject TEXT;
+
+CREATE UNIQUE INDEX idx_users_oidc_subject ON users (oidc_subject);
+
+-- Foreign key check
+PRAGMA foreign_key_check;
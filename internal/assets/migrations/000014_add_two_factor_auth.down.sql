@@ -0,0 +1,10 @@
+PRAGMA foreign_keys = ON;
+
+DROP INDEX IF EXISTS idx_user_recovery_codes_user_id;
+DROP TABLE IF EXISTS user_recovery_codes;
+ALTER TABLE users DROP COLUMN totp_last_step;
+ALTER TABLE users DROP COLUMN totp_enabled;
+ALTER TABLE users DROP COLUMN totp_secret;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
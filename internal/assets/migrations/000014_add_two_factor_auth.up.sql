@@ -0,0 +1,22 @@
+PRAGMA foreign_keys = ON;
+
+-- TOTP secret (base32). Set during enrollment and only enforced once totp_enabled
+-- is true, after the user has confirmed a code.
+ALTER TABLE users ADD COLUMN totp_secret TEXT;
+ALTER TABLE users ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT false;
+-- Time step of the last accepted code, so a code cannot be used twice
+ALTER TABLE users ADD COLUMN totp_last_step INTEGER NOT NULL DEFAULT 0;
+
+-- One-time recovery codes (SHA-256 of the normalized code)
+CREATE TABLE user_recovery_codes (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_recovery_codes_user_id ON user_recovery_codes (user_id);
+
+-- Foreign key check
+PRAGMA foreign_key_check;
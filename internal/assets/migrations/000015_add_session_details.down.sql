@@ -0,0 +1,21 @@
+PRAGMA foreign_keys = ON;
+
+CREATE TABLE sessions_old (
+    token TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    expiry TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO sessions_old (token, user_id, expiry)
+SELECT token, user_id, expiry FROM sessions;
+
+DROP INDEX IF EXISTS idx_sessions_user_id;
+DROP INDEX IF EXISTS sessions_expiry_idx;
+DROP TABLE sessions;
+ALTER TABLE sessions_old RENAME TO sessions;
+
+CREATE INDEX sessions_expiry_idx ON sessions (expiry);
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,28 @@
+PRAGMA foreign_keys = ON;
+
+-- Sessions get an ID (so they can be listed and revoked without exposing the token)
+-- and record the client they were created for and when they were last used.
+CREATE TABLE sessions_new (
+    id INTEGER PRIMARY KEY,
+    token TEXT NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expiry TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+INSERT INTO sessions_new (token, user_id, expiry)
+SELECT token, user_id, expiry FROM sessions;
+
+DROP INDEX IF EXISTS sessions_expiry_idx;
+DROP TABLE sessions;
+ALTER TABLE sessions_new RENAME TO sessions;
+
+CREATE INDEX sessions_expiry_idx ON sessions (expiry);
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,12 @@
+PRAGMA foreign_keys = ON;
+
+DROP INDEX IF EXISTS idx_failed_logins_attempted_at;
+DROP INDEX IF EXISTS idx_failed_logins_ip_address;
+DROP TABLE IF EXISTS failed_logins;
+
+ALTER TABLE users DROP COLUMN locked_until;
+ALTER TABLE users DROP COLUMN last_failed_login_at;
+ALTER TABLE users DROP COLUMN failed_login_count;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
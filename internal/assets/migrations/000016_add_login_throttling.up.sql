@@ -0,0 +1,24 @@
+PRAGMA foreign_keys = ON;
+
+-- Consecutive failed logins per account, and the time a locked account unlocks.
+ALTER TABLE users ADD COLUMN failed_login_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN last_failed_login_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP;
+
+-- Every failed login attempt, for per-IP throttling and for admins to review.
+-- The username is kept as typed, so attempts against unknown accounts are recorded too.
+CREATE TABLE failed_logins (
+    id INTEGER PRIMARY KEY,
+    username TEXT NOT NULL,
+    user_id INTEGER,
+    ip_address TEXT NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    attempted_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_failed_logins_ip_address ON failed_logins (ip_address, attempted_at);
+CREATE INDEX idx_failed_logins_attempted_at ON failed_logins (attempted_at);
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,7 @@
+PRAGMA foreign_keys = ON;
+
+DROP INDEX IF EXISTS idx_password_reset_tokens_user_id;
+DROP TABLE IF EXISTS password_reset_tokens;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
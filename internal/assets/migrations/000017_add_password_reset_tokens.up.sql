@@ -0,0 +1,16 @@
+PRAGMA foreign_keys = ON;
+
+-- One-time password reset tokens issued by admins. Only the SHA-256 of the token is stored.
+CREATE TABLE password_reset_tokens (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+
+-- Foreign key check
+PRAGMA foreign_key_check;
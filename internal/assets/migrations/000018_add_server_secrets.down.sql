@@ -0,0 +1,6 @@
+PRAGMA foreign_keys = ON;
+
+DROP TABLE IF EXISTS server_secrets;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,12 @@
+PRAGMA foreign_keys = ON;
+
+-- Random keys generated on first use, e.g. for signing resource proxy URLs.
+-- Keeping them in the database means signed URLs survive restarts.
+CREATE TABLE server_secrets (
+    name TEXT PRIMARY KEY,
+    value BLOB NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,12 @@
+PRAGMA foreign_keys = ON;
+
+-- Users with a custom role fall back to the built-in "user" role.
+ALTER TABLE users ADD COLUMN role_name TEXT NOT NULL DEFAULT 'user' CHECK(role_name IN ('admin', 'user'));
+UPDATE users SET role_name = CASE WHEN role = 'admin' THEN 'admin' ELSE 'user' END;
+ALTER TABLE users DROP COLUMN role;
+ALTER TABLE users RENAME COLUMN role_name TO role;
+
+DROP TABLE IF EXISTS roles;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
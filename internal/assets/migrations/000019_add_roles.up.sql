@@ -0,0 +1,25 @@
+PRAGMA foreign_keys = ON;
+
+-- Roles are named permission sets. "admin" and "user" are built in; admins can add their own.
+-- Permissions are stored as a comma-separated list of permission names.
+CREATE TABLE roles (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    permissions TEXT NOT NULL DEFAULT '',
+    built_in INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO roles (name, description, permissions, built_in) VALUES
+    ('admin', 'Full access to the server', 'read_library,manage_downloads,manage_tags,upload_covers,manage_plugins,manage_users,run_jobs', 1),
+    ('user', 'Read the library, tag folders, upload covers and use the downloader', 'read_library,manage_downloads,manage_tags,upload_covers', 1);
+
+-- users.role used to be limited to 'admin' and 'user' by a CHECK constraint. Swap the column
+-- for one without it; rebuilding the table would cascade-delete rows that reference users.
+ALTER TABLE users ADD COLUMN role_name TEXT NOT NULL DEFAULT 'user';
+UPDATE users SET role_name = role;
+ALTER TABLE users DROP COLUMN role;
+ALTER TABLE users RENAME COLUMN role_name TO role;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,8 @@
+PRAGMA foreign_keys = ON;
+
+DROP TABLE IF EXISTS user_hidden_tags;
+DROP TABLE IF EXISTS user_folder_access;
+ALTER TABLE users DROP COLUMN restrict_folders;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
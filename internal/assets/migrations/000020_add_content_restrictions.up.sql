@@ -0,0 +1,28 @@
+PRAGMA foreign_keys = ON;
+
+-- When restrict_folders is set, the user only sees the top-level folders listed in
+-- user_folder_access (and everything below them). It is a separate flag so that removing the
+-- last listed folder hides everything instead of lifting the restriction.
+ALTER TABLE users ADD COLUMN restrict_folders INTEGER NOT NULL DEFAULT 0;
+
+-- Folder grants are kept by path, like hidden tags are kept by name, so they survive the folder
+-- being pruned and re-created with a new ID by a rescan. A grant whose folder is missing is
+-- reported to admins and applies again once a folder with that path is back.
+CREATE TABLE user_folder_access (
+    user_id INTEGER NOT NULL,
+    folder_path TEXT NOT NULL,
+    PRIMARY KEY (user_id, folder_path),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Folders carrying one of these tags, and everything below them, are hidden from the user.
+-- Tags are stored by name so the restriction survives the tag being deleted and re-created.
+CREATE TABLE user_hidden_tags (
+    user_id INTEGER NOT NULL,
+    tag_name TEXT NOT NULL,
+    PRIMARY KEY (user_id, tag_name),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,10 @@
+PRAGMA foreign_keys = ON;
+
+DROP TRIGGER IF EXISTS audit_log_no_update;
+DROP INDEX IF EXISTS idx_audit_log_actor;
+DROP INDEX IF EXISTS idx_audit_log_action;
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+DROP TABLE IF EXISTS audit_log;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,28 @@
+PRAGMA foreign_keys = ON;
+
+-- Administrative and destructive actions, for admins to review. Actors are stored by ID and
+-- username without a foreign key, so entries outlive the accounts that made them.
+CREATE TABLE audit_log (
+    id INTEGER PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    actor_id INTEGER,
+    actor_username TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    target TEXT NOT NULL DEFAULT '',
+    payload TEXT NOT NULL DEFAULT '',
+    status INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at);
+CREATE INDEX idx_audit_log_action ON audit_log (action, created_at);
+CREATE INDEX idx_audit_log_actor ON audit_log (actor_username, created_at);
+
+-- The log is append-only: entries are never changed, and only removed by the retention purge.
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit log entries cannot be changed');
+END;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -0,0 +1,8 @@
+PRAGMA foreign_keys = ON;
+
+DROP TABLE IF EXISTS invites;
+
+ALTER TABLE users DROP COLUMN pending;
+
+-- Foreign key check
+PRAGMA foreign_key_check;
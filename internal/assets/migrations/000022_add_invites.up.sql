@@ -0,0 +1,23 @@
+PRAGMA foreign_keys = ON;
+
+-- Accounts created through open registration wait for an admin to approve them. Pending
+-- accounts cannot log in and their role grants them nothing until then.
+ALTER TABLE users ADD COLUMN pending INTEGER NOT NULL DEFAULT 0;
+
+-- Invite codes admins hand out so people can register themselves with a preset role. Only the
+-- SHA-256 of the code is stored. Deleting a role deletes the invites for it.
+CREATE TABLE invites (
+    id INTEGER PRIMARY KEY,
+    code_hash TEXT NOT NULL UNIQUE,
+    role TEXT NOT NULL,
+    max_uses INTEGER NOT NULL,
+    use_count INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_by INTEGER,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (role) REFERENCES roles(name) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Foreign key check
+PRAGMA foreign_key_check;
@@ -1,7 +1,7 @@
-import { checkAuth } from './auth.js';
+import { checkAuth, ADMIN_PERMISSIONS } from './auth.js';
 
 document.addEventListener('DOMContentLoaded', async () => {
-  const currentUser = await checkAuth('admin');
+  const currentUser = await checkAuth(ADMIN_PERMISSIONS);
   if (!currentUser) return;
 
   // Load and restore running job states on page load
@@ -1,7 +1,7 @@
 import { checkAuth } from './auth.js';
 
 document.addEventListener('DOMContentLoaded', async () => {
-  const currentUser = await checkAuth('admin');
+  const currentUser = await checkAuth('manage_users');
   if (!currentUser) return;
 
   const tableBody = document.getElementById('users-table-body');
@@ -13,7 +13,38 @@ document.addEventListener('DOMContentLoaded', async () => {
   const passwordInput = document.getElementById('password-input');
   const roleSelect = document.getElementById('role-select');
 
+  const rolesTableBody = document.getElementById('roles-table-body');
+  const roleModal = document.getElementById('role-modal');
+  const roleModalTitle = document.getElementById('role-modal-title');
+  const roleForm = document.getElementById('role-form');
+  const roleEditingInput = document.getElementById('role-editing');
+  const roleNameInput = document.getElementById('role-name-input');
+  const roleDescriptionInput = document.getElementById('role-description-input');
+  const rolePermissions = document.getElementById('role-permissions');
+
+  const restrictionsModal = document.getElementById('restrictions-modal');
+  const restrictionsModalTitle = document.getElementById('restrictions-modal-title');
+  const restrictionsForm = document.getElementById('restrictions-form');
+  const restrictionsUserIdInput = document.getElementById('restrictions-user-id');
+  const restrictFoldersInput = document.getElementById('restrict-folders-input');
+  const restrictionFolders = document.getElementById('restriction-folders');
+  const hiddenTagsInput = document.getElementById('hidden-tags-input');
+
+  const invitesTableBody = document.getElementById('invites-table-body');
+  const inviteModal = document.getElementById('invite-modal');
+  const inviteForm = document.getElementById('invite-form');
+  const inviteRoleSelect = document.getElementById('invite-role-select');
+  const inviteMaxUsesInput = document.getElementById('invite-max-uses-input');
+  const inviteExpiresInput = document.getElementById('invite-expires-input');
+
   let allUsers = [];
+  let allRoles = [];
+  let allPermissions = [];
+  // Granted folders that are not in the library right now, for the open restrictions form
+  let missingFolders = [];
+
+  const permissionLabel = permission =>
+    permission.replace(/_/g, ' ').replace(/^./, c => c.toUpperCase());
 
   const renderUsers = () => {
     tableBody.innerHTML = '';
@@ -21,11 +52,18 @@ document.addEventListener('DOMContentLoaded', async () => {
       const row = document.createElement('tr');
       const createdAt = new Date(user.created_at).toLocaleDateString();
       row.innerHTML = `
-    <td>${user.username}</td>
-    <td>${user.role}</td>
+    <td>${user.username}${user.locked_until ? ' <i class="ph-bold ph-lock" title="Locked after failed logins"></i>' : ''}</td>
+    <td>${user.role}${user.pending ? ' <small>(awaiting approval)</small>' : ''}</td>
+    <td>${user.totp_enabled ? 'Enabled' : 'Off'}</td>
     <td>${createdAt}</td>
     <td class="actions-cell">
+        ${user.pending ? `<button class="approve-btn" data-id="${user.id}" title="Approve Account"><i class="ph-bold ph-check"></i></button>` : ''}
         <button class="edit-btn" data-id="${user.id}" title="Edit User"><i class="ph-bold ph-pencil-simple"></i></button>
+        <button class="restrictions-btn" data-id="${user.id}" title="Content Restrictions"><i class="ph-bold ph-eye-slash"></i></button>
+        ${user.locked_until ? `<button class="unlock-btn" data-id="${user.id}" title="Unlock Account"><i class="ph-bold ph-lock-open"></i></button>` : ''}
+        <button class="reset-password-btn" data-id="${user.id}" title="Create Password Reset Link"><i class="ph-bold ph-key"></i></button>
+        <button class="logout-btn" data-id="${user.id}" title="Log Out Everywhere"><i class="ph-bold ph-sign-out"></i></button>
+        ${user.totp_enabled ? `<button class="reset-2fa-btn" data-id="${user.id}" title="Reset 2FA"><i class="ph-bold ph-shield-slash"></i></button>` : ''}
         <button class="delete-btn" data-id="${user.id}" title="Delete User" ${currentUser.id === user.id ? 'disabled' : ''}><i class="ph-bold ph-trash"></i></button>
     </td>
     `;
@@ -44,6 +82,199 @@ document.addEventListener('DOMContentLoaded', async () => {
     }
   };
 
+  const renderRoles = () => {
+    rolesTableBody.innerHTML = '';
+    allRoles.forEach(role => {
+      const row = document.createElement('tr');
+      row.innerHTML = `
+    <td>${role.name}${role.description ? `<br><small>${role.description}</small>` : ''}</td>
+    <td>${role.permissions.map(permissionLabel).join(', ') || 'None'}</td>
+    <td>${role.user_count}</td>
+    <td class="actions-cell">
+        <button class="edit-role-btn" data-name="${role.name}" title="Edit Role" ${role.name === 'admin' ? 'disabled' : ''}><i class="ph-bold ph-pencil-simple"></i></button>
+        <button class="delete-role-btn" data-name="${role.name}" title="Delete Role" ${role.built_in || role.user_count > 0 ? 'disabled' : ''}><i class="ph-bold ph-trash"></i></button>
+    </td>
+    `;
+      rolesTableBody.appendChild(row);
+    });
+
+    [roleSelect, inviteRoleSelect].forEach(select => {
+      const selected = select.value;
+      select.innerHTML = allRoles.map(role => `<option value="${role.name}">${role.name}</option>`).join('');
+      if (selected) select.value = selected;
+    });
+  };
+
+  const loadRoles = async () => {
+    try {
+      const [rolesResponse, permissionsResponse] = await Promise.all([
+        fetch('/api/admin/roles'),
+        fetch('/api/admin/permissions'),
+      ]);
+      allRoles = await rolesResponse.json();
+      allPermissions = await permissionsResponse.json();
+      renderRoles();
+    } catch (e) {
+      console.error('Failed to load roles:', e);
+      toast.error('Could not load roles.');
+    }
+  };
+
+  const renderInvites = invites => {
+    invitesTableBody.innerHTML = '';
+    invites.forEach(invite => {
+      const row = document.createElement('tr');
+      const expired = new Date(invite.expires_at) < new Date();
+      row.innerHTML = `
+    <td>${invite.role}</td>
+    <td>${invite.use_count} / ${invite.max_uses}</td>
+    <td>${new Date(invite.expires_at).toLocaleString()}${expired ? ' <small>(expired)</small>' : ''}</td>
+    <td>${invite.created_by_username || ''}</td>
+    <td class="actions-cell">
+        <button class="delete-invite-btn" data-id="${invite.id}" title="Revoke Invite"><i class="ph-bold ph-trash"></i></button>
+    </td>
+    `;
+      invitesTableBody.appendChild(row);
+    });
+  };
+
+  const loadInvites = async () => {
+    try {
+      const response = await fetch('/api/admin/invites');
+      renderInvites(await response.json());
+    } catch (e) {
+      console.error('Failed to load invites:', e);
+      toast.error('Could not load invites.');
+    }
+  };
+
+  const openInviteModal = () => {
+    inviteForm.reset();
+    inviteRoleSelect.value = 'user';
+    inviteModal.style.display = 'flex';
+  };
+
+  const closeInviteModal = () => {
+    inviteModal.style.display = 'none';
+  };
+
+  const handleInviteFormSubmit = async e => {
+    e.preventDefault();
+    const payload = {
+      role: inviteRoleSelect.value,
+      max_uses: Number(inviteMaxUsesInput.value),
+      expires_in_hours: Number(inviteExpiresInput.value),
+    };
+    try {
+      const response = await fetch('/api/admin/invites', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(payload),
+      });
+      if (response.ok) {
+        const result = await response.json();
+        closeInviteModal();
+        await loadInvites();
+        // The code is only shown once
+        const link = new URL(result.invite_url, window.location.origin).href;
+        window.prompt('Invite link (it will not be shown again):', link);
+      } else {
+        const error = await response.json();
+        toast.error(error.error);
+      }
+    } catch (e) {
+      toast.error('An unexpected error occurred.');
+    }
+  };
+
+  const handleDeleteInvite = async inviteId => {
+    if (!confirm('Revoke this invite? Accounts already created with it are kept.')) {
+      return;
+    }
+    try {
+      const response = await fetch(`/api/admin/invites/${inviteId}`, {
+        method: 'DELETE',
+      });
+      if (response.ok) {
+        await loadInvites();
+      } else {
+        const error = await response.json();
+        toast.error(error.error);
+      }
+    } catch (e) {
+      toast.error('An unexpected error occurred.');
+    }
+  };
+
+  const openRoleModal = (role = null) => {
+    roleForm.reset();
+    roleEditingInput.value = role ? role.name : '';
+    roleModalTitle.textContent = role ? 'Edit Role' : 'Add New Role';
+    roleNameInput.value = role ? role.name : '';
+    roleNameInput.disabled = !!role;
+    roleDescriptionInput.value = role ? role.description : '';
+    rolePermissions.innerHTML = allPermissions
+      .map(
+        permission => `
+      <label class="permission-option">
+        <input type="checkbox" value="${permission}" ${role && role.permissions.includes(permission) ? 'checked' : ''}>
+        ${permissionLabel(permission)}
+      </label>`
+      )
+      .join('');
+    roleModal.style.display = 'flex';
+  };
+
+  const closeRoleModal = () => {
+    roleModal.style.display = 'none';
+  };
+
+  const handleRoleFormSubmit = async e => {
+    e.preventDefault();
+    const editing = roleEditingInput.value;
+    const url = editing ? `/api/admin/roles/${encodeURIComponent(editing)}` : '/api/admin/roles';
+    const payload = {
+      name: roleNameInput.value,
+      description: roleDescriptionInput.value,
+      permissions: [...rolePermissions.querySelectorAll('input:checked')].map(el => el.value),
+    };
+    try {
+      const response = await fetch(url, {
+        method: editing ? 'PUT' : 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(payload),
+      });
+      if (response.ok) {
+        closeRoleModal();
+        await loadRoles();
+      } else {
+        const error = await response.json();
+        toast.error(error.error);
+      }
+    } catch (e) {
+      toast.error('An unexpected error occurred.');
+    }
+  };
+
+  const handleDeleteRole = async name => {
+    if (!confirm(`Are you sure you want to delete the role "${name}"?`)) {
+      return;
+    }
+    try {
+      const response = await fetch(`/api/admin/roles/${encodeURIComponent(name)}`, {
+        method: 'DELETE',
+      });
+      if (response.ok) {
+        await loadRoles();
+      } else {
+        const error = await response.json();
+        toast.error(error.error);
+      }
+    } catch (e) {
+      toast.error('An unexpected error occurred.');
+    }
+  };
+
   const openModal = (user = null) => {
     userForm.reset();
     if (user) {
@@ -94,7 +325,7 @@ document.addEventListener('DOMContentLoaded', async () => {
       });
       if (response.ok) {
         closeModal();
-        await loadUsers();
+        await Promise.all([loadUsers(), loadRoles()]);
       } else {
         const error = await response.json();
         toast.error(error.error);
@@ -112,6 +343,180 @@ document.addEventListener('DOMContentLoaded', async () => {
       const response = await fetch(`/api/admin/users/${userId}`, {
         method: 'DELETE',
       });
+      if (response.ok) {
+        await Promise.all([loadUsers(), loadRoles()]);
+      } else {
+        const error = await response.json();
+        toast.error(error.error);
+      }
+    } catch (e) {
+      toast.error('An unexpected error occurred.');
+    }
+  };
+
+  const openRestrictionsModal = async user => {
+    try {
+      const [restrictionsResponse, foldersResponse] = await Promise.all([
+        fetch(`/api/admin/users/${user.id}/restrictions`),
+        fetch('/api/folders'),
+      ]);
+      if (!restrictionsResponse.ok || !foldersResponse.ok) {
+        toast.error('Could not load restrictions.');
+        return;
+      }
+      const restrictions = await restrictionsResponse.json();
+      // Only top-level folders can be granted
+      const folders = ((await foldersResponse.json()) || []).filter(f => !f.path.includes('/'));
+
+      restrictionsForm.reset();
+      restrictionsModalTitle.textContent = `Content Restrictions: ${user.username}`;
+      restrictionsUserIdInput.value = user.id;
+      restrictFoldersInput.checked = restrictions.restrict_folders;
+      missingFolders = restrictions.missing_folders || [];
+      restrictionFolders.innerHTML =
+        folders
+          .map(
+            folder => `
+      <label class="permission-option">
+        <input type="checkbox" value="${folder.id}" ${restrictions.folder_ids.includes(folder.id) ? 'checked' : ''}>
+        ${folder.name}
+      </label>`
+          )
+          .concat(
+            missingFolders.map(
+              (path, i) => `
+      <label class="permission-option" title="Not in the library right now; the grant applies again once the folder is back">
+        <input type="checkbox" data-missing="${i}" checked>
+        ${path} (missing)
+      </label>`
+            )
+          )
+          .join('') || '<p>No folders in the library.</p>';
+      hiddenTagsInput.value = restrictions.hidden_tags.join(', ');
+      restrictionsModal.style.display = 'flex';
+    } catch (e) {
+      console.error('Failed to load restrictions:', e);
+      toast.error('Could not load restrictions.');
+    }
+  };
+
+  const closeRestrictionsModal = () => {
+    restrictionsModal.style.display = 'none';
+  };
+
+  const handleRestrictionsFormSubmit = async e => {
+    e.preventDefault();
+    const payload = {
+      restrict_folders: restrictFoldersInput.checked,
+      folder_ids: [...restrictionFolders.querySelectorAll('input:checked:not([data-missing])')].map(
+        el => Number(el.value)
+      ),
+      missing_folders: [...restrictionFolders.querySelectorAll('input[data-missing]:checked')].map(
+        el => missingFolders[Number(el.dataset.missing)]
+      ),
+      hidden_tags: hiddenTagsInput.value
+        .split(',')
+        .map(tag => tag.trim())
+        .filter(Boolean),
+    };
+    try {
+      const userId = restrictionsUserIdInput.value;
+      const response = await fetch(`/api/admin/users/${userId}/restrictions`, {
+        method: 'PUT',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(payload),
+      });
+      if (response.ok) {
+        closeRestrictionsModal();
+        toast.success('Restrictions saved.');
+      } else {
+        const error = await response.json();
+        toast.error(error.error);
+      }
+    } catch (e) {
+      toast.error('An unexpected error occurred.');
+    }
+  };
+
+  const handleApprove = async userId => {
+    try {
+      const response = await fetch(`/api/admin/users/${userId}/approve`, {
+        method: 'POST',
+      });
+      if (response.ok) {
+        await loadUsers();
+      } else {
+        const error = await response.json();
+        toast.error(error.error);
+      }
+    } catch (e) {
+      toast.error('An unexpected error occurred.');
+    }
+  };
+
+  const handleUnlock = async userId => {
+    try {
+      const response = await fetch(`/api/admin/users/${userId}/unlock`, {
+        method: 'POST',
+      });
+      if (response.ok) {
+        await loadUsers();
+      } else {
+        const error = await response.json();
+        toast.error(error.error);
+      }
+    } catch (e) {
+      toast.error('An unexpected error occurred.');
+    }
+  };
+
+  const handleCreateResetLink = async (userId, username) => {
+    try {
+      const response = await fetch(`/api/admin/users/${userId}/password-reset`, {
+        method: 'POST',
+      });
+      if (response.ok) {
+        const result = await response.json();
+        const link = new URL(result.reset_url, window.location.origin).href;
+        window.prompt(`One-time reset link for "${username}" (valid for 24 hours):`, link);
+      } else {
+        const error = await response.json();
+        toast.error(error.error);
+      }
+    } catch (e) {
+      toast.error('An unexpected error occurred.');
+    }
+  };
+
+  const handleForceLogout = async (userId, username) => {
+    if (!confirm(`Log "${username}" out of all their sessions?`)) {
+      return;
+    }
+    try {
+      const response = await fetch(`/api/admin/users/${userId}/sessions`, {
+        method: 'DELETE',
+      });
+      if (response.ok) {
+        const result = await response.json();
+        toast.success(`Revoked ${result.revoked} session(s).`);
+        if (userId === currentUser.id) window.location.href = '/login';
+      } else {
+        const error = await response.json();
+        toast.error(error.error);
+      }
+    } catch (e) {
+      toast.error('An unexpected error occurred.');
+    }
+  };
+
+  const handleResetTwoFactor = async (userId, username) => {
+    if (!confirm(`Turn off two-factor authentication for "${username}"? They will be able to log in with their password alone.`)) {
+      return;
+    }
+    try {
+      const response = await fetch(`/api/admin/users/${userId}/2fa`, {
+        method: 'DELETE',
+      });
       if (response.ok) {
         await loadUsers();
       } else {
@@ -131,18 +536,96 @@ document.addEventListener('DOMContentLoaded', async () => {
   userForm.addEventListener('submit', handleFormSubmit);
 
   tableBody.addEventListener('click', e => {
+    const approveBtn = e.target.closest('.approve-btn');
+    if (approveBtn) {
+      handleApprove(Number(approveBtn.dataset.id));
+    }
+
     const editBtn = e.target.closest('.edit-btn');
     if (editBtn) {
       const user = allUsers.find(u => u.id == editBtn.dataset.id);
       if (user) openModal(user);
     }
 
+    const restrictionsBtn = e.target.closest('.restrictions-btn');
+    if (restrictionsBtn) {
+      const user = allUsers.find(u => u.id == restrictionsBtn.dataset.id);
+      if (user) openRestrictionsModal(user);
+    }
+
     const deleteBtn = e.target.closest('.delete-btn');
     if (deleteBtn) {
       const user = allUsers.find(u => u.id == deleteBtn.dataset.id);
       if (user) handleDelete(user.id, user.username);
     }
+
+    const unlockBtn = e.target.closest('.unlock-btn');
+    if (unlockBtn) {
+      handleUnlock(Number(unlockBtn.dataset.id));
+    }
+
+    const resetPasswordBtn = e.target.closest('.reset-password-btn');
+    if (resetPasswordBtn) {
+      const user = allUsers.find(u => u.id == resetPasswordBtn.dataset.id);
+      if (user) handleCreateResetLink(user.id, user.username);
+    }
+
+    const logoutBtn = e.target.closest('.logout-btn');
+    if (logoutBtn) {
+      const user = allUsers.find(u => u.id == logoutBtn.dataset.id);
+      if (user) handleForceLogout(user.id, user.username);
+    }
+
+    const resetBtn = e.target.closest('.reset-2fa-btn');
+    if (resetBtn) {
+      const user = allUsers.find(u => u.id == resetBtn.dataset.id);
+      if (user) handleResetTwoFactor(user.id, user.username);
+    }
+  });
+
+  document
+    .getElementById('restrictions-modal-cancel-btn')
+    .addEventListener('click', closeRestrictionsModal);
+  restrictionsModal.addEventListener('click', e => {
+    if (e.target === restrictionsModal) closeRestrictionsModal();
+  });
+  restrictionsForm.addEventListener('submit', handleRestrictionsFormSubmit);
+
+  document.getElementById('add-role-btn').addEventListener('click', () => openRoleModal());
+  document.getElementById('role-modal-cancel-btn').addEventListener('click', closeRoleModal);
+  roleModal.addEventListener('click', e => {
+    if (e.target === roleModal) closeRoleModal();
+  });
+  roleForm.addEventListener('submit', handleRoleFormSubmit);
+
+  rolesTableBody.addEventListener('click', e => {
+    const editBtn = e.target.closest('.edit-role-btn');
+    if (editBtn) {
+      const role = allRoles.find(r => r.name === editBtn.dataset.name);
+      if (role) openRoleModal(role);
+    }
+
+    const deleteBtn = e.target.closest('.delete-role-btn');
+    if (deleteBtn) {
+      handleDeleteRole(deleteBtn.dataset.name);
+    }
+  });
+
+  document.getElementById('add-invite-btn').addEventListener('click', openInviteModal);
+  document.getElementById('invite-modal-cancel-btn').addEventListener('click', closeInviteModal);
+  inviteModal.addEventListener('click', e => {
+    if (e.target === inviteModal) closeInviteModal();
+  });
+  inviteForm.addEventListener('submit', handleInviteFormSubmit);
+
+  invitesTableBody.addEventListener('click', e => {
+    const deleteBtn = e.target.closest('.delete-invite-btn');
+    if (deleteBtn) {
+      handleDeleteInvite(Number(deleteBtn.dataset.id));
+    }
   });
 
   loadUsers();
+  loadRoles();
+  loadInvites();
 });
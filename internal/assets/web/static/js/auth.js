@@ -1,12 +1,27 @@
+// Permissions that give access to the admin pages.
+const ADMIN_PERMISSIONS = ['run_jobs', 'manage_users', 'manage_plugins'];
+
+/**
+ * Reports whether the user's role grants any of the given permissions.
+ * @param {object} user - The user returned by /api/users/me.
+ * @param {string | string[]} permissions - A permission or a list of permissions.
+ * @returns {boolean}
+ */
+function hasPermission(user, permissions) {
+  const granted = (user && user.permissions) || [];
+  return [].concat(permissions).some(p => granted.includes(p));
+}
+
 /**
  * Checks the user's authentication status by calling the /api/users/me endpoint.
  * This function should be called on every page that requires authentication.
- * * @param {string | null} requiredRole - Optional. If provided (e.g., 'admin'), the function will
- * check if the user has the specified role and redirect if they do not.
+ * * @param {string | string[] | null} requiredPermission - Optional. If provided (e.g.,
+ * 'manage_users'), the function will check that the user has the permission (or any of a list)
+ * and redirect if they do not.
  * @returns {Promise<object | null>} A promise that resolves with the user object if authenticated
  * and authorized, or null otherwise.
  */
-async function checkAuth(requiredRole = null) {
+async function checkAuth(requiredPermission = null) {
   // Define public pages that don't require an auth check.
   const publicPages = ['/login'];
   const currentPath = window.location.pathname;
@@ -29,8 +44,8 @@ async function checkAuth(requiredRole = null) {
 
     const user = await response.json();
 
-    // If a specific role is required, check it.
-    if (requiredRole && user.role !== requiredRole) {
+    // If a specific permission is required, check it.
+    if (requiredPermission && !hasPermission(user, requiredPermission)) {
       if (window.toast) {
         toast.error('Access Denied: You do not have permission to view this page.');
       } else {
@@ -51,11 +66,16 @@ async function checkAuth(requiredRole = null) {
     // Show elements that are only for authenticated users
     document.querySelectorAll('.auth-only').forEach(el => (el.style.display = 'block'));
 
-    // Show elements that are only for admin users
-    if (user.role === 'admin') {
+    // Show elements that are only for users who can reach the admin pages
+    if (hasPermission(user, ADMIN_PERMISSIONS)) {
       document.querySelectorAll('.admin-only').forEach(el => (el.style.display = 'block'));
     }
 
+    // Hide elements that need a permission the user does not have
+    document.querySelectorAll('[data-permission]').forEach(el => {
+      if (!hasPermission(user, el.dataset.permission.split(','))) el.style.display = 'none';
+    });
+
     // Make the logout button functional
     const logoutBtn = document.getElementById('logout-btn');
     if (logoutBtn) {
@@ -74,4 +94,4 @@ async function checkAuth(requiredRole = null) {
   }
 }
 
-export { checkAuth };
+export { checkAuth, hasPermission, ADMIN_PERMISSIONS };
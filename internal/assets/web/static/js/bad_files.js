@@ -1,7 +1,7 @@
 import { checkAuth } from './auth.js';
 
 document.addEventListener('DOMContentLoaded', async () => {
-  const currentUser = await checkAuth('admin');
+  const currentUser = await checkAuth('run_jobs');
   if (!currentUser) return;
 
   // DOM elements
@@ -10,6 +10,126 @@ document.addEventListener('DOMContentLoaded', async () => {
   const loginForm = document.getElementById('login-form');
   const errorMessage = document.getElementById('error-message');
 
+  // Offer single sign-on when the server has an OIDC provider configured
+  fetch('/api/config')
+    .then(response => (response.ok ? response.json() : {}))
+    .then(config => {
+      if (config.oidc_enabled) {
+        document.getElementById('sso-login').style.display = 'block';
+      }
+      // With open registration an invite is optional, and accounts wait for approval
+      if (config.registration_open) {
+        document.getElementById('register-invite').required = false;
+        document.getElementById('register-invite-label').textContent = 'Invite code (optional)';
+      }
+    })
+    .catch(() => {});
+
+  // Failed SSO logins are redirected back here with a message
+  const ssoError = new URLSearchParams(window.location.search).get('sso_error');
+  if (ssoError) {
+    errorMessage.textContent = ssoError;
+    errorMessage.style.display = 'block';
+  }
+
+  // Accounts with two-factor authentication get a second step after the password
+  const twoFactorForm = document.getElementById('two-factor-form');
+  const twoFactorError = document.getElementById('two-factor-error');
+  let challenge = '';
+
+  const showTwoFactorForm = value => {
+    challenge = value;
+    loginForm.style.display = 'none';
+    twoFactorForm.style.display = 'block';
+    document.getElementById('two-factor-code').focus();
+  };
+
+  twoFactorForm.addEventListener('submit', async e => {
+    e.preventDefault();
+    twoFactorError.style.display = 'none';
+    const code = document.getElementById('two-factor-code').value.trim();
+
+    try {
+      const response = await fetch('/api/users/login/2fa', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ challenge, code }),
+      });
+      if (response.ok) {
+        window.location.href = '/';
+        return;
+      }
+      const errorData = await response.json();
+      twoFactorError.textContent = errorData.error || 'Invalid code.';
+      twoFactorError.style.display = 'block';
+    } catch (err) {
+      console.error('2FA request failed:', err);
+      twoFactorError.textContent = 'An error occurred. Please try again later.';
+      twoFactorError.style.display = 'block';
+    }
+  });
+
+  // Self-registration, opened directly by invite links (/login?invite=CODE)
+  const registerForm = document.getElementById('register-form');
+  const registerError = document.getElementById('register-error');
+  const registerPending = document.getElementById('register-pending');
+
+  const showRegisterForm = () => {
+    loginForm.style.display = 'none';
+    registerForm.style.display = 'block';
+    document.getElementById('register-username').focus();
+  };
+
+  document.getElementById('show-register').addEventListener('click', e => {
+    e.preventDefault();
+    showRegisterForm();
+  });
+  document.getElementById('show-login').addEventListener('click', e => {
+    e.preventDefault();
+    registerForm.style.display = 'none';
+    loginForm.style.display = 'block';
+  });
+
+  const inviteCode = new URLSearchParams(window.location.search).get('invite');
+  if (inviteCode) {
+    document.getElementById('register-invite').value = inviteCode;
+    showRegisterForm();
+  }
+
+  registerForm.addEventListener('submit', async e => {
+    e.preventDefault();
+    registerError.style.display = 'none';
+    registerPending.style.display = 'none';
+
+    const username = document.getElementById('register-username').value.trim();
+    const password = document.getElementById('register-password').value;
+    const invite_code = document.getElementById('register-invite').value.trim();
+
+    try {
+      const response = await fetch('/api/users/register', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ username, password, invite_code }),
+      });
+      const data = await response.json().catch(() => ({}));
+      if (!response.ok) {
+        registerError.textContent = data.error || 'Registration failed.';
+        registerError.style.display = 'block';
+        return;
+      }
+      if (data.status === 'pending') {
+        registerForm.reset();
+        registerPending.style.display = 'block';
+        return;
+      }
+      window.location.href = '/';
+    } catch (err) {
+      console.error('Registration request failed:', err);
+      registerError.textContent = 'An error occurred. Please try again later.';
+      registerError.style.display = 'block';
+    }
+  });
+
   loginForm.addEventListener('submit', async e => {
     e.preventDefault();
     errorMessage.style.display = 'none';
@@ -25,6 +145,11 @@ document.addEventListener('DOMContentLoaded', async () => {
       });
 
       if (response.ok) {
+        const data = await response.json().catch(() => ({}));
+        if (data.two_factor_required) {
+          showTwoFactorForm(data.challenge);
+          return;
+        }
         window.location.href = '/';
       } else {
         const errorData = await response.json();
@@ -0,0 +1,43 @@
+document.addEventListener('DOMContentLoaded', () => {
+  const resetForm = document.getElementById('reset-form');
+  const errorMessage = document.getElementById('error-message');
+  const token = new URLSearchParams(window.location.search).get('token') || '';
+
+  const showError = message => {
+    errorMessage.textContent = message;
+    errorMessage.style.display = 'block';
+  };
+
+  if (!token) {
+    showError('This reset link is missing its token. Ask an admin for a new link.');
+  }
+
+  resetForm.addEventListener('submit', async e => {
+    e.preventDefault();
+    errorMessage.style.display = 'none';
+
+    const newPassword = document.getElementById('new-password').value;
+    if (newPassword !== document.getElementById('confirm-password').value) {
+      showError('The passwords do not match.');
+      return;
+    }
+
+    try {
+      const response = await fetch('/api/users/reset-password', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ token, new_password: newPassword }),
+      });
+      if (response.ok) {
+        resetForm.style.display = 'none';
+        document.getElementById('success-message').style.display = 'block';
+        return;
+      }
+      const errorData = await response.json();
+      showError(errorData.error || 'Could not reset the password.');
+    } catch (err) {
+      console.error('Password reset request failed:', err);
+      showError('An error occurred. Please try again later.');
+    }
+  });
+});
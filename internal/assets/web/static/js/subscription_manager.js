@@ -1,7 +1,7 @@
 import { checkAuth } from './auth.js';
 
 document.addEventListener('DOMContentLoaded', async () => {
-  const currentUser = await checkAuth();
+  const currentUser = await checkAuth('manage_downloads');
   if (!currentUser) return;
 
   const providerSelect = document.getElementById('provider-select');
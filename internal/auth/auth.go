@@ -1,6 +1,11 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/md5"
+	"encoding/hex"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 // HashPassword generates a bcrypt hash of the password.
 // The cost parameter (14) is a good balance between security and performance.
@@ -15,3 +20,17 @@ func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// KOSyncKey returns the key KOReader derives from a password for its sync protocol
+// (the hex MD5 digest), which is what it sends in the x-auth-key header.
+func KOSyncKey(password string) string {
+	sum := md5.Sum([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashKOSyncKey generates the bcrypt hash stored for a user's KOReader sync key.
+// It uses the default cost because it is computed alongside the password hash on login.
+func HashKOSyncKey(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(KOSyncKey(password)), bcrypt.DefaultCost)
+	return string(bytes), err
+}
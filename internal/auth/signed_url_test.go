@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestURLSigner(t *testing.T) {
+	signer := NewURLSigner([]byte("0123456789abcdef0123456789abcdef"))
+	now := time.Now()
+
+	signed := signer.Sign("/api/proxy/resource", url.Values{"url": {"https://example.com/a.jpg"}}, now.Add(time.Hour))
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("Signed URL does not parse: %v", err)
+	}
+	if err := signer.Verify(u.Path, u.Query(), now); err != nil {
+		t.Fatalf("Expected a valid signature, got %v", err)
+	}
+
+	// Changing any parameter or the path breaks the signature
+	tampered := u.Query()
+	tampered.Set("url", "https://example.com/b.jpg")
+	if err := signer.Verify(u.Path, tampered, now); !errors.Is(err, ErrURLSignatureInvalid) {
+		t.Errorf("Expected a tampered URL to be rejected, got %v", err)
+	}
+	added := u.Query()
+	added.Set("referer", "https://example.com/")
+	if err := signer.Verify(u.Path, added, now); !errors.Is(err, ErrURLSignatureInvalid) {
+		t.Errorf("Expected an added parameter to be rejected, got %v", err)
+	}
+	if err := signer.Verify("/api/other", u.Query(), now); !errors.Is(err, ErrURLSignatureInvalid) {
+		t.Errorf("Expected a different path to be rejected, got %v", err)
+	}
+	if err := signer.Verify(u.Path, url.Values{"url": {"https://example.com/a.jpg"}}, now); !errors.Is(err, ErrURLSignatureInvalid) {
+		t.Errorf("Expected an unsigned URL to be rejected, got %v", err)
+	}
+
+	// Another key does not accept it
+	other := NewURLSigner([]byte("another key of thirty-two bytes!"))
+	if err := other.Verify(u.Path, u.Query(), now); !errors.Is(err, ErrURLSignatureInvalid) {
+		t.Errorf("Expected a different key to reject the URL, got %v", err)
+	}
+
+	if err := signer.Verify(u.Path, u.Query(), now.Add(2*time.Hour)); !errors.Is(err, ErrURLExpired) {
+		t.Errorf("Expected an expired URL to be rejected, got %v", err)
+	}
+}
+
+func TestResourceProxyURL(t *testing.T) {
+	signer := NewURLSigner([]byte("0123456789abcdef0123456789abcdef"))
+
+	proxyURL, err := signer.ResourceProxyURL(ResourceProxyRequest{
+		URL:     "https://example.com/a.jpg",
+		Referer: "https://example.com/",
+		Headers: map[string]string{"X-Test": "1"},
+	}, 0)
+	if err != nil {
+		t.Fatalf("ResourceProxyURL failed: %v", err)
+	}
+	if !strings.HasPrefix(proxyURL, ResourceProxyPath+"?") {
+		t.Fatalf("Expected a resource proxy URL, got %s", proxyURL)
+	}
+	u, _ := url.Parse(proxyURL)
+	q := u.Query()
+	if q.Get("url") != "https://example.com/a.jpg" || q.Get("referer") != "https://example.com/" || q.Get("headers") != `{"X-Test":"1"}` {
+		t.Errorf("Unexpected parameters %v", q)
+	}
+	if err := signer.Verify(u.Path, q, time.Now().Add(DefaultResourceProxyTTL-time.Minute)); err != nil {
+		t.Errorf("Expected the URL to be valid for the default TTL, got %v", err)
+	}
+
+	if _, err := signer.ResourceProxyURL(ResourceProxyRequest{}, time.Hour); err == nil {
+		t.Error("Expected an error without a resource URL")
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B test vectors (SHA-1), truncated to 6 digits.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range vectors {
+		got, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("At %d: expected %s, got %s", unix, want, got)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret failed: %v", err)
+	}
+	now := time.Now()
+	code, _ := TOTPCode(secret, TOTPStep(now)-1)
+
+	step, ok := ValidateTOTP(secret, code, now)
+	if !ok || step != TOTPStep(now)-1 {
+		t.Errorf("Expected the previous period's code to be accepted, got step %d ok=%v", step, ok)
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(3*time.Minute)); ok {
+		t.Error("Expected an old code to be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, "abc", now); ok {
+		t.Error("Expected a malformed code to be rejected")
+	}
+}
+
+func TestTOTPAuthURI(t *testing.T) {
+	uri := TOTPAuthURI("mango-go", "alice", "ABCDEF")
+	if !strings.HasPrefix(uri, "otpauth://totp/mango-go:alice?") || !strings.Contains(uri, "secret=ABCDEF") {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil || len(codes) != 10 {
+		t.Fatalf("Expected 10 codes, got %v (%v)", codes, err)
+	}
+	if len(codes[0]) != 11 || codes[0][5] != '-' {
+		t.Errorf("Unexpected code format %q", codes[0])
+	}
+	if HashRecoveryCode(codes[0]) != HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))) {
+		t.Error("Expected recovery codes to ignore case and separators")
+	}
+}
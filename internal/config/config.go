@@ -3,7 +3,9 @@ package config
 
 import (
 	// use Viper for loading the config.yml file.
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -13,7 +15,11 @@ import (
 type Config struct {
 	Port         int `mapstructure:"port"`
 	ScanInterval int `mapstructure:"scan_interval"`
-	Database     struct {
+	// TrustedProxies lists the reverse proxies (CIDRs or single IPs) whose X-Forwarded-For and
+	// X-Real-IP headers name the client. Requests from anywhere else are attributed to their TCP
+	// peer, whatever headers they carry.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	Database       struct {
 		Path string `mapstructure:"path"`
 	} `mapstructure:"database"`
 	Library struct {
@@ -23,6 +29,186 @@ type Config struct {
 		Path          string `mapstructure:"path"`
 		UnloadTimeout int    `mapstructure:"unload_timeout"` // Minutes of inactivity before unloading
 	} `mapstructure:"plugins"`
+	Thumbnails   ThumbnailConfig    `mapstructure:"thumbnails"`
+	PageCache    PageCacheConfig    `mapstructure:"page_cache"`
+	Prefetch     PrefetchConfig     `mapstructure:"prefetch"`
+	Sessions     SessionConfig      `mapstructure:"sessions"`
+	Login        LoginConfig        `mapstructure:"login"`
+	OIDC         OIDCConfig         `mapstructure:"oidc"`
+	ProxyAuth    ProxyAuthConfig    `mapstructure:"proxy_auth"`
+	Outbound     OutboundConfig     `mapstructure:"outbound"`
+	Audit        AuditConfig        `mapstructure:"audit"`
+	Registration RegistrationConfig `mapstructure:"registration"`
+}
+
+// ThumbnailConfig controls where generated cover thumbnails are stored.
+type ThumbnailConfig struct {
+	// Path is the thumbnail directory. When empty, a "thumbnails" directory next to the
+	// database is used.
+	Path string `mapstructure:"path"`
+}
+
+// ThumbnailDir returns the directory thumbnail files are stored in.
+func (c *Config) ThumbnailDir() string {
+	if c.Thumbnails.Path != "" {
+		return c.Thumbnails.Path
+	}
+	return filepath.Join(filepath.Dir(c.Database.Path), "thumbnails")
+}
+
+// PageCacheConfig controls the disk cache of pages resized or re-encoded for the reader.
+type PageCacheConfig struct {
+	// Path is the cache directory. When empty, a "page-cache" directory next to the database
+	// is used.
+	Path string `mapstructure:"path"`
+	// MaxSizeMB bounds the cache; the least recently used pages are deleted beyond it. 0
+	// disables the cache, so every resized page is re-encoded.
+	MaxSizeMB int `mapstructure:"max_size_mb"`
+}
+
+// PageCacheDir returns the directory resized pages are cached in.
+func (c *Config) PageCacheDir() string {
+	if c.PageCache.Path != "" {
+		return c.PageCache.Path
+	}
+	return filepath.Join(filepath.Dir(c.Database.Path), "page-cache")
+}
+
+// PrefetchConfig controls reading ahead: when a page is requested, the pages after it are read
+// from the chapter file into a memory cache, so they are ready when the reader turns the page.
+type PrefetchConfig struct {
+	// Pages is how many pages after the requested one are read ahead. 0 disables prefetching.
+	Pages int `mapstructure:"pages"`
+	// NextChapterPages is how many pages of the next chapter are read ahead once the reader is
+	// within Pages of a chapter's end.
+	NextChapterPages int `mapstructure:"next_chapter_pages"`
+	// CacheSizeMB bounds the memory used by prefetched pages.
+	CacheSizeMB int `mapstructure:"cache_size_mb"`
+	// PerUser is how many prefetches may run at once for one user; further page requests are
+	// not read ahead until one finishes.
+	PerUser int `mapstructure:"per_user"`
+}
+
+// SessionConfig controls how long browser logins last.
+type SessionConfig struct {
+	// LifetimeHours is how long a session stays valid. With Sliding set, it is measured from
+	// the last request rather than from login, so active users stay logged in.
+	LifetimeHours int  `mapstructure:"lifetime_hours"`
+	Sliding       bool `mapstructure:"sliding"`
+	// PurgeInterval is how often (in minutes) expired sessions are deleted. 0 disables the purge.
+	PurgeInterval int `mapstructure:"purge_interval"`
+}
+
+// DefaultSessionLifetime is used when no positive lifetime is configured.
+const DefaultSessionLifetime = 7 * 24 * time.Hour
+
+// Lifetime returns the session lifetime as a duration.
+func (c SessionConfig) Lifetime() time.Duration {
+	if c.LifetimeHours <= 0 {
+		return DefaultSessionLifetime
+	}
+	return time.Duration(c.LifetimeHours) * time.Hour
+}
+
+// LoginConfig limits password guessing. A zero value disables the corresponding limit.
+type LoginConfig struct {
+	// MaxFailures is how many consecutive failed logins lock an account.
+	MaxFailures    int `mapstructure:"max_failures"`
+	LockoutMinutes int `mapstructure:"lockout_minutes"`
+	// IPMaxFailures is how many failed logins from one IP address within WindowMinutes block
+	// further attempts from it until the window has passed.
+	IPMaxFailures int `mapstructure:"ip_max_failures"`
+	WindowMinutes int `mapstructure:"window_minutes"`
+	// BackoffSeconds is the wait after the first failure for an account or IP. It doubles with
+	// each further failure, up to MaxBackoffSeconds.
+	BackoffSeconds    int `mapstructure:"backoff_seconds"`
+	MaxBackoffSeconds int `mapstructure:"max_backoff_seconds"`
+	// RetentionDays is how long failed attempts are kept for review.
+	RetentionDays int `mapstructure:"retention_days"`
+}
+
+// AuditConfig controls the audit log of administrative and destructive actions.
+type AuditConfig struct {
+	// RetentionDays is how long entries are kept. 0 keeps them forever.
+	RetentionDays int `mapstructure:"retention_days"`
+}
+
+// RegistrationConfig controls self-registration on the login page. Registering with an invite code
+// always works; Open also lets people register without one, as pending accounts that an admin
+// has to approve before they can log in.
+type RegistrationConfig struct {
+	Open bool `mapstructure:"open"`
+}
+
+// OIDCConfig configures single sign-on through an OpenID Connect provider.
+// OIDC login is enabled when Issuer and ClientID are set.
+type OIDCConfig struct {
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	Scopes       []string `mapstructure:"scopes"`
+	// RedirectURL is the callback URL registered with the provider. If empty it is derived
+	// from the request, which only works when the Host header reaching mango-go is the public one;
+	// X-Forwarded-Proto is honoured only from TrustedProxies.
+	RedirectURL   string `mapstructure:"redirect_url"`
+	UsernameClaim string `mapstructure:"username_claim"`
+	GroupsClaim   string `mapstructure:"groups_claim"`
+	// AdminGroup, if set, makes members of this group admins and everyone else a regular user.
+	// The role is re-applied on every login, so the provider stays the source of truth.
+	AdminGroup string `mapstructure:"admin_group"`
+}
+
+// Enabled reports whether OIDC login is configured.
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != "" && c.ClientID != ""
+}
+
+// ProxyAuthConfig configures authentication by a trusted reverse proxy (forward auth, e.g.
+// Authelia or Authentik) that puts the logged-in username in a request header.
+type ProxyAuthConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	UserHeader   string `mapstructure:"user_header"`
+	GroupsHeader string `mapstructure:"groups_header"` // Comma-separated group names
+	// TrustedProxies lists the CIDRs (or single IPs) the headers are accepted from. Requests from
+	// anywhere else are authenticated normally, whatever headers they carry.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// AdminGroup, if set, makes members of this group admins and everyone else a regular user.
+	AdminGroup string `mapstructure:"admin_group"`
+}
+
+// OutboundConfig restricts the HTTP requests made by plugins, the downloader and the resource
+// proxy. Private, loopback and link-local addresses are always blocked unless allowed here.
+type OutboundConfig struct {
+	// Allow lists hosts ("nas.lan", "*.lan") or CIDRs that may be fetched even though they
+	// resolve to blocked addresses, e.g. a source on the local network.
+	Allow []string `mapstructure:"allow"`
+	// Deny lists hosts or CIDRs that are never fetched. It takes precedence over Allow.
+	Deny []string `mapstructure:"deny"`
+	// MaxResponseMB caps the size of a single response body.
+	MaxResponseMB int `mapstructure:"max_response_mb"`
+	MaxRedirects  int `mapstructure:"max_redirects"`
+}
+
+// Defaults used when the outbound limits are not positive.
+const (
+	DefaultMaxResponseMB = 50
+	DefaultMaxRedirects  = 10
+)
+
+// MaxResponseBytes returns the response size cap in bytes.
+func (c OutboundConfig) MaxResponseBytes() int64 {
+	if c.MaxResponseMB <= 0 {
+		return DefaultMaxResponseMB << 20
+	}
+	return int64(c.MaxResponseMB) << 20
+}
+
+// RedirectLimit returns how many redirects a request may follow.
+func (c OutboundConfig) RedirectLimit() int {
+	if c.MaxRedirects <= 0 {
+		return DefaultMaxRedirects
+	}
+	return c.MaxRedirects
 }
 
 // Load reads configuration from a file named "config.yml" in the
@@ -42,10 +228,47 @@ func Load() (*Config, error) {
 	// Set default values
 	viper.SetDefault("port", 8080)
 	viper.SetDefault("scan_interval", 0)
+	viper.SetDefault("trusted_proxies", []string{})
 	viper.SetDefault("database.path", "./mango.db")
 	viper.SetDefault("library.path", "./manga")
+	viper.SetDefault("thumbnails.path", "")
+	viper.SetDefault("page_cache.path", "")
+	viper.SetDefault("page_cache.max_size_mb", 512)
+	viper.SetDefault("prefetch.pages", 3)
+	viper.SetDefault("prefetch.next_chapter_pages", 2)
+	viper.SetDefault("prefetch.cache_size_mb", 64)
+	viper.SetDefault("prefetch.per_user", 2)
 	viper.SetDefault("plugins.path", "../mango-go-plugins")
 	viper.SetDefault("plugins.unload_timeout", 30)
+	viper.SetDefault("sessions.lifetime_hours", 7*24)
+	viper.SetDefault("sessions.sliding", true)
+	viper.SetDefault("sessions.purge_interval", 60)
+	viper.SetDefault("login.max_failures", 10)
+	viper.SetDefault("login.lockout_minutes", 15)
+	viper.SetDefault("login.ip_max_failures", 30)
+	viper.SetDefault("login.window_minutes", 15)
+	viper.SetDefault("login.backoff_seconds", 1)
+	viper.SetDefault("login.max_backoff_seconds", 30)
+	viper.SetDefault("login.retention_days", 30)
+	viper.SetDefault("oidc.issuer", "")
+	viper.SetDefault("oidc.client_id", "")
+	viper.SetDefault("oidc.client_secret", "")
+	viper.SetDefault("oidc.scopes", []string{"openid", "profile", "email", "groups"})
+	viper.SetDefault("oidc.redirect_url", "")
+	viper.SetDefault("oidc.username_claim", "preferred_username")
+	viper.SetDefault("oidc.groups_claim", "groups")
+	viper.SetDefault("oidc.admin_group", "")
+	viper.SetDefault("proxy_auth.enabled", false)
+	viper.SetDefault("proxy_auth.user_header", "Remote-User")
+	viper.SetDefault("proxy_auth.groups_header", "Remote-Groups")
+	viper.SetDefault("proxy_auth.trusted_proxies", []string{})
+	viper.SetDefault("proxy_auth.admin_group", "")
+	viper.SetDefault("outbound.allow", []string{})
+	viper.SetDefault("outbound.deny", []string{})
+	viper.SetDefault("outbound.max_response_mb", DefaultMaxResponseMB)
+	viper.SetDefault("outbound.max_redirects", DefaultMaxRedirects)
+	viper.SetDefault("audit.retention_days", 365)
+	viper.SetDefault("registration.open", false)
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
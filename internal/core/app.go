@@ -11,6 +11,7 @@ import (
 	"github.com/vrsandeep/mango-go/internal/db"
 	"github.com/vrsandeep/mango-go/internal/jobs"
 	"github.com/vrsandeep/mango-go/internal/library"
+	"github.com/vrsandeep/mango-go/internal/outbound"
 	"github.com/vrsandeep/mango-go/internal/websocket"
 )
 
@@ -26,17 +27,32 @@ type App struct {
 	WebFS        embed.FS
 	MigrationsFS embed.FS
 	jobManager   *jobs.JobManager
+	outbound     *outbound.Policy
 }
 
 func (a *App) DB() *sql.DB                               { return a.dB }
 func (a *App) Config() *config.Config                    { return a.config }
 func (a *App) WsHub() *websocket.Hub                     { return a.wsHub }
 func (a *App) JobManager() *jobs.JobManager              { return a.jobManager }
-func (a *App) SetConfig(cfg *config.Config)              { a.config = cfg }
 func (a *App) SetDB(db *sql.DB)                          { a.dB = db }
 func (a *App) SetWsHub(hub *websocket.Hub)               { a.wsHub = hub }
 func (a *App) SetJobManager(jobManager *jobs.JobManager) { a.jobManager = jobManager }
 
+// SetConfig sets the configuration and rebuilds the outbound HTTP policy from it.
+func (a *App) SetConfig(cfg *config.Config) {
+	a.config = cfg
+	a.outbound = outbound.NewPolicy(cfg.Outbound)
+}
+
+// Outbound returns the policy for HTTP requests made on behalf of plugins, the downloader and
+// the resource proxy. It is built from the configuration's outbound section.
+func (a *App) Outbound() *outbound.Policy {
+	if a.outbound == nil {
+		return outbound.Default()
+	}
+	return a.outbound
+}
+
 // New sets up and returns a new App instance. It handles loading the
 // configuration, initializing the database connection, and running migrations.
 func New() (*App, error) {
@@ -67,16 +83,17 @@ func New() (*App, error) {
 	go hub.Run()
 
 	app := &App{
-		config:  cfg,
 		dB:      database,
 		wsHub:   hub,
 		Version: Version,
 	}
+	app.SetConfig(cfg)
 
 	jobManager := jobs.NewManager(app)
 	app.jobManager = jobManager
 	app.jobManager.Register("library-sync", "Library Sync", library.LibrarySync)
 	app.jobManager.Register("regen-thumbnails", "Regenerate Thumbnails", library.RegenerateThumbnails)
+	app.jobManager.Register("migrate-thumbnails", "Migrate Thumbnails to Files", library.MigrateThumbnails)
 	app.jobManager.Register("delete-empty-tags", "Delete Empty Tags", library.DeleteEmptyTags)
 	app.jobManager.Register("detect-bad-files", "Detect Bad Chapter Files", library.DetectBadFiles)
 	return app, nil
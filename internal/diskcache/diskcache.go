@@ -0,0 +1,216 @@
+// Package diskcache stores byte blobs as files in a directory, deleting the least recently used
+// ones once the directory grows past a size limit.
+package diskcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache is a size-bounded cache of files. It is safe for concurrent use. The index of cached
+// files is rebuilt from the directory when the cache is opened, so it survives restarts.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List // of *entry, most recently used first
+	entries map[string]*list.Element
+}
+
+type entry struct {
+	name string
+	size int64
+}
+
+// New opens the cache in dir, creating the directory if needed, and trims it to maxBytes.
+func New(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	c := &Cache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+
+	// Files are touched when read, so their modification time orders them by last use.
+	type file struct {
+		entry
+		modTime time.Time
+	}
+	var files []file
+	shards, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Only the cache's own files are looked at: names from fileName inside the shard directory
+	// named after their first two characters. Anything else in dir is left alone.
+	for _, shard := range shards {
+		if !shard.IsDir() || len(shard.Name()) != 2 || !isHex(shard.Name()) {
+			continue
+		}
+		shardDir := filepath.Join(dir, shard.Name())
+		names, err := os.ReadDir(shardDir)
+		if err != nil {
+			continue
+		}
+		for _, d := range names {
+			name := d.Name()
+			if !d.Type().IsRegular() {
+				continue
+			}
+			if strings.HasPrefix(name, ".tmp-") {
+				os.Remove(filepath.Join(shardDir, name)) // left over from an interrupted write
+				continue
+			}
+			if len(name) != sha256.Size*2 || !isHex(name) || name[:2] != shard.Name() {
+				continue
+			}
+			info, err := d.Info()
+			if err != nil {
+				continue
+			}
+			files = append(files, file{entry{name, info.Size()}, info.ModTime()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	for _, f := range files {
+		c.entries[f.name] = c.lru.PushBack(&entry{f.name, f.size})
+		c.size += f.size
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict()
+	return c, nil
+}
+
+// Get returns the data cached under key.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	name := fileName(key)
+	c.mu.Lock()
+	elem, ok := c.entries[name]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := c.path(name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.remove(name)
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true
+}
+
+// Put stores data under key, deleting the least recently used files if the cache grows past
+// its limit. Data larger than the whole cache is not stored.
+func (c *Cache) Put(key string, data []byte) error {
+	size := int64(len(data))
+	if size > c.maxBytes {
+		return nil
+	}
+	name := fileName(key)
+	path := c.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[name]; ok {
+		c.size -= elem.Value.(*entry).size
+		c.lru.Remove(elem)
+	}
+	c.entries[name] = c.lru.PushFront(&entry{name, size})
+	c.size += size
+	c.evict()
+	return nil
+}
+
+// Size returns the total size of the cached files in bytes.
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// evict deletes the least recently used files until the cache fits its limit. c.mu must be held.
+func (c *Cache) evict() {
+	for c.size > c.maxBytes {
+		elem := c.lru.Back()
+		if elem == nil {
+			return
+		}
+		e := elem.Value.(*entry)
+		if err := os.Remove(c.path(e.name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+		c.lru.Remove(elem)
+		delete(c.entries, e.name)
+		c.size -= e.size
+	}
+}
+
+func (c *Cache) remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[name]; ok {
+		c.size -= elem.Value.(*entry).size
+		c.lru.Remove(elem)
+		delete(c.entries, name)
+	}
+}
+
+func (c *Cache) path(name string) string {
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+// isHex reports whether s consists of lowercase hex digits, as fileName produces.
+func isHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// fileName maps a key to the name of the file it is stored in.
+func fileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
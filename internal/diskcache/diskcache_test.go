@@ -0,0 +1,121 @@
+package diskcache_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/diskcache"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := diskcache.New(dir, 100)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	block := func(b byte) []byte { return bytes.Repeat([]byte{b}, 40) }
+
+	t.Run("Get and Put", func(t *testing.T) {
+		if _, ok := cache.Get("a"); ok {
+			t.Error("Expected a miss on an empty cache")
+		}
+		if err := cache.Put("a", block('a')); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+		data, ok := cache.Get("a")
+		if !ok || !bytes.Equal(data, block('a')) {
+			t.Errorf("Expected the stored data, got %q %v", data, ok)
+		}
+	})
+
+	t.Run("Evicts the least recently used", func(t *testing.T) {
+		cache.Put("b", block('b'))
+		cache.Get("a") // a is now more recently used than b
+		cache.Put("c", block('c'))
+
+		if _, ok := cache.Get("b"); ok {
+			t.Error("Expected b to be evicted")
+		}
+		for _, key := range []string{"a", "c"} {
+			if _, ok := cache.Get(key); !ok {
+				t.Errorf("Expected %s to be kept", key)
+			}
+		}
+		if cache.Size() != 80 {
+			t.Errorf("Expected 80 bytes cached, got %d", cache.Size())
+		}
+	})
+
+	t.Run("Skips data larger than the cache", func(t *testing.T) {
+		cache.Put("huge", bytes.Repeat([]byte{'h'}, 101))
+		if _, ok := cache.Get("huge"); ok {
+			t.Error("Expected oversized data not to be cached")
+		}
+	})
+
+	t.Run("Survives reopening", func(t *testing.T) {
+		reopened, err := diskcache.New(dir, 100)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		if data, ok := reopened.Get("c"); !ok || !bytes.Equal(data, block('c')) {
+			t.Error("Expected cached data to be found after reopening")
+		}
+		if reopened.Size() != 80 {
+			t.Errorf("Expected 80 bytes cached, got %d", reopened.Size())
+		}
+
+		smaller, err := diskcache.New(dir, 50)
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		if smaller.Size() > 50 {
+			t.Errorf("Expected the cache to be trimmed to its new limit, got %d bytes", smaller.Size())
+		}
+	})
+}
+
+func TestCacheLeavesOtherFilesAlone(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := diskcache.New(dir, 100)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	cache.Put("a", []byte("cached"))
+
+	shard, _ := filepath.Glob(filepath.Join(dir, "??"))
+	if len(shard) != 1 {
+		t.Fatalf("Expected one shard directory, got %v", shard)
+	}
+	leftover := filepath.Join(shard[0], ".tmp-123")
+	foreign := []string{
+		filepath.Join(dir, "notes.txt"),
+		filepath.Join(dir, ".tmp-top-level"),
+		filepath.Join(dir, "other", "data.bin"),
+		filepath.Join(shard[0], "README"),
+	}
+	for _, path := range append(foreign, leftover) {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte("keep"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := diskcache.New(dir, 100)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Error("Expected the interrupted write to be deleted")
+	}
+	for _, path := range foreign {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be left alone: %v", path, err)
+		}
+	}
+	if reopened.Size() != int64(len("cached")) {
+		t.Errorf("Expected only the cached file to be counted, got %d bytes", reopened.Size())
+	}
+}
@@ -0,0 +1,76 @@
+package downloader
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+)
+
+// comicInfo is the ComicInfo.xml (schema v2.0) document written into downloaded CBZs.
+// Empty fields are omitted so readers fall back to their own defaults.
+type comicInfo struct {
+	XMLName         xml.Name `xml:"ComicInfo"`
+	XMLNSXSI        string   `xml:"xmlns:xsi,attr"`
+	XMLNSXSD        string   `xml:"xmlns:xsd,attr"`
+	Title           string   `xml:"Title,omitempty"`
+	Series          string   `xml:"Series,omitempty"`
+	LocalizedSeries string   `xml:"LocalizedSeries,omitempty"`
+	Number          string   `xml:"Number,omitempty"`
+	Volume          *int     `xml:"Volume,omitempty"`
+	Year            int      `xml:"Year,omitempty"`
+	Month           int      `xml:"Month,omitempty"`
+	Day             int      `xml:"Day,omitempty"`
+	Translator      string   `xml:"Translator,omitempty"`
+	Web             string   `xml:"Web,omitempty"`
+	LanguageISO     string   `xml:"LanguageISO,omitempty"`
+	Manga           string   `xml:"Manga,omitempty"`
+	PageCount       int      `xml:"PageCount,omitempty"`
+	Notes           string   `xml:"Notes,omitempty"`
+}
+
+// BuildComicInfo renders the ComicInfo.xml for a downloaded chapter from the queue item's
+// provider metadata. If the series folder at seriesDir is already in the library and has
+// cached AniList data, the AniList title and URL are included too.
+func BuildComicInfo(st *store.Store, job *models.DownloadQueueItem, seriesDir string, pageCount int) ([]byte, error) {
+	info := comicInfo{
+		XMLNSXSI:    "http://www.w3.org/2001/XMLSchema-instance",
+		XMLNSXSD:    "http://www.w3.org/2001/XMLSchema",
+		Title:       job.ChapterTitle,
+		Series:      job.SeriesTitle,
+		Number:      strings.TrimSpace(job.ChapterNumber),
+		Translator:  job.GroupName,
+		LanguageISO: job.Language,
+		Manga:       "Yes",
+		PageCount:   pageCount,
+		Notes:       fmt.Sprintf("Downloaded by mango-go from %s (%s)", job.ProviderID, job.ChapterIdentifier),
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(job.Volume)); err == nil && v >= 0 {
+		info.Volume = &v
+	}
+	if job.PublishedAt != nil && !job.PublishedAt.IsZero() {
+		info.Year, info.Month, info.Day = job.PublishedAt.Year(), int(job.PublishedAt.Month()), job.PublishedAt.Day()
+	}
+
+	if folder, err := st.GetFolderByPath(seriesDir); err == nil {
+		if cache, err := st.GetFolderAnilist(folder.ID); err == nil {
+			info.Web = cache.SiteURL
+			title := cache.TitleEnglish
+			if title == "" {
+				title = cache.TitleRomaji
+			}
+			if title != job.SeriesTitle {
+				info.LocalizedSeries = title
+			}
+		}
+	}
+
+	data, err := xml.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
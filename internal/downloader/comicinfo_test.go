@@ -0,0 +1,109 @@
+package downloader_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/downloader"
+	"github.com/vrsandeep/mango-go/internal/library/chapterfiles"
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestBuildComicInfo(t *testing.T) {
+	app := testutil.SetupTestApp(t)
+	st := store.New(app.DB())
+
+	published := time.Date(2024, time.March, 9, 12, 0, 0, 0, time.UTC)
+	err := st.AddChaptersToQueue("Test Manga", "mockadex", []models.ChapterResult{{
+		Identifier:  "ch-10.5",
+		Title:       "Chapter 10.5: Extra",
+		Volume:      "3",
+		Chapter:     "10.5",
+		Language:    "en",
+		GroupID:     "a1b2c3",
+		GroupName:   "Some Scans",
+		PublishedAt: published,
+	}})
+	if err != nil {
+		t.Fatalf("Failed to queue chapter: %v", err)
+	}
+	items, err := st.GetQueuedDownloadItems(1)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("Failed to get queued item: %v", err)
+	}
+	job := items[0]
+	if job.ChapterNumber != "10.5" || job.Volume != "3" || job.GroupName != "Some Scans" || job.PublishedAt == nil {
+		t.Fatalf("Queue item did not keep chapter metadata: %+v", job)
+	}
+
+	seriesDir := filepath.Join(app.Config().Library.Path, "Test Manga")
+	folder, err := st.CreateFolder(seriesDir, "Test Manga", nil)
+	if err != nil {
+		t.Fatalf("Failed to create folder: %v", err)
+	}
+	if err := st.SetFolderAnilist(folder.ID, 42, "https://anilist.co/manga/42", "", "Tesuto Manga", "Test Manga: Localized"); err != nil {
+		t.Fatalf("Failed to cache AniList data: %v", err)
+	}
+
+	data, err := downloader.BuildComicInfo(st, job, seriesDir, 20)
+	if err != nil {
+		t.Fatalf("BuildComicInfo failed: %v", err)
+	}
+
+	// The document must round-trip through the library's own ComicInfo parser
+	info, err := chapterfiles.ParseComicInfo(data)
+	if err != nil {
+		t.Fatalf("Generated ComicInfo.xml does not parse: %v\n%s", err, data)
+	}
+	if info.Series != "Test Manga" || info.Number != "10.5" || info.Title != "Chapter 10.5: Extra" {
+		t.Errorf("Unexpected series/number/title: %+v", info)
+	}
+	if info.Volume == nil || *info.Volume != 3 {
+		t.Errorf("Expected volume 3, got %v", info.Volume)
+	}
+	if info.LanguageISO != "en" || info.PageCount != 20 {
+		t.Errorf("Unexpected language/page count: %+v", info)
+	}
+
+	doc := string(data)
+	for _, want := range []string{
+		"<Translator>Some Scans</Translator>",
+		"<Year>2024</Year>", "<Month>3</Month>", "<Day>9</Day>",
+		"<LocalizedSeries>Test Manga: Localized</LocalizedSeries>",
+		"<Web>https://anilist.co/manga/42</Web>",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("Expected %s in ComicInfo.xml:\n%s", want, doc)
+		}
+	}
+
+	if strings.Contains(doc, "a1b2c3") {
+		t.Errorf("Expected the group ID to be left out of ComicInfo.xml:\n%s", doc)
+	}
+
+	// Volume 0 is a real volume
+	job.Volume = "0"
+	data, err = downloader.BuildComicInfo(st, job, seriesDir, 20)
+	if err != nil {
+		t.Fatalf("BuildComicInfo failed: %v", err)
+	}
+	if !strings.Contains(string(data), "<Volume>0</Volume>") {
+		t.Errorf("Expected volume 0 in ComicInfo.xml:\n%s", data)
+	}
+
+	// Without library or provider metadata the optional elements are left out
+	bare := &models.DownloadQueueItem{SeriesTitle: "Other", ChapterTitle: "Oneshot", ProviderID: "mockadex"}
+	data, err = downloader.BuildComicInfo(st, bare, filepath.Join(app.Config().Library.Path, "Other"), 5)
+	if err != nil {
+		t.Fatalf("BuildComicInfo failed: %v", err)
+	}
+	for _, unwanted := range []string{"<Volume>", "<Number>", "<Web>", "<Year>"} {
+		if strings.Contains(string(data), unwanted) {
+			t.Errorf("Did not expect %s in ComicInfo.xml:\n%s", unwanted, data)
+		}
+	}
+}
@@ -45,6 +45,7 @@ func (p *MockadexProvider) GetChapters(seriesIdentifier string) ([]models.Chapte
 			Pages:       20 + i,
 			Language:    "en",
 			GroupID:     "mock-group",
+			GroupName:   "Mock Scans",
 			PublishedAt: time.Now().AddDate(0, 0, -i),
 		})
 	}
@@ -4,7 +4,6 @@ import (
 	"archive/zip"
 	"bytes"
 	"fmt"
-	"io"
 	"log"
 	"math"
 	"net/http"
@@ -150,10 +149,8 @@ func processDownload(app *core.App, st *store.Store, job *models.DownloadQueueIt
 			return fmt.Errorf("failed to create request for page %d: %w", i+1, err)
 		}
 
-		// Use HTTP client with timeout
-		client := &http.Client{
-			Timeout: 30 * time.Second,
-		}
+		// Use the outbound HTTP policy, which blocks internal addresses and caps response sizes
+		client := app.Outbound().Client(30 * time.Second)
 
 		resp, err := client.Do(req)
 		if err != nil {
@@ -166,7 +163,7 @@ func processDownload(app *core.App, st *store.Store, job *models.DownloadQueueIt
 			return fmt.Errorf("failed to download page %d: server returned status %d", i+1, resp.StatusCode)
 		}
 
-		pageData, err := io.ReadAll(resp.Body)
+		pageData, err := app.Outbound().ReadBody(resp.Body)
 		if err != nil {
 			return fmt.Errorf("failed to read page %d data: %w", i+1, err)
 		}
@@ -237,13 +234,26 @@ func processDownload(app *core.App, st *store.Store, job *models.DownloadQueueIt
 		sendDownloaderProgressUpdate(app, job.ID, fmt.Sprintf("Downloaded page %d of %d", i+1, total), status, float64(progress), done, nil, nil)
 	}
 
+	cbzPath := getDownloadPath(app, st, job)
+
+	// Embed the chapter metadata so it survives moving the file to other readers
+	comicInfoData, err := BuildComicInfo(st, job, filepath.Dir(cbzPath), total)
+	if err != nil {
+		return fmt.Errorf("failed to build ComicInfo.xml: %w", err)
+	}
+	f, err := zipWriter.Create("ComicInfo.xml")
+	if err != nil {
+		return fmt.Errorf("failed to create file in zip: %w", err)
+	}
+	if _, err := f.Write(comicInfoData); err != nil {
+		return fmt.Errorf("failed to write file to zip: %w", err)
+	}
+
 	if err := zipWriter.Close(); err != nil {
 		return fmt.Errorf("failed to finalize zip archive: %w", err)
 	}
 
 	// Save the CBZ file
-	cbzPath := getDownloadPath(app, st, job)
-
 	if err := os.MkdirAll(filepath.Dir(cbzPath), os.ModePerm); err != nil {
 		return fmt.Errorf("failed to create series directory: %w", err)
 	}
@@ -371,7 +381,7 @@ func RetryQueueItem(app *core.App, st *store.Store, itemID int64) error {
 }
 
 func sendDownloaderProgressUpdate(app *core.App, itemID int64, message string, status string, progress float64, done bool, localChapterID, localFolderID *int64) {
-	app.WsHub().BroadcastJSON(models.ProgressUpdate{
+	app.WsHub().BroadcastJSONTo(models.PermManageDownloads, models.ProgressUpdate{
 		JobID:          "downloader",
 		Message:        message,
 		Progress:       progress,
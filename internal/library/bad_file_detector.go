@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io/fs"
 	"log"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -32,7 +31,8 @@ func DetectBadFiles(ctx jobs.JobContext) {
 		if err != nil {
 			return err
 		}
-		if !d.IsDir() && chapterfiles.IsSupportedChapterFile(d.Name()) {
+		if d.IsDir() && path != rootPath && chapterfiles.IsImageDirectory(path) ||
+			!d.IsDir() && chapterfiles.IsSupportedChapterFile(d.Name()) {
 			chapterFilePaths = append(chapterFilePaths, path)
 		}
 		return nil
@@ -59,7 +59,7 @@ func DetectBadFiles(ctx jobs.JobContext) {
 		sendProgress(ctx, jobId, fmt.Sprintf("Checking file %d/%d: %s", i+1, totalFiles, filepath.Base(filePath)), progress, false)
 
 		// Check if file is accessible
-		fileInfo, err := os.Stat(filePath)
+		_, fileSize, err := chapterfiles.StatChapter(filePath)
 		if err != nil {
 			// File doesn't exist or can't be accessed
 			log.Printf("File %s is not accessible: %v", filePath, err)
@@ -71,7 +71,7 @@ func DetectBadFiles(ctx jobs.JobContext) {
 		if parseErr != nil {
 			// File is corrupted or invalid
 			errorMsg := categorizeError(parseErr)
-			err := badFileStore.CreateBadFile(filePath, errorMsg, fileInfo.Size())
+			err := badFileStore.CreateBadFile(filePath, errorMsg, fileSize)
 			if err != nil {
 				log.Printf("Failed to record bad file %s: %v", filePath, err)
 			} else {
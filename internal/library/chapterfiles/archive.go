@@ -41,14 +41,78 @@ func (h archiveHandler) Inspect(ctx context.Context, filePath string) ([]*models
 }
 
 func (h archiveHandler) Page(ctx context.Context, filePath string, pageIndex int) ([]byte, string, error) {
+	chapter, err := h.Open(ctx, filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer chapter.Close()
+	return chapter.Page(ctx, pageIndex)
+}
+
+// Open opens the archive and lists its pages in order.
+func (h archiveHandler) Open(ctx context.Context, filePath string) (OpenChapter, error) {
+	switch h.archiveType(filePath) {
+	case "zip":
+		return h.openCBZ(filePath)
+	case "rar":
+		return h.openCBR(ctx, filePath)
+	default:
+		return nil, fmt.Errorf("unsupported chapter file type: %s", filepath.Ext(filePath))
+	}
+}
+
+// ComicInfo reads the archive's ComicInfo.xml, preferring one at the archive root.
+func (h archiveHandler) ComicInfo(ctx context.Context, filePath string) (*models.ComicInfo, error) {
+	var data []byte
 	switch h.archiveType(filePath) {
 	case "zip":
-		return h.getPageFromCBZ(filePath, pageIndex)
+		r, err := zip.OpenReader(filePath)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		var found *zip.File
+		for _, f := range r.Reader.File {
+			if !f.FileInfo().IsDir() && isComicInfoFile(f.Name) && (found == nil || !strings.Contains(f.Name, "/")) {
+				found = f
+			}
+		}
+		if found == nil {
+			return nil, nil
+		}
+		if data, err = readZipFile(found); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", found.Name, err)
+		}
 	case "rar":
-		return h.getPageFromCBR(ctx, filePath, pageIndex)
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		fsys, err := archives.FileSystem(ctx, filePath, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file system: %w", err)
+		}
+		var found string
+		err = fs.WalkDir(fsys, ".", func(fpath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && isComicInfoFile(fpath) && (found == "" || !strings.Contains(fpath, "/")) {
+				found = fpath
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk file system: %w", err)
+		}
+		if found == "" {
+			return nil, nil
+		}
+		if data, err = fs.ReadFile(fsys, found); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", found, err)
+		}
 	default:
-		return nil, "", fmt.Errorf("unsupported chapter file type: %s", filepath.Ext(filePath))
+		return nil, fmt.Errorf("unsupported chapter file type: %s", filepath.Ext(filePath))
 	}
+	return ParseComicInfo(data)
 }
 
 func (archiveHandler) archiveType(filePath string) string {
@@ -223,85 +287,136 @@ func (archiveHandler) parseCBR(ctx context.Context, path string) ([]*models.Page
 	return pages, firstPageData, nil
 }
 
-// getPageFromCBZ extracts a specific page from a .cbz (zip) file.
-func (archiveHandler) getPageFromCBZ(filePath string, pageIndex int) ([]byte, string, error) {
+// openCBZ opens a .cbz (zip) file and sorts its image entries into page order.
+func (archiveHandler) openCBZ(filePath string) (OpenChapter, error) {
 	r, err := zip.OpenReader(filePath)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
-	defer r.Close()
 
 	imageFiles := findImageFilesInZip(r)
 	if len(imageFiles) == 0 {
-		return nil, "", fmt.Errorf("no image files found in chapter file")
+		r.Close()
+		return nil, fmt.Errorf("no image files found in chapter file")
 	}
 
 	// Sort files alphabetically to ensure correct order
 	sort.Slice(imageFiles, func(i, j int) bool {
 		return pageSortFunc(imageFiles[i].Name, imageFiles[j].Name)
 	})
-
-	if pageIndex < 0 || pageIndex >= len(imageFiles) {
-		return nil, "", fmt.Errorf("page index %d out of bounds (0-%d)", pageIndex, len(imageFiles)-1)
-	}
-
-	imageFile := imageFiles[pageIndex]
-	rc, err := imageFile.Open()
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to open image file: %w", err)
-	}
-	defer rc.Close()
-
-	data, err := io.ReadAll(rc)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to read image file: %w", err)
-	}
-
-	return data, imageFile.Name, nil
+	return &zipChapter{r: r, pages: imageFiles}, nil
 }
 
-// getPageFromCBR extracts a specific page from a .cbr (rar) file.
-func (archiveHandler) getPageFromCBR(ctx context.Context, filePath string, pageIndex int) ([]byte, string, error) {
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	fsys, err := archives.FileSystem(ctx, filePath, nil)
+// openCBR opens a .cbr (rar) or 7z file and sorts its image files into page order. Archives are
+// read through their file system, which extracts files on demand without keeping the archive open.
+func (archiveHandler) openCBR(ctx context.Context, filePath string) (OpenChapter, error) {
+	// The file system keeps its context for later reads, so it lives until the chapter is closed
+	// rather than until this request ends. Each read is bounded by archiveReadTimeout instead.
+	fsCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	fsys, err := archives.FileSystem(fsCtx, filePath, nil)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to open file system: %w", err)
+		cancel()
+		return nil, fmt.Errorf("failed to open file system: %w", err)
 	}
 
-	imageFiles, err := findImageFilesInFS(fsys)
+	var imageFiles []string
+	if waitErr := boundedRead(ctx, func() { imageFiles, err = findImageFilesInFS(fsys) }); waitErr != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to list archive: %w", waitErr)
+	}
 	if err != nil {
-		return nil, "", err
+		cancel()
+		return nil, err
 	}
 
 	if len(imageFiles) == 0 {
-		return nil, "", fmt.Errorf("no image files found in chapter file")
+		cancel()
+		return nil, fmt.Errorf("no image files found in chapter file")
 	}
 
 	// Sort files alphabetically to ensure correct order
 	sort.Slice(imageFiles, func(i, j int) bool {
 		return pageSortFunc(imageFiles[i], imageFiles[j])
 	})
+	return &fsChapter{fsys: fsys, pages: imageFiles, cancel: cancel}, nil
+}
 
-	if pageIndex < 0 || pageIndex >= len(imageFiles) {
-		return nil, "", fmt.Errorf("page index %d out of bounds (0-%d)", pageIndex, len(imageFiles)-1)
+// archiveReadTimeout bounds a single read from an opened RAR or 7z archive, so a corrupt archive
+// cannot hang a request.
+const archiveReadTimeout = 30 * time.Second
+
+// boundedRead runs read in the background and waits for it until ctx is done or
+// archiveReadTimeout passes. A read that is given up on keeps running until its archive is
+// closed, which cancels it; its results must not be used.
+func boundedRead(ctx context.Context, read func()) error {
+	ctx, cancel := context.WithTimeout(ctx, archiveReadTimeout)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		read()
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	// Open the specific image file
-	f, err := fsys.Open(imageFiles[pageIndex])
+// zipChapter is an open zip-based chapter (CBZ or EPUB) with its page entries in reading order.
+type zipChapter struct {
+	r     *zip.ReadCloser
+	pages []*zip.File
+}
+
+func (c *zipChapter) NumPages() int { return len(c.pages) }
+
+func (c *zipChapter) Page(ctx context.Context, pageIndex int) ([]byte, string, error) {
+	if pageIndex < 0 || pageIndex >= len(c.pages) {
+		return nil, "", fmt.Errorf("page index %d out of bounds (0-%d)", pageIndex, len(c.pages)-1)
+	}
+	data, err := readZipFile(c.pages[pageIndex])
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to open image file: %w", err)
+		return nil, "", fmt.Errorf("failed to read image file: %w", err)
 	}
-	defer f.Close()
+	return data, c.pages[pageIndex].Name, nil
+}
+
+func (c *zipChapter) Close() error { return c.r.Close() }
 
-	// Read all bytes from the image file
-	data, err := io.ReadAll(f)
+// fsChapter is an open archive read through a file system, with its page paths in order.
+type fsChapter struct {
+	fsys   fs.FS
+	pages  []string
+	cancel context.CancelFunc // cancels the file system's context, stopping reads in progress
+}
+
+func (c *fsChapter) NumPages() int { return len(c.pages) }
+
+func (c *fsChapter) Page(ctx context.Context, pageIndex int) ([]byte, string, error) {
+	if pageIndex < 0 || pageIndex >= len(c.pages) {
+		return nil, "", fmt.Errorf("page index %d out of bounds (0-%d)", pageIndex, len(c.pages)-1)
+	}
+	var data []byte
+	var err error
+	if waitErr := boundedRead(ctx, func() { data, err = fs.ReadFile(c.fsys, c.pages[pageIndex]) }); waitErr != nil {
+		return nil, "", fmt.Errorf("failed to read image file: %w", waitErr)
+	}
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to read image file: %w", err)
 	}
+	return data, c.pages[pageIndex], nil
+}
 
-	return data, imageFiles[pageIndex], nil
+func (c *fsChapter) Close() error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if closer, ok := c.fsys.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
 }
 
 // pageSortFunc sorts pages by filename.
@@ -0,0 +1,68 @@
+package chapterfiles
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+// ComicInfoFileName is the conventional name of the metadata file embedded in comic archives.
+const ComicInfoFileName = "ComicInfo.xml"
+
+// ComicInfoReader is implemented by handlers whose chapter kind can carry a ComicInfo.xml.
+type ComicInfoReader interface {
+	// ComicInfo returns the parsed ComicInfo.xml, or nil (and no error) if the chapter has none.
+	ComicInfo(ctx context.Context, path string) (*models.ComicInfo, error)
+}
+
+// comicInfoXML mirrors the subset of the ComicInfo schema (v2.0) that we store.
+type comicInfoXML struct {
+	XMLName     xml.Name `xml:"ComicInfo"`
+	Series      string   `xml:"Series"`
+	Number      string   `xml:"Number"`
+	Volume      string   `xml:"Volume"`
+	Title       string   `xml:"Title"`
+	Writer      string   `xml:"Writer"`
+	Genre       string   `xml:"Genre"`
+	LanguageISO string   `xml:"LanguageISO"`
+	Manga       string   `xml:"Manga"`
+	PageCount   string   `xml:"PageCount"`
+}
+
+// ParseComicInfo decodes a ComicInfo.xml document.
+func ParseComicInfo(data []byte) (*models.ComicInfo, error) {
+	var raw comicInfoXML
+	if err := xml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", ComicInfoFileName, err)
+	}
+	info := &models.ComicInfo{
+		Series:      strings.TrimSpace(raw.Series),
+		Number:      strings.TrimSpace(raw.Number),
+		Title:       strings.TrimSpace(raw.Title),
+		Writer:      strings.TrimSpace(raw.Writer),
+		Genre:       strings.TrimSpace(raw.Genre),
+		LanguageISO: strings.TrimSpace(raw.LanguageISO),
+		Manga:       strings.TrimSpace(raw.Manga),
+	}
+	// The schema uses -1 as the "unknown" default for Volume.
+	if v, err := strconv.Atoi(strings.TrimSpace(raw.Volume)); err == nil && v >= 0 {
+		info.Volume = &v
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(raw.PageCount)); err == nil && n > 0 {
+		info.PageCount = n
+	}
+	return info, nil
+}
+
+// isComicInfoFile reports whether an archive entry or file name is a ComicInfo.xml (any case).
+func isComicInfoFile(name string) bool {
+	base := name
+	if i := strings.LastIndexAny(base, `/\`); i >= 0 {
+		base = base[i+1:]
+	}
+	return strings.EqualFold(base, ComicInfoFileName)
+}
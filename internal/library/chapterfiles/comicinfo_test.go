@@ -0,0 +1,86 @@
+package chapterfiles
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testComicInfo = `<?xml version="1.0" encoding="utf-8"?>
+<ComicInfo xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <Series>Test Series</Series>
+  <Number>10.5</Number>
+  <Volume>3</Volume>
+  <Title>An Extra</Title>
+  <Writer>Someone</Writer>
+  <Genre>Action, Comedy</Genre>
+  <LanguageISO>en</LanguageISO>
+  <Manga>YesAndRightToLeft</Manga>
+  <PageCount>2</PageCount>
+</ComicInfo>`
+
+func TestParseComicInfo(t *testing.T) {
+	info, err := ParseComicInfo([]byte(testComicInfo))
+	require.NoError(t, err)
+	require.Equal(t, "Test Series", info.Series)
+	require.Equal(t, "10.5", info.Number)
+	require.NotNil(t, info.Volume)
+	require.Equal(t, 3, *info.Volume)
+	require.Equal(t, "An Extra", info.Title)
+	require.Equal(t, "Someone", info.Writer)
+	require.Equal(t, "Action, Comedy", info.Genre)
+	require.Equal(t, "en", info.LanguageISO)
+	require.True(t, info.RightToLeft())
+	require.Equal(t, 2, info.PageCount)
+
+	// Schema defaults (-1 volume, 0 pages) mean "unknown"
+	info, err = ParseComicInfo([]byte(`<ComicInfo><Number>1</Number><Volume>-1</Volume><PageCount>0</PageCount></ComicInfo>`))
+	require.NoError(t, err)
+	require.Nil(t, info.Volume)
+	require.Zero(t, info.PageCount)
+	require.False(t, info.RightToLeft())
+
+	_, err = ParseComicInfo([]byte(`<NotComicInfo/>`))
+	require.Error(t, err)
+}
+
+func TestReadComicInfo_Archive(t *testing.T) {
+	cbzPath := createTestZip(t, "with-info.cbz", map[string]string{
+		"01.jpg":               "first",
+		"extras/ComicInfo.xml": `<ComicInfo><Series>Nested</Series></ComicInfo>`,
+		"ComicInfo.xml":        testComicInfo,
+	})
+
+	info, err := ReadComicInfo(context.Background(), cbzPath)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	require.Equal(t, "Test Series", info.Series)
+
+	// ComicInfo.xml is not a page
+	pages, _, err := InspectChapterFile(context.Background(), cbzPath)
+	require.NoError(t, err)
+	require.Len(t, pages, 1)
+
+	cbzPath = createTestZip(t, "without-info.cbz", map[string]string{"01.jpg": "first"})
+
+	info, err = ReadComicInfo(context.Background(), cbzPath)
+	require.NoError(t, err)
+	require.Nil(t, info)
+}
+
+func TestReadComicInfo_ImageDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "001.jpg"), []byte("first"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ComicInfo.xml"), []byte(testComicInfo), 0644))
+
+	// The metadata file does not stop the folder being a chapter
+	require.True(t, IsImageDirectory(dir))
+
+	info, err := ReadComicInfo(context.Background(), dir)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	require.Equal(t, "10.5", info.Number)
+}
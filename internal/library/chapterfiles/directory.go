@@ -0,0 +1,158 @@
+package chapterfiles
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+// imageDirHandler serves a plain directory of image files as a chapter.
+// Directories are matched by path (see IsImageDirectory), never by basename.
+type imageDirHandler struct{}
+
+func (imageDirHandler) SupportsBaseName(string) bool {
+	return false
+}
+
+func (imageDirHandler) Inspect(ctx context.Context, dirPath string) ([]*models.Page, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	pages, err := listImageDirPages(dirPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	firstPageData, err := os.ReadFile(filepath.Join(dirPath, pages[0].FileName))
+	if err != nil {
+		return pages, nil, fmt.Errorf("failed to read first page for thumbnail: %w", err)
+	}
+	return pages, firstPageData, nil
+}
+
+func (imageDirHandler) Page(ctx context.Context, dirPath string, pageIndex int) ([]byte, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	pages, err := listImageDirPages(dirPath)
+	if err != nil {
+		return nil, "", err
+	}
+	if pageIndex < 0 || pageIndex >= len(pages) {
+		return nil, "", fmt.Errorf("page index %d out of bounds (0-%d)", pageIndex, len(pages)-1)
+	}
+	name := pages[pageIndex].FileName
+	data, err := os.ReadFile(filepath.Join(dirPath, name))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image file: %w", err)
+	}
+	return data, name, nil
+}
+
+// ComicInfo reads a ComicInfo.xml placed alongside the images.
+func (imageDirHandler) ComicInfo(ctx context.Context, dirPath string) (*models.ComicInfo, error) {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open directory: %w", err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() && isComicInfoFile(e.Name()) {
+			data, err := os.ReadFile(filepath.Join(dirPath, e.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", e.Name(), err)
+			}
+			return ParseComicInfo(data)
+		}
+	}
+	return nil, nil
+}
+
+// listImageDirPages returns the images directly inside dirPath, sorted like archive pages.
+func listImageDirPages(dirPath string) ([]*models.Page, error) {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open directory: %w", err)
+	}
+	var filenames []string
+	for _, e := range entries {
+		if !e.IsDir() && hasImageExtension(e.Name()) && !isIgnorableDirEntry(e.Name()) {
+			filenames = append(filenames, e.Name())
+		}
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("no image files found in chapter directory %s", dirPath)
+	}
+	return createAndSortPages(filenames), nil
+}
+
+// isIgnorableDirEntry reports whether a directory entry is metadata (ComicInfo.xml) or OS clutter
+// (hidden files, Thumbs.db, desktop.ini) that should not stop a folder of images being a chapter.
+func isIgnorableDirEntry(name string) bool {
+	if strings.HasPrefix(name, ".") || isComicInfoFile(name) {
+		return true
+	}
+	switch strings.ToLower(name) {
+	case "thumbs.db", "desktop.ini":
+		return true
+	default:
+		return false
+	}
+}
+
+// IsImageDirectory reports whether dirPath is a leaf directory that contains only image files
+// (ignoring hidden files and OS clutter) and at least one image. Such directories are chapters.
+func IsImageDirectory(dirPath string) bool {
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		return false
+	}
+	images := 0
+	for _, e := range entries {
+		if isIgnorableDirEntry(e.Name()) {
+			continue
+		}
+		if e.IsDir() || !hasImageExtension(e.Name()) {
+			return false
+		}
+		images++
+	}
+	return images > 0
+}
+
+// StatChapter returns the modification time and size used to detect changes to a chapter.
+// For files this is the file's own mtime and size; for image directories it is the latest
+// mtime of the directory or any of its images, and the total size of the images.
+func StatChapter(path string) (time.Time, int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if !info.IsDir() {
+		return info.ModTime(), info.Size(), nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	mtime := info.ModTime()
+	var size int64
+	for _, e := range entries {
+		if e.IsDir() || !hasImageExtension(e.Name()) || isIgnorableDirEntry(e.Name()) {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return time.Time{}, 0, err
+		}
+		if fi.ModTime().After(mtime) {
+			mtime = fi.ModTime()
+		}
+		size += fi.Size()
+	}
+	return mtime, size, nil
+}
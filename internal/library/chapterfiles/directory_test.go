@@ -0,0 +1,72 @@
+package chapterfiles
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+}
+
+func TestIsImageDirectory(t *testing.T) {
+	images := t.TempDir()
+	writeTestFiles(t, images, map[string]string{"01.jpg": "a", "02.png": "b", ".DS_Store": "x", "Thumbs.db": "x"})
+	require.True(t, IsImageDirectory(images))
+	require.True(t, IsSupportedChapterPath(images))
+
+	mixed := t.TempDir()
+	writeTestFiles(t, mixed, map[string]string{"01.jpg": "a", "notes.txt": "b"})
+	require.False(t, IsImageDirectory(mixed))
+
+	nested := t.TempDir()
+	writeTestFiles(t, nested, map[string]string{"01.jpg": "a"})
+	require.NoError(t, os.Mkdir(filepath.Join(nested, "extras"), 0o755))
+	require.False(t, IsImageDirectory(nested))
+
+	require.False(t, IsImageDirectory(t.TempDir()), "empty directory")
+	require.False(t, IsImageDirectory(filepath.Join(images, "01.jpg")), "file")
+	require.False(t, IsSupportedChapterPath(mixed))
+}
+
+func TestImageDirHandler_InspectAndPage(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"page10.jpg": "ten", "page2.jpg": "two", "page1.png": "one"})
+
+	pages, first, err := InspectChapterFile(context.Background(), dir)
+	require.NoError(t, err)
+	require.Len(t, pages, 3)
+	require.Equal(t, "page1.png", pages[0].FileName)
+	require.Equal(t, "page2.jpg", pages[1].FileName)
+	require.Equal(t, "page10.jpg", pages[2].FileName)
+	require.Equal(t, []byte("one"), first)
+
+	data, name, err := GetChapterPage(context.Background(), dir, 2)
+	require.NoError(t, err)
+	require.Equal(t, "page10.jpg", name)
+	require.Equal(t, []byte("ten"), data)
+
+	_, _, err = GetChapterPage(context.Background(), dir, 3)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "out of bounds")
+}
+
+func TestStatChapter_Directory(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"01.jpg": "abc", "02.jpg": "defgh", ".hidden": "ignored"})
+
+	_, size, err := StatChapter(dir)
+	require.NoError(t, err)
+	require.Equal(t, int64(8), size)
+
+	_, size, err = StatChapter(filepath.Join(dir, "02.jpg"))
+	require.NoError(t, err)
+	require.Equal(t, int64(5), size)
+}
@@ -0,0 +1,259 @@
+package chapterfiles
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+// epubContainerPath is where every EPUB declares the location of its OPF package document.
+const epubContainerPath = "META-INF/container.xml"
+
+type epubHandler struct{}
+
+func (epubHandler) SupportsBaseName(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".epub")
+}
+
+func (epubHandler) Inspect(ctx context.Context, filePath string) ([]*models.Page, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+
+	entries, err := epubPageEntries(&r.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Spine order is the reading order, so pages are not re-sorted by name.
+	pages := make([]*models.Page, len(entries))
+	for i, entry := range entries {
+		pages[i] = &models.Page{FileName: entry.Name, Index: i}
+	}
+
+	firstPageData, err := readZipFile(entries[0])
+	if err != nil {
+		return pages, nil, fmt.Errorf("failed to read first page for thumbnail: %w", err)
+	}
+	return pages, firstPageData, nil
+}
+
+func (h epubHandler) Page(ctx context.Context, filePath string, pageIndex int) ([]byte, string, error) {
+	chapter, err := h.Open(ctx, filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer chapter.Close()
+	return chapter.Page(ctx, pageIndex)
+}
+
+// Open opens the EPUB and resolves its spine into page entries.
+func (epubHandler) Open(ctx context.Context, filePath string) (OpenChapter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := epubPageEntries(&r.Reader)
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	return &zipChapter{r: r, pages: entries}, nil
+}
+
+// epubContainer mirrors the parts of META-INF/container.xml we need.
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// epubPackage mirrors the manifest and spine of an OPF package document.
+type epubPackage struct {
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// epubPageEntries walks the OPF spine and returns the image entry for every page in reading order.
+// Spine items that are images are used directly; XHTML items contribute the first image they wrap
+// (an <img> or an SVG <image>), and text-only items are skipped.
+func epubPageEntries(r *zip.Reader) ([]*zip.File, error) {
+	files := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+
+	opfPath, err := epubRootFile(files)
+	if err != nil {
+		return nil, err
+	}
+	opfFile, ok := files[opfPath]
+	if !ok {
+		return nil, fmt.Errorf("epub package document %s not found", opfPath)
+	}
+	opfData, err := readZipFile(opfFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read epub package document: %w", err)
+	}
+	var pkg epubPackage
+	if err := xml.Unmarshal(opfData, &pkg); err != nil {
+		return nil, fmt.Errorf("invalid epub package document: %w", err)
+	}
+
+	type manifestItem struct {
+		href      string
+		mediaType string
+	}
+	manifest := make(map[string]manifestItem, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		manifest[item.ID] = manifestItem{href: resolveEPUBHref(opfPath, item.Href), mediaType: item.MediaType}
+	}
+
+	var entries []*zip.File
+	seen := make(map[string]bool)
+	for _, ref := range pkg.Spine {
+		item, ok := manifest[ref.IDRef]
+		if !ok {
+			continue
+		}
+		imagePath := item.href
+		if !strings.HasPrefix(item.mediaType, "image/") && !hasImageExtension(item.href) {
+			f, ok := files[item.href]
+			if !ok {
+				continue
+			}
+			imagePath, err = firstImageInXHTML(f)
+			if err != nil {
+				return nil, err
+			}
+			if imagePath == "" {
+				continue
+			}
+		}
+		f, ok := files[imagePath]
+		if !ok || seen[imagePath] || !hasImageExtension(imagePath) {
+			continue
+		}
+		seen[imagePath] = true
+		entries = append(entries, f)
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no image files found in chapter file")
+	}
+	return entries, nil
+}
+
+// epubRootFile reads META-INF/container.xml and returns the OPF path inside the archive.
+func epubRootFile(files map[string]*zip.File) (string, error) {
+	f, ok := files[epubContainerPath]
+	if !ok {
+		return "", fmt.Errorf("not a valid epub: missing %s", epubContainerPath)
+	}
+	data, err := readZipFile(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to read epub container: %w", err)
+	}
+	var container epubContainer
+	if err := xml.Unmarshal(data, &container); err != nil {
+		return "", fmt.Errorf("invalid epub container: %w", err)
+	}
+	for _, rf := range container.Rootfiles {
+		if rf.MediaType == "" || rf.MediaType == "application/oebps-package+xml" {
+			return path.Clean(rf.FullPath), nil
+		}
+	}
+	return "", fmt.Errorf("not a valid epub: no package document in %s", epubContainerPath)
+}
+
+// firstImageInXHTML returns the archive path of the first image referenced by an XHTML page,
+// or "" if the page has none.
+func firstImageInXHTML(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open epub page %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	// Pages in the wild are frequently sloppy HTML rather than XHTML, so parse leniently.
+	dec := xml.NewDecoder(rc)
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid epub page %s: %w", f.Name, err)
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var attrName string
+		switch strings.ToLower(el.Name.Local) {
+		case "img":
+			attrName = "src"
+		case "image":
+			// SVG <image> uses xlink:href (or plain href in SVG 2).
+			attrName = "href"
+		default:
+			continue
+		}
+		for _, attr := range el.Attr {
+			if strings.EqualFold(attr.Name.Local, attrName) && attr.Value != "" {
+				return resolveEPUBHref(f.Name, attr.Value), nil
+			}
+		}
+	}
+}
+
+// resolveEPUBHref resolves a (URL-encoded, possibly fragment-bearing) href relative to the document
+// that references it, returning a slash-separated archive path.
+func resolveEPUBHref(basePath, href string) string {
+	if i := strings.IndexAny(href, "#?"); i >= 0 {
+		href = href[:i]
+	}
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	if strings.HasPrefix(href, "/") {
+		return path.Clean(strings.TrimPrefix(href, "/"))
+	}
+	return path.Join(path.Dir(basePath), href)
+}
+
+// readZipFile reads a whole entry from a zip archive.
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,126 @@
+package chapterfiles
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testEPUBContainer = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>`
+
+// The spine deliberately lists pages out of filename order and mixes page kinds:
+// an <img> wrapper, an SVG wrapper, a bare image item, a text-only page and an unknown idref.
+const testEPUBPackage = `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <manifest>
+    <item id="p1" href="Text/page_b.xhtml" media-type="application/xhtml+xml"/>
+    <item id="p2" href="Text/page_a.xhtml" media-type="application/xhtml+xml"/>
+    <item id="p3" href="Images/z%20last.png" media-type="image/png"/>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml"/>
+    <item id="img1" href="Images/p01.jpg" media-type="image/jpeg"/>
+    <item id="img2" href="Images/p02.png" media-type="image/png"/>
+  </manifest>
+  <spine>
+    <itemref idref="p1"/>
+    <itemref idref="nav"/>
+    <itemref idref="missing"/>
+    <itemref idref="p2"/>
+    <itemref idref="p3"/>
+  </spine>
+</package>`
+
+const testEPUBImgPage = `<html xmlns="http://www.w3.org/1999/xhtml"><body><div><img src="../Images/p01.jpg" alt=""/></div></body></html>`
+
+const testEPUBSVGPage = `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:xlink="http://www.w3.org/1999/xlink">
+<body><svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><image xlink:href="../Images/p02.png" width="10" height="10"/></svg></body>
+</html>`
+
+const testEPUBNavPage = `<html><body><nav><ol><li><a href="Text/page_b.xhtml">Start</a></li></ol></nav></body></html>`
+
+func createTestEPUB(t *testing.T, files map[string]string) string {
+	t.Helper()
+	return createTestZip(t, "volume.epub", files)
+}
+
+// createTestZip writes files into a zip archive with the given name in a temporary directory.
+func createTestZip(t testing.TB, name string, files map[string]string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(p)
+	require.NoError(t, err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return p
+}
+
+func TestEPUBHandler_InspectAndPage(t *testing.T) {
+	epubPath := createTestEPUB(t, map[string]string{
+		"mimetype":                  "application/epub+zip",
+		epubContainerPath:           testEPUBContainer,
+		"OEBPS/content.opf":         testEPUBPackage,
+		"OEBPS/Text/page_b.xhtml":   testEPUBImgPage,
+		"OEBPS/Text/page_a.xhtml":   testEPUBSVGPage,
+		"OEBPS/nav.xhtml":           testEPUBNavPage,
+		"OEBPS/Images/p01.jpg":      "first",
+		"OEBPS/Images/p02.png":      "second",
+		"OEBPS/Images/z last.png":   "third",
+		"OEBPS/Images/unlisted.png": "not in spine",
+	})
+
+	pages, first, err := InspectChapterFile(context.Background(), epubPath)
+	require.NoError(t, err)
+	require.Len(t, pages, 3)
+	require.Equal(t, "OEBPS/Images/p01.jpg", pages[0].FileName)
+	require.Equal(t, "OEBPS/Images/p02.png", pages[1].FileName)
+	require.Equal(t, "OEBPS/Images/z last.png", pages[2].FileName)
+	for i, p := range pages {
+		require.Equal(t, i, p.Index)
+	}
+	require.Equal(t, []byte("first"), first)
+
+	data, name, err := GetChapterPage(context.Background(), epubPath, 1)
+	require.NoError(t, err)
+	require.Equal(t, "OEBPS/Images/p02.png", name)
+	require.Equal(t, []byte("second"), data)
+
+	_, _, err = GetChapterPage(context.Background(), epubPath, len(pages))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "out of bounds")
+}
+
+func TestEPUBHandler_TextOnly(t *testing.T) {
+	epubPath := createTestEPUB(t, map[string]string{
+		epubContainerPath:   testEPUBContainer,
+		"OEBPS/content.opf": `<package><manifest><item id="nav" href="nav.xhtml" media-type="application/xhtml+xml"/></manifest><spine><itemref idref="nav"/></spine></package>`,
+		"OEBPS/nav.xhtml":   testEPUBNavPage,
+	})
+
+	_, _, err := InspectChapterFile(context.Background(), epubPath)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no image files found")
+}
+
+func TestEPUBHandler_MissingContainer(t *testing.T) {
+	epubPath := createTestEPUB(t, map[string]string{"OEBPS/Images/p01.jpg": "first"})
+
+	_, _, err := InspectChapterFile(context.Background(), epubPath)
+	require.Error(t, err)
+}
@@ -49,24 +49,49 @@ func (pdfHandler) Inspect(ctx context.Context, path string) ([]*models.Page, []b
 	return pages, first, nil
 }
 
-func (pdfHandler) Page(ctx context.Context, path string, pageIndex int) ([]byte, string, error) {
-	if err := ctx.Err(); err != nil {
+func (h pdfHandler) Page(ctx context.Context, path string, pageIndex int) ([]byte, string, error) {
+	chapter, err := h.Open(ctx, path)
+	if err != nil {
 		return nil, "", err
 	}
+	defer chapter.Close()
+	return chapter.Page(ctx, pageIndex)
+}
+
+// Open parses the PDF so its pages can be rasterized one by one.
+func (pdfHandler) Open(ctx context.Context, path string) (OpenChapter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	doc, err := fitz.New(path)
 	if err != nil {
-		return nil, "", fmt.Errorf("pdf page: %w", err)
+		return nil, fmt.Errorf("pdf page: %w", err)
 	}
-	defer doc.Close()
+	return pdfChapter{doc}, nil
+}
 
-	n := doc.NumPage()
+// pdfChapter is an open PDF. fitz documents lock around every call, so pages can be rendered
+// concurrently.
+type pdfChapter struct {
+	doc *fitz.Document
+}
+
+func (c pdfChapter) NumPages() int { return c.doc.NumPage() }
+
+func (c pdfChapter) Page(ctx context.Context, pageIndex int) ([]byte, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	n := c.doc.NumPage()
 	if pageIndex < 0 || pageIndex >= n {
 		return nil, "", fmt.Errorf("page index %d out of range for %d pages", pageIndex, n)
 	}
 
-	data, err := doc.ImagePNG(pageIndex, pdfRasterDPI)
+	data, err := c.doc.ImagePNG(pageIndex, pdfRasterDPI)
 	if err != nil {
 		return nil, "", fmt.Errorf("pdf page raster: %w", err)
 	}
 	return data, syntheticPageFileName, nil
 }
+
+func (c pdfChapter) Close() error { return c.doc.Close() }
@@ -0,0 +1,180 @@
+package chapterfiles
+
+// Opening a chapter file and building its page index is most of the cost of serving a page:
+// reading a zip's central directory, walking a whole RAR/7z archive or parsing a PDF. The
+// registry keeps recently read chapters open in a pool, so paging through a chapter opens it
+// once instead of once per page.
+
+import (
+	"container/list"
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMaxOpenChapters bounds how many chapters the pool keeps open, and so the file
+	// descriptors it holds.
+	defaultMaxOpenChapters = 16
+	// defaultChapterIdleTimeout is how long an unused chapter stays open.
+	defaultChapterIdleTimeout = 2 * time.Minute
+)
+
+// Opener is implemented by handlers that can open a chapter once and serve many pages from it.
+// The registry pools the chapters they open.
+type Opener interface {
+	Open(ctx context.Context, path string) (OpenChapter, error)
+}
+
+// OpenChapter is an opened chapter file with its page index built. Page may be called
+// concurrently.
+type OpenChapter interface {
+	NumPages() int
+	Page(ctx context.Context, pageIndex int) (data []byte, fileName string, err error)
+	Close() error
+}
+
+// pooledChapter is an open chapter in a chapterPool, with the file's modification time and size
+// when it was opened.
+type pooledChapter struct {
+	path     string
+	modTime  time.Time
+	size     int64
+	chapter  OpenChapter
+	refs     int // requests using the chapter
+	lastUsed time.Time
+	removed  bool // no longer pooled; closed once refs drops to zero
+	elem     *list.Element
+}
+
+// chapterPool is an LRU of open chapters. Chapters are closed when the pool holds more than
+// maxOpen, when they have been unused for idleTimeout, and when their file changes. A chapter
+// in use is never closed, so the pool only grows past maxOpen while more chapters than that are
+// being read at once.
+type chapterPool struct {
+	maxOpen     int
+	idleTimeout time.Duration
+
+	mu     sync.Mutex
+	lru    *list.List // of *pooledChapter, most recently used first
+	byPath map[string]*pooledChapter
+	sweep  *time.Timer // pending idle sweep, if any
+}
+
+func newChapterPool(maxOpen int, idleTimeout time.Duration) *chapterPool {
+	return &chapterPool{
+		maxOpen:     maxOpen,
+		idleTimeout: idleTimeout,
+		lru:         list.New(),
+		byPath:      make(map[string]*pooledChapter),
+	}
+}
+
+// acquire returns the chapter at path, opening it with opener unless it is pooled and its file
+// is unchanged since. The returned func must be called once the caller is done with it.
+func (p *chapterPool) acquire(ctx context.Context, path string, opener Opener) (OpenChapter, func(), error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p.mu.Lock()
+	if pc, ok := p.byPath[path]; ok {
+		if pc.modTime.Equal(info.ModTime()) && pc.size == info.Size() {
+			pc.refs++
+			p.lru.MoveToFront(pc.elem)
+			p.mu.Unlock()
+			return pc.chapter, func() { p.release(pc) }, nil
+		}
+		p.remove(pc)
+	}
+	p.mu.Unlock()
+
+	chapter, err := opener.Open(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	pc := &pooledChapter{path: path, modTime: info.ModTime(), size: info.Size(), chapter: chapter, refs: 1}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Another request may have opened the chapter meanwhile; the newest one is kept.
+	if other, ok := p.byPath[path]; ok {
+		p.remove(other)
+	}
+	pc.elem = p.lru.PushFront(pc)
+	p.byPath[path] = pc
+	p.evict()
+	return chapter, func() { p.release(pc) }, nil
+}
+
+func (p *chapterPool) release(pc *pooledChapter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pc.refs--
+	pc.lastUsed = time.Now()
+	if pc.removed {
+		if pc.refs == 0 {
+			pc.chapter.Close()
+		}
+		return
+	}
+	p.evict()
+	p.scheduleSweep()
+}
+
+// evict closes the least recently used idle chapters until the pool fits maxOpen. p.mu must be
+// held.
+func (p *chapterPool) evict() {
+	for e := p.lru.Back(); e != nil && p.lru.Len() > p.maxOpen; {
+		prev := e.Prev()
+		if pc := e.Value.(*pooledChapter); pc.refs == 0 {
+			p.remove(pc)
+		}
+		e = prev
+	}
+}
+
+// remove takes pc out of the pool and closes it, or leaves it for its last user to close. p.mu
+// must be held.
+func (p *chapterPool) remove(pc *pooledChapter) {
+	p.lru.Remove(pc.elem)
+	delete(p.byPath, pc.path)
+	pc.removed = true
+	if pc.refs == 0 {
+		pc.chapter.Close()
+	}
+}
+
+// scheduleSweep arranges for idle chapters to be closed, unless a sweep is already pending. p.mu
+// must be held.
+func (p *chapterPool) scheduleSweep() {
+	if p.sweep == nil && p.lru.Len() > 0 {
+		p.sweep = time.AfterFunc(p.idleTimeout, p.closeIdle)
+	}
+}
+
+// closeIdle closes the chapters unused for idleTimeout, and sweeps again later while any remain
+// open.
+func (p *chapterPool) closeIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sweep = nil
+	now := time.Now()
+	for e := p.lru.Back(); e != nil; {
+		prev := e.Prev()
+		if pc := e.Value.(*pooledChapter); pc.refs == 0 && now.Sub(pc.lastUsed) >= p.idleTimeout {
+			p.remove(pc)
+		}
+		e = prev
+	}
+	p.scheduleSweep()
+}
+
+// size returns how many chapters the pool holds open.
+func (p *chapterPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lru.Len()
+}
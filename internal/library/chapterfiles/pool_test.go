@@ -0,0 +1,226 @@
+package chapterfiles
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingOpener opens fake chapters and counts how many are open.
+type countingOpener struct {
+	mu     sync.Mutex
+	opened int
+	open   int
+}
+
+func (o *countingOpener) Open(ctx context.Context, path string) (OpenChapter, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.opened++
+	o.open++
+	return &fakeChapter{opener: o}, nil
+}
+
+func (o *countingOpener) counts() (opened, open int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.opened, o.open
+}
+
+type fakeChapter struct {
+	opener *countingOpener
+	closed bool
+}
+
+func (c *fakeChapter) NumPages() int { return 1 }
+
+func (c *fakeChapter) Page(ctx context.Context, pageIndex int) ([]byte, string, error) {
+	if c.closed {
+		return nil, "", fmt.Errorf("chapter is closed")
+	}
+	return []byte("page"), "page.jpg", nil
+}
+
+func (c *fakeChapter) Close() error {
+	c.opener.mu.Lock()
+	defer c.opener.mu.Unlock()
+	c.closed = true
+	c.opener.open--
+	return nil
+}
+
+func writeChapterFiles(t *testing.T, n int) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("ch%d.cbz", i))
+		require.NoError(t, os.WriteFile(paths[i], []byte("chapter"), 0o644))
+	}
+	return paths
+}
+
+// hangingFS stands in for a corrupt archive whose reads never finish until released.
+type hangingFS struct {
+	files   fstest.MapFS
+	release chan struct{}
+}
+
+func (f hangingFS) Open(name string) (fs.File, error) {
+	<-f.release
+	return f.files.Open(name)
+}
+
+func TestFSChapterPageIsBounded(t *testing.T) {
+	fsys := hangingFS{fstest.MapFS{"1.jpg": {Data: []byte("page")}}, make(chan struct{})}
+	defer close(fsys.release)
+	chapter := &fsChapter{fsys: fsys, pages: []string{"1.jpg"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := chapter.Page(ctx, 0)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 5*time.Second, "a hanging read should be given up on")
+}
+
+func TestChapterPool(t *testing.T) {
+	ctx := context.Background()
+	read := func(p *chapterPool, opener *countingOpener, path string) {
+		t.Helper()
+		chapter, release, err := p.acquire(ctx, path, opener)
+		require.NoError(t, err)
+		_, _, err = chapter.Page(ctx, 0)
+		require.NoError(t, err)
+		release()
+	}
+
+	t.Run("Reuses open chapters", func(t *testing.T) {
+		opener := &countingOpener{}
+		p := newChapterPool(2, time.Hour)
+		paths := writeChapterFiles(t, 1)
+		for i := 0; i < 3; i++ {
+			read(p, opener, paths[0])
+		}
+		opened, open := opener.counts()
+		require.Equal(t, 1, opened)
+		require.Equal(t, 1, open)
+	})
+
+	t.Run("Bounds open chapters", func(t *testing.T) {
+		opener := &countingOpener{}
+		p := newChapterPool(2, time.Hour)
+		paths := writeChapterFiles(t, 3)
+		read(p, opener, paths[0])
+		read(p, opener, paths[1])
+		read(p, opener, paths[0]) // paths[1] is now the least recently used
+		read(p, opener, paths[2])
+		_, open := opener.counts()
+		require.Equal(t, 2, open)
+
+		read(p, opener, paths[0])
+		opened, _ := opener.counts()
+		require.Equal(t, 3, opened, "the most recently used chapter should still be open")
+		read(p, opener, paths[1])
+		opened, _ = opener.counts()
+		require.Equal(t, 4, opened, "the least recently used chapter should have been closed")
+	})
+
+	t.Run("Does not close chapters in use", func(t *testing.T) {
+		opener := &countingOpener{}
+		p := newChapterPool(1, time.Hour)
+		paths := writeChapterFiles(t, 2)
+		busy, release, err := p.acquire(ctx, paths[0], opener)
+		require.NoError(t, err)
+		read(p, opener, paths[1])
+		_, _, err = busy.Page(ctx, 0)
+		require.NoError(t, err)
+		release()
+		_, open := opener.counts()
+		require.Equal(t, 1, open, "the pool should shrink back once the chapter is released")
+	})
+
+	t.Run("Reopens changed files", func(t *testing.T) {
+		opener := &countingOpener{}
+		p := newChapterPool(2, time.Hour)
+		paths := writeChapterFiles(t, 1)
+		read(p, opener, paths[0])
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(paths[0], later, later))
+		read(p, opener, paths[0])
+		opened, open := opener.counts()
+		require.Equal(t, 2, opened)
+		require.Equal(t, 1, open)
+	})
+
+	t.Run("Closes idle chapters", func(t *testing.T) {
+		opener := &countingOpener{}
+		p := newChapterPool(2, 10*time.Millisecond)
+		paths := writeChapterFiles(t, 2)
+		read(p, opener, paths[0])
+		read(p, opener, paths[1])
+		require.Eventually(t, func() bool {
+			_, open := opener.counts()
+			return open == 0 && p.size() == 0
+		}, time.Second, 5*time.Millisecond)
+	})
+}
+
+// The benchmarks read every page of a chapter in order, as a reader does, either with the
+// handler's Page (which opens the file for each page) or through the registry's pool.
+
+func BenchmarkChapterPages(b *testing.B) {
+	files := map[string]string{}
+	for i := 0; i < 100; i++ {
+		files[fmt.Sprintf("%03d.jpg", i)] = strings.Repeat("x", 64<<10)
+	}
+	cbzPath := createTestZip(b, "chapter.cbz", files)
+	pdfPath := filepath.Join("testdata", "test.pdf")
+	// RAR is where pooling matters most, as every page otherwise walks the whole archive.
+	cbrPath := filepath.Join("..", "..", "testutil", "asset", "test.cbr")
+
+	for _, tc := range []struct {
+		name     string
+		path     string
+		handler  Handler
+		optional bool // skipped when the archive backend cannot read the file
+	}{
+		{"CBZ", cbzPath, archiveHandler{}, false},
+		{"PDF", pdfPath, pdfHandler{}, false},
+		{"CBR", cbrPath, archiveHandler{}, true},
+	} {
+		ctx := context.Background()
+		pages, _, err := InspectChapterFile(ctx, tc.path)
+		if err != nil && tc.optional {
+			b.Run(tc.name, func(b *testing.B) { b.Skipf("cannot read %s: %v", tc.path, err) })
+			continue
+		}
+		require.NoError(b, err)
+
+		b.Run(tc.name+"/Page", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, err := tc.handler.Page(ctx, tc.path, i%len(pages)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(tc.name+"/Pooled", func(b *testing.B) {
+			registry := NewRegistry()
+			registry.Register(tc.handler)
+			for i := 0; i < b.N; i++ {
+				if _, _, err := registry.GetChapterPage(ctx, tc.path, i%len(pages)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,9 +1,10 @@
-// Package chapterfiles routes chapter files (archives and PDF) to type-specific handlers.
+// Package chapterfiles routes chapters (archives, PDF, EPUB and directories of images) to type-specific handlers.
 package chapterfiles
 
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/vrsandeep/mango-go/internal/models"
@@ -19,11 +20,12 @@ type Handler interface {
 // Registry holds ordered handlers; the first match for SupportsBaseName wins.
 type Registry struct {
 	handlers []Handler
+	chapters *chapterPool // chapters opened by Opener handlers, kept for later pages
 }
 
 // NewRegistry returns an empty registry.
 func NewRegistry() *Registry {
-	return &Registry{}
+	return &Registry{chapters: newChapterPool(defaultMaxOpenChapters, defaultChapterIdleTimeout)}
 }
 
 // Register adds a handler (later registrations are checked first).
@@ -32,6 +34,12 @@ func (r *Registry) Register(h Handler) {
 }
 
 func (r *Registry) handlerForPath(path string) Handler {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		if IsImageDirectory(path) {
+			return imageDirHandler{}
+		}
+		return nil
+	}
 	base := filepath.Base(path)
 	for _, h := range r.handlers {
 		if h.SupportsBaseName(base) {
@@ -51,6 +59,11 @@ func (r *Registry) IsSupportedChapterFile(baseName string) bool {
 	return false
 }
 
+// IsSupportedChapterPath reports whether path is a chapter: a supported chapter file or an image directory.
+func (r *Registry) IsSupportedChapterPath(path string) bool {
+	return r.handlerForPath(path) != nil
+}
+
 // InspectChapterFile loads page list and first-page raster bytes for hashing/thumbnails.
 func (r *Registry) InspectChapterFile(ctx context.Context, path string) ([]*models.Page, []byte, error) {
 	h := r.handlerForPath(path)
@@ -60,13 +73,37 @@ func (r *Registry) InspectChapterFile(ctx context.Context, path string) ([]*mode
 	return h.Inspect(ctx, path)
 }
 
+// ReadComicInfo returns the chapter's embedded ComicInfo.xml metadata, or nil if the chapter kind
+// cannot carry one or the chapter has none.
+func (r *Registry) ReadComicInfo(ctx context.Context, path string) (*models.ComicInfo, error) {
+	h := r.handlerForPath(path)
+	if h == nil {
+		return nil, fmt.Errorf("unsupported chapter file type: %s", filepath.Ext(path))
+	}
+	reader, ok := h.(ComicInfoReader)
+	if !ok {
+		return nil, nil
+	}
+	return reader.ComicInfo(ctx, path)
+}
+
 // GetChapterPage returns raw page bytes and a logical filename (for Content-Type from extension).
+// Chapters of Opener handlers are read from the registry's pool of open chapters.
 func (r *Registry) GetChapterPage(ctx context.Context, path string, pageIndex int) ([]byte, string, error) {
 	h := r.handlerForPath(path)
 	if h == nil {
 		return nil, "", fmt.Errorf("unsupported chapter file type: %s", filepath.Ext(path))
 	}
-	return h.Page(ctx, path, pageIndex)
+	opener, ok := h.(Opener)
+	if !ok {
+		return h.Page(ctx, path, pageIndex)
+	}
+	chapter, release, err := r.chapters.acquire(ctx, path, opener)
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+	return chapter.Page(ctx, pageIndex)
 }
 
 var defaultRegistry *Registry
@@ -75,6 +112,7 @@ func init() {
 	defaultRegistry = NewRegistry()
 	defaultRegistry.Register(&archiveHandler{})
 	defaultRegistry.Register(&pdfHandler{})
+	defaultRegistry.Register(&epubHandler{})
 }
 
 // RegisterHandler adds a handler to the default registry (e.g. PDF from another package's init).
@@ -93,11 +131,21 @@ func IsSupportedChapterFile(baseName string) bool {
 	return defaultRegistry.IsSupportedChapterFile(baseName)
 }
 
+// IsSupportedChapterPath uses the default registry.
+func IsSupportedChapterPath(path string) bool {
+	return defaultRegistry.IsSupportedChapterPath(path)
+}
+
 // InspectChapterFile uses the default registry.
 func InspectChapterFile(ctx context.Context, path string) ([]*models.Page, []byte, error) {
 	return defaultRegistry.InspectChapterFile(ctx, path)
 }
 
+// ReadComicInfo uses the default registry.
+func ReadComicInfo(ctx context.Context, path string) (*models.ComicInfo, error) {
+	return defaultRegistry.ReadComicInfo(ctx, path)
+}
+
 // GetChapterPage uses the default registry.
 func GetChapterPage(ctx context.Context, path string, pageIndex int) ([]byte, string, error) {
 	return defaultRegistry.GetChapterPage(ctx, path, pageIndex)
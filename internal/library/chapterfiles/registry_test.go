@@ -8,7 +8,7 @@ import (
 )
 
 func TestIsSupportedChapterFile_ArchiveExtensions(t *testing.T) {
-	for _, ext := range []string{"book.cbz", "a.zip", "x.cbr", "y.rar", "z.7z", "q.cb7", "doc.pdf", "scan.PDF"} {
+	for _, ext := range []string{"book.cbz", "a.zip", "x.cbr", "y.rar", "z.7z", "q.cb7", "doc.pdf", "scan.PDF", "vol.epub", "Vol.EPUB"} {
 		if !IsSupportedChapterFile(ext) {
 			t.Errorf("expected supported: %q", ext)
 		}
@@ -0,0 +1,38 @@
+package library
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+)
+
+// KOReaderDigest computes the partial MD5 KOReader uses to identify a document for progress
+// sync: 1 KiB samples read at offsets 0 and 1024<<(2*i) for i in 0..10, stopping at end of file.
+// (KOReader's first sample is at 1024<<-2, which LuaJIT's 32-bit shift turns into offset 0.)
+func KOReaderDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	const step, size = 1024, 1024
+	hasher := md5.New()
+	buf := make([]byte, size)
+	for i := -1; i <= 10; i++ {
+		var offset int64
+		if i >= 0 {
+			offset = step << (2 * i)
+		}
+		n, err := f.ReadAt(buf, offset)
+		if n == 0 {
+			if err != nil && err != io.EOF {
+				return "", err
+			}
+			break
+		}
+		hasher.Write(buf[:n])
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,41 @@
+package library_test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/library"
+)
+
+func TestKOReaderDigest(t *testing.T) {
+	data := make([]byte, 5000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	path := filepath.Join(t.TempDir(), "chapter.cbz")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	// Samples at offsets 0, 1024 and 4096 (truncated at EOF); 16384 is past the end.
+	hasher := md5.New()
+	hasher.Write(data[0:1024])
+	hasher.Write(data[1024:2048])
+	hasher.Write(data[4096:])
+	want := hex.EncodeToString(hasher.Sum(nil))
+
+	got, err := library.KOReaderDigest(path)
+	if err != nil {
+		t.Fatalf("KOReaderDigest failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("Expected digest %s, got %s", want, got)
+	}
+
+	if _, err := library.KOReaderDigest(filepath.Join(t.TempDir(), "missing.cbz")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
@@ -0,0 +1,89 @@
+package library
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	"github.com/nfnt/resize"
+)
+
+// DefaultPageQuality is the JPEG quality used when re-encoding a page without a quality given.
+const DefaultPageQuality = 85
+
+// PageTransform describes how a page should be scaled and re-encoded. The page is scaled down,
+// keeping its aspect ratio, to fit within Width x Height; a zero side is unconstrained. Format is
+// "jpeg" or "png", and empty keeps JPEG and PNG pages in their format. Quality is the JPEG
+// quality, 0 meaning DefaultPageQuality.
+type PageTransform struct {
+	Width   int
+	Height  int
+	Quality int
+	Format  string
+}
+
+// IsZero reports whether t leaves pages unchanged.
+func (t PageTransform) IsZero() bool {
+	return t == PageTransform{}
+}
+
+// TransformPage applies t to a page image and returns the result with its format. Pages are
+// never enlarged. Pages that need no change, and pages in a format we cannot decode (e.g. WebP),
+// are returned as they are, with changed set to false.
+func TransformPage(imageData []byte, t PageTransform) (data []byte, format string, changed bool, err error) {
+	cfg, srcFormat, err := image.DecodeConfig(bytes.NewReader(imageData))
+	if errors.Is(err, image.ErrFormat) {
+		return imageData, "", false, nil
+	}
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	format = t.Format
+	if format == "" {
+		format = srcFormat
+		if format != "jpeg" && format != "png" {
+			format = "jpeg"
+		}
+	}
+	scale := 1.0
+	if t.Width > 0 && t.Width < cfg.Width {
+		scale = float64(t.Width) / float64(cfg.Width)
+	}
+	if t.Height > 0 && t.Height < cfg.Height {
+		scale = math.Min(scale, float64(t.Height)/float64(cfg.Height))
+	}
+	if scale == 1 && format == srcFormat && (format != "jpeg" || t.Quality == 0) {
+		return imageData, srcFormat, false, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if scale < 1 {
+		width := uint(math.Max(1, math.Round(float64(cfg.Width)*scale)))
+		height := uint(math.Max(1, math.Round(float64(cfg.Height)*scale)))
+		img = resize.Resize(width, height, img, resize.Lanczos3)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	default:
+		quality := t.Quality
+		if quality == 0 {
+			quality = DefaultPageQuality
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to encode %s: %w", format, err)
+	}
+	return buf.Bytes(), format, true, nil
+}
@@ -0,0 +1,60 @@
+package library_test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/library"
+)
+
+func TestTransformPage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 400, 600))); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	pngData := buf.Bytes()
+
+	decode := func(t *testing.T, data []byte) (image.Config, string) {
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Transformed page does not decode: %v", err)
+		}
+		return cfg, format
+	}
+
+	t.Run("Fits within width and height", func(t *testing.T) {
+		data, format, changed, err := library.TransformPage(pngData, library.PageTransform{Width: 300, Height: 300})
+		if err != nil || !changed || format != "png" {
+			t.Fatalf("Expected a resized png, got format=%q changed=%v err=%v", format, changed, err)
+		}
+		if cfg, _ := decode(t, data); cfg.Width != 200 || cfg.Height != 300 {
+			t.Errorf("Expected 200x300, got %dx%d", cfg.Width, cfg.Height)
+		}
+	})
+
+	t.Run("Converts formats", func(t *testing.T) {
+		data, format, changed, err := library.TransformPage(pngData, library.PageTransform{Format: "jpeg", Quality: 50})
+		if err != nil || !changed || format != "jpeg" {
+			t.Fatalf("Expected a jpeg, got format=%q changed=%v err=%v", format, changed, err)
+		}
+		if cfg, f := decode(t, data); f != "jpeg" || cfg.Width != 400 {
+			t.Errorf("Expected a 400px wide jpeg, got %dpx %s", cfg.Width, f)
+		}
+	})
+
+	t.Run("Never enlarges", func(t *testing.T) {
+		data, _, changed, err := library.TransformPage(pngData, library.PageTransform{Width: 2000})
+		if err != nil || changed || !bytes.Equal(data, pngData) {
+			t.Errorf("Expected the original page, got changed=%v err=%v", changed, err)
+		}
+	})
+
+	t.Run("Passes through unknown formats", func(t *testing.T) {
+		data, _, changed, err := library.TransformPage([]byte("RIFF....WEBP"), library.PageTransform{Width: 100})
+		if err != nil || changed || string(data) != "RIFF....WEBP" {
+			t.Errorf("Expected unknown formats to pass through, got changed=%v err=%v", changed, err)
+		}
+	})
+}
@@ -1,5 +1,5 @@
 // This file contains the main logic for scanning the library directory.
-// It walks the directory tree, finds supported chapter files, and uses
+// It walks the directory tree, finds supported chapter files and image directories, and uses
 // chapterfiles handlers to parse them and extract metadata.
 
 package library
@@ -173,7 +173,7 @@ func performSync(ctx jobs.JobContext, jobId string, diskItems map[string]diskIte
 
 	// 3. Reconcile Chapters
 	sendProgress(ctx, jobId, "Syncing chapters...", 50, false)
-	parsingErrors := syncChapters(st, diskItems, dbChapters, dbChaptersByPath, dbFolders)
+	parsingErrors := syncChapters(st, ctx.Config().ThumbnailDir(), diskItems, dbChapters, dbChaptersByPath, dbFolders)
 
 	// 4. Check for bad files during sync
 	sendProgress(ctx, jobId, "Checking for bad files...", 65, false)
@@ -236,7 +236,7 @@ func syncFolders(st *store.Store, rootPath string, diskItems map[string]diskItem
 
 // syncChapters handles new, moved, and existing chapters.
 // It uses file metadata (mtime, size) to skip parsing unchanged files.
-func syncChapters(st *store.Store, diskItems map[string]diskItem, dbChapters map[string]store.ChapterInfo, dbChaptersByPath map[string]store.ChapterInfo, dbFolders map[string]*models.Folder) map[string]error {
+func syncChapters(st *store.Store, thumbnailDir string, diskItems map[string]diskItem, dbChapters map[string]store.ChapterInfo, dbChaptersByPath map[string]store.ChapterInfo, dbFolders map[string]*models.Folder) map[string]error {
 
 	// Track parsing errors to avoid re-parsing in checkBadFilesDuringSync
 	parsingErrors := make(map[string]error)
@@ -244,27 +244,31 @@ func syncChapters(st *store.Store, diskItems map[string]diskItem, dbChapters map
 	parsedCount := 0
 
 	for path, item := range diskItems {
-		if item.isDir || !chapterfiles.IsSupportedChapterFile(filepath.Base(path)) {
+		if !isChapterItem(path, item) {
 			continue
 		}
 
 		// Get file metadata before parsing
-		fileInfo, err := os.Stat(path)
+		fileMtime, fileSize, err := chapterfiles.StatChapter(path)
 		if err != nil {
 			log.Printf("Cannot stat file %s: %v", path, err)
 			parsingErrors[path] = err
 			continue
 		}
 
-		fileMtime := fileInfo.ModTime()
-		fileSize := fileInfo.Size()
-
 		// Check if we already know about this file by path
 		if existingChapterByPath, existsByPath := dbChaptersByPath[path]; existsByPath {
 			// File exists at this path - check if metadata changed
 			if existingChapterByPath.FileMtime != nil && existingChapterByPath.FileSize != nil {
 				if fileMtime.Equal(*existingChapterByPath.FileMtime) && fileSize == *existingChapterByPath.FileSize {
-					// File metadata unchanged - skip parsing
+					// File metadata unchanged - skip parsing, but backfill ComicInfo and the KOReader
+					// digest for chapters scanned before they were recorded
+					if !existingChapterByPath.ComicInfoChecked {
+						syncComicInfo(st, existingChapterByPath.ID, path)
+					}
+					if existingChapterByPath.KOReaderHash == "" {
+						syncKOReaderHash(st, existingChapterByPath.ID, path, item)
+					}
 					skippedCount++
 					continue
 				}
@@ -299,15 +303,32 @@ func syncChapters(st *store.Store, diskItems map[string]diskItem, dbChapters map
 					st.UpdateChapterPathWithMetadata(existingChapter.ID, path, parentFolder.ID, &fileMtime, &fileSize)
 				}
 			}
+			// Pages may have been added or removed without touching the first page
+			st.UpdateChapterPageCount(existingChapter.ID, len(pages))
+			syncComicInfo(st, existingChapter.ID, path)
+			syncKOReaderHash(st, existingChapter.ID, path, item)
+		} else if existingChapterByPath, existsByPath := dbChaptersByPath[path]; existsByPath {
+			// Same path but the first page changed, so the content hash did too
+			var thumb string
+			if firstPageData != nil {
+				thumb, _ = GenerateThumbnail(thumbnailDir, firstPageData)
+			}
+			st.UpdateChapterContent(existingChapterByPath.ID, hash, len(pages), thumb, &fileMtime, &fileSize)
+			syncComicInfo(st, existingChapterByPath.ID, path)
+			syncKOReaderHash(st, existingChapterByPath.ID, path, item)
 		} else {
 			// New chapter - create with metadata
 			parentFolder, ok := dbFolders[filepath.Dir(path)]
 			if ok {
 				var thumb string
 				if firstPageData != nil {
-					thumb, _ = GenerateThumbnail(firstPageData)
+					thumb, _ = GenerateThumbnail(thumbnailDir, firstPageData)
+				}
+				chapter, err := st.CreateChapterWithMetadata(parentFolder.ID, path, hash, len(pages), thumb, &fileMtime, &fileSize)
+				if err == nil {
+					syncComicInfo(st, chapter.ID, path)
+					syncKOReaderHash(st, chapter.ID, path, item)
 				}
-				st.CreateChapterWithMetadata(parentFolder.ID, path, hash, len(pages), thumb, &fileMtime, &fileSize)
 			}
 		}
 	}
@@ -320,17 +341,54 @@ func syncChapters(st *store.Store, diskItems map[string]diskItem, dbChapters map
 	return parsingErrors
 }
 
+// syncComicInfo stores the ComicInfo.xml metadata embedded in a chapter, if any.
+// Broken metadata is logged and ignored; it never makes the chapter itself unreadable.
+func syncComicInfo(st *store.Store, chapterID int64, path string) {
+	info, err := chapterfiles.ReadComicInfo(context.Background(), path)
+	if err != nil {
+		log.Printf("Ignoring unreadable ComicInfo.xml in %s: %v", path, err)
+		info = nil
+	}
+	if err := st.UpdateChapterComicInfo(chapterID, info); err != nil {
+		log.Printf("Failed to store ComicInfo for %s: %v", path, err)
+	}
+}
+
+// syncKOReaderHash stores KOReader's document identifier for a chapter file. Image directories
+// cannot be opened by KOReader, so they have none.
+func syncKOReaderHash(st *store.Store, chapterID int64, path string, item diskItem) {
+	if item.isDir {
+		return
+	}
+	digest, err := KOReaderDigest(path)
+	if err != nil {
+		log.Printf("Failed to compute KOReader digest for %s: %v", path, err)
+		return
+	}
+	if err := st.UpdateChapterKOReaderHash(chapterID, digest); err != nil {
+		log.Printf("Failed to store KOReader digest for %s: %v", path, err)
+	}
+}
+
 // prune removes items from the DB that are no longer on disk or are corrupted.
 func prune(st *store.Store, diskItems map[string]diskItem, dbFolders map[string]*models.Folder, dbChapters map[string]store.ChapterInfo, parsingErrors map[string]error) {
 	// Prune chapters that are deleted or corrupted
 	for hash, chapInfo := range dbChapters {
 		// Check if chapter file no longer exists on disk
-		if _, exists := diskItems[chapInfo.Path]; !exists {
+		item, exists := diskItems[chapInfo.Path]
+		if !exists {
 			log.Printf("Pruning deleted chapter: %s", chapInfo.Path)
 			st.DeleteChapterByHash(hash)
 			continue
 		}
 
+		// Check if the path is still a chapter (e.g. an image directory that lost its images)
+		if !isChapterItem(chapInfo.Path, item) {
+			log.Printf("Pruning path that is no longer a chapter: %s", chapInfo.Path)
+			st.DeleteChapterByHash(hash)
+			continue
+		}
+
 		// Check if chapter file is corrupted
 		if parseErr, isCorrupted := parsingErrors[chapInfo.Path]; isCorrupted {
 			log.Printf("Pruning corrupted chapter: %s - %v", chapInfo.Path, parseErr)
@@ -361,7 +419,16 @@ func generateContentHash(data []byte, filename string) string {
 	return hex.EncodeToString(hasher.Sum(nil))
 }
 
-// hasChapterFiles reports whether dirPath contains any supported chapter files.
+// isChapterItem reports whether a disk item is a chapter: a supported chapter file,
+// or a leaf directory holding only images.
+func isChapterItem(path string, item diskItem) bool {
+	if item.isDir {
+		return chapterfiles.IsImageDirectory(path)
+	}
+	return chapterfiles.IsSupportedChapterFile(filepath.Base(path))
+}
+
+// hasChapterFiles reports whether dirPath contains any supported chapter files or image directories.
 func hasChapterFiles(dirPath string) bool {
 	found := false
 	filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
@@ -372,10 +439,11 @@ func hasChapterFiles(dirPath string) bool {
 		if path == dirPath {
 			return nil
 		}
-		// If we find a chapter file, mark this directory as non-empty
-		if !d.IsDir() && chapterfiles.IsSupportedChapterFile(d.Name()) {
+		// If we find a chapter file or image directory, mark this directory as non-empty
+		if d.IsDir() && chapterfiles.IsImageDirectory(path) ||
+			!d.IsDir() && chapterfiles.IsSupportedChapterFile(d.Name()) {
 			found = true
-			return filepath.SkipAll // Stop walking once we find a supported chapter file
+			return filepath.SkipAll // Stop walking once we find a chapter
 		}
 		return nil
 	})
@@ -385,12 +453,12 @@ func hasChapterFiles(dirPath string) bool {
 // checkBadFilesDuringSync checks for bad files during library sync
 func checkBadFilesDuringSync(badFileStore *store.BadFileStore, diskItems map[string]diskItem, parsingErrors map[string]error) {
 	for path, item := range diskItems {
-		if item.isDir || !chapterfiles.IsSupportedChapterFile(filepath.Base(path)) {
+		if !isChapterItem(path, item) {
 			continue
 		}
 
 		// Check if file is accessible
-		fileInfo, err := os.Stat(path)
+		_, fileSize, err := chapterfiles.StatChapter(path)
 		if err != nil {
 			log.Printf("File %s is not accessible: %v", path, err)
 			// Record as bad file due to I/O error
@@ -402,7 +470,7 @@ func checkBadFilesDuringSync(badFileStore *store.BadFileStore, diskItems map[str
 		if parseErr, exists := parsingErrors[path]; exists {
 			// File is corrupted or invalid
 			errorMsg := categorizeError(parseErr)
-			err := badFileStore.CreateBadFile(path, errorMsg, fileInfo.Size())
+			err := badFileStore.CreateBadFile(path, errorMsg, fileSize)
 			if err != nil {
 				log.Printf("Failed to record bad file %s: %v", path, err)
 			} else {
@@ -197,6 +197,65 @@ func TestPruningDeletedFiles(t *testing.T) {
 	assertFolderCount(t, st, 0, "After pruning")
 }
 
+// TestFolderGrantsSurviveRescan tests that a user's folder grants outlive the folder being
+// pruned and re-created with a new ID.
+func TestFolderGrantsSurviveRescan(t *testing.T) {
+	app := testutil.SetupTestApp(t)
+	st := store.New(app.DB())
+	libraryRoot := app.Config().Library.Path
+
+	for _, series := range []string{"Series A", "Series B"} {
+		os.MkdirAll(filepath.Join(libraryRoot, series), 0755)
+		testutil.CreateTestCBZ(t, filepath.Join(libraryRoot, series), "ch1.cbz", []string{"p1.jpg"})
+	}
+	library.LibrarySync(app)
+
+	seriesPath := filepath.Join(libraryRoot, "Series A")
+	granted, err := st.GetFolderByPath(seriesPath)
+	if err != nil {
+		t.Fatalf("Series A was not scanned: %v", err)
+	}
+	user, _ := st.CreateUser("reader", "hash", "user")
+	if err := st.SetContentRestrictions(user.ID, &models.ContentRestrictions{RestrictFolders: true, FolderIDs: []int64{granted.ID}}); err != nil {
+		t.Fatalf("SetContentRestrictions failed: %v", err)
+	}
+
+	// Moving the series out of the library prunes it; the grant is reported as missing
+	outside := filepath.Join(t.TempDir(), "Series A")
+	if err := os.Rename(seriesPath, outside); err != nil {
+		t.Fatalf("Failed to move series: %v", err)
+	}
+	library.LibrarySync(app)
+	r, err := st.GetContentRestrictions(user.ID)
+	if err != nil {
+		t.Fatalf("GetContentRestrictions failed: %v", err)
+	}
+	if len(r.FolderIDs) != 0 || len(r.MissingFolders) != 1 || r.MissingFolders[0] != seriesPath {
+		t.Errorf("Expected the grant to be reported missing, got %+v", r)
+	}
+
+	// Moving it back re-creates the folder with a new ID, and the grant applies to it again
+	if err := os.Rename(outside, seriesPath); err != nil {
+		t.Fatalf("Failed to move series back: %v", err)
+	}
+	library.LibrarySync(app)
+	rescanned, err := st.GetFolderByPath(seriesPath)
+	if err != nil {
+		t.Fatalf("Series A was not rescanned: %v", err)
+	}
+	if ok, _ := st.CanAccessFolder(user.ID, rescanned.ID); !ok {
+		t.Error("Expected the rescanned folder to be visible again")
+	}
+	other, _ := st.GetFolderByPath(filepath.Join(libraryRoot, "Series B"))
+	if ok, _ := st.CanAccessFolder(user.ID, other.ID); ok {
+		t.Error("Expected folders outside the grant to stay hidden")
+	}
+	r, _ = st.GetContentRestrictions(user.ID)
+	if len(r.FolderIDs) != 1 || r.FolderIDs[0] != rescanned.ID || len(r.MissingFolders) != 0 {
+		t.Errorf("Expected the grant to name the rescanned folder, got %+v", r)
+	}
+}
+
 // TestEmptyDirectoryHandling tests that empty directories are ignored
 func TestEmptyDirectoryHandling(t *testing.T) {
 	app := testutil.SetupTestApp(t)
@@ -479,3 +538,122 @@ func TestMetadataBasedSkipping(t *testing.T) {
 	// For this test, we'll just verify the mechanism works
 	// In a real scenario, changing the file would trigger re-parsing
 }
+
+// TestImageDirectoryChapters tests that leaf folders of loose images are scanned as chapters
+func TestImageDirectoryChapters(t *testing.T) {
+	app := testutil.SetupTestApp(t)
+	st := store.New(app.DB())
+	libraryRoot := app.Config().Library.Path
+
+	seriesDir := filepath.Join(libraryRoot, "Loose Series")
+	chapterDir := filepath.Join(seriesDir, "Chapter 1")
+	os.MkdirAll(chapterDir, 0755)
+	testutil.CreateTestCBZ(t, seriesDir, "Chapter 2.cbz", []string{"p1.jpg"})
+	for _, name := range []string{"002.jpg", "001.jpg"} {
+		if err := os.WriteFile(filepath.Join(chapterDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to create image: %v", err)
+		}
+	}
+
+	library.LibrarySync(app)
+
+	// The image directory is a chapter of the series, not a folder of its own
+	folders, _ := st.GetAllFoldersByPath()
+	assertFolderCount(t, st, 1, "Image directory scan")
+	assertFolderExists(t, folders, seriesDir, true, "Image directory scan")
+	assertFolderExists(t, folders, chapterDir, false, "Image directory scan")
+	assertChapterCount(t, st, 2, "Image directory scan")
+
+	var pageCount int
+	if err := app.DB().QueryRow("SELECT page_count FROM chapters WHERE path = ?", chapterDir).Scan(&pageCount); err != nil {
+		t.Fatalf("Image directory chapter not found: %v", err)
+	}
+	if pageCount != 2 {
+		t.Errorf("Expected 2 pages, got %d", pageCount)
+	}
+
+	// Adding a page updates the existing chapter
+	if err := os.WriteFile(filepath.Join(chapterDir, "003.jpg"), []byte("003.jpg"), 0644); err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+	library.LibrarySync(app)
+	assertChapterCount(t, st, 2, "After adding a page")
+	app.DB().QueryRow("SELECT page_count FROM chapters WHERE path = ?", chapterDir).Scan(&pageCount)
+	if pageCount != 3 {
+		t.Errorf("Expected 3 pages after adding one, got %d", pageCount)
+	}
+
+	// A non-image file turns the directory back into a plain folder
+	if err := os.WriteFile(filepath.Join(chapterDir, "notes.txt"), []byte("notes"), 0644); err != nil {
+		t.Fatalf("Failed to create text file: %v", err)
+	}
+	library.LibrarySync(app)
+	assertChapterCount(t, st, 1, "After adding a non-image file")
+}
+
+func TestComicInfoSync(t *testing.T) {
+	app := testutil.SetupTestApp(t)
+	st := store.New(app.DB())
+	libraryRoot := app.Config().Library.Path
+
+	seriesDir := filepath.Join(libraryRoot, "Tagged Series")
+	os.MkdirAll(seriesDir, 0755)
+	testutil.CreateTestCBZWithComicInfo(t, seriesDir, "tagged.cbz", []string{"p1.jpg", "p2.jpg"},
+		`<ComicInfo><Series>Tagged</Series><Number>7</Number><Volume>2</Volume><Manga>YesAndRightToLeft</Manga></ComicInfo>`)
+
+	library.LibrarySync(app)
+	assertChapterCount(t, st, 1, "ComicInfo scan")
+
+	chapters, _ := st.GetAllChaptersByHash()
+	var info store.ChapterInfo
+	for _, c := range chapters {
+		info = c
+	}
+	chapter, err := st.GetChapterByID(info.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to get chapter: %v", err)
+	}
+	if chapter.PageCount != 2 {
+		t.Errorf("ComicInfo.xml should not count as a page, got %d pages", chapter.PageCount)
+	}
+	if chapter.ComicInfo == nil || chapter.ComicInfo.Series != "Tagged" || chapter.ComicInfo.Number != "7" {
+		t.Fatalf("Expected ComicInfo to be stored, got %+v", chapter.ComicInfo)
+	}
+	if chapter.ComicInfo.Volume == nil || *chapter.ComicInfo.Volume != 2 {
+		t.Errorf("Expected volume 2, got %v", chapter.ComicInfo.Volume)
+	}
+
+	// Chapters scanned before ComicInfo support are backfilled without a file change
+	app.DB().Exec("UPDATE chapters SET comicinfo_series = NULL, comicinfo_checked = false")
+	library.LibrarySync(app)
+	chapter, _ = st.GetChapterByID(info.ID, 1)
+	if chapter.ComicInfo == nil || chapter.ComicInfo.Series != "Tagged" {
+		t.Errorf("Expected ComicInfo to be backfilled, got %+v", chapter.ComicInfo)
+	}
+}
+
+func TestKOReaderDigestSync(t *testing.T) {
+	app := testutil.SetupTestApp(t)
+	st := store.New(app.DB())
+	libraryRoot := app.Config().Library.Path
+
+	seriesDir := filepath.Join(libraryRoot, "Synced Series")
+	os.MkdirAll(seriesDir, 0755)
+	path := testutil.CreateTestCBZ(t, seriesDir, "synced.cbz", []string{"p1.jpg"})
+	want, err := library.KOReaderDigest(path)
+	if err != nil {
+		t.Fatalf("KOReaderDigest failed: %v", err)
+	}
+
+	library.LibrarySync(app)
+	if id, err := st.GetChapterIDByDocument(want); err != nil || id == 0 {
+		t.Fatalf("Expected the chapter to be found by its KOReader digest, got %d, %v", id, err)
+	}
+
+	// Chapters scanned before the digest was recorded are backfilled without a file change
+	app.DB().Exec("UPDATE chapters SET koreader_hash = NULL")
+	library.LibrarySync(app)
+	if _, err := st.GetChapterIDByDocument(want); err != nil {
+		t.Errorf("Expected the KOReader digest to be backfilled: %v", err)
+	}
+}
@@ -43,7 +43,7 @@ func TestDeleteEmptyTags(t *testing.T) {
 	}
 
 	// Verify tags were created
-	tags, err := st.ListTagsWithCounts()
+	tags, err := st.ListTagsWithCounts(0)
 	if err != nil {
 		t.Fatalf("Failed to get tags: %v", err)
 	}
@@ -56,7 +56,7 @@ func TestDeleteEmptyTags(t *testing.T) {
 	library.DeleteEmptyTags(ctx)
 
 	// Verify that only tags with associations remain
-	tags, err = st.ListTagsWithCounts()
+	tags, err = st.ListTagsWithCounts(0)
 	if err != nil {
 		t.Fatalf("Failed to get tags after cleanup: %v", err)
 	}
@@ -98,7 +98,7 @@ func TestDeleteEmptyTagsWithNoEmptyTags(t *testing.T) {
 	}
 
 	// Get initial tag count
-	initialTags, err := st.ListTagsWithCounts()
+	initialTags, err := st.ListTagsWithCounts(0)
 	if err != nil {
 		t.Fatalf("Failed to get initial tags: %v", err)
 	}
@@ -109,7 +109,7 @@ func TestDeleteEmptyTagsWithNoEmptyTags(t *testing.T) {
 	library.DeleteEmptyTags(ctx)
 
 	// Verify tag count remains the same
-	finalTags, err := st.ListTagsWithCounts()
+	finalTags, err := st.ListTagsWithCounts(0)
 	if err != nil {
 		t.Fatalf("Failed to get final tags: %v", err)
 	}
@@ -3,54 +3,203 @@ package library
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"image"
+	_ "image/gif" // Register GIF decoder
 	"image/jpeg"
 	_ "image/png" // Register PNG decoder
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/nfnt/resize"
 	"github.com/vrsandeep/mango-go/internal/library/chapterfiles"
 )
 
-const thumbnailWidth uint = 200
-const thumbnailHeight uint = 300
+// ThumbnailSize is one of the variants stored for every thumbnail. Portrait images are scaled
+// to Width and landscape ones to Height, so covers fill the cards they are shown on.
+type ThumbnailSize struct {
+	Name   string
+	Width  uint
+	Height uint
+}
+
+// ThumbnailSizes are the variants generated for every thumbnail.
+var ThumbnailSizes = []ThumbnailSize{
+	{Name: "small", Width: 100, Height: 150},
+	{Name: "medium", Width: 200, Height: 300},
+	{Name: "large", Width: 400, Height: 600},
+}
+
+// DefaultThumbnailSize is served when no size is asked for.
+const DefaultThumbnailSize = "medium"
+
+// ThumbnailURLPrefix is where thumbnails are served from. Chapters and folders store this
+// followed by the thumbnail's hash.
+const ThumbnailURLPrefix = "/api/thumbnails/"
+
+// GenerateThumbnail takes raw image data, stores resized JPEG copies of it in every thumbnail
+// size under dir, and returns the URL they are served from. Files are named after the SHA-256
+// of the image data, so the same cover is only stored once.
+func GenerateThumbnail(dir string, imageData []byte) (string, error) {
+	sum := sha256.Sum256(imageData)
+	hash := hex.EncodeToString(sum[:])
+	url := ThumbnailURLPrefix + hash
+	if thumbnailExists(dir, hash) {
+		return url, nil
+	}
 
-// GenerateThumbnail takes raw image data, resizes it, encodes it as a
-// Base64 JPEG, and returns it as a data URI string.
-func GenerateThumbnail(imageData []byte) (string, error) {
 	img, _, err := image.Decode(bytes.NewReader(imageData))
 	if err != nil {
 		return "", fmt.Errorf("failed to decode image: %w", err)
 	}
+	if err := os.MkdirAll(filepath.Join(dir, hash[:2]), 0755); err != nil {
+		return "", fmt.Errorf("failed to create thumbnail directory: %w", err)
+	}
 
 	// Get image dimensions
 	imgHeight := img.Bounds().Dy()
 	imgWidth := img.Bounds().Dx()
 
-	var resizedImg image.Image
-	if imgHeight > imgWidth {
-		resizedImg = resize.Resize(thumbnailWidth, 0, img, resize.Lanczos3)
-	} else {
-		resizedImg = resize.Resize(0, thumbnailHeight, img, resize.Lanczos3)
+	for _, size := range ThumbnailSizes {
+		// Images are only ever scaled down; small sources give identical larger variants.
+		resizedImg := img
+		if imgHeight > imgWidth {
+			if uint(imgWidth) > size.Width {
+				resizedImg = resize.Resize(size.Width, 0, img, resize.Lanczos3)
+			}
+		} else if uint(imgHeight) > size.Height {
+			resizedImg = resize.Resize(0, size.Height, img, resize.Lanczos3)
+		}
 
+		var buf bytes.Buffer
+		// Encode the resized image as a JPEG. Quality 75 is a good balance.
+		if err := jpeg.Encode(&buf, resizedImg, &jpeg.Options{Quality: 75}); err != nil {
+			return "", fmt.Errorf("failed to encode jpeg: %w", err)
+		}
+		path, _ := ThumbnailPath(dir, hash, size.Name)
+		if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+			return "", fmt.Errorf("failed to write thumbnail: %w", err)
+		}
 	}
+	return url, nil
+}
+
+// ThumbnailPath returns the file holding one size of a thumbnail. ok is false when hash is not
+// a thumbnail hash or size is not a thumbnail size, so request input can be passed straight in.
+func ThumbnailPath(dir, hash, size string) (path string, ok bool) {
+	if len(hash) != sha256.Size*2 || strings.Trim(hash, "0123456789abcdef") != "" {
+		return "", false
+	}
+	for _, s := range ThumbnailSizes {
+		if s.Name == size {
+			return filepath.Join(dir, hash[:2], hash+"-"+size+".jpg"), true
+		}
+	}
+	return "", false
+}
 
-	var buf bytes.Buffer
-	// Encode the resized image as a JPEG. Quality 75 is a good balance.
-	if err := jpeg.Encode(&buf, resizedImg, &jpeg.Options{Quality: 75}); err != nil {
-		return "", fmt.Errorf("failed to encode jpeg: %w", err)
+// ThumbnailHash returns the hash in a stored thumbnail URL.
+func ThumbnailHash(thumbnail string) (string, bool) {
+	hash, ok := strings.CutPrefix(thumbnail, ThumbnailURLPrefix)
+	if _, valid := ThumbnailPath("", hash, DefaultThumbnailSize); !ok || !valid {
+		return "", false
 	}
+	return hash, true
+}
 
-	// Encode the byte buffer to a Base64 string.
-	base64Str := base64.StdEncoding.EncodeToString(buf.Bytes())
+func thumbnailExists(dir, hash string) bool {
+	for _, size := range ThumbnailSizes {
+		path, _ := ThumbnailPath(dir, hash, size.Name)
+		if _, err := os.Stat(path); err != nil {
+			return false
+		}
+	}
+	return true
+}
 
-	// Format as a Data URI.
-	return fmt.Sprintf("data:image/jpeg;base64,%s", base64Str), nil
+// writeFileAtomic writes data through a temporary file, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// PruneThumbnails deletes thumbnail files whose hash is not in keep, e.g. covers of chapters
+// that were removed or changed. Files modified after before are left alone, as they may belong
+// to a chapter whose thumbnail was stored after keep was listed; so are files that are not
+// thumbnails, like the temporary files of thumbnails being written. It returns how many
+// thumbnails were deleted.
+func PruneThumbnails(dir string, keep map[string]bool, before time.Time) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	removed := map[string]bool{}
+	for _, entry := range entries {
+		if !entry.IsDir() || len(entry.Name()) != 2 {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return len(removed), err
+		}
+		for _, file := range files {
+			hash, ok := thumbnailFileHash(file.Name())
+			if !ok || keep[hash] {
+				continue
+			}
+			info, err := file.Info()
+			if err != nil || info.ModTime().After(before) {
+				continue
+			}
+			if err := os.Remove(filepath.Join(dir, entry.Name(), file.Name())); err != nil {
+				return len(removed), err
+			}
+			removed[hash] = true
+		}
+	}
+	return len(removed), nil
+}
+
+// thumbnailFileHash returns the hash of a thumbnail file named "<hash>-<size>.jpg".
+func thumbnailFileHash(name string) (string, bool) {
+	hash, rest, ok := strings.Cut(name, "-")
+	size, isJPEG := strings.CutSuffix(rest, ".jpg")
+	if !ok || !isJPEG {
+		return "", false
+	}
+	if _, valid := ThumbnailPath("", hash, size); !valid {
+		return "", false
+	}
+	return hash, true
 }
 
-// ThumbnailForChapterFile builds a data-URI thumbnail from the first page of a chapter file.
-func ThumbnailForChapterFile(ctx context.Context, path string) (string, error) {
+// ThumbnailForChapterFile stores a thumbnail of the first page of a chapter file under dir and
+// returns its URL.
+func ThumbnailForChapterFile(ctx context.Context, dir, path string) (string, error) {
 	_, first, err := chapterfiles.InspectChapterFile(ctx, path)
 	if err != nil {
 		return "", err
@@ -58,5 +207,5 @@ func ThumbnailForChapterFile(ctx context.Context, path string) (string, error) {
 	if len(first) == 0 {
 		return "", fmt.Errorf("no first page bytes for thumbnail")
 	}
-	return GenerateThumbnail(first)
+	return GenerateThumbnail(dir, first)
 }
@@ -0,0 +1,86 @@
+// This file moves thumbnails stored as base64 data URIs in the database into thumbnail files.
+
+package library
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/vrsandeep/mango-go/internal/jobs"
+	"github.com/vrsandeep/mango-go/internal/store"
+)
+
+// MigrateThumbnails converts chapter and folder thumbnails that are still inline data URIs into
+// thumbnail files, then shrinks the database file. Unreadable data URIs are cleared; running
+// RegenerateThumbnails afterwards rebuilds them, and sharper large variants, from the chapter files.
+func MigrateThumbnails(ctx jobs.JobContext) {
+	jobId := "migrate-thumbnails"
+	sendProgress(ctx, jobId, "Migrating thumbnails...", 0, false)
+	st := store.New(ctx.DB())
+	thumbnailDir := ctx.Config().ThumbnailDir()
+
+	total, err := st.CountDataURIThumbnails()
+	if err != nil {
+		log.Printf("Error counting thumbnails to migrate: %v", err)
+		sendProgress(ctx, jobId, "Thumbnail migration failed.", 100, true)
+		return
+	}
+
+	migrated := 0
+	batches := []struct {
+		list   func(limit int) ([]store.ThumbnailRef, error)
+		update func(id int64, thumbnail string) error
+	}{
+		{st.ListChapterDataURIThumbnails, st.UpdateChapterThumbnail},
+		{st.ListFolderDataURIThumbnails, st.UpdateFolderThumbnail},
+	}
+	for _, batch := range batches {
+		for {
+			// Every row is rewritten, so each query returns the next batch.
+			refs, err := batch.list(500)
+			if err != nil {
+				log.Printf("Error listing thumbnails to migrate: %v", err)
+				break
+			}
+			if len(refs) == 0 {
+				break
+			}
+			for _, ref := range refs {
+				thumbnail, err := migrateDataURI(thumbnailDir, ref.Thumbnail)
+				if err != nil {
+					log.Printf("Clearing unreadable thumbnail (id %d): %v", ref.ID, err)
+				}
+				if err := batch.update(ref.ID, thumbnail); err != nil {
+					log.Printf("Error saving migrated thumbnail (id %d): %v", ref.ID, err)
+					return
+				}
+				migrated++
+				progress := float64(migrated) / float64(total) * 90
+				sendProgress(ctx, jobId, fmt.Sprintf("Migrated thumbnail %d/%d", migrated, total), progress, false)
+			}
+		}
+	}
+
+	if migrated > 0 {
+		sendProgress(ctx, jobId, "Compacting database...", 95, false)
+		if err := st.Vacuum(); err != nil {
+			log.Printf("Error compacting database after thumbnail migration: %v", err)
+		}
+	}
+	sendProgress(ctx, jobId, fmt.Sprintf("Migrated %d thumbnails.", migrated), 100, true)
+}
+
+// migrateDataURI stores the image in a base64 data URI as a thumbnail file and returns its URL.
+func migrateDataURI(thumbnailDir, dataURI string) (string, error) {
+	meta, payload, ok := strings.Cut(strings.TrimPrefix(dataURI, "data:"), ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return "", fmt.Errorf("not a base64 data URI")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", err
+	}
+	return GenerateThumbnail(thumbnailDir, data)
+}
@@ -0,0 +1,47 @@
+package library_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/library"
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestMigrateThumbnails(t *testing.T) {
+	app := testutil.SetupTestApp(t)
+	st := store.New(app.DB())
+	dataURI := "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNkYAAAAAYAAjCB0C8AAAAASUVORK5CYII="
+
+	folder, _ := st.CreateFolder("/A", "Series A", nil)
+	st.UpdateFolderThumbnail(folder.ID, dataURI)
+	good, _ := st.CreateChapter(folder.ID, "/A/ch1.cbz", "h1", 10, dataURI)
+	broken, _ := st.CreateChapter(folder.ID, "/A/ch2.cbz", "h2", 10, "data:image/jpeg;base64,!!!")
+
+	library.MigrateThumbnails(&testutil.MockJobContext{App: app})
+
+	chapter, _ := st.GetChapterByID(good.ID, 1)
+	hash, ok := library.ThumbnailHash(chapter.Thumbnail)
+	if !ok {
+		t.Fatalf("Expected the chapter thumbnail to be a thumbnail URL, got %.40s", chapter.Thumbnail)
+	}
+	if path, _ := library.ThumbnailPath(app.Config().ThumbnailDir(), hash, "small"); !fileExists(path) {
+		t.Error("Expected the thumbnail file to exist")
+	}
+	if f, _ := st.GetFolder(folder.ID); f.Thumbnail != chapter.Thumbnail {
+		t.Errorf("Expected the folder to share the chapter's thumbnail, got %.40s", f.Thumbnail)
+	}
+	if c, _ := st.GetChapterByID(broken.ID, 1); c.Thumbnail != "" {
+		t.Errorf("Expected an unreadable thumbnail to be cleared, got %q", c.Thumbnail)
+	}
+	if count, _ := st.CountDataURIThumbnails(); count != 0 {
+		t.Errorf("Expected no data URIs to be left, got %d", count)
+	}
+
+	// Running it again finds nothing to do
+	library.MigrateThumbnails(&testutil.MockJobContext{App: app})
+	if c, _ := st.GetChapterByID(good.ID, 1); !strings.HasPrefix(c.Thumbnail, library.ThumbnailURLPrefix) {
+		t.Errorf("Expected the thumbnail to be kept, got %q", c.Thumbnail)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"math"
 	"path/filepath"
+	"time"
 
 	"github.com/vrsandeep/mango-go/internal/jobs"
 	"github.com/vrsandeep/mango-go/internal/models"
@@ -19,6 +20,7 @@ func RegenerateThumbnails(ctx jobs.JobContext) {
 	jobId := "regen-thumbnails"
 	sendProgress(ctx, jobId, "Regenerating thumbnails...", 0, false)
 	st := store.New(ctx.DB())
+	thumbnailDir := ctx.Config().ThumbnailDir()
 
 	// Set the thumbnail for all chapters
 	limit := 1000
@@ -35,13 +37,14 @@ func RegenerateThumbnails(ctx jobs.JobContext) {
 		if len(chapters) == 0 {
 			break
 		}
-		updateChaptersThumbnails(ctx, jobId, st, chapters, offset, totalChapters)
+		updateChaptersThumbnails(ctx, jobId, st, thumbnailDir, chapters, offset, totalChapters)
 		offset += limit
 	}
 
 	// Set the thumbnail for all folders
 	sendProgress(ctx, jobId, "Updating folders thumbnails...", 90, false)
 	st.UpdateAllFolderThumbnails()
+	pruneThumbnails(st, thumbnailDir)
 
 	sendProgress(ctx, jobId, "Thumbnail regeneration complete.", 100, true)
 }
@@ -51,12 +54,13 @@ func updateChaptersThumbnails(
 	ctx jobs.JobContext,
 	jobId string,
 	st *store.Store,
+	thumbnailDir string,
 	chapters []*models.Chapter,
 	offset,
 	totalChapters int,
 ) {
 	for i, chapter := range chapters {
-		thumbnail, err := ThumbnailForChapterFile(context.Background(), chapter.Path)
+		thumbnail, err := ThumbnailForChapterFile(context.Background(), thumbnailDir, chapter.Path)
 		if err != nil {
 			log.Printf("Error regenerating thumbnail for chapter %s (id %d): %v", chapter.Path, chapter.ID, err)
 			continue
@@ -69,3 +73,30 @@ func updateChaptersThumbnails(
 		sendProgress(ctx, jobId, fmt.Sprintf("Updating chapter thumbnail %d/%d: %s", currentProgress, totalChapters, filepath.Base(chapter.Path)), progress, false)
 	}
 }
+
+// thumbnailPruneGrace is how old a thumbnail file must be before it can be pruned. Scans and the
+// file watcher store a thumbnail's file before its chapter, so newer files may be about to be used.
+const thumbnailPruneGrace = time.Minute
+
+// pruneThumbnails deletes thumbnail files no chapter or folder uses any more.
+func pruneThumbnails(st *store.Store, thumbnailDir string) {
+	before := time.Now().Add(-thumbnailPruneGrace)
+	inUse, err := st.ThumbnailsInUse()
+	if err != nil {
+		log.Printf("Error listing thumbnails in use: %v", err)
+		return
+	}
+	keep := make(map[string]bool, len(inUse))
+	for thumbnail := range inUse {
+		if hash, ok := ThumbnailHash(thumbnail); ok {
+			keep[hash] = true
+		}
+	}
+	removed, err := PruneThumbnails(thumbnailDir, keep, before)
+	if err != nil {
+		log.Printf("Error pruning thumbnails: %v", err)
+	}
+	if removed > 0 {
+		log.Printf("Deleted %d unused thumbnails", removed)
+	}
+}
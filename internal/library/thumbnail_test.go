@@ -1,9 +1,15 @@
 package library_test
 
 import (
+	"bytes"
 	"encoding/base64"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/vrsandeep/mango-go/internal/library"
 )
@@ -12,25 +18,103 @@ func TestGenerateThumbnail(t *testing.T) {
 	// A valid 1x1 PNG, base64 encoded.
 	validPngB64 := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNkYAAAAAYAAjCB0C8AAAAASUVORK5CYII="
 	pngData, _ := base64.StdEncoding.DecodeString(validPngB64)
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 800, 1200)))
+	pagePng := buf.Bytes()
+	dir := t.TempDir()
 
 	t.Run("Success case", func(t *testing.T) {
-		thumb, err := library.GenerateThumbnail(pngData)
+		thumb, err := library.GenerateThumbnail(dir, pagePng)
 		if err != nil {
 			t.Fatalf("GenerateThumbnail failed with valid data: %v", err)
 		}
-		if !strings.HasPrefix(thumb, "data:image/jpeg;base64,") {
-			t.Errorf("Generated thumbnail is not a valid data URI, got: %s", thumb)
+		hash, ok := library.ThumbnailHash(thumb)
+		if !ok || !strings.HasPrefix(thumb, library.ThumbnailURLPrefix) {
+			t.Fatalf("Generated thumbnail is not a thumbnail URL, got: %s", thumb)
 		}
-		if len(thumb) < 50 {
-			t.Errorf("Generated thumbnail seems too short: %s", thumb)
+		for _, size := range library.ThumbnailSizes {
+			path, _ := library.ThumbnailPath(dir, hash, size.Name)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Missing %s thumbnail: %v", size.Name, err)
+			}
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil || format != "jpeg" || cfg.Width != int(size.Width) || cfg.Height != int(size.Height) {
+				t.Errorf("Expected a %dx%d jpeg for %s, got %dx%d %s (%v)", size.Width, size.Height, size.Name, cfg.Width, cfg.Height, format, err)
+			}
+		}
+
+		again, err := library.GenerateThumbnail(dir, pagePng)
+		if err != nil || again != thumb {
+			t.Errorf("Expected the same image to give the same thumbnail, got %s (%v)", again, err)
+		}
+	})
+
+	t.Run("Small images are not enlarged", func(t *testing.T) {
+		thumb, err := library.GenerateThumbnail(dir, pngData)
+		if err != nil {
+			t.Fatalf("GenerateThumbnail failed with valid data: %v", err)
+		}
+		hash, _ := library.ThumbnailHash(thumb)
+		path, _ := library.ThumbnailPath(dir, hash, "large")
+		data, _ := os.ReadFile(path)
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil || cfg.Width != 1 {
+			t.Errorf("Expected a 1px wide thumbnail, got %d (%v)", cfg.Width, err)
 		}
 	})
 
 	t.Run("Error case with invalid data", func(t *testing.T) {
 		invalidData := []byte("this is not an image")
-		_, err := library.GenerateThumbnail(invalidData)
+		_, err := library.GenerateThumbnail(dir, invalidData)
 		if err == nil {
 			t.Error("GenerateThumbnail should have failed with invalid data, but it did not")
 		}
 	})
+
+	t.Run("Rejects bad hashes and sizes", func(t *testing.T) {
+		for _, hash := range []string{"", "../../etc/passwd", strings.Repeat("A", 64), strings.Repeat("a", 63)} {
+			if _, ok := library.ThumbnailPath(dir, hash, "small"); ok {
+				t.Errorf("Expected hash %q to be rejected", hash)
+			}
+		}
+		if _, ok := library.ThumbnailPath(dir, strings.Repeat("a", 64), "huge"); ok {
+			t.Error("Expected an unknown size to be rejected")
+		}
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		keep, _ := library.GenerateThumbnail(dir, pagePng)
+		keepHash, _ := library.ThumbnailHash(keep)
+		unusedPath, _ := library.ThumbnailPath(dir, strings.Repeat("b", 64), "small")
+		os.MkdirAll(filepath.Dir(unusedPath), 0755)
+		os.WriteFile(unusedPath, []byte("new"), 0644)
+		// A thumbnail being written, next to the kept one
+		keepPath, _ := library.ThumbnailPath(dir, keepHash, "small")
+		tmpPath := filepath.Join(filepath.Dir(keepPath), ".tmp-123")
+		os.WriteFile(tmpPath, []byte("partial"), 0644)
+
+		// Files written after the cutoff may be about to be used
+		if removed, err := library.PruneThumbnails(dir, map[string]bool{keepHash: true}, time.Now().Add(-time.Hour)); err != nil || removed != 0 {
+			t.Fatalf("Expected nothing to be pruned, got %d (%v)", removed, err)
+		}
+
+		removed, err := library.PruneThumbnails(dir, map[string]bool{keepHash: true}, time.Now().Add(time.Second))
+		if err != nil || removed != 2 {
+			t.Fatalf("Expected 2 thumbnails to be pruned, got %d (%v)", removed, err)
+		}
+		if !fileExists(keepPath) {
+			t.Error("Expected the kept thumbnail to stay")
+		}
+		if fileExists(unusedPath) {
+			t.Error("Expected the unused thumbnail to be deleted")
+		}
+		if !fileExists(tmpPath) {
+			t.Error("Expected temporary files to be left alone")
+		}
+	})
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
@@ -23,7 +23,7 @@ func sendProgress(ctx jobs.JobContext, jobId string, message string, progress fl
 		Progress: progress,
 		Done:     done,
 	}
-	ctx.WsHub().BroadcastJSON(update)
+	ctx.WsHub().BroadcastJSONTo(models.PermRunJobs, update)
 }
 
 // isTestEnvironment checks if we're running in a test environment
@@ -142,7 +142,7 @@ func (w *WatcherService) handleEvent(event fsnotify.Event) {
 		return
 	}
 
-	// For file events, only trigger on supported archive files
+	// For file events, only trigger on chapter files and images
 	if !isDir && w.isRelevantFile(event.Name) {
 		w.mu.Lock()
 		w.changedPaths[event.Name] = true
@@ -161,9 +161,10 @@ func (w *WatcherService) handleEvent(event fsnotify.Event) {
 
 // isRelevantFile checks if a path is a relevant file (not a directory) for library scanning.
 func (w *WatcherService) isRelevantFile(path string) bool {
-	// Only trigger on actual archive files, not directories
+	// Only trigger on chapter files and images (pages of image-directory chapters), not directories
 	// This prevents triggering scans when folders are opened/accessed
-	return chapterfiles.IsSupportedChapterFile(filepath.Base(path))
+	base := filepath.Base(path)
+	return chapterfiles.IsSupportedChapterFile(base) || chapterfiles.IsImageFile(base)
 }
 
 // TriggerIncrementalScanForPath manually triggers an incremental scan for a specific path.
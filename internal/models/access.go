@@ -0,0 +1,22 @@
+// Per-user limits on which parts of the library are visible.
+
+package models
+
+// ContentRestrictions limits the folders a user can see. With RestrictFolders set, only the
+// top-level folders in FolderIDs (and everything below them) are visible. Folders carrying a
+// tag in HiddenTags are hidden along with everything below them.
+//
+// Grants are kept by folder path. MissingFolders lists the paths of granted folders that are
+// not in the library at the moment, e.g. while they are moved or renamed; they are visible
+// again once a folder with that path is scanned. They are kept when sent back on update.
+type ContentRestrictions struct {
+	RestrictFolders bool     `json:"restrict_folders"`
+	FolderIDs       []int64  `json:"folder_ids"`
+	MissingFolders  []string `json:"missing_folders"`
+	HiddenTags      []string `json:"hidden_tags"`
+}
+
+// IsRestricted reports whether any restriction applies.
+func (r *ContentRestrictions) IsRestricted() bool {
+	return r.RestrictFolders || len(r.HiddenTags) > 0
+}
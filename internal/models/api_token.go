@@ -0,0 +1,22 @@
+// Personal API tokens for scripts and third-party clients.
+
+package models
+
+import "time"
+
+// APIToken describes a personal API token. The token itself is only returned once,
+// when it is created; the database keeps a hash of it.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	ReadOnly   bool       `json:"read_only"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// Expired reports whether the token has passed its expiry time.
+func (t *APIToken) Expired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
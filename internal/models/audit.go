@@ -0,0 +1,19 @@
+// Audit log of administrative and destructive actions.
+
+package models
+
+import "time"
+
+// AuditEntry records one administrative or destructive action. ActorID is nil for actions not
+// made by a signed-in user, and Status is the HTTP status the request was answered with.
+type AuditEntry struct {
+	ID            int64     `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	ActorID       *int64    `json:"actor_id"`
+	ActorUsername string    `json:"actor_username"`
+	IPAddress     string    `json:"ip_address"`
+	Action        string    `json:"action"`
+	Target        string    `json:"target"`
+	Payload       string    `json:"payload"`
+	Status        int       `json:"status"`
+}
@@ -25,4 +25,11 @@ type DownloadQueueItem struct {
 	LocalChapterID    *int64    `json:"local_chapter_id,omitempty"`
 	LocalFolderID     *int64    `json:"local_folder_id,omitempty"`
 	CreatedAt         time.Time `json:"created_at"`
+	// Chapter metadata from the provider's ChapterResult, written into the CBZ's ComicInfo.xml
+	Volume        string     `json:"volume,omitempty"`
+	ChapterNumber string     `json:"chapter_number,omitempty"`
+	Language      string     `json:"language,omitempty"`
+	GroupID       string     `json:"group_id,omitempty"`
+	GroupName     string     `json:"group_name,omitempty"`
+	PublishedAt   *time.Time `json:"published_at,omitempty"`
 }
@@ -0,0 +1,16 @@
+// Failed login attempts, recorded for throttling and for admins to review.
+
+package models
+
+import "time"
+
+// FailedLogin is one rejected login attempt. UserID is nil when the username did not match
+// an account.
+type FailedLogin struct {
+	ID          int64     `json:"id"`
+	Username    string    `json:"username"`
+	UserID      *int64    `json:"user_id"`
+	IPAddress   string    `json:"ip_address"`
+	UserAgent   string    `json:"user_agent"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
@@ -0,0 +1,18 @@
+// Invite codes that let people register themselves.
+
+package models
+
+import "time"
+
+// Invite lets up to MaxUses people register with Role until it expires. The code itself is only
+// shown when the invite is created.
+type Invite struct {
+	ID                int64     `json:"id"`
+	Role              string    `json:"role"`
+	MaxUses           int       `json:"max_uses"`
+	UseCount          int       `json:"use_count"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	CreatedBy         *int64    `json:"created_by"`
+	CreatedByUsername string    `json:"created_by_username"`
+	CreatedAt         time.Time `json:"created_at"`
+}
@@ -7,11 +7,19 @@ import "time"
 
 // User represents a user account.
 type User struct {
-	ID           int64     `json:"id"`
-	Username     string    `json:"username"`
-	PasswordHash string    `json:"-"` // Never expose password hash
-	Role         string    `json:"role"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            int64     `json:"id"`
+	Username      string    `json:"username"`
+	PasswordHash  string    `json:"-"` // Never expose password hash
+	KOSyncKeyHash string    `json:"-"` // Hash of the KOReader sync key; empty until the password is next seen
+	Role          string    `json:"role"`
+	TOTPEnabled   bool      `json:"totp_enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	// Permissions are the permissions granted by the user's role (set when loading a single user).
+	Permissions []string `json:"permissions,omitempty"`
+	// LockedUntil is set (by ListUsers) while the account is locked after failed logins.
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	// Pending is set for self-registered accounts waiting for an admin's approval.
+	Pending bool `json:"pending,omitempty"`
 }
 
 // Folder represents a directory in the user's library.
@@ -44,9 +52,32 @@ type Chapter struct {
 	PageCount   int       `json:"page_count"`
 	CreatedAt   time.Time `json:"created_at"` // `json:"-"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	// Modification time and size of the chapter file when it was last scanned
+	FileMtime *time.Time `json:"-"`
+	FileSize  *int64     `json:"-"`
 	// Per-user progress
 	Read            bool `json:"read"`
 	ProgressPercent int  `json:"progress_percent"`
+	// Metadata from an embedded ComicInfo.xml, if the chapter file has one
+	ComicInfo *ComicInfo `json:"comic_info,omitempty"`
+}
+
+// ComicInfo holds the chapter metadata parsed from a ComicInfo.xml file.
+type ComicInfo struct {
+	Series      string `json:"series,omitempty"`
+	Number      string `json:"number,omitempty"` // e.g. "10.5"; ComicInfo stores numbers as text
+	Volume      *int   `json:"volume,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Writer      string `json:"writer,omitempty"`
+	Genre       string `json:"genre,omitempty"`
+	LanguageISO string `json:"language_iso,omitempty"`
+	Manga       string `json:"manga,omitempty"` // "Yes", "No" or "YesAndRightToLeft"
+	PageCount   int    `json:"page_count,omitempty"`
+}
+
+// RightToLeft reports whether the chapter is marked as read right-to-left.
+func (c *ComicInfo) RightToLeft() bool {
+	return c != nil && c.Manga == "YesAndRightToLeft"
 }
 
 // Page represents a single page within a chapter, which is an image
@@ -24,6 +24,7 @@ type ChapterResult struct {
 	Pages       int       `json:"pages"`
 	Language    string    `json:"language"`
 	GroupID     string    `json:"group_id"`
+	GroupName   string    `json:"group_name"` // Scanlation group, for display and ComicInfo.xml
 	PublishedAt time.Time `json:"published_at"`
 }
 
@@ -0,0 +1,71 @@
+// Roles and the permissions they grant.
+
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Permissions that can be granted to a role.
+const (
+	PermReadLibrary     = "read_library"     // browse and read the library, track progress, OPDS and KOReader sync
+	PermManageDownloads = "manage_downloads" // search providers, queue downloads and manage subscriptions
+	PermManageTags      = "manage_tags"      // add and remove folder tags
+	PermUploadCovers    = "upload_covers"    // upload folder covers and link AniList entries
+	PermManagePlugins   = "manage_plugins"   // install, reload and remove plugins and plugin repositories
+	PermManageUsers     = "manage_users"     // manage users, roles and failed logins
+	PermRunJobs         = "run_jobs"         // run library jobs and manage bad files
+)
+
+// AllPermissions lists every permission, in the order they are shown to admins.
+var AllPermissions = []string{
+	PermReadLibrary,
+	PermManageDownloads,
+	PermManageTags,
+	PermUploadCovers,
+	PermManagePlugins,
+	PermManageUsers,
+	PermRunJobs,
+}
+
+// Built-in roles. The admin role always has every permission and cannot be changed.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Role is a named set of permissions.
+type Role struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	BuiltIn     bool      `json:"built_in"`
+	UserCount   int       `json:"user_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// IsValidPermission reports whether p is a known permission.
+func IsValidPermission(p string) bool {
+	return slices.Contains(AllPermissions, p)
+}
+
+// HasPermission reports whether the user's role grants any of the given permissions.
+func (u *User) HasPermission(perms ...string) bool {
+	for _, p := range perms {
+		if slices.Contains(u.Permissions, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAllPermissions reports whether the user's role grants every one of the given permissions.
+func (u *User) HasAllPermissions(perms ...string) bool {
+	for _, p := range perms {
+		if !slices.Contains(u.Permissions, p) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,18 @@
+// Login sessions held by browsers.
+
+package models
+
+import "time"
+
+// Session describes a login session. The token is only ever sent to the browser that
+// logged in, so it is not part of the JSON.
+type Session struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // Set when listing, for the session making the request
+}
@@ -0,0 +1,255 @@
+// Package outbound enforces the policy for HTTP requests the server makes on behalf of plugins,
+// the downloader and the resource proxy, so they cannot be used to reach internal services.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/config"
+)
+
+var (
+	ErrBlockedDestination = errors.New("destination is not allowed")
+	ErrResponseTooLarge   = errors.New("response is too large")
+)
+
+// Address ranges that are blocked besides the ones the net package classifies as private,
+// loopback, link-local, multicast or unspecified.
+var extraBlockedNets = mustParseCIDRs(
+	"0.0.0.0/8",     // "this network"
+	"100.64.0.0/10", // carrier-grade NAT, used for some cloud metadata services
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+)
+
+// Policy decides which destinations may be fetched and how large responses may be.
+// Checks happen when connecting, after DNS resolution, so redirects and hostnames that
+// resolve to internal addresses are caught too. A Policy is safe for concurrent use.
+type Policy struct {
+	allowHosts   []string
+	denyHosts    []string
+	allowNets    []*net.IPNet
+	denyNets     []*net.IPNet
+	maxBytes     int64
+	maxRedirects int
+
+	resolver  *net.Resolver
+	dialer    *net.Dialer
+	transport *http.Transport
+}
+
+// NewPolicy builds a policy from the outbound configuration. Allow and deny entries that
+// parse as a CIDR or IP address match addresses; anything else is a host name, where
+// "*.example.com" matches every subdomain of example.com.
+func NewPolicy(cfg config.OutboundConfig) *Policy {
+	p := &Policy{
+		maxBytes:     cfg.MaxResponseBytes(),
+		maxRedirects: cfg.RedirectLimit(),
+		resolver:     net.DefaultResolver,
+		dialer:       &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+	}
+	p.allowHosts, p.allowNets = parseRules(cfg.Allow)
+	p.denyHosts, p.denyNets = parseRules(cfg.Deny)
+	p.transport = &http.Transport{
+		// Environment proxies are ignored: the policy has to see the real destination.
+		Proxy:                 nil,
+		DialContext:           p.dialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	return p
+}
+
+var (
+	defaultPolicy     *Policy
+	defaultPolicyOnce sync.Once
+)
+
+// Default returns a policy with the default limits and no allow or deny entries.
+func Default() *Policy {
+	defaultPolicyOnce.Do(func() {
+		defaultPolicy = NewPolicy(config.OutboundConfig{})
+	})
+	return defaultPolicy
+}
+
+// Client returns an HTTP client that enforces the policy, with the given overall timeout.
+func (p *Policy) Client(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport:     p.transport,
+		Timeout:       timeout,
+		CheckRedirect: p.checkRedirect,
+	}
+}
+
+// ReadBody reads a response body, failing with ErrResponseTooLarge if it exceeds the cap.
+func (p *Policy) ReadBody(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, p.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > p.maxBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, p.maxBytes)
+	}
+	return data, nil
+}
+
+// CheckURL rejects URLs that are not http(s) or whose host is denied. Hosts given as IP
+// addresses are checked against the address rules too; names are checked when connecting.
+func (p *Policy) CheckURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrBlockedDestination, u.Scheme)
+	}
+	host := normalizeHost(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrBlockedDestination)
+	}
+	if matchHost(p.denyHosts, host) {
+		return fmt.Errorf("%w: %s is denied", ErrBlockedDestination, host)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return p.checkIP(host, ip, matchHost(p.allowHosts, host))
+	}
+	return nil
+}
+
+func (p *Policy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > p.maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", p.maxRedirects)
+	}
+	return p.CheckURL(req.URL)
+}
+
+// dialContext resolves the host itself and connects to the first permitted address, so the
+// address that was checked is the one used (a second lookup could return something else).
+func (p *Policy) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	host = normalizeHost(host)
+	if matchHost(p.denyHosts, host) {
+		return nil, fmt.Errorf("%w: %s is denied", ErrBlockedDestination, host)
+	}
+	hostAllowed := matchHost(p.allowHosts, host)
+
+	addrs, err := p.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var lastErr error
+	for _, a := range addrs {
+		if err := p.checkIP(host, a.IP, hostAllowed); err != nil {
+			lastErr = err
+			continue
+		}
+		conn, err := p.dialer.DialContext(ctx, network, net.JoinHostPort(a.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no addresses found for %s", host)
+	}
+	return nil, lastErr
+}
+
+func (p *Policy) checkIP(host string, ip net.IP, hostAllowed bool) error {
+	if containsIP(p.denyNets, ip) {
+		return fmt.Errorf("%w: %s (%s) is denied", ErrBlockedDestination, host, ip)
+	}
+	if hostAllowed || containsIP(p.allowNets, ip) {
+		return nil
+	}
+	if IsInternalIP(ip) {
+		return fmt.Errorf("%w: %s resolves to internal address %s", ErrBlockedDestination, host, ip)
+	}
+	return nil
+}
+
+// IsInternalIP reports whether ip is a private, loopback, link-local (including the cloud
+// metadata address 169.254.169.254), multicast, unspecified or otherwise non-public address.
+func IsInternalIP(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		containsIP(extraBlockedNets, ip)
+}
+
+func parseRules(entries []string) (hosts []string, nets []*net.IPNet) {
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			nets = append(nets, ipNet)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv6len
+			if v4 := ip.To4(); v4 != nil {
+				ip, bits = v4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		hosts = append(hosts, normalizeHost(entry))
+	}
+	return hosts, nets
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// matchHost reports whether host equals a rule or, for "*.example.com" rules, is a subdomain.
+func matchHost(rules []string, host string) bool {
+	for _, rule := range rules {
+		if suffix, ok := strings.CutPrefix(rule, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+		} else if host == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
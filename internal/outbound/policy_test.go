@@ -0,0 +1,114 @@
+package outbound
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/config"
+)
+
+func TestIsInternalIP(t *testing.T) {
+	internal := []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"100.100.100.200", "0.0.0.0", "::1", "fe80::1", "fd00:ec2::254", "::ffff:127.0.0.1", "::",
+	}
+	for _, s := range internal {
+		if !IsInternalIP(net.ParseIP(s)) {
+			t.Errorf("Expected %s to be internal", s)
+		}
+	}
+	for _, s := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		if IsInternalIP(net.ParseIP(s)) {
+			t.Errorf("Expected %s to be public", s)
+		}
+	}
+}
+
+func TestPolicyBlocksInternalDestinations(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(target.URL, "http://"))
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer redirector.Close()
+	_, redirectPort, _ := net.SplitHostPort(strings.TrimPrefix(redirector.URL, "http://"))
+
+	get := func(p *Policy, rawURL string) error {
+		resp, err := p.Client(5 * time.Second).Get(rawURL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(NewPolicy(config.OutboundConfig{}), target.URL); !errors.Is(err, ErrBlockedDestination) {
+		t.Errorf("Expected loopback to be blocked by default, got %v", err)
+	}
+	if err := get(NewPolicy(config.OutboundConfig{Allow: []string{"127.0.0.0/8"}}), target.URL); err != nil {
+		t.Errorf("Expected an allowed CIDR to be reachable, got %v", err)
+	}
+	if err := get(NewPolicy(config.OutboundConfig{Allow: []string{"localhost"}}), "http://localhost:"+port); err != nil {
+		t.Errorf("Expected an allowed host to be reachable, got %v", err)
+	}
+
+	// Deny wins over allow
+	p := NewPolicy(config.OutboundConfig{Allow: []string{"127.0.0.0/8"}, Deny: []string{"127.0.0.1"}})
+	if err := get(p, target.URL); !errors.Is(err, ErrBlockedDestination) {
+		t.Errorf("Expected a denied address to be blocked, got %v", err)
+	}
+	p = NewPolicy(config.OutboundConfig{Allow: []string{"127.0.0.0/8"}, Deny: []string{"*.localhost", "localhost"}})
+	if err := get(p, "http://localhost:"+port); !errors.Is(err, ErrBlockedDestination) {
+		t.Errorf("Expected a denied host to be blocked, got %v", err)
+	}
+
+	// A permitted host cannot redirect to a blocked one
+	p = NewPolicy(config.OutboundConfig{Allow: []string{"localhost"}})
+	if err := get(p, "http://localhost:"+redirectPort); !errors.Is(err, ErrBlockedDestination) {
+		t.Errorf("Expected the redirect to a blocked address to fail, got %v", err)
+	}
+	p = NewPolicy(config.OutboundConfig{Allow: []string{"127.0.0.1"}, MaxRedirects: 1})
+	if err := get(p, redirector.URL); err != nil {
+		t.Errorf("Expected one allowed redirect to be followed, got %v", err)
+	}
+}
+
+func TestPolicyCheckURL(t *testing.T) {
+	p := NewPolicy(config.OutboundConfig{Deny: []string{"*.example.org"}})
+	cases := map[string]bool{
+		"https://example.com/a.jpg":        true,
+		"https://cdn.example.org/a.jpg":    false,
+		"file:///etc/passwd":               false,
+		"http://169.254.169.254/latest/":   false,
+		"http://[::1]:8080/":               false,
+		"http://internal.example.com:8080": true, // names are checked when connecting
+	}
+	for raw, ok := range cases {
+		u, _ := url.Parse(raw)
+		err := p.CheckURL(u)
+		if ok && err != nil {
+			t.Errorf("Expected %s to pass, got %v", raw, err)
+		}
+		if !ok && !errors.Is(err, ErrBlockedDestination) {
+			t.Errorf("Expected %s to be blocked, got %v", raw, err)
+		}
+	}
+}
+
+func TestPolicyReadBody(t *testing.T) {
+	p := NewPolicy(config.OutboundConfig{MaxResponseMB: 1})
+	if _, err := p.ReadBody(strings.NewReader(strings.Repeat("x", 1<<20))); err != nil {
+		t.Errorf("Expected a body at the cap to be read, got %v", err)
+	}
+	if _, err := p.ReadBody(strings.NewReader(strings.Repeat("x", 1<<20+1))); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("Expected a body over the cap to fail, got %v", err)
+	}
+}
@@ -243,6 +243,7 @@ func (a *PluginProviderAdapter) jsToChapterResults(val goja.Value) []models.Chap
 			Pages:       pages,
 			Language:    a.getStringFromMap(itemMap, "language"),
 			GroupID:     a.getStringFromMap(itemMap, "group_id"),
+			GroupName:   a.getStringFromMap(itemMap, "group_name"),
 			PublishedAt: publishedAt,
 		}
 		chapters = append(chapters, chapter)
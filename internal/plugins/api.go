@@ -15,7 +15,9 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"github.com/antchfx/xpath"
 	"github.com/dop251/goja"
+	"github.com/vrsandeep/mango-go/internal/auth"
 	"github.com/vrsandeep/mango-go/internal/core"
+	"github.com/vrsandeep/mango-go/internal/store"
 	"golang.org/x/net/html"
 )
 
@@ -28,6 +30,9 @@ type MangoAPI struct {
 	stateMu    sync.RWMutex
 	stateDirty bool
 	vm         *goja.Runtime // Current VM context
+
+	signerMu sync.Mutex
+	signer   *auth.URLSigner // loaded on first use by proxyURL
 }
 
 // NewMangoAPI creates a new Mango API instance for a plugin.
@@ -86,6 +91,7 @@ func (m *MangoAPI) Inject(vm *goja.Runtime) {
 	utilsObj.Set("querySelector", m.querySelector)
 	utilsObj.Set("querySelectorAll", m.querySelectorAll)
 	utilsObj.Set("xpath", m.xpathQuery)
+	utilsObj.Set("proxyURL", m.proxyURL)
 	mango.Set("utils", utilsObj)
 
 	vm.Set("mango", mango)
@@ -128,9 +134,7 @@ func (m *MangoAPI) httpGet(call goja.FunctionCall) goja.Value {
 		}
 	}
 
-	client := &http.Client{
-		Timeout: timeout,
-	}
+	client := m.app.Outbound().Client(timeout)
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -159,7 +163,7 @@ func (m *MangoAPI) httpGet(call goja.FunctionCall) goja.Value {
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := m.app.Outbound().ReadBody(resp.Body)
 	if err != nil {
 		vm.Interrupt(fmt.Sprintf("HTTP GET error: failed to read response body from '%s': %v", url, err))
 		return goja.Undefined()
@@ -214,9 +218,7 @@ func (m *MangoAPI) httpPost(call goja.FunctionCall) goja.Value {
 		}
 	}
 
-	client := &http.Client{
-		Timeout: timeout,
-	}
+	client := m.app.Outbound().Client(timeout)
 
 	var body io.Reader
 	contentType := "application/json"
@@ -258,7 +260,7 @@ func (m *MangoAPI) httpPost(call goja.FunctionCall) goja.Value {
 	}
 	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	respBody, err := m.app.Outbound().ReadBody(resp.Body)
 	if err != nil {
 		vm.Interrupt(fmt.Sprintf("HTTP POST error: failed to read response body from '%s': %v", url, err))
 		return goja.Undefined()
@@ -381,6 +383,64 @@ func (m *MangoAPI) sanitizeFilename(call goja.FunctionCall) goja.Value {
 	return m.vm.ToValue(sanitized)
 }
 
+// proxyURL returns a signed URL that fetches a resource through the server's resource proxy,
+// for images the browser cannot load directly (e.g. because they need a Referer).
+// Options: referer, userAgent, origin, headers (object) and ttl (seconds, default 24 hours).
+func (m *MangoAPI) proxyURL(call goja.FunctionCall) goja.Value {
+	vm := m.vm
+	req := auth.ResourceProxyRequest{URL: call.Argument(0).String()}
+	if goja.IsUndefined(call.Argument(0)) || req.URL == "" {
+		vm.Interrupt("proxyURL error: URL is required")
+		return goja.Undefined()
+	}
+
+	var ttl time.Duration
+	if len(call.Arguments) > 1 && !goja.IsUndefined(call.Argument(1)) {
+		options, _ := call.Argument(1).ToObject(vm).Export().(map[string]interface{})
+		req.Referer, _ = options["referer"].(string)
+		req.UserAgent, _ = options["userAgent"].(string)
+		req.Origin, _ = options["origin"].(string)
+		if headers, ok := options["headers"].(map[string]interface{}); ok {
+			req.Headers = make(map[string]string, len(headers))
+			for k, v := range headers {
+				req.Headers[k] = fmt.Sprint(v)
+			}
+		}
+		switch v := options["ttl"].(type) {
+		case float64:
+			ttl = time.Duration(v * float64(time.Second))
+		case int64:
+			ttl = time.Duration(v) * time.Second
+		}
+	}
+
+	signer, err := m.urlSigner()
+	if err != nil {
+		vm.Interrupt(fmt.Sprintf("proxyURL error: failed to load signing key: %v", err))
+		return goja.Undefined()
+	}
+	proxyURL, err := signer.ResourceProxyURL(req, ttl)
+	if err != nil {
+		vm.Interrupt(fmt.Sprintf("proxyURL error: %v", err))
+		return goja.Undefined()
+	}
+	return vm.ToValue(proxyURL)
+}
+
+// urlSigner loads the resource proxy signing key from the database on first use.
+func (m *MangoAPI) urlSigner() (*auth.URLSigner, error) {
+	m.signerMu.Lock()
+	defer m.signerMu.Unlock()
+	if m.signer == nil {
+		key, err := store.New(m.app.DB()).GetOrCreateSecret(store.ResourceProxySecret, 32)
+		if err != nil {
+			return nil, err
+		}
+		m.signer = auth.NewURLSigner(key)
+	}
+	return m.signer, nil
+}
+
 // GoToJS converts a Go value to a goja value (exported for use in runtime).
 func (m *MangoAPI) GoToJS(vm *goja.Runtime, v interface{}) goja.Value {
 	return m.goToJS(vm, v)
@@ -5,14 +5,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/vrsandeep/mango-go/internal/auth"
+	"github.com/vrsandeep/mango-go/internal/config"
 	"github.com/vrsandeep/mango-go/internal/downloader/providers"
 	"github.com/vrsandeep/mango-go/internal/plugins"
+	"github.com/vrsandeep/mango-go/internal/store"
 	"github.com/vrsandeep/mango-go/internal/testutil"
 )
 
@@ -566,6 +570,44 @@ exports.getPageURLs = async () => [];
 		}
 	})
 
+	t.Run("HTTP Client Blocks Internal Addresses", func(t *testing.T) {
+		mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"title": "Internal"}`))
+		}))
+		defer mockServer.Close()
+
+		pluginDir := t.TempDir()
+		pluginJS := `
+exports.search = async (query, mango) => {
+	const response = await mango.http.get("` + mockServer.URL + `/test");
+	return [{ title: response.data.title, identifier: "1", cover_url: "" }];
+};
+exports.getChapters = async () => [];
+exports.getPageURLs = async () => [];
+`
+		if err := os.WriteFile(filepath.Join(pluginDir, "index.js"), []byte(pluginJS), 0644); err != nil {
+			t.Fatalf("Failed to write plugin: %v", err)
+		}
+		app := testutil.SetupTestApp(t)
+		app.SetConfig(&config.Config{}) // default outbound policy: loopback is blocked
+		runtime, err := plugins.NewPluginRuntime(app, &plugins.PluginManifest{
+			ID:         "test-plugin",
+			Name:       "Test Plugin",
+			Version:    "1.0.0",
+			PluginType: "downloader",
+			EntryPoint: "index.js",
+			APIVersion: "1.0",
+		}, pluginDir)
+		if err != nil {
+			t.Fatalf("Failed to create runtime: %v", err)
+		}
+
+		_, err = plugins.NewPluginProviderAdapter(runtime).Search("test")
+		if err == nil || !strings.Contains(err.Error(), "not allowed") {
+			t.Errorf("Expected the request to a loopback address to be blocked, got %v", err)
+		}
+	})
+
 	t.Run("HTTP Client with Timeout", func(t *testing.T) {
 		// Setup mock HTTP server with delay
 		mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -742,6 +784,55 @@ exports.getPageURLs = async () => [];
 			t.Errorf("Expected title 'Nested Title', got '%s'", results[0].Title)
 		}
 	})
+
+	t.Run("Proxy URL", func(t *testing.T) {
+		pluginDir := t.TempDir()
+		pluginJS := `
+exports.search = async (query, mango) => {
+	const cover = mango.utils.proxyURL("https://example.com/cover.jpg", { referer: "https://example.com/", ttl: 60 });
+	return [{ title: "Proxied", identifier: "1", cover_url: cover }];
+};
+exports.getChapters = async () => [];
+exports.getPageURLs = async () => [];
+`
+		if err := os.WriteFile(filepath.Join(pluginDir, "index.js"), []byte(pluginJS), 0644); err != nil {
+			t.Fatalf("Failed to write plugin: %v", err)
+		}
+		app := testutil.SetupTestApp(t)
+		runtime, err := plugins.NewPluginRuntime(app, &plugins.PluginManifest{
+			ID:         "test-plugin",
+			Name:       "Test Plugin",
+			Version:    "1.0.0",
+			PluginType: "downloader",
+			EntryPoint: "index.js",
+			APIVersion: "1.0",
+		}, pluginDir)
+		if err != nil {
+			t.Fatalf("Failed to create runtime: %v", err)
+		}
+
+		results, err := plugins.NewPluginProviderAdapter(runtime).Search("test")
+		if err != nil {
+			t.Fatalf("Search() failed: %v", err)
+		}
+		u, err := url.Parse(results[0].CoverURL)
+		if err != nil || u.Path != auth.ResourceProxyPath {
+			t.Fatalf("Expected a resource proxy URL, got %q", results[0].CoverURL)
+		}
+		if u.Query().Get("url") != "https://example.com/cover.jpg" || u.Query().Get("referer") != "https://example.com/" {
+			t.Errorf("Unexpected proxy parameters %v", u.Query())
+		}
+
+		// The URL is signed with the server's key and honours the requested TTL
+		key, _ := store.New(app.DB()).GetOrCreateSecret(store.ResourceProxySecret, 32)
+		signer := auth.NewURLSigner(key)
+		if err := signer.Verify(u.Path, u.Query(), time.Now()); err != nil {
+			t.Errorf("Expected a valid signature, got %v", err)
+		}
+		if err := signer.Verify(u.Path, u.Query(), time.Now().Add(2*time.Minute)); err == nil {
+			t.Error("Expected the URL to expire after its TTL")
+		}
+	})
 }
 
 func TestPluginLoader(t *testing.T) {
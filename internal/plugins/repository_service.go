@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -24,15 +23,14 @@ type RepositoryService struct {
 	client  *http.Client
 }
 
-// NewRepositoryService creates a new repository service
+// NewRepositoryService creates a new repository service. Repositories are fetched under the
+// outbound request policy, like every other request made on behalf of plugins.
 func NewRepositoryService(app *core.App, storeInstance *store.Store, manager PluginManagerInterface) *RepositoryService {
 	return &RepositoryService{
 		app:     app,
 		store:   storeInstance,
 		manager: manager,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:  app.Outbound().Client(30 * time.Second),
 	}
 }
 
@@ -48,7 +46,7 @@ func (rs *RepositoryService) FetchRepository(url string) (*models.RepositoryMani
 		return nil, fmt.Errorf("repository returned status %d", resp.StatusCode)
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	data, err := rs.app.Outbound().ReadBody(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read repository data: %w", err)
 	}
@@ -305,7 +303,7 @@ func (rs *RepositoryService) downloadFile(url string) ([]byte, error) {
 		return nil, fmt.Errorf("download returned status %d", resp.StatusCode)
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	data, err := rs.app.Outbound().ReadBody(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
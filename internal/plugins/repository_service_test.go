@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/vrsandeep/mango-go/internal/config"
 	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/outbound"
 	"github.com/vrsandeep/mango-go/internal/plugins"
 	"github.com/vrsandeep/mango-go/internal/store"
 	"github.com/vrsandeep/mango-go/internal/testutil"
@@ -160,6 +161,32 @@ func TestRepositoryService_FetchRepository(t *testing.T) {
 			t.Error("Expected error for 404")
 		}
 	})
+
+	t.Run("Outbound Policy", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"version":"1.0","plugins":[]}`))
+		}))
+		defer server.Close()
+
+		// Without the loopback allowance, the local test server is off limits
+		app := testutil.SetupTestApp(t)
+		app.SetConfig(&config.Config{})
+		repoService := plugins.NewRepositoryService(app, store.New(app.DB()), new(MockPluginManagerForRepo))
+		if _, err := repoService.FetchRepository(server.URL); err == nil {
+			t.Error("Expected a repository on a loopback address to be refused")
+		}
+
+		// Oversized manifests are refused
+		app.SetConfig(&config.Config{Outbound: config.OutboundConfig{Allow: testutil.LoopbackOutbound.Allow, MaxResponseMB: 1}})
+		big := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write(make([]byte, 2<<20))
+		}))
+		defer big.Close()
+		repoService = plugins.NewRepositoryService(app, store.New(app.DB()), new(MockPluginManagerForRepo))
+		if _, err := repoService.FetchRepository(big.URL); !errors.Is(err, outbound.ErrResponseTooLarge) {
+			t.Errorf("Expected an oversized manifest to be refused, got %v", err)
+		}
+	})
 }
 
 func TestRepositoryService_GetAvailablePlugins(t *testing.T) {
@@ -260,6 +287,7 @@ func TestRepositoryService_InstallPlugin(t *testing.T) {
 				Path          string `mapstructure:"path"`
 				UnloadTimeout int    `mapstructure:"unload_timeout"`
 			}{Path: pluginDir, UnloadTimeout: 30},
+			Outbound: testutil.LoopbackOutbound,
 		})
 
 		storeInstance := store.New(app.DB())
@@ -442,6 +470,7 @@ exports.getPageURLs = async () => [];
 				Path          string `mapstructure:"path"`
 				UnloadTimeout int    `mapstructure:"unload_timeout"`
 			}{Path: pluginDir, UnloadTimeout: 30},
+			Outbound: testutil.LoopbackOutbound,
 		})
 
 		storeInstance := store.New(app.DB())
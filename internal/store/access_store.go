@@ -0,0 +1,253 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+var ErrNotTopLevelFolder = errors.New("only top-level folders can be granted")
+
+// GetContentRestrictions returns the folder and tag restrictions for a user.
+func (s *Store) GetContentRestrictions(userID int64) (*models.ContentRestrictions, error) {
+	r := &models.ContentRestrictions{FolderIDs: []int64{}, MissingFolders: []string{}, HiddenTags: []string{}}
+	if err := s.db.QueryRow("SELECT restrict_folders FROM users WHERE id = ?", userID).Scan(&r.RestrictFolders); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT a.folder_path, f.id FROM user_folder_access a
+		LEFT JOIN folders f ON f.path = a.folder_path AND f.parent_id IS NULL
+		WHERE a.user_id = ? ORDER BY f.id, a.folder_path`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var path string
+		var id sql.NullInt64
+		if err := rows.Scan(&path, &id); err != nil {
+			return nil, err
+		}
+		if id.Valid {
+			r.FolderIDs = append(r.FolderIDs, id.Int64)
+		} else {
+			r.MissingFolders = append(r.MissingFolders, path)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tagRows, err := s.db.Query("SELECT tag_name FROM user_hidden_tags WHERE user_id = ? ORDER BY tag_name", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var name string
+		if err := tagRows.Scan(&name); err != nil {
+			return nil, err
+		}
+		r.HiddenTags = append(r.HiddenTags, name)
+	}
+	return r, nil
+}
+
+// SetContentRestrictions replaces a user's restrictions. Granted folders must be top-level
+// folders, and are stored by path along with the missing folders; hidden tag names are
+// normalized the same way tags are when they are added.
+func (s *Store) SetContentRestrictions(userID int64, r *models.ContentRestrictions) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET restrict_folders = ? WHERE id = ?", r.RestrictFolders, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_folder_access WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, folderID := range r.FolderIDs {
+		var topLevel bool
+		var path string
+		err := tx.QueryRow("SELECT parent_id IS NULL, path FROM folders WHERE id = ?", folderID).Scan(&topLevel, &path)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrFolderNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if !topLevel {
+			return ErrNotTopLevelFolder
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO user_folder_access (user_id, folder_path) VALUES (?, ?)", userID, path); err != nil {
+			return err
+		}
+	}
+	for _, path := range r.MissingFolders {
+		if path == "" {
+			continue
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO user_folder_access (user_id, folder_path) VALUES (?, ?)", userID, path); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM user_hidden_tags WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, name := range r.HiddenTags {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO user_hidden_tags (user_id, tag_name) VALUES (?, ?)", userID, name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// hasContentRestrictions reports whether the user's folder access is limited in any way, and
+// whether that includes a folder allow-list. Unknown users (e.g. ID 0 for background jobs)
+// are not restricted.
+func (s *Store) hasContentRestrictions(userID int64) (restricted, restrictFolders, hideTags bool, err error) {
+	err = s.db.QueryRow("SELECT restrict_folders, EXISTS(SELECT 1 FROM user_hidden_tags WHERE user_id = ?) FROM users WHERE id = ?",
+		userID, userID).Scan(&restrictFolders, &hideTags)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, false, nil
+	}
+	return restrictFolders || hideTags, restrictFolders, hideTags, err
+}
+
+// notHiddenByTag matches folders (the %s column) without any of the user's hidden tags.
+const notHiddenByTag = `NOT EXISTS (
+	SELECT 1 FROM folder_tags ft
+	JOIN tags t ON t.id = ft.tag_id
+	JOIN user_hidden_tags h ON h.tag_name = t.name
+	WHERE ft.folder_id = %s AND h.user_id = ?)`
+
+// folderFilter returns a SQL condition limiting column, which holds a folder ID, to the folders
+// the user can see, along with its arguments. It is "1=1" with no arguments for users without
+// restrictions, and always has arguments otherwise.
+func (s *Store) folderFilter(userID int64, column string) (string, []interface{}, error) {
+	restricted, restrictFolders, hideTags, err := s.hasContentRestrictions(userID)
+	if err != nil || !restricted {
+		return "1=1", nil, err
+	}
+
+	// Walk down from the visible top-level folders, stopping at folders with a hidden tag.
+	var args []interface{}
+	root, anchorHidden, recursiveHidden := "1=1", "1=1", "1=1"
+	if restrictFolders {
+		root = "f.path IN (SELECT folder_path FROM user_folder_access WHERE user_id = ?)"
+		args = append(args, userID)
+	}
+	if hideTags {
+		anchorHidden = fmt.Sprintf(notHiddenByTag, "f.id")
+		recursiveHidden = fmt.Sprintf(notHiddenByTag, "sub.id")
+		args = append(args, userID, userID)
+	}
+
+	cond := fmt.Sprintf(`%s IN (
+		WITH RECURSIVE visible_folders(id) AS (
+			SELECT f.id FROM folders f WHERE f.parent_id IS NULL AND %s AND %s
+			UNION ALL
+			SELECT sub.id FROM folders sub JOIN visible_folders v ON sub.parent_id = v.id WHERE %s
+		)
+		SELECT id FROM visible_folders)`, column, root, anchorHidden, recursiveHidden)
+	return cond, args, nil
+}
+
+// CanAccessFolder reports whether a folder is visible to the user. Users without restrictions
+// can access everything, so whether the folder exists is only checked for restricted users.
+func (s *Store) CanAccessFolder(userID, folderID int64) (bool, error) {
+	cond, args, err := s.folderFilter(userID, "f.id")
+	if err != nil || args == nil {
+		return err == nil, err
+	}
+	var ok bool
+	err = s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM folders f WHERE f.id = ? AND "+cond+")",
+		append([]interface{}{folderID}, args...)...).Scan(&ok)
+	return ok, err
+}
+
+// CanAccessChapter reports whether a chapter's folder is visible to the user, in the same way
+// as CanAccessFolder.
+func (s *Store) CanAccessChapter(userID, chapterID int64) (bool, error) {
+	cond, args, err := s.folderFilter(userID, "c.folder_id")
+	if err != nil || args == nil {
+		return err == nil, err
+	}
+	var ok bool
+	err = s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM chapters c WHERE c.id = ? AND "+cond+")",
+		append([]interface{}{chapterID}, args...)...).Scan(&ok)
+	return ok, err
+}
+
+// CanAccessThumbnail reports whether a thumbnail, as stored on chapters and folders, belongs to
+// at least one chapter or folder visible to the user, in the same way as CanAccessFolder.
+func (s *Store) CanAccessThumbnail(userID int64, thumbnail string) (bool, error) {
+	chapterCond, chapterArgs, err := s.folderFilter(userID, "c.folder_id")
+	if err != nil || chapterArgs == nil {
+		return err == nil, err
+	}
+	folderCond, folderArgs, err := s.folderFilter(userID, "f.id")
+	if err != nil {
+		return false, err
+	}
+	query := `SELECT EXISTS(SELECT 1 FROM chapters c WHERE c.thumbnail = ? AND ` + chapterCond + `)
+		OR EXISTS(SELECT 1 FROM folders f WHERE f.thumbnail = ? AND ` + folderCond + `)`
+	args := append([]interface{}{thumbnail}, chapterArgs...)
+	args = append(append(args, thumbnail), folderArgs...)
+	var ok bool
+	err = s.db.QueryRow(query, args...).Scan(&ok)
+	return ok, err
+}
+
+// CanAccessTag reports whether a tag is visible to the user: for restricted users it must not
+// be one of their hidden tags and must be on at least one visible folder.
+func (s *Store) CanAccessTag(userID, tagID int64) (bool, error) {
+	cond, args, err := s.folderFilter(userID, "ft.folder_id")
+	if err != nil || args == nil {
+		return err == nil, err
+	}
+	query := `SELECT EXISTS(
+		SELECT 1 FROM tags t JOIN folder_tags ft ON ft.tag_id = t.id
+		WHERE t.id = ? AND t.name NOT IN (SELECT tag_name FROM user_hidden_tags WHERE user_id = ?) AND ` + cond + `)`
+	var ok bool
+	err = s.db.QueryRow(query, append([]interface{}{tagID, userID}, args...)...).Scan(&ok)
+	return ok, err
+}
+
+// VisibleFolderIDs returns the set of folders the user can see, or nil if they are not restricted.
+func (s *Store) VisibleFolderIDs(userID int64) (map[int64]bool, error) {
+	restricted, _, _, err := s.hasContentRestrictions(userID)
+	if err != nil || !restricted {
+		return nil, err
+	}
+	cond, args, err := s.folderFilter(userID, "f.id")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query("SELECT f.id FROM folders f WHERE "+cond, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	visible := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		visible[id] = true
+	}
+	return visible, rows.Err()
+}
@@ -0,0 +1,177 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestContentRestrictions(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	s := store.New(db)
+
+	user, _ := s.CreateUser("reader", "hash", "user")
+	other, _ := s.CreateUser("other", "hash", "user")
+
+	// Library: A (with A/V1 tagged "mature"), B
+	fA, _ := s.CreateFolder("/A", "Series A", nil)
+	fAV1, _ := s.CreateFolder("/A/V1", "Vol 1", &fA.ID)
+	fAV2, _ := s.CreateFolder("/A/V2", "Vol 2", &fA.ID)
+	fB, _ := s.CreateFolder("/B", "Series B", nil)
+	chAV1, _ := s.CreateChapter(fAV1.ID, "/A/V1/ch1.cbz", "h_av1", 10, "")
+	chAV2, _ := s.CreateChapter(fAV2.ID, "/A/V2/ch1.cbz", "h_av2", 10, "")
+	chB, _ := s.CreateChapter(fB.ID, "/B/ch1.cbz", "h_b", 10, "")
+	mature, _ := s.AddTagToFolder(fAV1.ID, "Mature")
+	action, _ := s.AddTagToFolder(fB.ID, "action")
+
+	t.Run("Defaults", func(t *testing.T) {
+		r, err := s.GetContentRestrictions(user.ID)
+		if err != nil {
+			t.Fatalf("GetContentRestrictions failed: %v", err)
+		}
+		if r.IsRestricted() || len(r.FolderIDs) != 0 || len(r.HiddenTags) != 0 {
+			t.Errorf("Expected no restrictions, got %+v", r)
+		}
+		if ok, _ := s.CanAccessChapter(user.ID, chAV1.ID); !ok {
+			t.Error("Expected unrestricted user to access every chapter")
+		}
+	})
+
+	t.Run("Only top-level folders can be granted", func(t *testing.T) {
+		err := s.SetContentRestrictions(user.ID, &models.ContentRestrictions{RestrictFolders: true, FolderIDs: []int64{fAV1.ID}})
+		if !errors.Is(err, store.ErrNotTopLevelFolder) {
+			t.Errorf("Expected ErrNotTopLevelFolder, got %v", err)
+		}
+		err = s.SetContentRestrictions(user.ID, &models.ContentRestrictions{RestrictFolders: true, FolderIDs: []int64{9999}})
+		if !errors.Is(err, store.ErrFolderNotFound) {
+			t.Errorf("Expected ErrFolderNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Folder allow-list", func(t *testing.T) {
+		err := s.SetContentRestrictions(user.ID, &models.ContentRestrictions{RestrictFolders: true, FolderIDs: []int64{fA.ID}})
+		if err != nil {
+			t.Fatalf("SetContentRestrictions failed: %v", err)
+		}
+
+		if ok, _ := s.CanAccessFolder(user.ID, fAV2.ID); !ok {
+			t.Error("Expected subfolder of a granted folder to be visible")
+		}
+		if ok, _ := s.CanAccessFolder(user.ID, fB.ID); ok {
+			t.Error("Expected folder outside the allow-list to be hidden")
+		}
+		if ok, _ := s.CanAccessChapter(user.ID, chB.ID); ok {
+			t.Error("Expected chapter outside the allow-list to be hidden")
+		}
+		if ok, _ := s.CanAccessChapter(other.ID, chB.ID); !ok {
+			t.Error("Expected other users to be unaffected")
+		}
+
+		root := int64(0)
+		_, folders, _, total, err := s.ListItems(store.ListItemsOptions{UserID: user.ID, ParentID: &root, Page: 1, PerPage: 50})
+		if err != nil {
+			t.Fatalf("ListItems failed: %v", err)
+		}
+		if total != 1 || len(folders) != 1 || folders[0].ID != fA.ID {
+			t.Errorf("Expected only Series A at the root, got %d items", total)
+		}
+		if _, _, _, _, err := s.ListItems(store.ListItemsOptions{UserID: user.ID, ParentID: &fB.ID, Page: 1, PerPage: 50}); !errors.Is(err, store.ErrFolderNotFound) {
+			t.Errorf("Expected ErrFolderNotFound listing a hidden folder, got %v", err)
+		}
+
+		results, _ := s.SearchFoldersByName(user.ID, "Series", 10)
+		if len(results) != 1 {
+			t.Errorf("Expected search to return 1 folder, got %d", len(results))
+		}
+
+		s.UpdateChapterThumbnail(chB.ID, "/api/thumbnails/b")
+		s.UpdateFolderThumbnail(fA.ID, "/api/thumbnails/a")
+		if ok, _ := s.CanAccessThumbnail(user.ID, "/api/thumbnails/b"); ok {
+			t.Error("Expected the cover of a hidden chapter to be hidden")
+		}
+		if ok, _ := s.CanAccessThumbnail(user.ID, "/api/thumbnails/a"); !ok {
+			t.Error("Expected the cover of a visible folder to be visible")
+		}
+		if ok, _ := s.CanAccessThumbnail(other.ID, "/api/thumbnails/b"); !ok {
+			t.Error("Expected other users to see every cover")
+		}
+
+		// The tag on B is not on any visible folder
+		if ok, _ := s.CanAccessTag(user.ID, action.ID); ok {
+			t.Error("Expected tag only on hidden folders to be hidden")
+		}
+		tags, _ := s.ListTagsWithCounts(user.ID)
+		if len(tags) != 1 || tags[0].ID != mature.ID {
+			t.Errorf("Expected only the 'mature' tag, got %d tags", len(tags))
+		}
+	})
+
+	t.Run("Hidden tags hide the folder subtree", func(t *testing.T) {
+		err := s.SetContentRestrictions(user.ID, &models.ContentRestrictions{HiddenTags: []string{" MATURE "}})
+		if err != nil {
+			t.Fatalf("SetContentRestrictions failed: %v", err)
+		}
+		r, _ := s.GetContentRestrictions(user.ID)
+		if r.RestrictFolders || len(r.HiddenTags) != 1 || r.HiddenTags[0] != "mature" {
+			t.Errorf("Expected normalized hidden tag without a folder allow-list, got %+v", r)
+		}
+
+		if ok, _ := s.CanAccessFolder(user.ID, fAV1.ID); ok {
+			t.Error("Expected folder with a hidden tag to be hidden")
+		}
+		if ok, _ := s.CanAccessChapter(user.ID, chAV1.ID); ok {
+			t.Error("Expected chapter below a hidden tag to be hidden")
+		}
+		if ok, _ := s.CanAccessChapter(user.ID, chAV2.ID); !ok {
+			t.Error("Expected sibling folder's chapter to stay visible")
+		}
+		if ok, _ := s.CanAccessTag(user.ID, mature.ID); ok {
+			t.Error("Expected hidden tag to be inaccessible")
+		}
+		tags, _ := s.ListTagsWithCounts(user.ID)
+		if len(tags) != 1 || tags[0].ID != action.ID {
+			t.Errorf("Expected only the 'action' tag, got %d tags", len(tags))
+		}
+
+		visible, _ := s.VisibleFolderIDs(user.ID)
+		if visible[fAV1.ID] || !visible[fAV2.ID] || !visible[fB.ID] {
+			t.Errorf("Unexpected visible folders: %v", visible)
+		}
+	})
+
+	t.Run("Home sections", func(t *testing.T) {
+		s.UpdateChapterProgress(chAV1.ID, user.ID, 50, false)
+		s.UpdateChapterProgress(chAV2.ID, user.ID, 50, false)
+
+		items, err := s.GetContinueReading(user.ID, 10)
+		if err != nil {
+			t.Fatalf("GetContinueReading failed: %v", err)
+		}
+		if len(items) != 1 || *items[0].ChapterID != chAV2.ID {
+			t.Errorf("Expected only the visible chapter in Continue Reading, got %d items", len(items))
+		}
+
+		recent, err := s.GetRecentlyAdded(user.ID, 10)
+		if err != nil {
+			t.Fatalf("GetRecentlyAdded failed: %v", err)
+		}
+		for _, item := range recent {
+			if item.ChapterID != nil && *item.ChapterID == chAV1.ID {
+				t.Error("Expected hidden chapter to be left out of Recently Added")
+			}
+		}
+
+		start, err := s.GetStartReading(user.ID, 10)
+		if err != nil {
+			t.Fatalf("GetStartReading failed: %v", err)
+		}
+		for _, item := range start {
+			if item.SeriesID == fAV1.ID {
+				t.Error("Expected hidden folder to be left out of Start Reading")
+			}
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+var ErrAPITokenNotFound = errors.New("api token not found")
+
+const (
+	// APITokenPrefix marks mango-go tokens so they are easy to recognise in scripts and secret scanners.
+	APITokenPrefix = "mgo_"
+	// apiTokenTouchInterval limits how often a token's last-used time is written, so clients
+	// streaming pages with a token do not turn every request into a database write.
+	apiTokenTouchInterval = time.Minute
+)
+
+// hashAPIToken returns the value stored for a token. Tokens are random and long, so a fast
+// hash is enough to keep a database leak from exposing usable credentials.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIToken creates a personal API token for a user and returns it along with the
+// plaintext token, which is not stored and cannot be retrieved later.
+func (s *Store) CreateAPIToken(userID int64, name string, readOnly bool, expiresAt *time.Time) (*models.APIToken, string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, "", err
+	}
+	token := APITokenPrefix + hex.EncodeToString(tokenBytes)
+
+	now := time.Now()
+	query := "INSERT INTO api_tokens (user_id, name, token_hash, read_only, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)"
+	res, err := s.db.Exec(query, userID, name, hashAPIToken(token), readOnly, now, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	id, _ := res.LastInsertId()
+	return &models.APIToken{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		ReadOnly:  readOnly,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}, token, nil
+}
+
+// ListAPITokens returns a user's API tokens, newest first.
+func (s *Store) ListAPITokens(userID int64) ([]*models.APIToken, error) {
+	query := `SELECT id, user_id, name, read_only, created_at, expires_at, last_used_at
+	          FROM api_tokens WHERE user_id = ? ORDER BY created_at DESC, id DESC`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*models.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// DeleteAPIToken revokes one of a user's tokens. Tokens belonging to other users are
+// reported as not found.
+func (s *Store) DeleteAPIToken(userID, tokenID int64) error {
+	result, err := s.db.Exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", tokenID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// GetUserFromAPIToken retrieves the user a token belongs to, along with the token's details,
+// and records that the token was used, at most once per apiTokenTouchInterval. Unknown and
+// expired tokens are rejected.
+func (s *Store) GetUserFromAPIToken(token string) (*models.User, *models.APIToken, error) {
+	query := `SELECT id, user_id, name, read_only, created_at, expires_at, last_used_at
+	          FROM api_tokens WHERE token_hash = ?`
+	apiToken, err := scanAPIToken(s.db.QueryRow(query, hashAPIToken(token)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, errors.New("invalid api token")
+		}
+		return nil, nil, err
+	}
+	if apiToken.Expired() {
+		return nil, nil, errors.New("api token expired")
+	}
+
+	user, err := s.GetUserByID(apiToken.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if apiToken.LastUsedAt == nil || time.Since(*apiToken.LastUsedAt) >= apiTokenTouchInterval {
+		s.db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", time.Now(), apiToken.ID)
+	}
+	return user, apiToken, nil
+}
+
+func scanAPIToken(row rowScanner) (*models.APIToken, error) {
+	var t models.APIToken
+	var expiresAt, lastUsedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.ReadOnly, &t.CreatedAt, &expiresAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		t.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		t.LastUsedAt = &lastUsedAt.Time
+	}
+	return &t, nil
+}
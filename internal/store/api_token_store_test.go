@@ -0,0 +1,77 @@
+package store_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestAPITokenStore(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	s := store.New(db)
+	user, _ := s.CreateUser("scripter", "hash", "user")
+	other, _ := s.CreateUser("other", "hash", "user")
+
+	apiToken, token, err := s.CreateAPIToken(user.ID, "cli", true, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIToken failed: %v", err)
+	}
+	if !strings.HasPrefix(token, "mgo_") || !apiToken.ReadOnly {
+		t.Errorf("Unexpected token %q (%+v)", token, apiToken)
+	}
+
+	var stored string
+	db.QueryRow("SELECT token_hash FROM api_tokens WHERE id = ?", apiToken.ID).Scan(&stored)
+	if stored == token || stored == "" {
+		t.Error("Expected the token to be stored hashed")
+	}
+
+	got, gotToken, err := s.GetUserFromAPIToken(token)
+	if err != nil || got.ID != user.ID || gotToken.ID != apiToken.ID {
+		t.Fatalf("Expected token to resolve to its user, got %v, %v", got, err)
+	}
+	if _, _, err := s.GetUserFromAPIToken(token + "x"); err == nil {
+		t.Error("Expected an unknown token to be rejected")
+	}
+
+	tokens, _ := s.ListAPITokens(user.ID)
+	if len(tokens) != 1 || tokens[0].LastUsedAt == nil {
+		t.Fatalf("Expected one used token, got %+v", tokens)
+	}
+
+	// Using the token again straight away does not write its last-used time
+	recent := time.Now().Add(-time.Second).Truncate(time.Second)
+	db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", recent, apiToken.ID)
+	s.GetUserFromAPIToken(token)
+	tokens, _ = s.ListAPITokens(user.ID)
+	if !tokens[0].LastUsedAt.Equal(recent) {
+		t.Errorf("Expected last_used_at to stay %v, got %v", recent, tokens[0].LastUsedAt)
+	}
+	stale := time.Now().Add(-time.Hour)
+	db.Exec("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", stale, apiToken.ID)
+	s.GetUserFromAPIToken(token)
+	tokens, _ = s.ListAPITokens(user.ID)
+	if tokens[0].LastUsedAt.Before(recent) {
+		t.Errorf("Expected a stale last_used_at to be updated, got %v", tokens[0].LastUsedAt)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	_, expired, _ := s.CreateAPIToken(user.ID, "old", false, &past)
+	if _, _, err := s.GetUserFromAPIToken(expired); err == nil {
+		t.Error("Expected an expired token to be rejected")
+	}
+
+	if err := s.DeleteAPIToken(other.ID, apiToken.ID); !errors.Is(err, store.ErrAPITokenNotFound) {
+		t.Errorf("Expected another user's token to be not found, got %v", err)
+	}
+	if err := s.DeleteAPIToken(user.ID, apiToken.ID); err != nil {
+		t.Fatalf("DeleteAPIToken failed: %v", err)
+	}
+	if _, _, err := s.GetUserFromAPIToken(token); err == nil {
+		t.Error("Expected a revoked token to be rejected")
+	}
+}
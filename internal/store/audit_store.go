@@ -0,0 +1,110 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+// AuditFilter narrows ListAuditEntries. Zero fields are ignored.
+type AuditFilter struct {
+	Actor  string // actor username
+	Action string // exact action, or a prefix ending in "." such as "user."
+	Target string // substring of the target
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+// RecordAudit appends an entry to the audit log. CreatedAt defaults to now.
+func (s *Store) RecordAudit(entry *models.AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	var actorID interface{}
+	if entry.ActorID != nil {
+		actorID = *entry.ActorID
+	}
+	result, err := s.db.Exec(`INSERT INTO audit_log (created_at, actor_id, actor_username, ip_address, action, target, payload, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.CreatedAt.UTC(), actorID, entry.ActorUsername, entry.IPAddress, entry.Action, entry.Target, entry.Payload, entry.Status)
+	if err != nil {
+		return err
+	}
+	entry.ID, err = result.LastInsertId()
+	return err
+}
+
+// ListAuditEntries returns audit log entries matching the filter, newest first, along with the
+// total number of matches.
+func (s *Store) ListAuditEntries(filter AuditFilter) ([]*models.AuditEntry, int, error) {
+	var conds []string
+	var args []interface{}
+	if filter.Actor != "" {
+		conds = append(conds, "actor_username = ?")
+		args = append(args, filter.Actor)
+	}
+	if strings.HasSuffix(filter.Action, ".") {
+		conds = append(conds, "substr(action, 1, ?) = ?")
+		args = append(args, len(filter.Action), filter.Action)
+	} else if filter.Action != "" {
+		conds = append(conds, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Target != "" {
+		conds = append(conds, "instr(target, ?) > 0")
+		args = append(args, filter.Target)
+	}
+	if !filter.Since.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, created_at, actor_id, actor_username, ip_address, action, target, payload, status
+		FROM audit_log` + where + " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+	rows, err := s.db.Query(query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []*models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var actorID sql.NullInt64
+		if err := rows.Scan(&entry.ID, &entry.CreatedAt, &actorID, &entry.ActorUsername, &entry.IPAddress,
+			&entry.Action, &entry.Target, &entry.Payload, &entry.Status); err != nil {
+			return nil, 0, err
+		}
+		if actorID.Valid {
+			entry.ActorID = &actorID.Int64
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, total, rows.Err()
+}
+
+// PurgeAuditLog deletes audit log entries older than a time and returns how many were removed.
+func (s *Store) PurgeAuditLog(before time.Time) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM audit_log WHERE created_at < ?", before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,92 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestAuditLog(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	s := store.New(db)
+
+	adminID := int64(1)
+	now := time.Now()
+	entries := []*models.AuditEntry{
+		{CreatedAt: now.Add(-48 * time.Hour), ActorID: &adminID, ActorUsername: "admin", Action: "user.create", Target: "", Payload: `{"username":"bob"}`, Status: 201},
+		{CreatedAt: now.Add(-2 * time.Hour), ActorID: &adminID, ActorUsername: "admin", Action: "user.delete", Target: "userID=7", Status: 204},
+		{CreatedAt: now.Add(-1 * time.Hour), ActorUsername: "alice", IPAddress: "10.0.0.2", Action: "plugin.unload", Target: "pluginID=mangadex", Status: 200},
+	}
+	for _, entry := range entries {
+		if err := s.RecordAudit(entry); err != nil {
+			t.Fatalf("RecordAudit failed: %v", err)
+		}
+		if entry.ID == 0 {
+			t.Error("Expected RecordAudit to set the entry ID")
+		}
+	}
+
+	t.Run("Filters", func(t *testing.T) {
+		cases := []struct {
+			name   string
+			filter store.AuditFilter
+			want   int
+		}{
+			{"all", store.AuditFilter{}, 3},
+			{"actor", store.AuditFilter{Actor: "admin"}, 2},
+			{"exact action", store.AuditFilter{Action: "user.delete"}, 1},
+			{"action prefix", store.AuditFilter{Action: "user."}, 2},
+			{"target", store.AuditFilter{Target: "mangadex"}, 1},
+			{"since", store.AuditFilter{Since: now.Add(-24 * time.Hour)}, 2},
+			{"until", store.AuditFilter{Until: now.Add(-24 * time.Hour)}, 1},
+		}
+		for _, tc := range cases {
+			tc.filter.Limit = 10
+			got, total, err := s.ListAuditEntries(tc.filter)
+			if err != nil {
+				t.Fatalf("%s: ListAuditEntries failed: %v", tc.name, err)
+			}
+			if len(got) != tc.want || total != tc.want {
+				t.Errorf("%s: expected %d entries, got %d (total %d)", tc.name, tc.want, len(got), total)
+			}
+		}
+	})
+
+	t.Run("Newest first and paginated", func(t *testing.T) {
+		got, total, err := s.ListAuditEntries(store.AuditFilter{Limit: 2, Offset: 1})
+		if err != nil {
+			t.Fatalf("ListAuditEntries failed: %v", err)
+		}
+		if total != 3 || len(got) != 2 {
+			t.Fatalf("Expected 2 of 3 entries, got %d of %d", len(got), total)
+		}
+		if got[0].Action != "user.delete" || got[1].Action != "user.create" {
+			t.Errorf("Unexpected order: %s, %s", got[0].Action, got[1].Action)
+		}
+		if got[0].ActorID == nil || *got[0].ActorID != adminID {
+			t.Errorf("Expected actor ID %d, got %v", adminID, got[0].ActorID)
+		}
+	})
+
+	t.Run("Entries cannot be changed", func(t *testing.T) {
+		if _, err := db.Exec("UPDATE audit_log SET action = 'nothing'"); err == nil {
+			t.Error("Expected updating the audit log to fail")
+		}
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		purged, err := s.PurgeAuditLog(now.Add(-24 * time.Hour))
+		if err != nil {
+			t.Fatalf("PurgeAuditLog failed: %v", err)
+		}
+		if purged != 1 {
+			t.Errorf("Expected 1 entry purged, got %d", purged)
+		}
+		if _, total, _ := s.ListAuditEntries(store.AuditFilter{Limit: 10}); total != 2 {
+			t.Errorf("Expected 2 entries left, got %d", total)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -12,10 +13,48 @@ import (
 var ErrChapterNotFound = errors.New("chapter not found")
 
 type ChapterInfo struct {
-	ID        int64
-	Path      string
-	FileMtime *time.Time // File modification time (nil if not set)
-	FileSize  *int64     // File size in bytes (nil if not set)
+	ID               int64
+	Path             string
+	FileMtime        *time.Time // File modification time (nil if not set)
+	FileSize         *int64     // File size in bytes (nil if not set)
+	ComicInfoChecked bool       // Whether the file has been checked for ComicInfo.xml
+	KOReaderHash     string     // KOReader's partial MD5 of the file (empty if not computed)
+}
+
+// comicInfoColumns are the chapter columns holding ComicInfo.xml metadata, in comicInfoRow order.
+const comicInfoColumns = `c.comicinfo_series, c.comicinfo_number, c.comicinfo_volume, c.comicinfo_title,
+	c.comicinfo_writer, c.comicinfo_genre, c.comicinfo_language_iso, c.comicinfo_manga, c.comicinfo_page_count`
+
+// comicInfoRow scans the nullable comicInfoColumns.
+type comicInfoRow struct {
+	series, number, title, writer, genre, languageISO, manga sql.NullString
+	volume, pageCount                                        sql.NullInt64
+}
+
+func (r *comicInfoRow) dest() []interface{} {
+	return []interface{}{&r.series, &r.number, &r.volume, &r.title, &r.writer, &r.genre, &r.languageISO, &r.manga, &r.pageCount}
+}
+
+// toModel returns the scanned metadata, or nil if the chapter has none.
+func (r *comicInfoRow) toModel() *models.ComicInfo {
+	info := &models.ComicInfo{
+		Series:      r.series.String,
+		Number:      r.number.String,
+		Title:       r.title.String,
+		Writer:      r.writer.String,
+		Genre:       r.genre.String,
+		LanguageISO: r.languageISO.String,
+		Manga:       r.manga.String,
+		PageCount:   int(r.pageCount.Int64),
+	}
+	if r.volume.Valid {
+		v := int(r.volume.Int64)
+		info.Volume = &v
+	}
+	if *info == (models.ComicInfo{}) {
+		return nil
+	}
+	return info
 }
 
 // CreateChapter inserts a new chapter record into the database.
@@ -39,26 +78,40 @@ func (s *Store) CreateChapterWithMetadata(folderID int64, path, hash string, pag
 func (s *Store) GetChapterByID(id int64, userID int64) (*models.Chapter, error) {
 	var chapter models.Chapter
 	var thumb sql.NullString
+	var mtime sql.NullTime
+	var size sql.NullInt64
+	var comicInfo comicInfoRow
 	query := `
 		SELECT c.id, c.folder_id, c.path, c.content_hash, c.page_count,
 		       COALESCE(ucp.read, 0) as read,
 		       COALESCE(ucp.progress_percent, 0) as progress_percent,
 		       c.thumbnail,
 			   c.created_at,
-			   c.updated_at
+			   c.updated_at,
+			   c.file_mtime,
+			   c.file_size,
+			   ` + comicInfoColumns + `
 		FROM chapters c
 		LEFT JOIN user_chapter_progress ucp ON c.id = ucp.chapter_id AND ucp.user_id = ?
 		WHERE c.id = ?
 	`
-	err := s.db.QueryRow(query, userID, id).Scan(
+	dest := []interface{}{
 		&chapter.ID, &chapter.FolderID, &chapter.Path, &chapter.ContentHash, &chapter.PageCount,
 		&chapter.Read, &chapter.ProgressPercent,
-		&thumb, &chapter.CreatedAt, &chapter.UpdatedAt,
-	)
+		&thumb, &chapter.CreatedAt, &chapter.UpdatedAt, &mtime, &size,
+	}
+	err := s.db.QueryRow(query, userID, id).Scan(append(dest, comicInfo.dest()...)...)
 	if err != nil {
 		return nil, err
 	}
 	chapter.Thumbnail = thumb.String
+	if mtime.Valid {
+		chapter.FileMtime = &mtime.Time
+	}
+	if size.Valid {
+		chapter.FileSize = &size.Int64
+	}
+	chapter.ComicInfo = comicInfo.toModel()
 	return &chapter, nil
 }
 
@@ -77,7 +130,7 @@ func (s *Store) GetChapterByDiskPath(diskPath string) (*models.Chapter, error) {
 
 // GetAllChaptersByHash retrieves all chapters and maps them by their content hash for efficient lookup.
 func (s *Store) GetAllChaptersByHash() (map[string]ChapterInfo, error) {
-	rows, err := s.db.Query("SELECT id, path, content_hash, file_mtime, file_size FROM chapters")
+	rows, err := s.db.Query("SELECT id, path, content_hash, file_mtime, file_size, comicinfo_checked, koreader_hash FROM chapters")
 	if err != nil {
 		return nil, err
 	}
@@ -89,9 +142,11 @@ func (s *Store) GetAllChaptersByHash() (map[string]ChapterInfo, error) {
 		var hash sql.NullString
 		var mtime sql.NullTime
 		var size sql.NullInt64
-		if err := rows.Scan(&info.ID, &info.Path, &hash, &mtime, &size); err != nil {
+		var koreaderHash sql.NullString
+		if err := rows.Scan(&info.ID, &info.Path, &hash, &mtime, &size, &info.ComicInfoChecked, &koreaderHash); err != nil {
 			return nil, err
 		}
+		info.KOReaderHash = koreaderHash.String
 		if hash.Valid {
 			if mtime.Valid {
 				info.FileMtime = &mtime.Time
@@ -117,6 +172,53 @@ func (s *Store) UpdateChapterPathWithMetadata(id int64, newPath string, newFolde
 	return err
 }
 
+// UpdateChapterPageCount updates the number of pages recorded for a chapter.
+func (s *Store) UpdateChapterPageCount(id int64, pageCount int) error {
+	_, err := s.db.Exec("UPDATE chapters SET page_count = ?, updated_at = ? WHERE id = ?", pageCount, time.Now(), id)
+	return err
+}
+
+// UpdateChapterContent replaces the content-derived fields of a chapter whose content changed in place.
+func (s *Store) UpdateChapterContent(id int64, hash string, pageCount int, thumbnail string, fileMtime *time.Time, fileSize *int64) error {
+	query := "UPDATE chapters SET content_hash = ?, page_count = ?, thumbnail = ?, file_mtime = ?, file_size = ?, updated_at = ? WHERE id = ?"
+	_, err := s.db.Exec(query, hash, pageCount, thumbnail, fileMtime, fileSize, time.Now(), id)
+	return err
+}
+
+// UpdateChapterComicInfo stores the chapter's ComicInfo.xml metadata (nil clears it) and
+// marks the chapter as checked.
+func (s *Store) UpdateChapterComicInfo(id int64, info *models.ComicInfo) error {
+	if info == nil {
+		info = &models.ComicInfo{}
+	}
+	query := `
+		UPDATE chapters SET
+			comicinfo_series = ?, comicinfo_number = ?, comicinfo_volume = ?, comicinfo_title = ?,
+			comicinfo_writer = ?, comicinfo_genre = ?, comicinfo_language_iso = ?, comicinfo_manga = ?,
+			comicinfo_page_count = ?, comicinfo_checked = true
+		WHERE id = ?
+	`
+	_, err := s.db.Exec(query,
+		nullIfEmpty(info.Series), nullIfEmpty(info.Number), info.Volume, nullIfEmpty(info.Title),
+		nullIfEmpty(info.Writer), nullIfEmpty(info.Genre), nullIfEmpty(info.LanguageISO), nullIfEmpty(info.Manga),
+		nullIfZero(info.PageCount), id)
+	return err
+}
+
+func nullIfEmpty(v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	return v
+}
+
+func nullIfZero(v int) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
 // DeleteChapterByHash removes a chapter from the database using its unique content hash.
 func (s *Store) DeleteChapterByHash(hash string) error {
 	_, err := s.db.Exec("DELETE FROM chapters WHERE content_hash = ?", hash)
@@ -189,6 +291,32 @@ func (s *Store) GetFolderStats(folderID int64, userID int64) (int, int, error) {
 	return totalChapters, readChapters, nil
 }
 
+// chapterSortTitles returns the strings chapters are sorted by naturally, keyed by chapter ID.
+// Chapters sort by their ComicInfo.xml volume and number only when every chapter has one, as
+// "Ch.2" and a file title such as "Chapter 10" do not compare meaningfully; otherwise all of
+// them sort by the title from their path.
+func chapterSortTitles(chapters []*models.Chapter) map[int64]string {
+	useComicInfo := true
+	for _, chapter := range chapters {
+		if chapter.ComicInfo == nil || chapter.ComicInfo.Number == "" {
+			useComicInfo = false
+			break
+		}
+	}
+	titles := make(map[int64]string, len(chapters))
+	for _, chapter := range chapters {
+		switch {
+		case !useComicInfo:
+			titles[chapter.ID] = GetChapterTitle(chapter)
+		case chapter.ComicInfo.Volume != nil:
+			titles[chapter.ID] = fmt.Sprintf("Vol.%d Ch.%s", *chapter.ComicInfo.Volume, chapter.ComicInfo.Number)
+		default:
+			titles[chapter.ID] = "Ch." + chapter.ComicInfo.Number
+		}
+	}
+	return titles
+}
+
 // GetChapterTitle extracts the title from a chapter's path.
 func GetChapterTitle(chapter *models.Chapter) string {
 	// Extract the last part of the path as the title
@@ -197,13 +325,26 @@ func GetChapterTitle(chapter *models.Chapter) string {
 		return ""
 	}
 	title := parts[len(parts)-1]
-	// Remove file extension if present
-	if dotIndex := strings.LastIndex(title, "."); dotIndex != -1 {
+	// Remove file extension if present. A purely numeric suffix is kept, since
+	// image-directory chapters have no extension and may be named e.g. "Chapter 10.5".
+	if dotIndex := strings.LastIndex(title, "."); dotIndex != -1 && !isNumeric(title[dotIndex+1:]) {
 		title = title[:dotIndex]
 	}
 	return title
 }
 
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // GetChapterNeighbors finds the previous and next chapter IDs based on sort settings.
 func (s *Store) GetChapterNeighbors(folderID, currentChapterID, userID int64) (map[string]*int64, error) {
 	var chapters []*models.Chapter
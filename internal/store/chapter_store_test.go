@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/vrsandeep/mango-go/internal/models"
 	"github.com/vrsandeep/mango-go/internal/store"
 	"github.com/vrsandeep/mango-go/internal/testutil"
 )
@@ -213,6 +214,14 @@ func TestChapterStore(t *testing.T) {
 			t.Errorf("Expected title '%s', got '%s'", expectedTitle, title)
 		}
 
+		// Test image directory with a decimal chapter number
+		chapter.Path = "/library/Test Series/Chapter 10.5"
+		title = store.GetChapterTitle(chapter)
+		expectedTitle = "Chapter 10.5"
+		if title != expectedTitle {
+			t.Errorf("Expected title '%s', got '%s'", expectedTitle, title)
+		}
+
 		// Test with empty path
 		chapter.Path = ""
 		title = store.GetChapterTitle(chapter)
@@ -406,3 +415,73 @@ func TestGetChapterByDiskPath(t *testing.T) {
 		}
 	})
 }
+
+func TestChapterComicInfo(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	s := store.New(db)
+	folder, _ := s.CreateFolder("/library/Series A", "Series A", nil)
+
+	// File names disagree with the embedded numbering, which should win when sorting
+	extra, _ := s.CreateChapter(folder.ID, "/library/Series A/a.cbz", "hash-a", 10, "")
+	second, _ := s.CreateChapter(folder.ID, "/library/Series A/b.cbz", "hash-b", 10, "")
+	first, _ := s.CreateChapter(folder.ID, "/library/Series A/c.cbz", "hash-c", 10, "")
+
+	vol := 1
+	infos := map[int64]*models.ComicInfo{
+		first.ID:  {Series: "Series A", Number: "1", Volume: &vol, Manga: "YesAndRightToLeft", PageCount: 10},
+		extra.ID:  {Number: "1.5", Volume: &vol},
+		second.ID: {Number: "2", Volume: &vol},
+	}
+	for id, info := range infos {
+		if err := s.UpdateChapterComicInfo(id, info); err != nil {
+			t.Fatalf("UpdateChapterComicInfo failed: %v", err)
+		}
+	}
+
+	chap, err := s.GetChapterByID(first.ID, 1)
+	if err != nil {
+		t.Fatalf("GetChapterByID failed: %v", err)
+	}
+	if chap.ComicInfo == nil || chap.ComicInfo.Series != "Series A" || chap.ComicInfo.Number != "1" {
+		t.Fatalf("Expected stored ComicInfo, got %+v", chap.ComicInfo)
+	}
+	if chap.ComicInfo.Volume == nil || *chap.ComicInfo.Volume != 1 {
+		t.Errorf("Expected volume 1, got %v", chap.ComicInfo.Volume)
+	}
+	if !chap.ComicInfo.RightToLeft() || chap.ComicInfo.PageCount != 10 {
+		t.Errorf("Expected manga RTL with 10 pages, got %+v", chap.ComicInfo)
+	}
+
+	_, _, chapters, _, err := s.ListItems(store.ListItemsOptions{UserID: 1, ParentID: &folder.ID, Page: 1, PerPage: 10})
+	if err != nil {
+		t.Fatalf("ListItems failed: %v", err)
+	}
+	want := []int64{first.ID, extra.ID, second.ID}
+	for i, c := range chapters {
+		if c.ID != want[i] {
+			t.Fatalf("Expected chapters sorted by ComicInfo number %v, got chapter %d at %d", want, c.ID, i)
+		}
+	}
+
+	// Clearing the metadata leaves the chapter without ComicInfo but checked
+	if err := s.UpdateChapterComicInfo(first.ID, nil); err != nil {
+		t.Fatalf("UpdateChapterComicInfo failed: %v", err)
+	}
+	chap, _ = s.GetChapterByID(first.ID, 1)
+	if chap.ComicInfo != nil {
+		t.Errorf("Expected no ComicInfo after clearing, got %+v", chap.ComicInfo)
+	}
+	all, _ := s.GetAllChaptersByHash()
+	if !all["hash-c"].ComicInfoChecked {
+		t.Error("Expected chapter to be marked as checked")
+	}
+
+	// With only some chapters numbered, all of them sort by file name
+	_, _, chapters, _, _ = s.ListItems(store.ListItemsOptions{UserID: 1, ParentID: &folder.ID, Page: 1, PerPage: 10})
+	want = []int64{extra.ID, second.ID, first.ID}
+	for i, c := range chapters {
+		if c.ID != want[i] {
+			t.Fatalf("Expected chapters sorted by file name %v, got chapter %d at %d", want, c.ID, i)
+		}
+	}
+}
@@ -31,8 +31,9 @@ func (s *Store) AddChaptersToQueue(seriesTitle, providerID string, chapters []mo
 
 	stmt, err := tx.Prepare(`
         INSERT OR IGNORE INTO download_queue
-        (series_title, chapter_title, chapter_identifier, provider_id, created_at)
-        VALUES (?, ?, ?, ?, ?)
+        (series_title, chapter_title, chapter_identifier, provider_id, created_at,
+         volume, chapter_number, language, group_id, group_name, published_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `)
 	if err != nil {
 		return err
@@ -40,7 +41,12 @@ func (s *Store) AddChaptersToQueue(seriesTitle, providerID string, chapters []mo
 	defer stmt.Close()
 
 	for _, ch := range chapters {
-		_, err := stmt.Exec(seriesTitle, ch.Title, ch.Identifier, providerID, time.Now())
+		var publishedAt *time.Time
+		if !ch.PublishedAt.IsZero() {
+			publishedAt = &ch.PublishedAt
+		}
+		_, err := stmt.Exec(seriesTitle, ch.Title, ch.Identifier, providerID, time.Now(),
+			nullIfEmpty(ch.Volume), nullIfEmpty(ch.Chapter), nullIfEmpty(ch.Language), nullIfEmpty(ch.GroupID), nullIfEmpty(ch.GroupName), publishedAt)
 		if err != nil {
 			return err
 		}
@@ -84,8 +90,7 @@ func (s *Store) SubscribeToSeriesWithFolder(seriesTitle, seriesIdentifier, provi
 
 func (s *Store) GetDownloadQueue() ([]*models.DownloadQueueItem, error) {
 	query := `
-        SELECT id, series_title, chapter_title, chapter_identifier, provider_id, status, progress, message, created_at,
-               local_chapter_id, local_folder_id
+        SELECT ` + downloadQueueColumns + `
         FROM download_queue ORDER BY created_at DESC
     `
 	rows, err := s.db.Query(query)
@@ -96,22 +101,11 @@ func (s *Store) GetDownloadQueue() ([]*models.DownloadQueueItem, error) {
 
 	var items []*models.DownloadQueueItem
 	for rows.Next() {
-		var item models.DownloadQueueItem
-		var msg sql.NullString
-		var localChap, localFolder sql.NullInt64
-		if err := rows.Scan(&item.ID, &item.SeriesTitle, &item.ChapterTitle, &item.ChapterIdentifier, &item.ProviderID, &item.Status, &item.Progress, &msg, &item.CreatedAt, &localChap, &localFolder); err != nil {
+		item, err := scanDownloadQueueItem(rows)
+		if err != nil {
 			return nil, err
 		}
-		item.Message = msg.String
-		if localChap.Valid {
-			v := localChap.Int64
-			item.LocalChapterID = &v
-		}
-		if localFolder.Valid {
-			v := localFolder.Int64
-			item.LocalFolderID = &v
-		}
-		items = append(items, &item)
+		items = append(items, item)
 	}
 	return items, nil
 }
@@ -119,8 +113,7 @@ func (s *Store) GetDownloadQueue() ([]*models.DownloadQueueItem, error) {
 // GetQueuedDownloadItems retrieves a limited number of items with a 'queued' status.
 func (s *Store) GetQueuedDownloadItems(limit int) ([]*models.DownloadQueueItem, error) {
 	query := `
-        SELECT id, series_title, chapter_title, chapter_identifier, provider_id, status, progress, message, created_at,
-               local_chapter_id, local_folder_id
+        SELECT ` + downloadQueueColumns + `
         FROM download_queue WHERE status = 'queued' ORDER BY created_at ASC LIMIT ?
     `
 	rows, err := s.db.Query(query, limit)
@@ -131,22 +124,11 @@ func (s *Store) GetQueuedDownloadItems(limit int) ([]*models.DownloadQueueItem,
 
 	var items []*models.DownloadQueueItem
 	for rows.Next() {
-		var item models.DownloadQueueItem
-		var msg sql.NullString
-		var localChap, localFolder sql.NullInt64
-		if err := rows.Scan(&item.ID, &item.SeriesTitle, &item.ChapterTitle, &item.ChapterIdentifier, &item.ProviderID, &item.Status, &item.Progress, &msg, &item.CreatedAt, &localChap, &localFolder); err != nil {
+		item, err := scanDownloadQueueItem(rows)
+		if err != nil {
 			return nil, err
 		}
-		item.Message = msg.String
-		if localChap.Valid {
-			v := localChap.Int64
-			item.LocalChapterID = &v
-		}
-		if localFolder.Valid {
-			v := localFolder.Int64
-			item.LocalFolderID = &v
-		}
-		items = append(items, &item)
+		items = append(items, item)
 	}
 	return items, nil
 }
@@ -154,14 +136,28 @@ func (s *Store) GetQueuedDownloadItems(limit int) ([]*models.DownloadQueueItem,
 // GetDownloadQueueItem retrieves a single item from the download queue by ID.
 func (s *Store) GetDownloadQueueItem(id int64) (*models.DownloadQueueItem, error) {
 	query := `
-        SELECT id, series_title, chapter_title, chapter_identifier, provider_id, status, progress, message, created_at,
-               local_chapter_id, local_folder_id
+        SELECT ` + downloadQueueColumns + `
         FROM download_queue WHERE id = ?
     `
+	return scanDownloadQueueItem(s.db.QueryRow(query, id))
+}
+
+// downloadQueueColumns are the download_queue columns read by scanDownloadQueueItem, in order.
+const downloadQueueColumns = `id, series_title, chapter_title, chapter_identifier, provider_id, status, progress, message, created_at,
+               local_chapter_id, local_folder_id, volume, chapter_number, language, group_id, group_name, published_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDownloadQueueItem(row rowScanner) (*models.DownloadQueueItem, error) {
 	var item models.DownloadQueueItem
-	var msg sql.NullString
+	var msg, volume, chapterNumber, language, groupID, groupName sql.NullString
 	var localChap, localFolder sql.NullInt64
-	err := s.db.QueryRow(query, id).Scan(&item.ID, &item.SeriesTitle, &item.ChapterTitle, &item.ChapterIdentifier, &item.ProviderID, &item.Status, &item.Progress, &msg, &item.CreatedAt, &localChap, &localFolder)
+	var publishedAt sql.NullTime
+	err := row.Scan(&item.ID, &item.SeriesTitle, &item.ChapterTitle, &item.ChapterIdentifier, &item.ProviderID, &item.Status, &item.Progress, &msg, &item.CreatedAt,
+		&localChap, &localFolder, &volume, &chapterNumber, &language, &groupID, &groupName, &publishedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -174,6 +170,14 @@ func (s *Store) GetDownloadQueueItem(id int64) (*models.DownloadQueueItem, error
 		v := localFolder.Int64
 		item.LocalFolderID = &v
 	}
+	item.Volume = volume.String
+	item.ChapterNumber = chapterNumber.String
+	item.Language = language.String
+	item.GroupID = groupID.String
+	item.GroupName = groupName.String
+	if publishedAt.Valid {
+		item.PublishedAt = &publishedAt.Time
+	}
 	return &item, nil
 }
 
@@ -112,8 +112,15 @@ func (s *Store) GetAllFoldersByPath() (map[string]*models.Folder, error) {
 	return folderMap, nil
 }
 
-func (s *Store) SearchFoldersByName(name string, limit int) ([]*models.Folder, error) {
-	rows, err := s.db.Query("SELECT id, path, name, parent_id, thumbnail FROM folders WHERE name LIKE ? LIMIT ?", "%"+name+"%", limit)
+// SearchFoldersByName returns up to limit folders visible to the user whose name contains name.
+func (s *Store) SearchFoldersByName(userID int64, name string, limit int) ([]*models.Folder, error) {
+	access, accessArgs, err := s.folderFilter(userID, "f.id")
+	if err != nil {
+		return nil, err
+	}
+	args := append([]interface{}{"%" + name + "%"}, accessArgs...)
+	rows, err := s.db.Query("SELECT f.id, f.path, f.name, f.parent_id, f.thumbnail FROM folders f WHERE f.name LIKE ? AND "+access+" LIMIT ?",
+		append(args, limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -232,9 +239,15 @@ type ListItemsOptions struct {
 }
 
 // ListItems is the new generic function for fetching folders and chapters.
+// Folders the user cannot see are left out, and listing one of them returns ErrFolderNotFound.
 func (s *Store) ListItems(opts ListItemsOptions) (*models.Folder, []*models.Folder, []*models.Chapter, int, error) {
 	var currentFolder *models.Folder
 	if opts.ParentID != nil && *opts.ParentID != 0 {
+		if ok, err := s.CanAccessFolder(opts.UserID, *opts.ParentID); err != nil {
+			return nil, nil, nil, 0, err
+		} else if !ok {
+			return nil, nil, nil, 0, ErrFolderNotFound
+		}
 		f, err := s.GetFolder(*opts.ParentID)
 		if err != nil {
 			return nil, nil, nil, 0, err
@@ -264,6 +277,20 @@ func (s *Store) ListItems(opts ListItemsOptions) (*models.Folder, []*models.Fold
 		chapterArgs = append(chapterArgs, *opts.ParentID)
 	}
 
+	// Only include what the user is allowed to see
+	folderAccess, folderAccessArgs, err := s.folderFilter(opts.UserID, "f.id")
+	if err != nil {
+		return currentFolder, nil, nil, 0, err
+	}
+	folderWhere += " AND " + folderAccess
+	folderArgs = append(folderArgs, folderAccessArgs...)
+	chapterAccess, chapterAccessArgs, err := s.folderFilter(opts.UserID, "c.folder_id")
+	if err != nil {
+		return currentFolder, nil, nil, 0, err
+	}
+	chapterWhere += " AND " + chapterAccess
+	chapterArgs = append(chapterArgs, chapterAccessArgs...)
+
 	if opts.Search != "" {
 		folderWhere += " AND f.name LIKE ?"
 		folderArgs = append(folderArgs, "%"+opts.Search+"%")
@@ -290,7 +317,9 @@ func (s *Store) ListItems(opts ListItemsOptions) (*models.Folder, []*models.Fold
 			NULL as user_read,
 			NULL as user_progress,
 			f.created_at as sort_created_at,
-			f.name as sort_name
+			f.name as sort_name,
+			NULL as comicinfo_number,
+			NULL as comicinfo_volume
 		FROM folders f %s WHERE %s
 		UNION ALL
 		-- Select Chapters
@@ -306,7 +335,9 @@ func (s *Store) ListItems(opts ListItemsOptions) (*models.Folder, []*models.Fold
 			COALESCE(ucp.read, 0) as user_read,
 			COALESCE(ucp.progress_percent, 0) as user_progress,
 			c.created_at as sort_created_at,
-			c.path as sort_name
+			c.path as sort_name,
+			c.comicinfo_number,
+			c.comicinfo_volume
 		FROM chapters c
 		LEFT JOIN user_chapter_progress ucp ON c.id = ucp.chapter_id AND ucp.user_id = ?
 		WHERE %s
@@ -363,11 +394,14 @@ func (s *Store) ListItems(opts ListItemsOptions) (*models.Folder, []*models.Fold
 		var createdAtStr, updatedAtStr sql.NullString
 		var createdAt, updatedAt sql.NullTime
 		var sortDate sql.NullTime
+		var comicInfoNumber sql.NullString
+		var comicInfoVolume sql.NullInt64
 
 		if err := rows.Scan(
 			&itemType, &chapter.ID, &chapPath, &folder.Name, &folderThumb,
 			&pageCount,
-			&createdAtStr, &updatedAtStr, &userRead, &userProgress, &sortDate, &sortName); err != nil {
+			&createdAtStr, &updatedAtStr, &userRead, &userProgress, &sortDate, &sortName,
+			&comicInfoNumber, &comicInfoVolume); err != nil {
 			return currentFolder, nil, nil, 0, err
 		}
 		if createdAtStr.Valid {
@@ -396,6 +430,13 @@ func (s *Store) ListItems(opts ListItemsOptions) (*models.Folder, []*models.Fold
 			if updatedAt.Valid {
 				chapter.UpdatedAt = updatedAt.Time
 			}
+			if comicInfoNumber.Valid || comicInfoVolume.Valid {
+				chapter.ComicInfo = &models.ComicInfo{Number: comicInfoNumber.String}
+				if comicInfoVolume.Valid {
+					volume := int(comicInfoVolume.Int64)
+					chapter.ComicInfo.Volume = &volume
+				}
+			}
 			chapters = append(chapters, &chapter)
 		}
 	}
@@ -426,14 +467,15 @@ func (s *Store) ListItems(opts ListItemsOptions) (*models.Folder, []*models.Fold
 		})
 		subfolders = limitAndOffsetFolders(subfolders, opts.Page, opts.PerPage)
 
-		// Sort chapters naturally
+		// Sort chapters naturally, by ComicInfo volume/number where every chapter has one
+		sortTitles := chapterSortTitles(chapters)
 		chapterTitles := make([]string, len(chapters))
 		for i, chapter := range chapters {
-			chapterTitles[i] = GetChapterTitle(chapter)
+			chapterTitles[i] = sortTitles[chapter.ID]
 		}
 		cs := util.NewChapterSorter(chapterTitles)
 		slices.SortFunc(chapters, func(a, b *models.Chapter) int {
-			comparison := cs.Compare(GetChapterTitle(a), GetChapterTitle(b))
+			comparison := cs.Compare(sortTitles[a.ID], sortTitles[b.ID])
 			if strings.ToLower(sortDir) == "desc" {
 				return -comparison
 			}
@@ -92,7 +92,7 @@ func TestSearchFoldersByName(t *testing.T) {
 	}
 
 	t.Run("Search with matching query", func(t *testing.T) {
-		folders, err := s.SearchFoldersByName("One", 20)
+		folders, err := s.SearchFoldersByName(0, "One", 20)
 		if err != nil {
 			t.Fatalf("SearchFoldersByName failed: %v", err)
 		}
@@ -108,7 +108,7 @@ func TestSearchFoldersByName(t *testing.T) {
 	})
 
 	t.Run("Search with exact match", func(t *testing.T) {
-		folders, err := s.SearchFoldersByName("Naruto", 20)
+		folders, err := s.SearchFoldersByName(0, "Naruto", 20)
 		if err != nil {
 			t.Fatalf("SearchFoldersByName failed: %v", err)
 		}
@@ -124,7 +124,7 @@ func TestSearchFoldersByName(t *testing.T) {
 	})
 
 	t.Run("Search with no matches", func(t *testing.T) {
-		folders, err := s.SearchFoldersByName("NonExistent", 20)
+		folders, err := s.SearchFoldersByName(0, "NonExistent", 20)
 		if err != nil {
 			t.Fatalf("SearchFoldersByName failed: %v", err)
 		}
@@ -134,7 +134,7 @@ func TestSearchFoldersByName(t *testing.T) {
 	})
 
 	t.Run("Search with limit", func(t *testing.T) {
-		folders, err := s.SearchFoldersByName("One", 2)
+		folders, err := s.SearchFoldersByName(0, "One", 2)
 		if err != nil {
 			t.Fatalf("SearchFoldersByName failed: %v", err)
 		}
@@ -144,7 +144,7 @@ func TestSearchFoldersByName(t *testing.T) {
 	})
 
 	t.Run("Search with case insensitive partial match", func(t *testing.T) {
-		folders, err := s.SearchFoldersByName("piece", 20)
+		folders, err := s.SearchFoldersByName(0, "piece", 20)
 		if err != nil {
 			t.Fatalf("SearchFoldersByName failed: %v", err)
 		}
@@ -161,7 +161,7 @@ func TestSearchFoldersByName(t *testing.T) {
 	})
 
 	t.Run("Search with empty query", func(t *testing.T) {
-		folders, err := s.SearchFoldersByName("", 20)
+		folders, err := s.SearchFoldersByName(0, "", 20)
 		if err != nil {
 			t.Fatalf("SearchFoldersByName failed: %v", err)
 		}
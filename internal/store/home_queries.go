@@ -11,6 +11,10 @@ import (
 
 // GetContinueReading fetches chapters the user has started but not finished, only one per series.
 func (s *Store) GetContinueReading(userID int64, limit int) ([]*models.HomeSectionItem, error) {
+	access, accessArgs, err := s.folderFilter(userID, "f.id")
+	if err != nil {
+		return nil, err
+	}
 	// --COALESCE(f.custom_cover_url, f.thumbnail, '') as cover_art,
 	query := `
 		SELECT *
@@ -28,13 +32,14 @@ func (s *Store) GetContinueReading(userID int64, limit int) ([]*models.HomeSecti
 			FROM user_chapter_progress ucp
 			JOIN chapters c ON ucp.chapter_id = c.id
 			JOIN folders f ON c.folder_id = f.id
-			WHERE ucp.user_id = ? AND ucp.read = 0 AND ucp.progress_percent > 0
+			WHERE ucp.user_id = ? AND ucp.read = 0 AND ucp.progress_percent > 0 AND ` + access + `
 		)
 		WHERE rn = 1
 		ORDER BY updated_at DESC
 		LIMIT ?
 	`
-	rows, err := s.db.Query(query, userID, limit)
+	args := append(append([]interface{}{userID}, accessArgs...), limit)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -66,18 +71,23 @@ func (s *Store) GetContinueReading(userID int64, limit int) ([]*models.HomeSecti
 
 // GetNextUp fetches the next unread chapter in series the user is actively reading.
 func (s *Store) GetNextUp(userID int64, limit int) ([]*models.HomeSectionItem, error) {
+	access, accessArgs, err := s.folderFilter(userID, "f.id")
+	if err != nil {
+		return nil, err
+	}
 	// Simple approach: Get all folders that the user has read chapters in
 	query := `
 		SELECT DISTINCT f.id, f.name, f.thumbnail, MAX(ucp.updated_at) as last_read_time
 		FROM folders f
 		JOIN chapters c ON c.folder_id = f.id
 		JOIN user_chapter_progress ucp ON ucp.chapter_id = c.id
-		WHERE ucp.user_id = ? AND ucp.read = 1
+		WHERE ucp.user_id = ? AND ucp.read = 1 AND ` + access + `
 		GROUP BY f.id, f.name, f.thumbnail
 		ORDER BY last_read_time DESC
 		LIMIT ?
 	`
-	rows, err := s.db.Query(query, userID, limit*2)
+	args := append(append([]interface{}{userID}, accessArgs...), limit*2)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -109,7 +119,7 @@ func (s *Store) GetNextUp(userID int64, limit int) ([]*models.HomeSectionItem, e
 		nextChapter, err := s.findNextChapterInFolder(userID, folder.id)
 		if err != nil {
 			// If no unread chapters in this folder, try to find the next folder in the hierarchy
-			nextChapter, err = s.findNextChapterInSiblingFolder(userID, folder.id)
+			nextChapter, err = s.findNextChapterInSiblingFolder(userID, folder.id, access, accessArgs)
 			if err != nil {
 				continue
 			}
@@ -167,8 +177,9 @@ func (s *Store) findNextChapterInFolder(userID, folderID int64) (*models.Chapter
 	return nil, sql.ErrNoRows
 }
 
-// findNextChapterInSiblingFolder finds the next unread chapter in a sibling folder
-func (s *Store) findNextChapterInSiblingFolder(userID, folderID int64) (*models.Chapter, error) {
+// findNextChapterInSiblingFolder finds the next unread chapter in a sibling folder. Only
+// siblings matching the access condition on "f.id" (from folderFilter) are considered.
+func (s *Store) findNextChapterInSiblingFolder(userID, folderID int64, access string, accessArgs []interface{}) (*models.Chapter, error) {
 	// Get the parent folder of the current folder
 	var parentID *int64
 	err := s.db.QueryRow("SELECT parent_id FROM folders WHERE id = ?", folderID).Scan(&parentID)
@@ -183,11 +194,11 @@ func (s *Store) findNextChapterInSiblingFolder(userID, folderID int64) (*models.
 
 	// Get all sibling folders (folders with the same parent)
 	query := `
-		SELECT id, name FROM folders
-		WHERE parent_id = ?
-		ORDER BY name ASC
+		SELECT f.id, f.name FROM folders f
+		WHERE f.parent_id = ? AND ` + access + `
+		ORDER BY f.name ASC
 	`
-	rows, err := s.db.Query(query, *parentID)
+	rows, err := s.db.Query(query, append([]interface{}{*parentID}, accessArgs...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -227,11 +238,15 @@ func (s *Store) findNextChapterInSiblingFolder(userID, folderID int64) (*models.
 	}
 
 	// If no next sibling found, try the parent's siblings
-	return s.findNextChapterInSiblingFolder(userID, *parentID)
+	return s.findNextChapterInSiblingFolder(userID, *parentID, access, accessArgs)
 }
 
 // GetRecentlyAdded fetches recently added chapters and groups them by series and creation date.
 func (s *Store) GetRecentlyAdded(userID int64, limit int) ([]*models.HomeSectionItem, error) {
+	access, accessArgs, err := s.folderFilter(userID, "f.id")
+	if err != nil {
+		return nil, err
+	}
 	query := `
 		SELECT
 			f.id as series_id,
@@ -244,10 +259,12 @@ func (s *Store) GetRecentlyAdded(userID int64, limit int) ([]*models.HomeSection
 		FROM chapters c
 		JOIN folders f ON f.id = c.folder_id
 		LEFT JOIN user_chapter_progress ucp ON c.id = ucp.chapter_id AND ucp.user_id = ?
+		WHERE ` + access + `
 		ORDER BY c.created_at DESC
 		LIMIT ?
 	`
-	rows, err := s.db.Query(query, userID, limit*2) // Fetch more to ensure we have enough unique series
+	args := append(append([]interface{}{userID}, accessArgs...), limit*2) // Fetch more to ensure we have enough unique series
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -314,6 +331,10 @@ func (s *Store) GetRecentlyAdded(userID int64, limit int) ([]*models.HomeSection
 
 // GetStartReading fetches top-level folders which the user has not started reading yet.
 func (s *Store) GetStartReading(userID int64, limit int) ([]*models.HomeSectionItem, error) {
+	access, accessArgs, err := s.folderFilter(userID, "f.id")
+	if err != nil {
+		return nil, err
+	}
 	query := `
 		SELECT
 			f.id,
@@ -334,11 +355,12 @@ func (s *Store) GetStartReading(userID int64, limit int) ([]*models.HomeSectionI
 				JOIN chapters c ON c.folder_id = subtree.id
 				JOIN user_chapter_progress ucp ON ucp.chapter_id = c.id
 				WHERE ucp.user_id = ?
-			)
+			) AND ` + access + `
 		ORDER BY f.created_at DESC
 		LIMIT ?
 	`
-	rows, err := s.db.Query(query, userID, limit)
+	args := append(append([]interface{}{userID}, accessArgs...), limit)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
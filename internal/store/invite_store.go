@@ -0,0 +1,171 @@
+package store
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+var (
+	ErrInviteNotFound     = errors.New("invite not found")
+	ErrInviteInvalid      = errors.New("invite code is invalid, used up or expired")
+	ErrRegistrationClosed = errors.New("registration requires an invite code")
+	ErrUsernameTaken      = errors.New("username is already taken")
+	ErrUserNotPending     = errors.New("user is not waiting for approval")
+)
+
+// CreateInvite issues an invite code that lets up to maxUses people register with role until
+// it expires, and returns the invite with the code. Only a hash of the code is stored.
+func (s *Store) CreateInvite(role string, maxUses int, lifetime time.Duration, createdBy int64) (*models.Invite, string, error) {
+	codeBytes := make([]byte, 16)
+	if _, err := rand.Read(codeBytes); err != nil {
+		return nil, "", err
+	}
+	code := hex.EncodeToString(codeBytes)
+
+	now := time.Now().UTC()
+	invite := &models.Invite{Role: role, MaxUses: maxUses, ExpiresAt: now.Add(lifetime), CreatedAt: now}
+	if createdBy != 0 {
+		invite.CreatedBy = &createdBy
+	}
+	result, err := s.db.Exec("INSERT INTO invites (code_hash, role, max_uses, expires_at, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		hashAPIToken(code), role, maxUses, invite.ExpiresAt, nullIfZero(int(createdBy)), now)
+	if err != nil {
+		return nil, "", err
+	}
+	invite.ID, _ = result.LastInsertId()
+	return invite, code, nil
+}
+
+// ListInvites returns every invite, newest first, including used up and expired ones.
+func (s *Store) ListInvites() ([]*models.Invite, error) {
+	rows, err := s.db.Query(`
+		SELECT i.id, i.role, i.max_uses, i.use_count, i.expires_at, i.created_by, COALESCE(u.username, ''), i.created_at
+		FROM invites i LEFT JOIN users u ON u.id = i.created_by
+		ORDER BY i.created_at DESC, i.id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []*models.Invite{}
+	for rows.Next() {
+		var invite models.Invite
+		var createdBy sql.NullInt64
+		if err := rows.Scan(&invite.ID, &invite.Role, &invite.MaxUses, &invite.UseCount, &invite.ExpiresAt,
+			&createdBy, &invite.CreatedByUsername, &invite.CreatedAt); err != nil {
+			return nil, err
+		}
+		if createdBy.Valid {
+			invite.CreatedBy = &createdBy.Int64
+		}
+		invites = append(invites, &invite)
+	}
+	return invites, rows.Err()
+}
+
+// DeleteInvite revokes an invite. Accounts already registered with it are kept.
+func (s *Store) DeleteInvite(id int64) error {
+	result, err := s.db.Exec("DELETE FROM invites WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrInviteNotFound
+	}
+	return nil
+}
+
+// CheckRegistration returns the error RegisterUser would return for username and inviteCode,
+// without using up the invite, so a registration can be refused before its password is hashed.
+func (s *Store) CheckRegistration(username, inviteCode string, allowOpen bool) error {
+	if inviteCode != "" {
+		var valid bool
+		err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM invites
+			WHERE code_hash = ? AND use_count < max_uses AND expires_at > ?)`,
+			hashAPIToken(inviteCode), time.Now().UTC()).Scan(&valid)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return ErrInviteInvalid
+		}
+	} else if !allowOpen {
+		return ErrRegistrationClosed
+	}
+
+	var taken bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)", username).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return ErrUsernameTaken
+	}
+	return nil
+}
+
+// RegisterUser creates an account for someone registering themselves. With an invite code the
+// account gets the invite's role and can be used straight away. Without one it is only created
+// when allowOpen is set, as a pending account with the user role that an admin has to approve.
+func (s *Store) RegisterUser(username, passwordHash, inviteCode string, allowOpen bool) (*models.User, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	role, pending := models.RoleUser, true
+	if inviteCode != "" {
+		// Checking and counting the use in one statement keeps concurrent registrations within max_uses.
+		err := tx.QueryRow(`UPDATE invites SET use_count = use_count + 1
+			WHERE code_hash = ? AND use_count < max_uses AND expires_at > ? RETURNING role`,
+			hashAPIToken(inviteCode), time.Now().UTC()).Scan(&role)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInviteInvalid
+		}
+		if err != nil {
+			return nil, err
+		}
+		pending = false
+	} else if !allowOpen {
+		return nil, ErrRegistrationClosed
+	}
+
+	// The username is only checked once the invite is known to be good, so invalid invites
+	// cannot be used to find out which usernames exist.
+	var taken bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username = ?)", username).Scan(&taken); err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrUsernameTaken
+	}
+
+	now := time.Now()
+	result, err := tx.Exec("INSERT INTO users (username, password_hash, role, created_at, pending) VALUES (?, ?, ?, ?, ?)",
+		username, passwordHash, role, now, pending)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := result.LastInsertId()
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &models.User{ID: id, Username: username, Role: role, CreatedAt: now, Pending: pending}, nil
+}
+
+// ApproveUser lets a pending account log in.
+func (s *Store) ApproveUser(id int64) error {
+	result, err := s.db.Exec("UPDATE users SET pending = 0 WHERE id = ? AND pending = 1", id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrUserNotPending
+	}
+	return nil
+}
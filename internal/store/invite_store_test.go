@@ -0,0 +1,110 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestInvites(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	s := store.New(db)
+	admin, _ := s.CreateUser("admin", "hash", "admin")
+
+	t.Run("Register with an invite", func(t *testing.T) {
+		invite, code, err := s.CreateInvite("admin", 2, time.Hour, admin.ID)
+		if err != nil {
+			t.Fatalf("CreateInvite failed: %v", err)
+		}
+		if code == "" || invite.ID == 0 {
+			t.Fatalf("Expected an invite with a code, got %+v %q", invite, code)
+		}
+
+		user, err := s.RegisterUser("first", "hash", code, false)
+		if err != nil {
+			t.Fatalf("RegisterUser failed: %v", err)
+		}
+		if user.Pending || user.Role != "admin" {
+			t.Errorf("Expected an active account with the invite's role, got %+v", user)
+		}
+		if _, err := s.RegisterUser("first", "hash", code, false); !errors.Is(err, store.ErrUsernameTaken) {
+			t.Errorf("Expected ErrUsernameTaken, got %v", err)
+		}
+		if err := s.CheckRegistration("first", code, false); !errors.Is(err, store.ErrUsernameTaken) {
+			t.Errorf("Expected CheckRegistration to report ErrUsernameTaken, got %v", err)
+		}
+		if err := s.CheckRegistration("second", code, false); err != nil {
+			t.Errorf("Expected CheckRegistration to allow a free username, got %v", err)
+		}
+		if _, err := s.RegisterUser("second", "hash", code, false); err != nil {
+			t.Fatalf("Expected the second use to work, got %v", err)
+		}
+		if _, err := s.RegisterUser("third", "hash", code, false); !errors.Is(err, store.ErrInviteInvalid) {
+			t.Errorf("Expected a used up invite to be rejected, got %v", err)
+		}
+
+		invites, _ := s.ListInvites()
+		if len(invites) != 1 || invites[0].UseCount != 2 || invites[0].CreatedByUsername != "admin" {
+			t.Errorf("Unexpected invites: %+v", invites[0])
+		}
+	})
+
+	t.Run("Expired, revoked and unknown codes", func(t *testing.T) {
+		_, expired, _ := s.CreateInvite("user", 1, -time.Minute, admin.ID)
+		if _, err := s.RegisterUser("late", "hash", expired, false); !errors.Is(err, store.ErrInviteInvalid) {
+			t.Errorf("Expected an expired invite to be rejected, got %v", err)
+		}
+		if _, err := s.RegisterUser("guess", "hash", "not-a-code", true); !errors.Is(err, store.ErrInviteInvalid) {
+			t.Errorf("Expected an unknown code to be rejected, got %v", err)
+		}
+		// The invite is checked before the username, so existing usernames are not revealed
+		if _, err := s.RegisterUser("admin", "hash", "not-a-code", false); !errors.Is(err, store.ErrInviteInvalid) {
+			t.Errorf("Expected an unknown code to be rejected before the username, got %v", err)
+		}
+		if err := s.CheckRegistration("admin", "not-a-code", false); !errors.Is(err, store.ErrInviteInvalid) {
+			t.Errorf("Expected CheckRegistration to reject the code, got %v", err)
+		}
+
+		invite, code, _ := s.CreateInvite("user", 1, time.Hour, admin.ID)
+		if err := s.DeleteInvite(invite.ID); err != nil {
+			t.Fatalf("DeleteInvite failed: %v", err)
+		}
+		if _, err := s.RegisterUser("revoked", "hash", code, false); !errors.Is(err, store.ErrInviteInvalid) {
+			t.Errorf("Expected a revoked invite to be rejected, got %v", err)
+		}
+		if err := s.DeleteInvite(invite.ID); !errors.Is(err, store.ErrInviteNotFound) {
+			t.Errorf("Expected ErrInviteNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Open registration", func(t *testing.T) {
+		if _, err := s.RegisterUser("walkin", "hash", "", false); !errors.Is(err, store.ErrRegistrationClosed) {
+			t.Errorf("Expected ErrRegistrationClosed, got %v", err)
+		}
+		user, err := s.RegisterUser("walkin", "hash", "", true)
+		if err != nil {
+			t.Fatalf("RegisterUser failed: %v", err)
+		}
+		if !user.Pending || user.Role != "user" {
+			t.Errorf("Expected a pending user account, got %+v", user)
+		}
+
+		stored, _ := s.GetUserByID(user.ID)
+		if !stored.Pending || len(stored.Permissions) != 0 {
+			t.Errorf("Expected a pending account without permissions, got %+v", stored)
+		}
+		if err := s.ApproveUser(user.ID); err != nil {
+			t.Fatalf("ApproveUser failed: %v", err)
+		}
+		stored, _ = s.GetUserByID(user.ID)
+		if stored.Pending || len(stored.Permissions) == 0 {
+			t.Errorf("Expected an approved account with its role's permissions, got %+v", stored)
+		}
+		if err := s.ApproveUser(user.ID); !errors.Is(err, store.ErrUserNotPending) {
+			t.Errorf("Expected ErrUserNotPending, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// KOSyncProgress is the last reading position a KOReader device pushed for a document.
+type KOSyncProgress struct {
+	Document   string
+	Progress   string // KOReader's own position: a page number or an XPointer
+	Percentage float64
+	Device     string
+	DeviceID   string
+	UpdatedAt  time.Time
+}
+
+// SaveKOSyncProgress stores a device's reading position, replacing the previous one.
+func (s *Store) SaveKOSyncProgress(userID int64, p *KOSyncProgress) error {
+	query := `
+		INSERT INTO kosync_progress (user_id, document, progress, percentage, device, device_id, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, document) DO UPDATE SET
+			progress = excluded.progress,
+			percentage = excluded.percentage,
+			device = excluded.device,
+			device_id = excluded.device_id,
+			updated_at = excluded.updated_at
+	`
+	_, err := s.db.Exec(query, userID, p.Document, p.Progress, p.Percentage, p.Device, p.DeviceID, p.UpdatedAt)
+	return err
+}
+
+// GetKOSyncProgress returns the last position pushed for a document, or nil if there is none.
+func (s *Store) GetKOSyncProgress(userID int64, document string) (*KOSyncProgress, error) {
+	var p KOSyncProgress
+	query := `SELECT document, progress, percentage, device, device_id, updated_at
+	          FROM kosync_progress WHERE user_id = ? AND document = ?`
+	err := s.db.QueryRow(query, userID, document).Scan(&p.Document, &p.Progress, &p.Percentage, &p.Device, &p.DeviceID, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetChapterIDByDocument finds the chapter a KOReader document identifier refers to, matching
+// either the chapter's content hash or KOReader's partial MD5 of the file.
+func (s *Store) GetChapterIDByDocument(document string) (int64, error) {
+	var id int64
+	err := s.db.QueryRow("SELECT id FROM chapters WHERE content_hash = ? OR koreader_hash = ? LIMIT 1", document, document).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrChapterNotFound
+	}
+	return id, err
+}
+
+// UpdateChapterKOReaderHash stores KOReader's partial MD5 of the chapter file.
+func (s *Store) UpdateChapterKOReaderHash(id int64, hash string) error {
+	_, err := s.db.Exec("UPDATE chapters SET koreader_hash = ? WHERE id = ?", nullIfEmpty(hash), id)
+	return err
+}
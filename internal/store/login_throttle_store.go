@@ -0,0 +1,132 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+// LoginState is an account's failed-login state.
+type LoginState struct {
+	FailedCount  int        // consecutive failures since the last successful login or lockout
+	LastFailedAt *time.Time // time of the most recent failure
+	LockedUntil  *time.Time // set while the account is locked
+}
+
+// GetUserLoginState returns an account's failed-login state.
+func (s *Store) GetUserLoginState(userID int64) (*LoginState, error) {
+	var state LoginState
+	var lastFailed, lockedUntil sql.NullTime
+	err := s.db.QueryRow("SELECT failed_login_count, last_failed_login_at, locked_until FROM users WHERE id = ?", userID).
+		Scan(&state.FailedCount, &lastFailed, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	if lastFailed.Valid {
+		state.LastFailedAt = &lastFailed.Time
+	}
+	if lockedUntil.Valid && lockedUntil.Time.After(time.Now()) {
+		state.LockedUntil = &lockedUntil.Time
+	}
+	return &state, nil
+}
+
+// RecordFailedLogin records a rejected login attempt. userID is 0 when the username did not
+// match an account; otherwise the account's consecutive failure count is incremented and
+// returned.
+func (s *Store) RecordFailedLogin(userID int64, username, ipAddress, userAgent string) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	_, err = tx.Exec("INSERT INTO failed_logins (username, user_id, ip_address, user_agent, attempted_at) VALUES (?, ?, ?, ?, ?)",
+		username, nullIfZero(int(userID)), ipAddress, userAgent, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if userID != 0 {
+		err = tx.QueryRow("UPDATE users SET failed_login_count = failed_login_count + 1, last_failed_login_at = ? WHERE id = ? RETURNING failed_login_count",
+			now, userID).Scan(&count)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return count, tx.Commit()
+}
+
+// LockUser locks an account until the given time and starts its failure count afresh, so it
+// gets the full number of attempts once the lock expires.
+func (s *Store) LockUser(userID int64, until time.Time) error {
+	_, err := s.db.Exec("UPDATE users SET locked_until = ?, failed_login_count = 0 WHERE id = ?", until.UTC(), userID)
+	return err
+}
+
+// ResetLoginFailures clears an account's failure count and lock, after a successful login or
+// when an admin unlocks it.
+func (s *Store) ResetLoginFailures(userID int64) error {
+	_, err := s.db.Exec("UPDATE users SET failed_login_count = 0, last_failed_login_at = NULL, locked_until = NULL WHERE id = ?", userID)
+	return err
+}
+
+// FailedLoginsFromIP returns how many failed logins came from an IP address since a time, and
+// when the most recent one was.
+func (s *Store) FailedLoginsFromIP(ipAddress string, since time.Time) (int, time.Time, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM failed_logins WHERE ip_address = ? AND attempted_at > ?",
+		ipAddress, since.UTC()).Scan(&count)
+	if err != nil || count == 0 {
+		return count, time.Time{}, err
+	}
+	var last time.Time
+	err = s.db.QueryRow("SELECT attempted_at FROM failed_logins WHERE ip_address = ? ORDER BY attempted_at DESC LIMIT 1",
+		ipAddress).Scan(&last)
+	return count, last, err
+}
+
+// ListFailedLogins returns failed login attempts, newest first, optionally only those for one
+// username.
+func (s *Store) ListFailedLogins(username string, limit, offset int) ([]*models.FailedLogin, error) {
+	query := "SELECT id, username, user_id, ip_address, user_agent, attempted_at FROM failed_logins"
+	var args []interface{}
+	if username != "" {
+		query += " WHERE username = ?"
+		args = append(args, username)
+	}
+	query += " ORDER BY attempted_at DESC, id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []*models.FailedLogin{}
+	for rows.Next() {
+		var attempt models.FailedLogin
+		var userID sql.NullInt64
+		if err := rows.Scan(&attempt.ID, &attempt.Username, &userID, &attempt.IPAddress, &attempt.UserAgent, &attempt.AttemptedAt); err != nil {
+			return nil, err
+		}
+		if userID.Valid {
+			attempt.UserID = &userID.Int64
+		}
+		attempts = append(attempts, &attempt)
+	}
+	return attempts, rows.Err()
+}
+
+// PurgeFailedLogins deletes failed login records older than a time and returns how many were removed.
+func (s *Store) PurgeFailedLogins(before time.Time) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM failed_logins WHERE attempted_at < ?", before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
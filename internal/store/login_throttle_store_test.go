@@ -0,0 +1,63 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestLoginThrottleStore(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	s := store.New(db)
+	user, _ := s.CreateUser("reader", "hash", "user")
+
+	for i := 1; i <= 2; i++ {
+		count, err := s.RecordFailedLogin(user.ID, "reader", "10.0.0.1", "curl")
+		if err != nil || count != i {
+			t.Fatalf("Expected failure count %d, got %d (%v)", i, count, err)
+		}
+	}
+	if count, err := s.RecordFailedLogin(0, "ghost", "10.0.0.1", ""); err != nil || count != 0 {
+		t.Errorf("Expected unknown users to have no count, got %d (%v)", count, err)
+	}
+
+	state, _ := s.GetUserLoginState(user.ID)
+	if state.FailedCount != 2 || state.LastFailedAt == nil || state.LockedUntil != nil {
+		t.Errorf("Unexpected state %+v", state)
+	}
+	count, last, err := s.FailedLoginsFromIP("10.0.0.1", time.Now().Add(-time.Minute))
+	if err != nil || count != 3 || time.Since(last) > time.Minute {
+		t.Errorf("Expected 3 recent failures from the address, got %d at %v (%v)", count, last, err)
+	}
+
+	if err := s.LockUser(user.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("LockUser failed: %v", err)
+	}
+	state, _ = s.GetUserLoginState(user.ID)
+	if state.LockedUntil == nil || state.FailedCount != 0 {
+		t.Errorf("Expected a locked account with a fresh count, got %+v", state)
+	}
+	users, _ := s.ListUsers()
+	if users[0].LockedUntil == nil {
+		t.Error("Expected ListUsers to report the lock")
+	}
+
+	s.ResetLoginFailures(user.ID)
+	if state, _ = s.GetUserLoginState(user.ID); state.LockedUntil != nil {
+		t.Error("Expected the lock to be cleared")
+	}
+
+	attempts, _ := s.ListFailedLogins("reader", 10, 0)
+	if len(attempts) != 2 || attempts[0].UserID == nil || *attempts[0].UserID != user.ID {
+		t.Errorf("Expected 2 attempts for reader, got %+v", attempts)
+	}
+	if all, _ := s.ListFailedLogins("", 10, 0); len(all) != 3 {
+		t.Errorf("Expected 3 attempts in total, got %d", len(all))
+	}
+
+	if n, _ := s.PurgeFailedLogins(time.Now().Add(time.Minute)); n != 3 {
+		t.Errorf("Expected 3 records purged, got %d", n)
+	}
+}
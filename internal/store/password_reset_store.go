@@ -0,0 +1,64 @@
+package store
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid or expired")
+
+// CreatePasswordResetToken issues a one-time password reset token for a user, replacing any
+// earlier token, and returns it with its expiry. Only a hash of the token is stored.
+func (s *Store) CreatePasswordResetToken(userID int64, lifetime time.Duration) (string, time.Time, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM password_reset_tokens WHERE user_id = ?", userID); err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now().UTC()
+	expiresAt := now.Add(lifetime)
+	_, err = tx.Exec("INSERT INTO password_reset_tokens (user_id, token_hash, created_at, expires_at) VALUES (?, ?, ?, ?)",
+		userID, hashAPIToken(token), now, expiresAt)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, tx.Commit()
+}
+
+// ConsumePasswordResetToken checks a password reset token and deletes it, so it cannot be used
+// again. It returns the user the token was issued for, or ErrPasswordResetTokenInvalid.
+func (s *Store) ConsumePasswordResetToken(token string) (int64, error) {
+	var id, userID int64
+	var expiresAt time.Time
+	err := s.db.QueryRow("SELECT id, user_id, expires_at FROM password_reset_tokens WHERE token_hash = ?", hashAPIToken(token)).
+		Scan(&id, &userID, &expiresAt)
+	if err == sql.ErrNoRows {
+		return 0, ErrPasswordResetTokenInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Deleting by ID settles concurrent uses of the same token: only one of them removes the row.
+	result, err := s.db.Exec("DELETE FROM password_reset_tokens WHERE id = ?", id)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 || time.Now().After(expiresAt) {
+		return 0, ErrPasswordResetTokenInvalid
+	}
+	return userID, nil
+}
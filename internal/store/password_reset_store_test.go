@@ -0,0 +1,40 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestPasswordResetStore(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	s := store.New(db)
+	user, _ := s.CreateUser("reader", "hash", "user")
+
+	first, _, err := s.CreatePasswordResetToken(user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("CreatePasswordResetToken failed: %v", err)
+	}
+	second, expiresAt, _ := s.CreatePasswordResetToken(user.ID, time.Hour)
+	if time.Until(expiresAt) < 59*time.Minute {
+		t.Errorf("Expected the token to expire in an hour, got %v", expiresAt)
+	}
+
+	if _, err := s.ConsumePasswordResetToken(first); !errors.Is(err, store.ErrPasswordResetTokenInvalid) {
+		t.Errorf("Expected a replaced token to be invalid, got %v", err)
+	}
+	if userID, err := s.ConsumePasswordResetToken(second); err != nil || userID != user.ID {
+		t.Fatalf("Expected the token to resolve to the user, got %d (%v)", userID, err)
+	}
+	if _, err := s.ConsumePasswordResetToken(second); !errors.Is(err, store.ErrPasswordResetTokenInvalid) {
+		t.Errorf("Expected a used token to be invalid, got %v", err)
+	}
+
+	expired, _, _ := s.CreatePasswordResetToken(user.ID, -time.Minute)
+	if _, err := s.ConsumePasswordResetToken(expired); !errors.Is(err, store.ErrPasswordResetTokenInvalid) {
+		t.Errorf("Expected an expired token to be invalid, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrRoleExists   = errors.New("role already exists")
+	ErrBuiltInRole  = errors.New("built-in roles cannot be changed")
+	ErrRoleInUse    = errors.New("role is assigned to users")
+)
+
+// ListRoles returns every role with the number of users assigned to it, built-in roles first.
+func (s *Store) ListRoles() ([]*models.Role, error) {
+	query := `
+		SELECT r.name, r.description, r.permissions, r.built_in, r.created_at,
+		       (SELECT COUNT(*) FROM users u WHERE u.role = r.name)
+		FROM roles r
+		ORDER BY r.built_in DESC, r.name ASC`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*models.Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// GetRole returns a single role by name, or ErrRoleNotFound.
+func (s *Store) GetRole(name string) (*models.Role, error) {
+	query := `
+		SELECT r.name, r.description, r.permissions, r.built_in, r.created_at,
+		       (SELECT COUNT(*) FROM users u WHERE u.role = r.name)
+		FROM roles r WHERE r.name = ?`
+	role, err := scanRole(s.db.QueryRow(query, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRoleNotFound
+	}
+	return role, err
+}
+
+// RoleExists reports whether a role with the given name exists.
+func (s *Store) RoleExists(name string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM roles WHERE name = ?)", name).Scan(&exists)
+	return exists, err
+}
+
+// CreateRole adds a custom role. Unknown permissions are dropped.
+func (s *Store) CreateRole(name, description string, permissions []string) (*models.Role, error) {
+	exists, err := s.RoleExists(name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrRoleExists
+	}
+	permissions = cleanPermissions(permissions)
+	now := time.Now()
+	_, err = s.db.Exec("INSERT INTO roles (name, description, permissions, built_in, created_at) VALUES (?, ?, ?, 0, ?)",
+		name, description, strings.Join(permissions, ","), now)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Role{Name: name, Description: description, Permissions: permissions, CreatedAt: now}, nil
+}
+
+// UpdateRole changes a role's description and permissions. The admin role cannot be changed,
+// so there is always a role that can manage users.
+func (s *Store) UpdateRole(name, description string, permissions []string) error {
+	if name == models.RoleAdmin {
+		return ErrBuiltInRole
+	}
+	result, err := s.db.Exec("UPDATE roles SET description = ?, permissions = ? WHERE name = ?",
+		description, strings.Join(cleanPermissions(permissions), ","), name)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// DeleteRole removes a custom role. Built-in roles and roles still assigned to users are kept.
+func (s *Store) DeleteRole(name string) error {
+	role, err := s.GetRole(name)
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return ErrBuiltInRole
+	}
+	if role.UserCount > 0 {
+		return ErrRoleInUse
+	}
+	_, err = s.db.Exec("DELETE FROM roles WHERE name = ?", name)
+	return err
+}
+
+func scanRole(row rowScanner) (*models.Role, error) {
+	var role models.Role
+	var permissions string
+	if err := row.Scan(&role.Name, &role.Description, &permissions, &role.BuiltIn, &role.CreatedAt, &role.UserCount); err != nil {
+		return nil, err
+	}
+	role.Permissions = rolePermissions(role.Name, sql.NullString{String: permissions, Valid: true})
+	return &role, nil
+}
+
+// rolePermissions parses a role's stored permission list. The admin role always has every
+// permission, including ones added after it was created.
+func rolePermissions(role string, stored sql.NullString) []string {
+	if role == models.RoleAdmin {
+		return append([]string(nil), models.AllPermissions...)
+	}
+	permissions := []string{}
+	if stored.String == "" {
+		return permissions
+	}
+	return cleanPermissions(strings.Split(stored.String, ","))
+}
+
+// cleanPermissions returns the known permissions in perms, deduplicated and in canonical order.
+func cleanPermissions(perms []string) []string {
+	cleaned := []string{}
+	for _, p := range models.AllPermissions {
+		for _, q := range perms {
+			if strings.TrimSpace(q) == p {
+				cleaned = append(cleaned, p)
+				break
+			}
+		}
+	}
+	return cleaned
+}
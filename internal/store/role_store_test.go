@@ -0,0 +1,88 @@
+package store_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestBuiltInRoles(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	s := store.New(db)
+
+	admin, err := s.GetRole(models.RoleAdmin)
+	if err != nil {
+		t.Fatalf("GetRole failed: %v", err)
+	}
+	if !admin.BuiltIn || !slices.Equal(admin.Permissions, models.AllPermissions) {
+		t.Errorf("Expected a built-in admin role with every permission, got %+v", admin)
+	}
+	user, _ := s.GetRole(models.RoleUser)
+	want := []string{models.PermReadLibrary, models.PermManageDownloads, models.PermManageTags, models.PermUploadCovers}
+	if !slices.Equal(user.Permissions, want) {
+		t.Errorf("Expected user role permissions %v, got %v", want, user.Permissions)
+	}
+
+	if err := s.UpdateRole(models.RoleAdmin, "", nil); !errors.Is(err, store.ErrBuiltInRole) {
+		t.Errorf("Expected the admin role to be immutable, got %v", err)
+	}
+	if err := s.DeleteRole(models.RoleUser); !errors.Is(err, store.ErrBuiltInRole) {
+		t.Errorf("Expected built-in roles to be kept, got %v", err)
+	}
+}
+
+func TestCustomRoles(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	s := store.New(db)
+
+	role, err := s.CreateRole("reader", "Read only", []string{models.PermReadLibrary, "bogus", models.PermReadLibrary})
+	if err != nil {
+		t.Fatalf("CreateRole failed: %v", err)
+	}
+	if !slices.Equal(role.Permissions, []string{models.PermReadLibrary}) {
+		t.Errorf("Expected unknown and duplicate permissions to be dropped, got %v", role.Permissions)
+	}
+	if _, err := s.CreateRole("reader", "", nil); !errors.Is(err, store.ErrRoleExists) {
+		t.Errorf("Expected ErrRoleExists, got %v", err)
+	}
+
+	u, err := s.CreateUser("alice", "hash", "reader")
+	if err != nil {
+		t.Fatalf("Expected a user with a custom role to be created, got %v", err)
+	}
+	loaded, _ := s.GetUserByID(u.ID)
+	if !loaded.HasPermission(models.PermReadLibrary) || loaded.HasPermission(models.PermManageTags) {
+		t.Errorf("Unexpected permissions %v", loaded.Permissions)
+	}
+
+	if err := s.UpdateRole("reader", "Readers and taggers", []string{models.PermManageTags, models.PermReadLibrary}); err != nil {
+		t.Fatalf("UpdateRole failed: %v", err)
+	}
+	loaded, _ = s.GetUserByUsername("alice")
+	if !slices.Equal(loaded.Permissions, []string{models.PermReadLibrary, models.PermManageTags}) {
+		t.Errorf("Expected updated permissions in canonical order, got %v", loaded.Permissions)
+	}
+
+	if err := s.DeleteRole("reader"); !errors.Is(err, store.ErrRoleInUse) {
+		t.Errorf("Expected ErrRoleInUse, got %v", err)
+	}
+	roles, _ := s.ListRoles()
+	if len(roles) != 3 || roles[2].Name != "reader" || roles[2].UserCount != 1 {
+		t.Errorf("Unexpected roles %+v", roles)
+	}
+
+	s.DeleteUser(u.ID)
+	if err := s.DeleteRole("reader"); err != nil {
+		t.Errorf("DeleteRole failed: %v", err)
+	}
+	if _, err := s.GetRole("reader"); !errors.Is(err, store.ErrRoleNotFound) {
+		t.Errorf("Expected ErrRoleNotFound, got %v", err)
+	}
+	if err := s.UpdateRole("reader", "", nil); !errors.Is(err, store.ErrRoleNotFound) {
+		t.Errorf("Expected ErrRoleNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package store
+
+import (
+	"crypto/rand"
+	"time"
+)
+
+// ResourceProxySecret names the key used to sign resource proxy URLs.
+const ResourceProxySecret = "resource_proxy"
+
+// GetOrCreateSecret returns the server secret with the given name, generating and storing a
+// random one of size bytes the first time it is asked for.
+func (s *Store) GetOrCreateSecret(name string, size int) ([]byte, error) {
+	value := make([]byte, size)
+	if _, err := rand.Read(value); err != nil {
+		return nil, err
+	}
+	// If two callers race, only the first insert wins and both read back the same value.
+	_, err := s.db.Exec("INSERT OR IGNORE INTO server_secrets (name, value, created_at) VALUES (?, ?, ?)",
+		name, value, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	var stored []byte
+	if err := s.db.QueryRow("SELECT value FROM server_secrets WHERE name = ?", name).Scan(&stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
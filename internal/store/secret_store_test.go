@@ -0,0 +1,30 @@
+package store_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestGetOrCreateSecret(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	s := store.New(db)
+
+	first, err := s.GetOrCreateSecret(store.ResourceProxySecret, 32)
+	if err != nil {
+		t.Fatalf("GetOrCreateSecret failed: %v", err)
+	}
+	if len(first) != 32 {
+		t.Fatalf("Expected a 32-byte secret, got %d bytes", len(first))
+	}
+	second, _ := s.GetOrCreateSecret(store.ResourceProxySecret, 32)
+	if !bytes.Equal(first, second) {
+		t.Error("Expected the stored secret to be returned again")
+	}
+	other, _ := s.GetOrCreateSecret("other", 32)
+	if bytes.Equal(first, other) {
+		t.Error("Expected differently named secrets to differ")
+	}
+}
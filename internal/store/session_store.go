@@ -0,0 +1,151 @@
+package store
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/models"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionExpired  = errors.New("session expired")
+)
+
+const sessionColumns = "id, user_id, user_agent, ip_address, created_at, last_seen_at, expiry"
+
+func scanSession(row rowScanner) (*models.Session, error) {
+	var session models.Session
+	err := row.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// CreateSession creates a session for a user that expires after lifetime, and returns the
+// session token. The user agent and IP address are recorded so users can tell their sessions apart.
+func (s *Store) CreateSession(userID int64, lifetime time.Duration, userAgent, ipAddress string) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+	now := time.Now().UTC()
+	_, err := s.db.Exec(
+		"INSERT INTO sessions (token, user_id, user_agent, ip_address, created_at, last_seen_at, expiry) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		token, userID, userAgent, ipAddress, now, now, now.Add(lifetime),
+	)
+	return token, err
+}
+
+// GetSession returns the session for a token. Expired sessions are deleted and reported as
+// ErrSessionExpired.
+func (s *Store) GetSession(token string) (*models.Session, error) {
+	session, err := scanSession(s.db.QueryRow("SELECT "+sessionColumns+" FROM sessions WHERE token = ?", token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		s.DeleteSession(token) // Clean up expired session
+		return nil, ErrSessionExpired
+	}
+	return session, nil
+}
+
+// GetUserFromSession retrieves a user based on a session token.
+func (s *Store) GetUserFromSession(token string) (*models.User, error) {
+	session, err := s.GetSession(token)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetUserByID(session.UserID)
+}
+
+// TouchSession records that a session was just used from ipAddress and moves its expiry to
+// expiresAt.
+func (s *Store) TouchSession(sessionID int64, ipAddress string, expiresAt time.Time) error {
+	_, err := s.db.Exec("UPDATE sessions SET last_seen_at = ?, ip_address = ?, expiry = ? WHERE id = ?",
+		time.Now().UTC(), ipAddress, expiresAt.UTC(), sessionID)
+	return err
+}
+
+// ListUserSessions returns a user's unexpired sessions, most recently used first.
+func (s *Store) ListUserSessions(userID int64) ([]*models.Session, error) {
+	rows, err := s.db.Query(
+		"SELECT "+sessionColumns+" FROM sessions WHERE user_id = ? AND expiry > ? ORDER BY last_seen_at DESC, id DESC",
+		userID, time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// DeleteSession removes a session from the database (used for logout).
+func (s *Store) DeleteSession(token string) error {
+	_, err := s.db.Exec("DELETE FROM sessions WHERE token = ?", token)
+	return err
+}
+
+// DeleteUserSession revokes one of a user's sessions. It returns ErrSessionNotFound if the
+// session does not exist or belongs to someone else.
+func (s *Store) DeleteUserSession(userID, sessionID int64) error {
+	result, err := s.db.Exec("DELETE FROM sessions WHERE id = ? AND user_id = ?", sessionID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteUserSessions logs a user out everywhere and returns how many sessions were removed.
+func (s *Store) DeleteUserSessions(userID int64) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteOtherUserSessions logs a user out of every session except keepSessionID (0 keeps none)
+// and returns how many sessions were removed.
+func (s *Store) DeleteOtherUserSessions(userID, keepSessionID int64) (int64, error) {
+	result, err := s.db.Exec("DELETE FROM sessions WHERE user_id = ? AND id != ?", userID, keepSessionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeExpiredSessions deletes sessions whose expiry has passed and returns how many were removed.
+func (s *Store) PurgeExpiredSessions() (int64, error) {
+	result, err := s.db.Exec("DELETE FROM sessions WHERE expiry <= ?", time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,81 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestSessionStore(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	s := store.New(db)
+	user, _ := s.CreateUser("reader", "hash", "user")
+	other, _ := s.CreateUser("other", "hash", "user")
+
+	token, err := s.CreateSession(user.ID, time.Hour, "Firefox", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	session, err := s.GetSession(token)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if session.UserAgent != "Firefox" || session.IPAddress != "10.0.0.1" || session.CreatedAt.IsZero() {
+		t.Errorf("Unexpected session %+v", session)
+	}
+	if until := time.Until(session.ExpiresAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("Expected the session to expire in an hour, got %v", until)
+	}
+
+	t.Run("Touch", func(t *testing.T) {
+		expires := time.Now().Add(48 * time.Hour)
+		if err := s.TouchSession(session.ID, "10.0.0.2", expires); err != nil {
+			t.Fatalf("TouchSession failed: %v", err)
+		}
+		touched, _ := s.GetSession(token)
+		if touched.IPAddress != "10.0.0.2" || touched.ExpiresAt.Sub(expires).Abs() > time.Second {
+			t.Errorf("Expected the session to move, got %+v", touched)
+		}
+	})
+
+	t.Run("List And Revoke", func(t *testing.T) {
+		second, _ := s.CreateSession(user.ID, time.Hour, "Chrome", "10.0.0.3")
+		sessions, _ := s.ListUserSessions(user.ID)
+		if len(sessions) != 2 {
+			t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+		}
+
+		if err := s.DeleteUserSession(other.ID, session.ID); !errors.Is(err, store.ErrSessionNotFound) {
+			t.Errorf("Expected another user's session to be not found, got %v", err)
+		}
+		if err := s.DeleteUserSession(user.ID, session.ID); err != nil {
+			t.Fatalf("DeleteUserSession failed: %v", err)
+		}
+		if _, err := s.GetSession(token); !errors.Is(err, store.ErrSessionNotFound) {
+			t.Errorf("Expected the revoked session to be gone, got %v", err)
+		}
+
+		s.CreateSession(user.ID, time.Hour, "Safari", "10.0.0.4")
+		if n, err := s.DeleteUserSessions(user.ID); err != nil || n != 2 {
+			t.Errorf("Expected 2 sessions revoked, got %d (%v)", n, err)
+		}
+		if _, err := s.GetSession(second); err == nil {
+			t.Error("Expected all sessions to be revoked")
+		}
+	})
+
+	t.Run("Purge Expired", func(t *testing.T) {
+		live, _ := s.CreateSession(user.ID, time.Hour, "", "")
+		db.Exec("INSERT INTO sessions (token, user_id, expiry) VALUES (?, ?, ?)", "stale", user.ID, time.Now().UTC().Add(-time.Minute))
+
+		if n, err := s.PurgeExpiredSessions(); err != nil || n != 1 {
+			t.Errorf("Expected 1 expired session purged, got %d (%v)", n, err)
+		}
+		if _, err := s.GetSession(live); err != nil {
+			t.Errorf("Expected the live session to remain, got %v", err)
+		}
+	})
+}
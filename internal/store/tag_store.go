@@ -9,15 +9,32 @@ import (
 )
 
 // ListTagsWithCounts returns all tags along with the count of series they are associated with.
-func (s *Store) ListTagsWithCounts() ([]*models.Tag, error) {
+// Only folders visible to the user are counted. The user's hidden tags are left out, as are, for
+// restricted users, tags without any visible folder.
+func (s *Store) ListTagsWithCounts(userID int64) ([]*models.Tag, error) {
+	restricted, _, _, err := s.hasContentRestrictions(userID)
+	if err != nil {
+		return nil, err
+	}
+	access, accessArgs, err := s.folderFilter(userID, "st.folder_id")
+	if err != nil {
+		return nil, err
+	}
+	having := ""
+	if restricted {
+		having = "HAVING folder_count > 0"
+	}
 	query := `
 		SELECT t.id, t.name, COUNT(st.folder_id) as folder_count
 		FROM tags t
-		LEFT JOIN folder_tags st ON t.id = st.tag_id
+		LEFT JOIN folder_tags st ON t.id = st.tag_id AND ` + access + `
+		WHERE t.name NOT IN (SELECT tag_name FROM user_hidden_tags WHERE user_id = ?)
 		GROUP BY t.id
+		` + having + `
 		ORDER BY t.name ASC
 	`
-	rows, err := s.db.Query(query)
+	args := append(accessArgs, userID)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
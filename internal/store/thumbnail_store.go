@@ -0,0 +1,74 @@
+package store
+
+// ThumbnailRef is a chapter's or folder's stored thumbnail.
+type ThumbnailRef struct {
+	ID        int64
+	Thumbnail string
+}
+
+// ListChapterDataURIThumbnails returns up to limit chapters whose thumbnail is still an inline
+// base64 data URI, from before thumbnails were stored as files.
+func (s *Store) ListChapterDataURIThumbnails(limit int) ([]ThumbnailRef, error) {
+	return s.listDataURIThumbnails("SELECT id, thumbnail FROM chapters WHERE thumbnail LIKE 'data:%' ORDER BY id LIMIT ?", limit)
+}
+
+// ListFolderDataURIThumbnails returns up to limit folders whose thumbnail is still an inline
+// base64 data URI.
+func (s *Store) ListFolderDataURIThumbnails(limit int) ([]ThumbnailRef, error) {
+	return s.listDataURIThumbnails("SELECT id, thumbnail FROM folders WHERE thumbnail LIKE 'data:%' ORDER BY id LIMIT ?", limit)
+}
+
+func (s *Store) listDataURIThumbnails(query string, limit int) ([]ThumbnailRef, error) {
+	rows, err := s.db.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []ThumbnailRef
+	for rows.Next() {
+		var ref ThumbnailRef
+		if err := rows.Scan(&ref.ID, &ref.Thumbnail); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// CountDataURIThumbnails returns how many chapters and folders still have a data URI thumbnail.
+func (s *Store) CountDataURIThumbnails() (int, error) {
+	var count int
+	err := s.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM chapters WHERE thumbnail LIKE 'data:%') +
+		       (SELECT COUNT(*) FROM folders WHERE thumbnail LIKE 'data:%')`).Scan(&count)
+	return count, err
+}
+
+// ThumbnailsInUse returns every distinct thumbnail stored on a chapter or folder.
+func (s *Store) ThumbnailsInUse() (map[string]bool, error) {
+	rows, err := s.db.Query(`
+		SELECT thumbnail FROM chapters WHERE thumbnail IS NOT NULL AND thumbnail != ''
+		UNION
+		SELECT thumbnail FROM folders WHERE thumbnail IS NOT NULL AND thumbnail != ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	thumbnails := make(map[string]bool)
+	for rows.Next() {
+		var thumbnail string
+		if err := rows.Scan(&thumbnail); err != nil {
+			return nil, err
+		}
+		thumbnails[thumbnail] = true
+	}
+	return thumbnails, rows.Err()
+}
+
+// Vacuum rebuilds the database file, returning the space freed by deleted data to the disk.
+func (s *Store) Vacuum() error {
+	_, err := s.db.Exec("VACUUM")
+	return err
+}
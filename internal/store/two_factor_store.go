@@ -0,0 +1,123 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// UserTOTP is a user's TOTP state.
+type UserTOTP struct {
+	Secret   string // empty if the user never started enrollment
+	Enabled  bool
+	LastStep int64 // time step of the last accepted code
+}
+
+// GetUserTOTP returns a user's TOTP state.
+func (s *Store) GetUserTOTP(userID int64) (*UserTOTP, error) {
+	var t UserTOTP
+	var secret sql.NullString
+	err := s.db.QueryRow("SELECT totp_secret, totp_enabled, totp_last_step FROM users WHERE id = ?", userID).Scan(&secret, &t.Enabled, &t.LastStep)
+	if err != nil {
+		return nil, err
+	}
+	t.Secret = secret.String
+	return &t, nil
+}
+
+// SetUserTOTPSecret stores a new, not yet confirmed, TOTP secret. It does not change whether
+// 2FA is enabled.
+func (s *Store) SetUserTOTPSecret(userID int64, secret string) error {
+	_, err := s.db.Exec("UPDATE users SET totp_secret = ? WHERE id = ?", secret, userID)
+	return err
+}
+
+// EnableUserTOTP turns on 2FA with the stored secret and replaces the user's recovery codes.
+func (s *Store) EnableUserTOTP(userID int64, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET totp_enabled = true, totp_last_step = ? WHERE id = ?", step, userID); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DisableUserTOTP turns off 2FA, forgetting the secret and recovery codes.
+func (s *Store) DisableUserTOTP(userID int64) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET totp_secret = NULL, totp_enabled = false, totp_last_step = 0 WHERE id = ?", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AcceptTOTPStep records that a code for the given time step was used. It returns false if a
+// code for that step (or a later one) was already accepted, so codes cannot be replayed.
+func (s *Store) AcceptTOTPStep(userID int64, step int64) (bool, error) {
+	result, err := s.db.Exec("UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?", step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// ReplaceRecoveryCodes replaces all of a user's recovery codes.
+func (s *Store) ReplaceRecoveryCodes(userID int64, codeHashes []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int64, codeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec("INSERT INTO user_recovery_codes (user_id, code_hash) VALUES (?, ?)", userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It returns false if the code does not
+// exist or was already used.
+func (s *Store) UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	query := `UPDATE user_recovery_codes SET used_at = ?
+	          WHERE id = (SELECT id FROM user_recovery_codes WHERE user_id = ? AND code_hash = ? AND used_at IS NULL LIMIT 1)`
+	result, err := s.db.Exec(query, time.Now(), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return rowsAffected > 0, err
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left.
+func (s *Store) CountRecoveryCodes(userID int64) (int, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = ? AND used_at IS NULL", userID).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,58 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/vrsandeep/mango-go/internal/store"
+	"github.com/vrsandeep/mango-go/internal/testutil"
+)
+
+func TestTwoFactorStore(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	s := store.New(db)
+	user, _ := s.CreateUser("careful", "hash", "user")
+
+	if err := s.SetUserTOTPSecret(user.ID, "SECRET"); err != nil {
+		t.Fatalf("SetUserTOTPSecret failed: %v", err)
+	}
+	totp, _ := s.GetUserTOTP(user.ID)
+	if totp.Secret != "SECRET" || totp.Enabled {
+		t.Fatalf("Expected an unconfirmed secret, got %+v", totp)
+	}
+
+	if err := s.EnableUserTOTP(user.ID, 100, []string{"a", "b"}); err != nil {
+		t.Fatalf("EnableUserTOTP failed: %v", err)
+	}
+	if u, _ := s.GetUserByID(user.ID); !u.TOTPEnabled {
+		t.Error("Expected the user to report 2FA enabled")
+	}
+
+	// Steps must move forward so a code cannot be used twice
+	if ok, _ := s.AcceptTOTPStep(user.ID, 100); ok {
+		t.Error("Expected the enrollment step to be rejected")
+	}
+	if ok, _ := s.AcceptTOTPStep(user.ID, 101); !ok {
+		t.Error("Expected a newer step to be accepted")
+	}
+
+	if ok, _ := s.UseRecoveryCode(user.ID, "a"); !ok {
+		t.Error("Expected the recovery code to be accepted")
+	}
+	if ok, _ := s.UseRecoveryCode(user.ID, "a"); ok {
+		t.Error("Expected a recovery code to work only once")
+	}
+	if n, _ := s.CountRecoveryCodes(user.ID); n != 1 {
+		t.Errorf("Expected 1 recovery code left, got %d", n)
+	}
+
+	if err := s.DisableUserTOTP(user.ID); err != nil {
+		t.Fatalf("DisableUserTOTP failed: %v", err)
+	}
+	totp, _ = s.GetUserTOTP(user.ID)
+	if totp.Enabled || totp.Secret != "" {
+		t.Errorf("Expected 2FA to be cleared, got %+v", totp)
+	}
+	if n, _ := s.CountRecoveryCodes(user.ID); n != 0 {
+		t.Errorf("Expected recovery codes to be removed, got %d", n)
+	}
+}
@@ -1,18 +1,18 @@
 package store
 
 import (
-	"crypto/rand"
 	"database/sql"
-	"encoding/hex"
 	"errors"
 	"time"
 
 	"github.com/vrsandeep/mango-go/internal/models"
 )
 
+var ErrUserAlreadyLinked = errors.New("user is linked to another identity")
+
 // ListUsers retrieves all users from the database, ordered by username.
 func (s *Store) ListUsers() ([]*models.User, error) {
-	rows, err := s.db.Query("SELECT id, username, role, created_at FROM users ORDER BY username ASC")
+	rows, err := s.db.Query("SELECT id, username, role, totp_enabled, created_at, locked_until, pending FROM users ORDER BY username ASC")
 	if err != nil {
 		return nil, err
 	}
@@ -21,9 +21,13 @@ func (s *Store) ListUsers() ([]*models.User, error) {
 	var users []*models.User
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt); err != nil {
+		var lockedUntil sql.NullTime
+		if err := rows.Scan(&user.ID, &user.Username, &user.Role, &user.TOTPEnabled, &user.CreatedAt, &lockedUntil, &user.Pending); err != nil {
 			return nil, err
 		}
+		if lockedUntil.Valid && lockedUntil.Time.After(time.Now()) {
+			user.LockedUntil = &lockedUntil.Time
+		}
 		users = append(users, &user)
 	}
 	return users, nil
@@ -64,41 +68,64 @@ func (s *Store) DeleteUser(id int64) error {
 	return err
 }
 
+// UpdateUserKOSyncKey stores the hash of the user's KOReader sync key.
+func (s *Store) UpdateUserKOSyncKey(id int64, keyHash string) error {
+	_, err := s.db.Exec("UPDATE users SET kosync_key_hash = ? WHERE id = ?", keyHash, id)
+	return err
+}
+
+// userSelect loads a user together with the permissions of their role.
+const userSelect = `SELECT u.id, u.username, u.password_hash, u.kosync_key_hash, u.role, u.totp_enabled, u.created_at, u.pending, r.permissions
+	FROM users u LEFT JOIN roles r ON r.name = u.role`
+
 // GetUserByUsername retrieves a user by their unique username.
 func (s *Store) GetUserByUsername(username string) (*models.User, error) {
-	var user models.User
-	query := "SELECT id, username, password_hash, role, created_at FROM users WHERE username = ?"
-	err := s.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt)
-	return &user, err
+	return scanUser(s.db.QueryRow(userSelect+" WHERE u.username = ?", username))
 }
 
 // GetUserByID retrieves a user by their primary key.
 func (s *Store) GetUserByID(id int64) (*models.User, error) {
+	return scanUser(s.db.QueryRow(userSelect+" WHERE u.id = ?", id))
+}
+
+// scanUser reads a row selected with userSelect. Pending accounts get no permissions, so they
+// are shut out of everything however they authenticate.
+func scanUser(row rowScanner) (*models.User, error) {
 	var user models.User
-	query := "SELECT id, username, password_hash, role, created_at FROM users WHERE id = ?"
-	err := s.db.QueryRow(query, id).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt)
+	var kosyncKeyHash, permissions sql.NullString
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &kosyncKeyHash, &user.Role, &user.TOTPEnabled, &user.CreatedAt, &user.Pending, &permissions)
+	user.KOSyncKeyHash = kosyncKeyHash.String
+	user.Permissions = rolePermissions(user.Role, permissions)
+	if user.Pending {
+		user.Permissions = []string{}
+	}
 	return &user, err
 }
 
-// GetUserFromSession retrieves a user based on a session token.
-func (s *Store) GetUserFromSession(token string) (*models.User, error) {
-	var userID int64
-	var expiry time.Time
-	query := "SELECT user_id, expiry FROM sessions WHERE token = ?"
-	err := s.db.QueryRow(query, token).Scan(&userID, &expiry)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("invalid session token")
-		}
+// GetUserByOIDCSubject retrieves the user linked to an OpenID Connect subject.
+func (s *Store) GetUserByOIDCSubject(subject string) (*models.User, error) {
+	var id int64
+	if err := s.db.QueryRow("SELECT id FROM users WHERE oidc_subject = ?", subject).Scan(&id); err != nil {
 		return nil, err
 	}
+	return s.GetUserByID(id)
+}
 
-	if time.Now().After(expiry) {
-		s.DeleteSession(token) // Clean up expired session
-		return nil, errors.New("session expired")
+// LinkUserOIDCSubject links a user to an OpenID Connect subject. Users already linked to a
+// different subject are left alone and ErrUserAlreadyLinked is returned.
+func (s *Store) LinkUserOIDCSubject(id int64, subject string) error {
+	result, err := s.db.Exec("UPDATE users SET oidc_subject = ? WHERE id = ? AND (oidc_subject IS NULL OR oidc_subject = ?)", subject, id, subject)
+	if err != nil {
+		return err
 	}
-
-	return s.GetUserByID(userID)
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrUserAlreadyLinked
+	}
+	return nil
 }
 
 // CountUsers returns the total number of users in the database.
@@ -107,21 +134,3 @@ func (s *Store) CountUsers() (int, error) {
 	err := s.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count)
 	return count, err
 }
-
-// CreateSession creates a new session for a user and returns the session token.
-func (s *Store) CreateSession(userID int64) (string, error) {
-	tokenBytes := make([]byte, 32)
-	if _, err := rand.Read(tokenBytes); err != nil {
-		return "", err
-	}
-	token := hex.EncodeToString(tokenBytes)
-	expiry := time.Now().Add(7 * 24 * time.Hour) // 1 week session
-	_, err := s.db.Exec("INSERT INTO sessions (token, user_id, expiry) VALUES (?, ?, ?)", token, userID, expiry)
-	return token, err
-}
-
-// DeleteSession removes a session from the database (used for logout).
-func (s *Store) DeleteSession(token string) error {
-	_, err := s.db.Exec("DELETE FROM sessions WHERE token = ?", token)
-	return err
-}
@@ -102,7 +102,7 @@ func TestUserStore_Sessions(t *testing.T) {
 	user, _ := s.CreateUser("sessionuser", passwordHash, "user")
 
 	t.Run("Create and Get Session", func(t *testing.T) {
-		token, err := s.CreateSession(user.ID)
+		token, err := s.CreateSession(user.ID, time.Hour, "test-agent", "127.0.0.1")
 		if err != nil {
 			t.Fatalf("CreateSession failed: %v", err)
 		}
@@ -135,7 +135,7 @@ func TestUserStore_Sessions(t *testing.T) {
 	})
 
 	t.Run("Delete Session", func(t *testing.T) {
-		token, _ := s.CreateSession(user.ID)
+		token, _ := s.CreateSession(user.ID, time.Hour, "test-agent", "127.0.0.1")
 		err := s.DeleteSession(token)
 		if err != nil {
 			t.Fatalf("DeleteSession failed: %v", err)
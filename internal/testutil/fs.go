@@ -55,3 +55,38 @@ func CreateTestCBZWithThumbnail(t *testing.T, dir, name string, pages []string,
 	}
 	return filePath
 }
+
+// CreateTestCBZWithComicInfo creates a CBZ like CreateTestCBZ with a ComicInfo.xml entry at its root.
+func CreateTestCBZWithComicInfo(t *testing.T, dir, name string, pages []string, comicInfo string) string {
+	t.Helper()
+
+	imageData, err := base64.StdEncoding.DecodeString(tinyPNG)
+	if err != nil {
+		t.Fatalf("Failed to decode image data: %v", err)
+	}
+
+	filePath := filepath.Join(dir, name)
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatalf("Failed to create temp cbz file: %v", err)
+	}
+	defer file.Close()
+
+	zipWriter := zip.NewWriter(file)
+	defer zipWriter.Close()
+
+	entries := map[string][]byte{"ComicInfo.xml": []byte(comicInfo)}
+	for _, page := range pages {
+		entries[page] = imageData
+	}
+	for entryName, data := range entries {
+		writer, err := zipWriter.Create(entryName)
+		if err != nil {
+			t.Fatalf("Failed to create entry '%s' in zip: %v", entryName, err)
+		}
+		if _, err := writer.Write(data); err != nil {
+			t.Fatalf("Failed to write zip entry '%s': %v", entryName, err)
+		}
+	}
+	return filePath
+}
@@ -0,0 +1,202 @@
+package testutil
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vrsandeep/mango-go/internal/config"
+)
+
+// FakeOIDCProvider is a minimal OpenID Connect provider for tests. Its authorization endpoint
+// approves every request immediately for the identity set with SetIdentity, and its token
+// endpoint enforces PKCE (S256) and signs RS256 ID tokens.
+type FakeOIDCProvider struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key *rsa.PrivateKey
+
+	mu      sync.Mutex
+	subject string
+	claims  map[string]interface{}
+	codes   map[string]fakeOIDCCode
+}
+
+type fakeOIDCCode struct {
+	challenge string
+	nonce     string
+	subject   string
+	claims    map[string]interface{}
+}
+
+// NewFakeOIDCProvider starts a fake provider that is shut down when the test ends.
+func NewFakeOIDCProvider(t *testing.T) *FakeOIDCProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate OIDC signing key: %v", err)
+	}
+	p := &FakeOIDCProvider{
+		ClientID:     "mango-test",
+		ClientSecret: "secret",
+		key:          key,
+		subject:      "user-1",
+		claims:       map[string]interface{}{},
+		codes:        make(map[string]fakeOIDCCode),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/authorize", p.handleAuthorize)
+	mux.HandleFunc("/token", p.handleToken)
+	mux.HandleFunc("/jwks", p.handleJWKS)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Server.Close)
+	return p
+}
+
+// Issuer returns the provider's issuer URL.
+func (p *FakeOIDCProvider) Issuer() string {
+	return p.Server.URL
+}
+
+// Config returns an OIDC configuration pointing at the fake provider.
+func (p *FakeOIDCProvider) Config() config.OIDCConfig {
+	return config.OIDCConfig{
+		Issuer:        p.Issuer(),
+		ClientID:      p.ClientID,
+		ClientSecret:  p.ClientSecret,
+		Scopes:        []string{"openid", "profile", "groups"},
+		UsernameClaim: "preferred_username",
+		GroupsClaim:   "groups",
+	}
+}
+
+// SetIdentity sets the subject and extra ID token claims for the next logins.
+func (p *FakeOIDCProvider) SetIdentity(subject string, claims map[string]interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subject = subject
+	p.claims = claims
+}
+
+func (p *FakeOIDCProvider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.Issuer() + "/authorize",
+		"token_endpoint":                        p.Issuer() + "/token",
+		"jwks_uri":                              p.Issuer() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *FakeOIDCProvider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != p.ClientID || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	code := randomToken()
+	p.mu.Lock()
+	p.codes[code] = fakeOIDCCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), subject: p.subject, claims: p.claims}
+	p.mu.Unlock()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *FakeOIDCProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.ClientID || clientSecret != p.ClientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+
+	p.mu.Lock()
+	grant, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":   p.Issuer(),
+		"sub":   grant.subject,
+		"aud":   p.ClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": grant.nonce,
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": randomToken(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     p.sign(claims),
+	})
+}
+
+func (p *FakeOIDCProvider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": "test",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// sign returns an RS256 JWT for the claims.
+func (p *FakeOIDCProvider) sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
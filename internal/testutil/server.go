@@ -15,6 +15,10 @@ import (
 	"github.com/vrsandeep/mango-go/internal/websocket"
 )
 
+// LoopbackOutbound lets tests fetch from httptest servers, which listen on loopback
+// addresses that the outbound HTTP policy blocks by default.
+var LoopbackOutbound = config.OutboundConfig{Allow: []string{"127.0.0.0/8", "::1"}}
+
 func SetupTestApp(t *testing.T) *core.App {
 	t.Helper()
 	db := SetupTestDB(t)
@@ -24,6 +28,8 @@ func SetupTestApp(t *testing.T) *core.App {
 		Library: struct {
 			Path string `mapstructure:"path"`
 		}{Path: t.TempDir()},
+		Thumbnails: config.ThumbnailConfig{Path: t.TempDir()},
+		Outbound:   LoopbackOutbound,
 	}
 	hub := websocket.NewHub()
 	go hub.Run()
@@ -45,6 +51,13 @@ func SetupTestApp(t *testing.T) *core.App {
 
 // SetupTestServer initializes a full core.App and api.Server for integration testing.
 func SetupTestServer(t *testing.T) (*api.Server, *sql.DB, *jobs.JobManager) {
+	t.Helper()
+	return SetupTestServerWithConfig(t, nil)
+}
+
+// SetupTestServerWithConfig is SetupTestServer with a hook to adjust the configuration
+// before the server is created.
+func SetupTestServerWithConfig(t *testing.T, configure func(cfg *config.Config)) (*api.Server, *sql.DB, *jobs.JobManager) {
 	t.Helper()
 	db := SetupTestDB(t)
 
@@ -52,6 +65,11 @@ func SetupTestServer(t *testing.T) (*api.Server, *sql.DB, *jobs.JobManager) {
 		Library: struct {
 			Path string `mapstructure:"path"`
 		}{Path: t.TempDir()},
+		Thumbnails: config.ThumbnailConfig{Path: t.TempDir()},
+		Outbound:   LoopbackOutbound,
+	}
+	if configure != nil {
+		configure(cfg)
 	}
 	hub := websocket.NewHub()
 	go hub.Run()
@@ -13,20 +13,28 @@ const (
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
 	maxMessageSize = 512
+	// refreshPeriod is how often a client's permissions are re-read, so role changes reach open
+	// sockets and sockets of revoked sessions or deleted users are closed.
+	refreshPeriod = 10 * time.Second
 )
 
+// The upgrader's default origin check rejects cross-origin requests, so other sites cannot
+// open a socket with a user's session cookie.
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins
-	},
 }
 
 type Client struct {
 	hub  *Hub
 	conn *websocket.Conn
 	send chan []byte
+	// permissions decide which messages are delivered. Only the hub's goroutine uses them; they
+	// are replaced through the hub's update channel.
+	permissions []string
+	// refresh re-reads the user's permissions, reporting false once the session or user the
+	// socket was opened with no longer exists.
+	refresh func() (permissions []string, ok bool)
 }
 
 func (c *Client) readPump() {
@@ -50,8 +58,10 @@ func (c *Client) readPump() {
 
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
+	refresh := time.NewTicker(refreshPeriod)
 	defer func() {
 		ticker.Stop()
+		refresh.Stop()
 		c.conn.Close()
 	}()
 	for {
@@ -75,17 +85,27 @@ func (c *Client) writePump() {
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+		case <-refresh.C:
+			permissions, ok := c.refresh()
+			if !ok {
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session ended"))
+				return
+			}
+			c.hub.update <- permissionUpdate{client: c, permissions: permissions}
 		}
 	}
 }
 
-func (h *Hub) ServeWs(w http.ResponseWriter, r *http.Request) {
+// ServeWs upgrades an already authenticated request to a websocket client of the hub, for a user
+// holding permissions. refresh is called every refreshPeriod, outside the hub, to re-read them.
+func (h *Hub) ServeWs(w http.ResponseWriter, r *http.Request, permissions []string, refresh func() ([]string, bool)) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	client := &Client{hub: h, conn: conn, send: make(chan []byte, 256)}
+	client := &Client{hub: h, conn: conn, send: make(chan []byte, 256), permissions: permissions, refresh: refresh}
 	client.hub.register <- client
 	go client.writePump()
 	go client.readPump()
@@ -3,20 +3,35 @@ package websocket
 import (
 	"encoding/json"
 	"log"
+	"slices"
 )
 
+// message is a broadcast payload. Messages with a permission are only sent to clients holding it.
+type message struct {
+	data       []byte
+	permission string
+}
+
+// permissionUpdate replaces a client's permissions with ones re-read from its user's role.
+type permissionUpdate struct {
+	client      *Client
+	permissions []string
+}
+
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan message
 	register   chan *Client
 	unregister chan *Client
+	update     chan permissionUpdate
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		broadcast:  make(chan []byte),
+		broadcast:  make(chan message),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		update:     make(chan permissionUpdate),
 		clients:    make(map[*Client]bool),
 	}
 }
@@ -33,10 +48,17 @@ func (h *Hub) Run() {
 				close(client.send)
 				log.Println("WebSocket client unregistered")
 			}
-		case message := <-h.broadcast:
+		case u := <-h.update:
+			if _, ok := h.clients[u.client]; ok {
+				u.client.permissions = u.permissions
+			}
+		case msg := <-h.broadcast:
 			for client := range h.clients {
+				if msg.permission != "" && !slices.Contains(client.permissions, msg.permission) {
+					continue
+				}
 				select {
-				case client.send <- message:
+				case client.send <- msg.data:
 				default:
 					close(client.send)
 					delete(h.clients, client)
@@ -46,16 +68,18 @@ func (h *Hub) Run() {
 	}
 }
 
+// BroadcastJSON sends v to every connected client.
 func (h *Hub) BroadcastJSON(v interface{}) {
+	h.BroadcastJSONTo("", v)
+}
+
+// BroadcastJSONTo sends v to the connected clients holding permission, e.g. job progress to
+// users who can run jobs. An empty permission sends it to every client.
+func (h *Hub) BroadcastJSONTo(permission string, v interface{}) {
 	bytes, err := json.Marshal(v)
 	if err != nil {
 		log.Printf("Error marshaling broadcast message: %v", err)
 		return
 	}
-	h.broadcast <- bytes
-}
-
-// Broadcast returns the broadcast channel for test listeners.
-func (h *Hub) Broadcast() <-chan []byte {
-	return h.broadcast
+	h.broadcast <- message{data: bytes, permission: permission}
 }
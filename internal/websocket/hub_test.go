@@ -22,13 +22,13 @@ func TestHub(t *testing.T) {
 	}
 
 	// Test broadcast
-	message := []byte("hello")
-	hub.broadcast <- message
+	hello := []byte("hello")
+	hub.broadcast <- message{data: hello}
 
 	select {
 	case received := <-client.send:
 		if string(received) != "hello" {
-			t.Errorf("Client received wrong message: got %s, want %s", received, message)
+			t.Errorf("Client received wrong message: got %s, want %s", received, hello)
 		}
 	case <-time.After(1 * time.Second):
 		t.Fatal("Client did not receive broadcast message in time")
@@ -42,3 +42,54 @@ func TestHub(t *testing.T) {
 		t.Fatalf("Expected 0 clients after unregistration, got %d", len(hub.clients))
 	}
 }
+
+func TestHubPermissionMessages(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	reader := &Client{hub: hub, send: make(chan []byte, 3), permissions: []string{"read_library"}}
+	downloader := &Client{hub: hub, send: make(chan []byte, 3), permissions: []string{"read_library", "manage_downloads"}}
+	admin := &Client{hub: hub, send: make(chan []byte, 3), permissions: []string{"read_library", "manage_downloads", "run_jobs"}}
+	hub.register <- reader
+	hub.register <- downloader
+	hub.register <- admin
+
+	hub.BroadcastJSONTo("run_jobs", map[string]string{"jobId": "library-sync"})
+	hub.BroadcastJSONTo("manage_downloads", map[string]string{"jobId": "downloader"})
+	hub.BroadcastJSON(map[string]string{"jobId": "everyone"})
+
+	expected := []struct {
+		name   string
+		client *Client
+		want   []string
+	}{
+		{"admin", admin, []string{`{"jobId":"library-sync"}`, `{"jobId":"downloader"}`, `{"jobId":"everyone"}`}},
+		{"downloader", downloader, []string{`{"jobId":"downloader"}`, `{"jobId":"everyone"}`}},
+		{"reader", reader, []string{`{"jobId":"everyone"}`}},
+	}
+	for _, e := range expected {
+		for i, want := range e.want {
+			select {
+			case received := <-e.client.send:
+				if string(received) != want {
+					t.Errorf("%s message %d: got %s, want %s", e.name, i, received, want)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%s did not receive message %d in time", e.name, i)
+			}
+		}
+	}
+
+	// Refreshed permissions apply to the open socket
+	hub.update <- permissionUpdate{client: downloader, permissions: []string{"read_library"}}
+	hub.BroadcastJSONTo("manage_downloads", map[string]string{"jobId": "downloader"})
+	hub.BroadcastJSON(map[string]string{"jobId": "after revoke"})
+	select {
+	case received := <-downloader.send:
+		if string(received) != `{"jobId":"after revoke"}` {
+			t.Errorf("Expected the revoked event to be withheld, got %s", received)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("downloader did not receive the broadcast in time")
+	}
+}
@@ -2,9 +2,10 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 	"log"
-	"math/rand"
+	"math/big"
 	"net/http"
 	"os"
 	"os/signal"
@@ -25,6 +26,11 @@ func main() {
 	log.SetOutput(os.Stdout)
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
+	// Subcommands (e.g. "user reset-password") run and exit without starting the server
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	// Initialize the core application components
 	app, err := core.New()
 
@@ -74,6 +80,39 @@ func main() {
 		log.Println("Periodic library scan disabled (scan_interval is 0).")
 	}
 
+	// Periodic purge of expired login sessions (disabled when sessions.purge_interval is 0)
+	if interval := app.Config().Sessions.PurgeInterval; interval > 0 {
+		go func() {
+			ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+			for ; ; <-ticker.C {
+				purged, err := st.PurgeExpiredSessions()
+				if err != nil {
+					log.Printf("Warning: failed to purge expired sessions: %v", err)
+				} else if purged > 0 {
+					log.Printf("Purged %d expired sessions.", purged)
+				}
+			}
+		}()
+	}
+
+	// Hourly purge of failed login records and audit log entries past their retention. It runs
+	// whatever the session purge interval is, so retention cannot be turned off by accident.
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		for ; ; <-ticker.C {
+			if days := app.Config().Login.RetentionDays; days > 0 {
+				if _, err := st.PurgeFailedLogins(time.Now().AddDate(0, 0, -days)); err != nil {
+					log.Printf("Warning: failed to purge failed login records: %v", err)
+				}
+			}
+			if days := app.Config().Audit.RetentionDays; days > 0 {
+				if _, err := st.PurgeAuditLog(time.Now().AddDate(0, 0, -days)); err != nil {
+					log.Printf("Warning: failed to purge audit log: %v", err)
+				}
+			}
+		}
+	}()
+
 	// Initialize plugin manager and discover plugins (lazy loading enabled)
 	pluginManager := plugins.NewPluginManager(app, app.Config().Plugins.Path)
 	plugins.SetGlobalManager(pluginManager)
@@ -90,7 +129,7 @@ func main() {
 
 	// Start the file system watcher for incremental scanning
 	// The watcher automatically triggers incremental scans when:
-	// - New archive files are added to existing directories
+	// - New chapter files (or images in image-directory chapters) are added to existing directories
 	// - Archive files are modified or deleted
 	// - New directories are created
 	// It filters out Chmod events to prevent false triggers when browsing folders
@@ -150,12 +189,15 @@ func main() {
 	log.Println("Server exiting.")
 }
 
+// generateRandomPassword returns a password of letters and digits drawn from crypto/rand, as it
+// is handed out as a real credential for the initial admin and CLI password resets.
 func generateRandomPassword(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	seededRand := rand.New(rand.NewSource(time.Now().UnixNano()))
 	b := make([]byte, length)
 	for i := range b {
-		b[i] = charset[seededRand.Intn(len(charset))]
+		// crypto/rand.Reader never fails, so neither does rand.Int.
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		b[i] = charset[n.Int64()]
 	}
 	return string(b)
 }